@@ -5,9 +5,16 @@ import "time"
 var Config *MapConfig
 
 type MapConfig struct {
-	AppHost             string        `mapstructure:"APP_HOST"`
-	DbConnectionString  string        `mapstructure:"DB_CONNECTION_STRING"`
-	JwtSecretKey        string        `mapstructure:"JWT_SECRET_KEY"`
-	JwtExpiresIn        time.Duration `mapstructure:"JWT_EXPIRE_DURATION"`
-	TokenExpirationDate time.Duration `mapstructure:"TOKEN_EXPIRATION_DATE"`
+	AppHost                     string        `mapstructure:"APP_HOST"`
+	DbConnectionString          string        `mapstructure:"DB_CONNECTION_STRING"`
+	JwtSecretKey                string        `mapstructure:"JWT_SECRET_KEY"`
+	JwtExpiresIn                time.Duration `mapstructure:"JWT_EXPIRE_DURATION"`
+	TokenExpirationDate         time.Duration `mapstructure:"TOKEN_EXPIRATION_DATE"`
+	MediaPath                   string        `mapstructure:"MEDIA_PATH"`
+	SchedulerInterval           time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	TrashRetention              time.Duration `mapstructure:"TRASH_RETENTION"`
+	ProgressCompletionThreshold float64       `mapstructure:"PROGRESS_COMPLETION_THRESHOLD"`
+	CertificateTemplate         string        `mapstructure:"CERTIFICATE_TEMPLATE"`
+	XAPIHomePage                string        `mapstructure:"XAPI_HOMEPAGE"`
+	RecommendationsInterval     time.Duration `mapstructure:"RECOMMENDATIONS_INTERVAL"`
 }
@@ -2,7 +2,10 @@ module go-EdTech
 
 go 1.24.0
 
-require github.com/gin-contrib/cors v1.7.6
+require (
+	github.com/gin-contrib/cors v1.7.6
+	golang.org/x/image v0.25.0
+)
 
 require (
 	github.com/KyleBanks/depth v1.2.1 // indirect
@@ -63,7 +66,7 @@ require (
 	go.uber.org/zap v1.27.0
 	golang.org/x/arch v0.19.0 // indirect
 	golang.org/x/crypto v0.40.0 // indirect
-	golang.org/x/net v0.42.0 // indirect
+	golang.org/x/net v0.42.0
 	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
 	golang.org/x/text v0.27.0 // indirect
@@ -0,0 +1,634 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// сколько файлов можно приложить к одной работе
+const maxSubmissionFiles = 10
+
+// AssignmentsHandler ведет домашние задания, прием работ и проверку
+type AssignmentsHandler struct {
+	assignmentsRepo   *repositories.AssignmentsRepository
+	lessonsRepo       *repositories.Lessonsrepository
+	coursesRepo       *repositories.Coursesrepository
+	prerequisitesRepo *repositories.PrerequisitesRepository
+	mediaRepo         *repositories.MediaRepository
+}
+
+func NewAssignmentsHandler(
+	assignmentsRepo *repositories.AssignmentsRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository,
+	prerequisitesRepo *repositories.PrerequisitesRepository,
+	mediaRepo *repositories.MediaRepository) *AssignmentsHandler {
+	return &AssignmentsHandler{
+		assignmentsRepo:   assignmentsRepo,
+		lessonsRepo:       lessonsRepo,
+		coursesRepo:       coursesRepo,
+		prerequisitesRepo: prerequisitesRepo,
+		mediaRepo:         mediaRepo,
+	}
+}
+
+type assignmentRequest struct {
+	Title           string                   `json:"title"`
+	Instructions    string                   `json:"instructions"`
+	Lesson_id       *int                     `json:"lesson_id"`
+	Course_id       *int                     `json:"course_id"`
+	Due_at          *time.Time               `json:"due_at"`
+	Accept_late     bool                     `json:"accept_late"`
+	Max_submissions *int                     `json:"max_submissions"`
+	Max_score       float64                  `json:"max_score"`
+	Rubric          []models.RubricCriterion `json:"rubric"`
+}
+
+type gradeSubmissionRequest struct {
+	Score         *float64             `json:"score"`
+	Rubric_scores []models.RubricScore `json:"rubric_scores"`
+	Feedback      string               `json:"feedback"`
+}
+
+func (h *AssignmentsHandler) bindAssignment(c *gin.Context) (models.Assignment, bool) {
+	logger := logger.GetLogger()
+
+	var request assignmentRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return models.Assignment{}, false
+	}
+
+	assignment := models.Assignment{
+		Title:           strings.TrimSpace(request.Title),
+		Instructions:    strings.TrimSpace(request.Instructions),
+		Lesson_id:       request.Lesson_id,
+		Course_id:       request.Course_id,
+		Due_at:          request.Due_at,
+		Accept_late:     request.Accept_late,
+		Max_submissions: request.Max_submissions,
+		Max_score:       request.Max_score,
+		Rubric:          request.Rubric,
+	}
+
+	if assignment.Title == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Assignment title is required"))
+		return models.Assignment{}, false
+	}
+	if assignment.Max_submissions != nil && *assignment.Max_submissions <= 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("max_submissions must be positive"))
+		return models.Assignment{}, false
+	}
+	if !validateAttachment(c, h.lessonsRepo, h.coursesRepo, assignment.Lesson_id, assignment.Course_id) {
+		return models.Assignment{}, false
+	}
+
+	// с рубрикой максимальный балл — сумма критериев
+	if len(assignment.Rubric) > 0 {
+		assignment.Max_score = 0
+		seen := make(map[string]bool)
+		for i := range assignment.Rubric {
+			criterion := &assignment.Rubric[i]
+			criterion.Title = strings.TrimSpace(criterion.Title)
+			if criterion.Id == "" {
+				criterion.Id = strconv.Itoa(i + 1)
+			}
+			if criterion.Title == "" || criterion.Max_points <= 0 {
+				c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Rubric criterion %d needs a title and positive max_points", i+1)))
+				return models.Assignment{}, false
+			}
+			if seen[criterion.Id] {
+				c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Duplicate rubric criterion id %q", criterion.Id)))
+				return models.Assignment{}, false
+			}
+			seen[criterion.Id] = true
+			assignment.Max_score += criterion.Max_points
+		}
+	}
+	if assignment.Max_score <= 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("max_score or rubric is required"))
+		return models.Assignment{}, false
+	}
+
+	return assignment, true
+}
+
+// findVisibleAssignment загружает задание из параметра :id; для задания урока возвращает и урок
+func (h *AssignmentsHandler) findVisibleAssignment(c *gin.Context) (models.Assignment, *models.Lesson, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid assignment id"))
+		return models.Assignment{}, nil, false
+	}
+
+	assignment, err := h.assignmentsRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Assignment not found"))
+		return models.Assignment{}, nil, false
+	}
+	if err != nil {
+		logger.Error("Failed to find assignment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Assignment{}, nil, false
+	}
+
+	lesson, ok := findAttachedContent(c, h.lessonsRepo, h.coursesRepo, assignment.Lesson_id, assignment.Course_id, "Assignment not found")
+	if !ok {
+		return models.Assignment{}, nil, false
+	}
+
+	return assignment, lesson, true
+}
+
+// FindByLesson godoc
+// @Summary 	assignments of the lesson
+// @Tags 		assignments
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson id"
+// @Success 	200 	{object} 	[]models.Assignment "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/assignments [get]
+func (h *AssignmentsHandler) FindByLesson(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+
+	assignments, err := h.assignmentsRepo.FindByLesson(c, lesson.Id)
+	if err != nil {
+		logger.Error("Failed to fetch lesson assignments", zap.Int("lesson_id", lesson.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, assignments)
+}
+
+// FindByCourse godoc
+// @Summary 	assignments of the course
+// @Tags 		assignments
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	[]models.Assignment "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/assignments [get]
+func (h *AssignmentsHandler) FindByCourse(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	assignments, err := h.assignmentsRepo.FindByCourse(c, course.Id)
+	if err != nil {
+		logger.Error("Failed to fetch course assignments", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, assignments)
+}
+
+// FindById godoc
+// @Summary 	find assignment by id
+// @Tags 		assignments
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Assignment id"
+// @Success 	200 	{object} 	models.Assignment "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/assignments/{id} [get]
+func (h *AssignmentsHandler) FindById(c *gin.Context) {
+	assignment, _, ok := h.findVisibleAssignment(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, assignment)
+}
+
+// Create godoc
+// @Summary 	create assignment for a lesson or a course
+// @Description With a rubric max_score is the sum of criteria max_points. max_submissions limits resubmissions, empty means unlimited.
+// @Description Submissions after due_at are rejected unless accept_late is set, then they are flagged as late.
+// @Tags 		assignments
+// @Accept 		json
+// @Produce 	json
+// @Param 		request body 		assignmentRequest 	true 	"Assignment"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/assignments [post]
+func (h *AssignmentsHandler) Create(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	assignment, ok := h.bindAssignment(c)
+	if !ok {
+		return
+	}
+	if teacherUUID, ok := utils.CurrentUserUUID(c); ok {
+		assignment.Created_by = &teacherUUID
+	}
+
+	id, err := h.assignmentsRepo.Create(c, assignment)
+	if err != nil {
+		logger.Error("Failed to create assignment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Assignment has been created", zap.Int("assignment_id", id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"id": id,
+	})
+}
+
+// Update godoc
+// @Summary 	update assignment
+// @Description Grades that are already given are not recalculated
+// @Tags 		assignments
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Assignment id"
+// @Param 		request body 		assignmentRequest 	true 	"Assignment"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/assignments/{id} [put]
+func (h *AssignmentsHandler) Update(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	existing, _, ok := h.findVisibleAssignment(c)
+	if !ok {
+		return
+	}
+
+	assignment, ok := h.bindAssignment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.assignmentsRepo.Update(c, existing.Id, assignment); err != nil {
+		logger.Error("Failed to update assignment", zap.Int("assignment_id", existing.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Summary 	delete assignment
+// @Description Assignments with submissions can not be deleted
+// @Tags 		assignments
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Assignment id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	409 	{object}	models.ApiError "Assignment has submissions"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/assignments/{id} [delete]
+func (h *AssignmentsHandler) Delete(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	assignment, _, ok := h.findVisibleAssignment(c)
+	if !ok {
+		return
+	}
+
+	err := h.assignmentsRepo.Delete(c, assignment.Id)
+	if errors.Is(err, repositories.ErrAssignmentHasSubmissions) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete assignment", zap.Int("assignment_id", assignment.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Submit godoc
+// @Summary 	hand in work for the assignment
+// @Description Text and up to 10 files. Every resubmission is kept as a new numbered submission.
+// @Tags 		assignments
+// @Accept 		multipart/form-data
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Assignment id"
+// @Param 		text 	formData	string 	false 	"Answer text"
+// @Param 		files 	formData	file 	false 	"Attached files"
+// @Success 	200 	{object} 	models.AssignmentSubmission "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.LockedContentError "Prerequisites are not completed"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	409 	{object}	models.ApiError "Past due or no resubmissions left"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/assignments/{id}/submissions [post]
+func (h *AssignmentsHandler) Submit(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	assignment, lesson, ok := h.findVisibleAssignment(c)
+	if !ok {
+		return
+	}
+	if lesson != nil && !ensureLessonUnlocked(c, h.prerequisitesRepo, *lesson) {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	submission := models.AssignmentSubmission{
+		User_uuid: userUUID,
+		Text:      strings.TrimSpace(c.PostForm("text")),
+		Late:      assignment.Due_at != nil && time.Now().After(*assignment.Due_at),
+	}
+	if submission.Late && !assignment.Accept_late {
+		c.JSON(http.StatusConflict, models.NewApiError("Assignment is past due"))
+		return
+	}
+
+	var fileHeaders []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		fileHeaders = form.File["files"]
+	}
+	if submission.Text == "" && len(fileHeaders) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Text or files are required"))
+		return
+	}
+	if len(fileHeaders) > maxSubmissionFiles {
+		c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("No more than %d files per submission", maxSubmissionFiles)))
+		return
+	}
+	for _, fileHeader := range fileHeaders {
+		limit, allowed := utils.ResourceSizeLimit(fileHeader.Filename)
+		if !allowed {
+			c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("File type of %q is not allowed", fileHeader.Filename)))
+			return
+		}
+		if fileHeader.Size > limit {
+			c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("File %q is too large", fileHeader.Filename)))
+			return
+		}
+	}
+
+	// лимит проверяем до загрузки, чтобы отклоненная работа не оставила файлов; Submit проверит его еще раз под блокировкой
+	if assignment.Max_submissions != nil {
+		submitted, err := h.assignmentsRepo.CountSubmissions(c, assignment.Id, userUUID)
+		if err != nil {
+			logger.Error("Failed to count submissions", zap.Int("assignment_id", assignment.Id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		if submitted >= *assignment.Max_submissions {
+			c.JSON(http.StatusConflict, models.NewApiError(repositories.ErrNoSubmissionsLeft.Error()))
+			return
+		}
+	}
+
+	for _, fileHeader := range fileHeaders {
+		media, err := utils.StoreUploadedMedia(fileHeader)
+		if err != nil {
+			logger.Error("Failed to store submission file", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not store file"))
+			return
+		}
+
+		media.Id, err = h.mediaRepo.Create(c, media)
+		if err != nil {
+			logger.Error("Failed to save media", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not store file"))
+			return
+		}
+
+		submission.Files = append(submission.Files, models.SubmissionFile{
+			Media_id:  media.Id,
+			Filename:  media.Filename,
+			Mime_type: media.Mime_type,
+			Size:      media.Size,
+		})
+	}
+
+	submission, err := h.assignmentsRepo.Submit(c, assignment, submission)
+	if errors.Is(err, repositories.ErrNoSubmissionsLeft) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to submit assignment", zap.Int("assignment_id", assignment.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Assignment has been submitted",
+		zap.Int("assignment_id", assignment.Id),
+		zap.Int("submission_id", submission.Id),
+		zap.Bool("late", submission.Late))
+
+	c.JSON(http.StatusOK, submission)
+}
+
+// FindSubmissions godoc
+// @Summary 	submissions of the assignment
+// @Description Students see their own submissions, teachers see everyone's and may filter by user_uuid
+// @Tags 		assignments
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Assignment id"
+// @Param 		user_uuid 	query 		string 	false 	"Student uuid, teachers only"
+// @Success 	200 	{object} 	[]models.AssignmentSubmission "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/assignments/{id}/submissions [get]
+func (h *AssignmentsHandler) FindSubmissions(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	assignment, _, ok := h.findVisibleAssignment(c)
+	if !ok {
+		return
+	}
+
+	var userUUID *uuid.UUID
+	if utils.CanManageContent(c) {
+		if raw := c.Query("user_uuid"); raw != "" {
+			studentUUID, err := uuid.Parse(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user uuid"))
+				return
+			}
+			userUUID = &studentUUID
+		}
+	} else {
+		currentUUID, ok := utils.CurrentUserUUID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+			return
+		}
+		userUUID = &currentUUID
+	}
+
+	submissions, err := h.assignmentsRepo.FindSubmissions(c, assignment.Id, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch submissions", zap.Int("assignment_id", assignment.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, submissions)
+}
+
+// GradeSubmission godoc
+// @Summary 	grade a submission
+// @Description With a rubric every criterion needs points, the score is their sum. Otherwise score is required.
+// @Description The student gets a notification. Grading again overwrites the previous grade.
+// @Tags 		assignments
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 				path		int 					true 	"Assignment id"
+// @Param 		submissionId 	path		int 					true 	"Submission id"
+// @Param 		request 		body 		gradeSubmissionRequest 	true 	"Grade"
+// @Success 	200 	{object} 	models.AssignmentSubmission "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/assignments/{id}/submissions/{submissionId}/grade [put]
+func (h *AssignmentsHandler) GradeSubmission(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	assignment, _, ok := h.findVisibleAssignment(c)
+	if !ok {
+		return
+	}
+
+	submissionId, err := strconv.Atoi(c.Param("submissionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid submission id"))
+		return
+	}
+
+	submission, err := h.assignmentsRepo.FindSubmission(c, submissionId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && submission.Assignment_id != assignment.Id) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Submission not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to find submission", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	var request gradeSubmissionRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+
+	score, err := gradeScore(assignment, request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+
+	submission.Score = &score
+	submission.Rubric_scores = request.Rubric_scores
+	submission.Feedback = strings.TrimSpace(request.Feedback)
+	if teacherUUID, ok := utils.CurrentUserUUID(c); ok {
+		submission.Graded_by = &teacherUUID
+	}
+
+	notification := models.Notification{
+		User_uuid:   submission.User_uuid,
+		Type:        models.NotificationAssignmentGraded,
+		Title:       fmt.Sprintf("%q has been graded", assignment.Title),
+		Body:        fmt.Sprintf("Score: %g of %g", score, assignment.Max_score),
+		Entity_type: models.EntityAssignment,
+		Entity_id:   assignment.Id,
+	}
+	if submission.Feedback != "" {
+		notification.Body += "\n" + submission.Feedback
+	}
+
+	submission, err = h.assignmentsRepo.Grade(c, submission, notification)
+	if err != nil {
+		logger.Error("Failed to grade submission", zap.Int("submission_id", submissionId), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Submission has been graded", zap.Int("submission_id", submission.Id), zap.Float64("score", score))
+
+	c.JSON(http.StatusOK, submission)
+}
+
+// gradeScore проверяет оценку по рубрике или общий балл и возвращает итог
+func gradeScore(assignment models.Assignment, request gradeSubmissionRequest) (float64, error) {
+	if len(assignment.Rubric) == 0 {
+		if len(request.Rubric_scores) > 0 {
+			return 0, fmt.Errorf("assignment has no rubric")
+		}
+		if request.Score == nil || *request.Score < 0 || *request.Score > assignment.Max_score {
+			return 0, fmt.Errorf("score must be between 0 and %g", assignment.Max_score)
+		}
+		return *request.Score, nil
+	}
+
+	given := make(map[string]models.RubricScore, len(request.Rubric_scores))
+	for _, score := range request.Rubric_scores {
+		given[score.Criterion_id] = score
+	}
+	if len(given) != len(request.Rubric_scores) {
+		return 0, fmt.Errorf("every rubric criterion can be scored only once")
+	}
+
+	total := 0.0
+	for _, criterion := range assignment.Rubric {
+		score, ok := given[criterion.Id]
+		if !ok {
+			return 0, fmt.Errorf("rubric criterion %q is not scored", criterion.Title)
+		}
+		if score.Points < 0 || score.Points > criterion.Max_points {
+			return 0, fmt.Errorf("points for %q must be between 0 and %g", criterion.Title, criterion.Max_points)
+		}
+		total += score.Points
+		delete(given, criterion.Id)
+	}
+	for id := range given {
+		return 0, fmt.Errorf("unknown rubric criterion %q", id)
+	}
+
+	return total, nil
+}
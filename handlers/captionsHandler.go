@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type CaptionsHandler struct {
+	captionsRepo      *repositories.CaptionsRepository
+	lessonsRepo       *repositories.Lessonsrepository
+	prerequisitesRepo *repositories.PrerequisitesRepository
+}
+
+func NewCaptionsHandler(
+	captionsRepo *repositories.CaptionsRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	prerequisitesRepo *repositories.PrerequisitesRepository) *CaptionsHandler {
+	return &CaptionsHandler{
+		captionsRepo:      captionsRepo,
+		lessonsRepo:       lessonsRepo,
+		prerequisitesRepo: prerequisitesRepo,
+	}
+}
+
+// FindByLesson godoc
+// @Summary 	list caption tracks of the lesson
+// @Tags 		captions
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson_id"
+// @Success 	200 	{object}	[]models.LessonCaption "OK"
+// @Failure 	400 	{object}	models.ApiError "Invalid id"
+// @Failure 	403 	{object}	models.ApiError "Lesson is locked"
+// @Failure 	404 	{object}	models.ApiError "Lesson not found"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/captions [get]
+func (h *CaptionsHandler) FindByLesson(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	// субтитры доступны тем же, кому доступен сам урок
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok || !ensureLessonUnlocked(c, h.prerequisitesRepo, lesson) {
+		return
+	}
+
+	captions, err := h.captionsRepo.FindByLessonId(c, lesson.Id)
+	if err != nil {
+		logger.Error("Failed to fetch captions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, captions)
+}
+
+// Serve godoc
+// @Summary 	get caption track in WebVTT format
+// @Tags 		captions
+// @Produce 	text/vtt
+// @Param 		id 			path		int 	true 	"Lesson_id"
+// @Param 		language 	path		string 	true 	"Caption language, e.g. en, ru, kk"
+// @Success 	200 		{string}	string "WebVTT track"
+// @Failure 	400 		{object}	models.ApiError "Invalid id"
+// @Failure 	403 		{object}	models.ApiError "Lesson is locked"
+// @Failure 	404 		{object}	models.ApiError "Caption not found"
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/captions/{language} [get]
+func (h *CaptionsHandler) Serve(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok || !ensureLessonUnlocked(c, h.prerequisitesRepo, lesson) {
+		return
+	}
+
+	language := strings.TrimSuffix(c.Param("language"), ".vtt")
+
+	caption, err := h.captionsRepo.FindByLanguage(c, lesson.Id, language)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Caption not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to find caption", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Header("Content-Language", caption.Language)
+	c.Data(http.StatusOK, "text/vtt; charset=utf-8", []byte(caption.Content))
+}
+
+// Upload godoc
+// @Summary 	upload caption track (SRT or WebVTT)
+// @Description SRT files are converted to WebVTT. Uploading a track for an existing language replaces it.
+// @Tags 		captions
+// @Accept 		multipart/form-data
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Lesson_id"
+// @Param 		file 		formData	file 	true 	"Caption file (.srt or .vtt)"
+// @Param 		language 	formData	string 	true 	"Caption language, e.g. en, ru, kk"
+// @Param 		label 		formData	string 	false 	"Human readable label"
+// @Param 		is_default 	formData	boolean false 	"Use as default track"
+// @Success 	200 		{object} 	object{id=int} 	"OK"
+// @Failure 	400 		{object}	models.ApiError "Invalid Payload"
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/captions [post]
+func (h *CaptionsHandler) Upload(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lessonId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Error("Invalid lesson Id", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid Lesson Id"))
+		return
+	}
+
+	_, err = h.lessonsRepo.FindById(c, lessonId)
+	if err != nil {
+		logger.Error("Requested lesson not found", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+
+	language := c.PostForm("language")
+	if !utils.IsValidLanguageTag(language) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid caption language"))
+		return
+	}
+
+	label := c.PostForm("label")
+	if label == "" {
+		label = language
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		logger.Error("Caption file missing", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Caption file is required"))
+		return
+	}
+	if fileHeader.Size > utils.MaxCaptionSize {
+		c.JSON(http.StatusBadRequest, models.NewApiError(utils.ErrCaptionTooLarge.Error()))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Error("Failed to open caption file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not read caption file"))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, utils.MaxCaptionSize+1))
+	if err != nil {
+		logger.Error("Failed to read caption file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not read caption file"))
+		return
+	}
+
+	content, err := utils.NormalizeCaptions(fileHeader.Filename, data)
+	if err != nil {
+		logger.Warn("Invalid caption file", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+
+	caption := models.LessonCaption{
+		Lesson_id:  lessonId,
+		Language:   language,
+		Label:      label,
+		Is_default: c.PostForm("is_default") == "true",
+		Content:    content,
+	}
+
+	id, err := h.captionsRepo.Create(c, caption)
+	if err != nil {
+		logger.Error("Failed to save caption", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not save caption"))
+		return
+	}
+
+	logger.Info("Caption has been uploaded", zap.Int("lesson_id", lessonId), zap.String("language", language))
+
+	c.JSON(http.StatusOK, gin.H{
+		"id": id,
+	})
+}
+
+// Delete godoc
+// @Summary 	delete caption track
+// @Tags 		captions
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Lesson_id"
+// @Param 		language 	path		string 	true 	"Caption language"
+// @Success 	200 		"OK"
+// @Failure 	400 		{object}	models.ApiError "Invalid id"
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/captions/{language} [delete]
+func (h *CaptionsHandler) Delete(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lessonId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Error("Invalid lesson Id", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid Lesson Id"))
+		return
+	}
+
+	err = h.captionsRepo.Delete(c, lessonId, c.Param("language"))
+	if err != nil {
+		logger.Error("Failed to delete caption", zap.Error(err))
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type CertificatesHandler struct {
+	certificatesRepo *repositories.CertificatesRepository
+}
+
+func NewCertificatesHandler(certificatesRepo *repositories.CertificatesRepository) *CertificatesHandler {
+	return &CertificatesHandler{certificatesRepo: certificatesRepo}
+}
+
+// FindMine godoc
+// @Summary 	certificates of the current user
+// @Description A certificate is issued when the student completes a course. media_id is empty until the PDF
+// @Description is generated by the background job, then the file is served by /media/{id}.
+// @Tags 		certificates
+// @Produce 	json
+// @Success 	200 	{object} 	[]models.Certificate "Newest first"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/certificates [get]
+func (h *CertificatesHandler) FindMine(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	certificates, err := h.certificatesRepo.FindByUser(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch certificates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, certificates)
+}
+
+// Verify godoc
+// @Summary 	verify a certificate
+// @Description Public endpoint for schools and parents: confirms the serial and shows whom and for which course it was issued
+// @Tags 		certificates
+// @Produce 	json
+// @Param 		serial 	path		string 	true 	"Certificate serial"
+// @Success 	200 	{object} 	models.CertificateVerification "OK"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/certificates/verify/{serial} [get]
+func (h *CertificatesHandler) Verify(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	certificate, err := h.certificatesRepo.FindBySerial(c, utils.NormalizeCertificateSerial(c.Param("serial")))
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Certificate not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to verify certificate", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CertificateVerification{
+		Valid:        true,
+		Serial:       certificate.Serial,
+		Student_name: certificate.Student_name,
+		Course_title: certificate.Course_title,
+		Issued_at:    certificate.Issued_at,
+	})
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type ClassesHandler struct {
+	classesRepo *repositories.ClassesRepository
+}
+
+func NewClassesHandler(classesRepo *repositories.ClassesRepository) *ClassesHandler {
+	return &ClassesHandler{classesRepo: classesRepo}
+}
+
+type classRequest struct {
+	Name    string      `json:"name"`
+	Members []uuid.UUID `json:"members"`
+}
+
+// bindClass читает класс и его состав из тела запроса
+func bindClass(c *gin.Context) (models.Class, []uuid.UUID, bool) {
+	var request classRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request payload"))
+		return models.Class{}, nil, false
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("name is required"))
+		return models.Class{}, nil, false
+	}
+
+	members := make([]uuid.UUID, 0, len(request.Members))
+	seen := make(map[uuid.UUID]bool)
+	for _, member := range request.Members {
+		if seen[member] {
+			c.JSON(http.StatusBadRequest, models.NewApiError("User "+member.String()+" is listed twice"))
+			return models.Class{}, nil, false
+		}
+		seen[member] = true
+		members = append(members, member)
+	}
+
+	return models.Class{Name: request.Name}, members, true
+}
+
+// findClass загружает класс из параметра :id; ученик видит только классы, в которых состоит
+func findClass(c *gin.Context, classesRepo *repositories.ClassesRepository) (models.Class, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid class id"))
+		return models.Class{}, false
+	}
+
+	class, err := classesRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Class not found"))
+		return models.Class{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find class", zap.Int("class_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Class{}, false
+	}
+
+	if !utils.CanManageContent(c) {
+		userUUID, ok := utils.CurrentUserUUID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+			return models.Class{}, false
+		}
+		member, err := classesRepo.IsMember(c, id, userUUID)
+		if err != nil {
+			logger.Error("Failed to check class membership", zap.Int("class_id", id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return models.Class{}, false
+		}
+		if !member {
+			c.JSON(http.StatusForbidden, models.NewApiError("You are not a member of the class"))
+			return models.Class{}, false
+		}
+	}
+
+	return class, true
+}
+
+// FindAll godoc
+// @Summary 	list classes
+// @Description Content managers see all classes, students see the classes they belong to
+// @Tags 		classes
+// @Produce 	json
+// @Success 	200 	{object} 	[]models.Class "OK"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/classes [get]
+func (h *ClassesHandler) FindAll(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	var member *uuid.UUID
+	if !utils.CanManageContent(c) {
+		userUUID, ok := utils.CurrentUserUUID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+			return
+		}
+		member = &userUUID
+	}
+
+	classes, err := h.classesRepo.FindAll(c, member)
+	if err != nil {
+		logger.Error("Failed to fetch classes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, classes)
+}
+
+// FindById godoc
+// @Summary 	get a class with its members
+// @Tags 		classes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Class id"
+// @Success 	200 	{object} 	models.Class "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/classes/{id} [get]
+func (h *ClassesHandler) FindById(c *gin.Context) {
+	class, ok := findClass(c, h.classesRepo)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, class)
+}
+
+// Create godoc
+// @Summary 	create a class
+// @Tags 		classes
+// @Accept 		json
+// @Produce 	json
+// @Param 		request 	body 		classRequest 	true 	"Class name and member uuids"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/classes [post]
+func (h *ClassesHandler) Create(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	class, members, ok := bindClass(c)
+	if !ok {
+		return
+	}
+	if userUUID, ok := utils.CurrentUserUUID(c); ok {
+		class.Created_by = &userUUID
+	}
+
+	id, err := h.classesRepo.Create(c, class, members)
+	if errors.Is(err, repositories.ErrUnknownClassMember) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Some of the members are not existing users"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create class", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Class has been created", zap.Int("class_id", id))
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+// Update godoc
+// @Summary 	rename a class and replace its members
+// @Tags 		classes
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 			path		int 			true 	"Class id"
+// @Param 		request 	body 		classRequest 	true 	"Class name and member uuids"
+// @Success 	200 	{object} 	models.Class "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/classes/{id} [put]
+func (h *ClassesHandler) Update(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid class id"))
+		return
+	}
+
+	class, members, ok := bindClass(c)
+	if !ok {
+		return
+	}
+
+	err = h.classesRepo.Update(c, id, class, members)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Class not found"))
+		return
+	}
+	if errors.Is(err, repositories.ErrUnknownClassMember) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Some of the members are not existing users"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update class", zap.Int("class_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	updated, err := h.classesRepo.FindById(c, id)
+	if err != nil {
+		logger.Error("Failed to find class", zap.Int("class_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// Delete godoc
+// @Summary 	delete a class
+// @Description Students and their leaderboard scores are kept
+// @Tags 		classes
+// @Param 		id 		path		int 	true 	"Class id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/classes/{id} [delete]
+func (h *ClassesHandler) Delete(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid class id"))
+		return
+	}
+
+	err = h.classesRepo.Delete(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Class not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete class", zap.Int("class_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Class has been deleted", zap.Int("class_id", id))
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type CloneHandler struct {
+	coursesRepo   *repositories.Coursesrepository
+	lessonsRepo   *repositories.Lessonsrepository
+	lineageRepo   *repositories.LineageRepository
+	revisionsRepo *repositories.RevisionsRepository
+}
+
+func NewCloneHandler(
+	coursesRepo *repositories.Coursesrepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	lineageRepo *repositories.LineageRepository,
+	revisionsRepo *repositories.RevisionsRepository) *CloneHandler {
+	return &CloneHandler{
+		coursesRepo:   coursesRepo,
+		lessonsRepo:   lessonsRepo,
+		lineageRepo:   lineageRepo,
+		revisionsRepo: revisionsRepo,
+	}
+}
+
+type cloneCourseRequest struct {
+	Title   string `json:"title"`
+	Publish bool   `json:"publish"`
+}
+
+// CloneCourse godoc
+// @Summary 	deep-clone course with its lessons
+// @Description Copies the course, lesson order, lessons with resources, captions, tags, translations and prerequisites.
+// @Description Media files are shared with the source. The copy remembers its source, see GET /courses/{id}/lineage.
+// @Description publish=true requires a role that may publish content, otherwise the copy is a draft.
+// @Tags 		courses
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Source course id"
+// @Param 		request body 		cloneCourseRequest 	false 	"New title (source title by default) and publication flag"
+// @Success 	200 	{object} 	models.CloneResult "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/clone [post]
+func (h *CloneHandler) CloneCourse(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	source, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	var request cloneCourseRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&request); err != nil {
+			logger.Error("Failed JSON binding", zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+			return
+		}
+	}
+
+	status := models.StatusDraft
+	if request.Publish {
+		// копия сразу публикуется, поэтому нужны те же права, что и для публикации из review
+		role := utils.CurrentRole(c)
+		if role == nil {
+			c.JSON(http.StatusForbidden, models.NewApiError("access denied"))
+			return
+		}
+		if err := utils.ValidateTransition(models.StatusInReview, models.StatusPublished, role.Name); err != nil {
+			c.JSON(http.StatusForbidden, models.NewApiError(err.Error()))
+			return
+		}
+		status = models.StatusPublished
+	}
+
+	result, err := h.lineageRepo.CloneCourse(c, source.Id, repositories.CloneOptions{
+		Name:    request.Title,
+		Publish: request.Publish,
+	})
+	if err != nil {
+		logger.Error("Failed to clone course", zap.Int("course_id", source.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not clone course"))
+		return
+	}
+
+	course, err := h.coursesRepo.FindById(c, result.Course_id)
+	if err != nil {
+		logger.Error("Failed to load cloned course", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+	comment := fmt.Sprintf("cloned from course %d", source.Id)
+	if _, err := recordRevision(c, h.revisionsRepo, models.EntityCourse, course.Id, status, comment, newCourseRevisionData(course)); err != nil {
+		logger.Error("Failed to record course revision", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+		return
+	}
+
+	for _, cloned := range result.Lessons {
+		lesson, err := h.lessonsRepo.FindById(c, cloned.Id)
+		if err != nil {
+			logger.Error("Failed to load cloned lesson", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		comment := fmt.Sprintf("cloned from lesson %d", cloned.Source_id)
+		if _, err := recordRevision(c, h.revisionsRepo, models.EntityLesson, lesson.Id, status, comment, newLessonRevisionData(lesson)); err != nil {
+			logger.Error("Failed to record lesson revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+			return
+		}
+	}
+
+	logger.Info("Course has been cloned",
+		zap.Int("source_id", source.Id),
+		zap.Int("course_id", result.Course_id),
+		zap.Int("lessons", len(result.Lessons)))
+
+	c.JSON(http.StatusOK, result)
+}
+
+// FindLineage godoc
+// @Summary 	sources of a cloned course and its lessons
+// @Description source_updated is true when the source got new revisions after it was cloned or last pulled,
+// @Description POST /courses/{id}/lineage/pull takes them over
+// @Tags 		courses
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	[]models.ContentLineage "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/lineage [get]
+func (h *CloneHandler) FindLineage(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	lineage, err := h.lineageRepo.FindByCourse(c, course.Id)
+	if err != nil {
+		logger.Error("Failed to fetch course lineage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, lineage)
+}
+
+// PullUpdates godoc
+// @Summary 	pull source updates into a cloned course
+// @Description Lessons whose source got new revisions take over the source content, the course takes over the source description
+// @Description (its own title is kept). Each pulled item gets a new revision. Deleted sources are skipped.
+// @Description Published items pulled by a teacher get a pending revision instead and stay as is until a content lead approves it.
+// @Tags 		courses
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	[]models.ContentLineage "Pulled items"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/lineage/pull [post]
+func (h *CloneHandler) PullUpdates(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	lineage, err := h.lineageRepo.FindByCourse(c, course.Id)
+	if err != nil {
+		logger.Error("Failed to fetch course lineage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	pulled := make([]models.ContentLineage, 0)
+	for _, item := range lineage {
+		if !item.Source_updated {
+			continue
+		}
+
+		var (
+			status string
+			data   any
+		)
+		switch item.Entity_type {
+		case models.EntityLesson:
+			status, data, err = h.pullLesson(c, item)
+		case models.EntityCourse:
+			status, data, err = h.pullCourse(c, item, course)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			// исходник удален, обновлять не из чего
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to pull source updates", zap.String("entity_type", item.Entity_type), zap.Int("id", item.Entity_id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		comment := fmt.Sprintf("pulled from %s %d revision %d", item.Entity_type, item.Source_id, item.Source_latest_revision)
+		if _, err := recordRevision(c, h.revisionsRepo, item.Entity_type, item.Entity_id, status, comment, data); err != nil {
+			logger.Error("Failed to record revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+			return
+		}
+
+		item.Source_revision = item.Source_latest_revision
+		item.Source_updated = false
+		item.Pending = status == models.StatusPending
+		pulled = append(pulled, item)
+	}
+
+	logger.Info("Source updates pulled", zap.Int("course_id", course.Id), zap.Int("items", len(pulled)))
+
+	c.JSON(http.StatusOK, pulled)
+}
+
+// pullLesson обновляет копию урока из исходника и возвращает статус и данные для ревизии.
+// Опубликованный урок без права публикации не меняется: содержимое исходника уходит в ревизию pending.
+func (h *CloneHandler) pullLesson(c *gin.Context, item models.ContentLineage) (string, any, error) {
+	lesson, err := h.lessonsRepo.FindById(c, item.Entity_id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if !needsApproval(c, lesson.Status) {
+		if err := h.lineageRepo.PullLesson(c, item.Entity_id, item.Source_id); err != nil {
+			return "", nil, err
+		}
+		lesson, err = h.lessonsRepo.FindById(c, item.Entity_id)
+		return lesson.Status, newLessonRevisionData(lesson), err
+	}
+
+	// видео исходника становится общим, чтобы ревизия сослалась на него через media
+	if err := h.lineageRepo.ShareLessonVideo(c, item.Source_id); err != nil {
+		return "", nil, err
+	}
+	source, err := h.lessonsRepo.FindById(c, item.Source_id)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := h.lineageRepo.MarkPulled(c, models.EntityLesson, item.Entity_id); err != nil {
+		return "", nil, err
+	}
+
+	return models.StatusPending, newLessonRevisionData(source), nil
+}
+
+// pullCourse — то же для курса: переносится только описание, название копии остается своим
+func (h *CloneHandler) pullCourse(c *gin.Context, item models.ContentLineage, course models.Course) (string, any, error) {
+	if !needsApproval(c, course.Status) {
+		if err := h.lineageRepo.PullCourse(c, item.Entity_id, item.Source_id); err != nil {
+			return "", nil, err
+		}
+		pulledCourse, err := h.coursesRepo.FindById(c, item.Entity_id)
+		return pulledCourse.Status, newCourseRevisionData(pulledCourse), err
+	}
+
+	source, err := h.coursesRepo.FindById(c, item.Source_id)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := h.lineageRepo.MarkPulled(c, models.EntityCourse, item.Entity_id); err != nil {
+		return "", nil, err
+	}
+
+	data := newCourseRevisionData(course)
+	data.Description = source.Description
+	return models.StatusPending, data, nil
+}
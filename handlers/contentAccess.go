@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// findVisibleLesson загружает урок из параметра :id и проверяет, что текущий пользователь может его видеть.
+// При ошибке ответ уже записан и вызывающему нужно просто вернуться.
+func findVisibleLesson(c *gin.Context, lessonsRepo *repositories.Lessonsrepository) (models.Lesson, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Error("Invalid lesson Id", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid Lesson Id"))
+		return models.Lesson{}, false
+	}
+
+	lesson, err := lessonsRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Lesson not found"))
+		return models.Lesson{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find lesson", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Lesson{}, false
+	}
+
+	// неопубликованные уроки и уроки вне окна публикации видят только авторы контента
+	if !utils.IsWithinPublicationWindow(lesson.Is_published, lesson.Publish_at, lesson.Unpublish_at, time.Now()) && !utils.CanManageContent(c) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Lesson not found"))
+		return models.Lesson{}, false
+	}
+
+	return lesson, true
+}
+
+// findVisibleCourse — то же самое для курса из параметра :id
+func findVisibleCourse(c *gin.Context, coursesRepo *repositories.Coursesrepository) (models.Course, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Error("Invalid course ID format", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("invalid course id"))
+		return models.Course{}, false
+	}
+
+	course, err := coursesRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Course not found"))
+		return models.Course{}, false
+	}
+	if err != nil {
+		logger.Error("Course doesn't exist", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Course{}, false
+	}
+
+	if !utils.IsWithinPublicationWindow(course.Is_published, course.Publish_at, course.Unpublish_at, time.Now()) && !utils.CanManageContent(c) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Course not found"))
+		return models.Course{}, false
+	}
+
+	return course, true
+}
+
+// ensureLessonUnlocked проверяет, что ученик прошел все предварительные требования урока и его курсов.
+// Иначе отвечает 403 со списком непройденных уроков и курсов. Авторов контента не ограничивает.
+func ensureLessonUnlocked(c *gin.Context, prerequisitesRepo *repositories.PrerequisitesRepository, lesson models.Lesson) bool {
+	logger := logger.GetLogger()
+
+	if utils.CanManageContent(c) {
+		return true
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return false
+	}
+
+	missingLessons, missingCourses, err := prerequisitesRepo.MissingForLesson(c, userUUID, lesson.Id)
+	if err != nil {
+		logger.Error("Failed to check prerequisites", zap.Int("lesson_id", lesson.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return false
+	}
+
+	if len(missingLessons) > 0 || len(missingCourses) > 0 {
+		c.JSON(http.StatusForbidden, models.LockedContentError{
+			Error:           "Lesson is locked until prerequisites are completed",
+			Missing_lessons: missingLessons,
+			Missing_courses: missingCourses,
+		})
+		return false
+	}
+
+	return true
+}
+
+// findAttachedContent проверяет урок или курс, к которому прикреплен квиз или задание: удаленный не виден никому,
+// неопубликованный — только авторам контента. Для прикрепленного к уроку возвращает урок.
+func findAttachedContent(
+	c *gin.Context,
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository,
+	lessonId, courseId *int,
+	notFound string) (*models.Lesson, bool) {
+	logger := logger.GetLogger()
+
+	var (
+		lesson  *models.Lesson
+		visible bool
+		err     error
+	)
+	switch {
+	case lessonId != nil:
+		var found models.Lesson
+		found, err = lessonsRepo.FindById(c, *lessonId)
+		lesson = &found
+		visible = utils.IsWithinPublicationWindow(found.Is_published, found.Publish_at, found.Unpublish_at, time.Now())
+	case courseId != nil:
+		var found models.Course
+		found, err = coursesRepo.FindById(c, *courseId)
+		visible = utils.IsWithinPublicationWindow(found.Is_published, found.Publish_at, found.Unpublish_at, time.Now())
+	default:
+		err = pgx.ErrNoRows
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError(notFound))
+		return nil, false
+	}
+	if err != nil {
+		logger.Error("Failed to find attached content", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return nil, false
+	}
+
+	if !visible && !utils.CanManageContent(c) {
+		c.JSON(http.StatusNotFound, models.NewApiError(notFound))
+		return nil, false
+	}
+
+	return lesson, true
+}
+
+// validateAttachment проверяет, что при создании квиза или задания указан ровно один существующий урок или курс
+func validateAttachment(
+	c *gin.Context,
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository,
+	lessonId, courseId *int) bool {
+	logger := logger.GetLogger()
+
+	var err error
+	switch {
+	case (lessonId == nil) == (courseId == nil):
+		c.JSON(http.StatusBadRequest, models.NewApiError("Either lesson_id or course_id is required"))
+		return false
+	case lessonId != nil:
+		_, err = lessonsRepo.FindById(c, *lessonId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Lesson not found"))
+			return false
+		}
+	default:
+		_, err = coursesRepo.FindById(c, *courseId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Course not found"))
+			return false
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to find attached content", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return false
+	}
+
+	return true
+}
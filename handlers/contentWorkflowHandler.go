@@ -0,0 +1,752 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// ContentWorkflowHandler обслуживает статусы публикации и ревизии уроков и курсов.
+// Методы возвращают обработчик для конкретного типа сущности (models.EntityLesson или models.EntityCourse).
+type ContentWorkflowHandler struct {
+	lessonsRepo   *repositories.Lessonsrepository
+	coursesRepo   *repositories.Coursesrepository
+	revisionsRepo *repositories.RevisionsRepository
+}
+
+func NewContentWorkflowHandler(
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository,
+	revisionsRepo *repositories.RevisionsRepository) *ContentWorkflowHandler {
+	return &ContentWorkflowHandler{
+		lessonsRepo:   lessonsRepo,
+		coursesRepo:   coursesRepo,
+		revisionsRepo: revisionsRepo,
+	}
+}
+
+type statusRequest struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+}
+
+// lessonRevisionData — содержимое урока, которое сохраняется в ревизии. Байты видео в ревизию не попадают,
+// видео из media хранится ссылкой; без ссылки при восстановлении остается текущее видео.
+type lessonRevisionData struct {
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	Body            string `json:"body"`
+	Subject_id      int    `json:"subject_id"`
+	Order           int    `json:"order"`
+	Level           string `json:"level"`
+	Interest        string `json:"interest"`
+	Target_age_min  int    `json:"target_age_min"`
+	Target_age_max  int    `json:"target_age_max"`
+	Video_media_id  *int   `json:"video_media_id,omitempty"`
+	Video_filename  string `json:"video_filename"`
+	Video_mime_type string `json:"video_mime_type"`
+	Duration_sec    int    `json:"duration_sec"`
+}
+
+type courseRevisionData struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func newLessonRevisionData(lesson models.Lesson) lessonRevisionData {
+	return lessonRevisionData{
+		Title:           lesson.Title,
+		Description:     lesson.Description,
+		Body:            lesson.Body,
+		Subject_id:      lesson.Subject_id,
+		Order:           lesson.Order,
+		Level:           lesson.Level,
+		Interest:        lesson.Interest,
+		Target_age_min:  lesson.Target_age_min,
+		Target_age_max:  lesson.Target_age_max,
+		Video_media_id:  lesson.Video_media_id,
+		Video_filename:  lesson.Video_filename,
+		Video_mime_type: lesson.Video_mime_type,
+		Duration_sec:    lesson.Duration_sec,
+	}
+}
+
+func newCourseRevisionData(course models.Course) courseRevisionData {
+	return courseRevisionData{
+		Name:        course.Name,
+		Description: course.Description,
+	}
+}
+
+// needsApproval сообщает, что правку контента со статусом status нельзя применить сразу. Опубликованный контент
+// без права публикации не меняется: ученики видят прежнюю версию, пока content lead не одобрит правку.
+func needsApproval(c *gin.Context, status string) bool {
+	return status == models.StatusPublished && !utils.HasRole(c, models.RoleAdmin, models.RoleContentLead)
+}
+
+// stagePendingRevision сохраняет правку как ревизию pending и отвечает 202 с ее номером
+func stagePendingRevision(c *gin.Context, revisionsRepo *repositories.RevisionsRepository, entityType string, entityId int, comment string, data any) {
+	logger := logger.GetLogger()
+
+	number, err := recordRevision(c, revisionsRepo, entityType, entityId, models.StatusPending, comment, data)
+	if err != nil {
+		logger.Error("Failed to record pending revision", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+		return
+	}
+
+	logger.Info("Edit is pending approval",
+		zap.String("entity_type", entityType),
+		zap.Int("id", entityId),
+		zap.Int("revision", number))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"revision": number,
+		"status":   models.StatusPending,
+	})
+}
+
+// recordRevision сохраняет неизменяемый снимок содержимого от имени текущего пользователя
+func recordRevision(c *gin.Context, revisionsRepo *repositories.RevisionsRepository, entityType string, entityId int, status string, comment string, data any) (int, error) {
+	revision, err := newRevision(c, entityType, entityId, status, comment, data)
+	if err != nil {
+		return 0, err
+	}
+
+	return revisionsRepo.Create(c, revision)
+}
+
+// newRevision собирает ревизию со снимком данных и автором из текущего запроса
+func newRevision(c *gin.Context, entityType string, entityId int, status string, comment string, data any) (models.ContentRevision, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return models.ContentRevision{}, err
+	}
+
+	revision := models.ContentRevision{
+		Entity_type: entityType,
+		Entity_id:   entityId,
+		Status:      status,
+		Comment:     comment,
+		Data:        raw,
+	}
+	if userUUID, ok := utils.CurrentUserUUID(c); ok {
+		revision.Author_uuid = &userUUID
+	}
+
+	return revision, nil
+}
+
+// loadContent возвращает текущий статус и снимок содержимого сущности
+func (h *ContentWorkflowHandler) loadContent(c *gin.Context, entityType string, id int) (string, any, error) {
+	switch entityType {
+	case models.EntityLesson:
+		lesson, err := h.lessonsRepo.FindById(c, id)
+		return lesson.Status, newLessonRevisionData(lesson), err
+	case models.EntityCourse:
+		course, err := h.coursesRepo.FindById(c, id)
+		return course.Status, newCourseRevisionData(course), err
+	default:
+		return "", nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+}
+
+func (h *ContentWorkflowHandler) findContent(c *gin.Context, entityType string) (int, string, any, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Error("Invalid id", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid id"))
+		return 0, "", nil, false
+	}
+
+	status, data, err := h.loadContent(c, entityType, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError(fmt.Sprintf("%s not found", entityType)))
+		return 0, "", nil, false
+	}
+	if err != nil {
+		logger.Error("Failed to load content", zap.String("entity_type", entityType), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return 0, "", nil, false
+	}
+
+	return id, status, data, true
+}
+
+func (h *ContentWorkflowHandler) findRevision(c *gin.Context, entityType string, id int, param string) (models.ContentRevision, bool) {
+	logger := logger.GetLogger()
+
+	number, err := strconv.Atoi(param)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid revision number"))
+		return models.ContentRevision{}, false
+	}
+
+	revision, err := h.revisionsRepo.FindByRevision(c, entityType, id, number)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError(fmt.Sprintf("Revision %d not found", number)))
+		return models.ContentRevision{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find revision", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.ContentRevision{}, false
+	}
+
+	return revision, true
+}
+
+// UpdateStatus godoc
+// @Summary 	move lesson or course through draft → in_review → published → archived
+// @Description Publishing from in_review requires the content_lead or admin role
+// @Tags 		workflow
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 			true 	"Lesson or course id"
+// @Param 		request body 		statusRequest 	true 	"Target status and optional review comment"
+// @Success 	200 	{object} 	object{status=string,revision=int} "OK"
+// @Failure 	400 	{object}	models.ApiError "Invalid transition"
+// @Failure 	403 	{object}	models.ApiError "Role is not allowed to make the transition"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/status [patch]
+// @Router 		/courses/{id}/status [patch]
+func (h *ContentWorkflowHandler) UpdateStatus(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, current, data, ok := h.findContent(c, entityType)
+		if !ok {
+			return
+		}
+
+		var request statusRequest
+		if err := c.BindJSON(&request); err != nil {
+			logger.Error("Failed JSON binding", zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+			return
+		}
+
+		role := utils.CurrentRole(c)
+		if role == nil {
+			c.JSON(http.StatusForbidden, models.NewApiError("access denied"))
+			return
+		}
+
+		if err := utils.ValidateTransition(current, request.Status, role.Name); err != nil {
+			logger.Warn("Rejected status transition", zap.String("entity_type", entityType), zap.Int("id", id), zap.Error(err))
+			status := http.StatusBadRequest
+			if errors.Is(err, utils.ErrTransitionForbidden) {
+				status = http.StatusForbidden
+			}
+			c.JSON(status, models.NewApiError(err.Error()))
+			return
+		}
+
+		var err error
+		switch entityType {
+		case models.EntityLesson:
+			err = h.lessonsRepo.UpdateStatus(c, id, request.Status)
+		case models.EntityCourse:
+			err = h.coursesRepo.UpdateStatus(c, id, request.Status)
+		}
+		if err != nil {
+			logger.Error("Failed to update status", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		number, err := recordRevision(c, h.revisionsRepo, entityType, id, request.Status, request.Comment, data)
+		if err != nil {
+			logger.Error("Failed to record revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+			return
+		}
+
+		logger.Info("Content status changed",
+			zap.String("entity_type", entityType),
+			zap.Int("id", id),
+			zap.String("from", current),
+			zap.String("to", request.Status))
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":   request.Status,
+			"revision": number,
+		})
+	}
+}
+
+// FindRevisions godoc
+// @Summary 	revision history of lesson or course
+// @Tags 		workflow
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson or course id"
+// @Success 	200 	{object} 	[]models.ContentRevision "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/revisions [get]
+// @Router 		/courses/{id}/revisions [get]
+func (h *ContentWorkflowHandler) FindRevisions(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, _, _, ok := h.findContent(c, entityType)
+		if !ok {
+			return
+		}
+
+		revisions, err := h.revisionsRepo.FindByEntity(c, entityType, id)
+		if err != nil {
+			logger.Error("Failed to fetch revisions", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	}
+}
+
+// FindRevision godoc
+// @Summary 	single revision with content snapshot
+// @Tags 		workflow
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Lesson or course id"
+// @Param 		revision 	path		int 	true 	"Revision number"
+// @Success 	200 		{object} 	models.ContentRevision "OK"
+// @Failure 	400 		{object}	models.ApiError
+// @Failure 	404 		{object}	models.ApiError
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/revisions/{revision} [get]
+// @Router 		/courses/{id}/revisions/{revision} [get]
+func (h *ContentWorkflowHandler) FindRevision(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _, _, ok := h.findContent(c, entityType)
+		if !ok {
+			return
+		}
+
+		revision, ok := h.findRevision(c, entityType, id, c.Param("revision"))
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, revision)
+	}
+}
+
+// Diff godoc
+// @Summary 	difference between two revisions
+// @Tags 		workflow
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson or course id"
+// @Param 		from 	query		int 	true 	"Older revision number"
+// @Param 		to 		query		int 	true 	"Newer revision number"
+// @Success 	200 	{object} 	models.RevisionDiff "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/revisions/diff [get]
+// @Router 		/courses/{id}/revisions/diff [get]
+func (h *ContentWorkflowHandler) Diff(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, _, _, ok := h.findContent(c, entityType)
+		if !ok {
+			return
+		}
+
+		from, ok := h.findRevision(c, entityType, id, c.Query("from"))
+		if !ok {
+			return
+		}
+		to, ok := h.findRevision(c, entityType, id, c.Query("to"))
+		if !ok {
+			return
+		}
+
+		changes, err := utils.DiffSnapshots(from.Data, to.Data)
+		if err != nil {
+			logger.Error("Failed to diff revisions", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		if from.Status != to.Status {
+			changes = append(changes, models.FieldChange{Field: "status", Old: from.Status, New: to.Status})
+		}
+
+		c.JSON(http.StatusOK, models.RevisionDiff{
+			Entity_type: entityType,
+			Entity_id:   id,
+			From:        from.Revision,
+			To:          to.Revision,
+			Changes:     changes,
+		})
+	}
+}
+
+// Restore godoc
+// @Summary 	restore content from an earlier revision
+// @Description Content is copied from the revision and saved as a new revision. For published content restored by a teacher
+// @Description the copy is saved as a pending revision (202) and applied once a content lead approves it.
+// @Description The response has the number of the new revision.
+// @Tags 		workflow
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Lesson or course id"
+// @Param 		revision 	path		int 	true 	"Revision number"
+// @Success 	200 		{object} 	object{revision=int,status=string} "OK"
+// @Success 	202 		{object} 	object{revision=int,status=string} "Pending approval"
+// @Failure 	400 		{object}	models.ApiError
+// @Failure 	404 		{object}	models.ApiError
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/revisions/{revision}/restore [post]
+// @Router 		/courses/{id}/revisions/{revision}/restore [post]
+func (h *ContentWorkflowHandler) Restore(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, status, _, ok := h.findContent(c, entityType)
+		if !ok {
+			return
+		}
+
+		revision, ok := h.findRevision(c, entityType, id, c.Param("revision"))
+		if !ok {
+			return
+		}
+
+		comment := fmt.Sprintf("restored from revision %d", revision.Revision)
+		if needsApproval(c, status) {
+			stagePendingRevision(c, h.revisionsRepo, entityType, id, comment, revision.Data)
+			return
+		}
+
+		restored, err := h.applyRevision(c, entityType, id, revision.Data)
+		if err != nil {
+			logger.Error("Failed to restore revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		number, err := recordRevision(c, h.revisionsRepo, entityType, id, status, comment, restored)
+		if err != nil {
+			logger.Error("Failed to record revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+			return
+		}
+
+		logger.Info("Content restored",
+			zap.String("entity_type", entityType),
+			zap.Int("id", id),
+			zap.Int("from_revision", revision.Revision),
+			zap.Int("revision", number),
+			zap.String("status", status))
+
+		c.JSON(http.StatusOK, gin.H{
+			"revision": number,
+			"status":   status,
+		})
+	}
+}
+
+// Approve godoc
+// @Summary 	apply a pending revision of lesson or course
+// @Description The content of the pending revision becomes live and is saved as a new revision with the current status.
+// @Description A revision submitted before the content changed again can't be approved, it has to be edited anew.
+// @Tags 		workflow
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Lesson or course id"
+// @Param 		revision 	path		int 	true 	"Pending revision number"
+// @Success 	200 		{object} 	object{revision=int,status=string} "OK"
+// @Failure 	400 		{object}	models.ApiError "Revision is not pending"
+// @Failure 	404 		{object}	models.ApiError
+// @Failure 	409 		{object}	models.ApiError "Content changed after the revision was submitted"
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/revisions/{revision}/approve [post]
+// @Router 		/courses/{id}/revisions/{revision}/approve [post]
+func (h *ContentWorkflowHandler) Approve(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, status, _, ok := h.findContent(c, entityType)
+		if !ok {
+			return
+		}
+
+		revision, ok := h.findPendingRevision(c, entityType, id)
+		if !ok {
+			return
+		}
+
+		applied, err := h.applyRevision(c, entityType, id, revision.Data)
+		if err != nil {
+			logger.Error("Failed to apply revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		number, err := recordRevision(c, h.revisionsRepo, entityType, id, status, fmt.Sprintf("approved revision %d", revision.Revision), applied)
+		if err != nil {
+			logger.Error("Failed to record revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+			return
+		}
+
+		logger.Info("Pending revision approved",
+			zap.String("entity_type", entityType),
+			zap.Int("id", id),
+			zap.Int("pending_revision", revision.Revision),
+			zap.Int("revision", number))
+
+		c.JSON(http.StatusOK, gin.H{
+			"revision": number,
+			"status":   status,
+		})
+	}
+}
+
+// Reject godoc
+// @Summary 	reject a pending revision of lesson or course
+// @Description The live content stays as is. The rejection is saved as a new revision with the current content and the reviewer comment.
+// @Tags 		workflow
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 			path		int 			true 	"Lesson or course id"
+// @Param 		revision 	path		int 			true 	"Pending revision number"
+// @Param 		request 	body 		statusRequest 	false 	"Review comment, status is ignored"
+// @Success 	200 		{object} 	object{revision=int,status=string} "OK"
+// @Failure 	400 		{object}	models.ApiError "Revision is not pending"
+// @Failure 	404 		{object}	models.ApiError
+// @Failure 	409 		{object}	models.ApiError "Content changed after the revision was submitted"
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/revisions/{revision}/reject [post]
+// @Router 		/courses/{id}/revisions/{revision}/reject [post]
+func (h *ContentWorkflowHandler) Reject(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, status, data, ok := h.findContent(c, entityType)
+		if !ok {
+			return
+		}
+
+		var request statusRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.BindJSON(&request); err != nil {
+				logger.Error("Failed JSON binding", zap.Error(err))
+				c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+				return
+			}
+		}
+
+		revision, ok := h.findPendingRevision(c, entityType, id)
+		if !ok {
+			return
+		}
+
+		comment := fmt.Sprintf("rejected revision %d", revision.Revision)
+		if request.Comment != "" {
+			comment += ": " + request.Comment
+		}
+
+		// ревизия с текущим содержимым закрывает pending: одобрить ее после этого уже нельзя
+		number, err := recordRevision(c, h.revisionsRepo, entityType, id, status, comment, data)
+		if err != nil {
+			logger.Error("Failed to record revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+			return
+		}
+
+		logger.Info("Pending revision rejected",
+			zap.String("entity_type", entityType),
+			zap.Int("id", id),
+			zap.Int("pending_revision", revision.Revision),
+			zap.Int("revision", number))
+
+		c.JSON(http.StatusOK, gin.H{
+			"revision": number,
+			"status":   status,
+		})
+	}
+}
+
+// findPendingRevision находит ревизию pending из пути запроса, которую еще можно применить:
+// после нее сущность не менялась
+func (h *ContentWorkflowHandler) findPendingRevision(c *gin.Context, entityType string, id int) (models.ContentRevision, bool) {
+	logger := logger.GetLogger()
+
+	revision, ok := h.findRevision(c, entityType, id, c.Param("revision"))
+	if !ok {
+		return models.ContentRevision{}, false
+	}
+
+	if revision.Status != models.StatusPending {
+		c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Revision %d is not pending approval", revision.Revision)))
+		return models.ContentRevision{}, false
+	}
+
+	changed, err := h.revisionsRepo.HasAppliedAfter(c, entityType, id, revision.Revision)
+	if err != nil {
+		logger.Error("Failed to check later revisions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.ContentRevision{}, false
+	}
+	if changed {
+		c.JSON(http.StatusConflict, models.NewApiError(fmt.Sprintf("%s changed after revision %d was submitted", entityType, revision.Revision)))
+		return models.ContentRevision{}, false
+	}
+
+	return revision, true
+}
+
+// applyRevision переносит снимок ревизии в сущность и возвращает примененные данные
+func (h *ContentWorkflowHandler) applyRevision(c *gin.Context, entityType string, id int, raw json.RawMessage) (any, error) {
+	switch entityType {
+	case models.EntityLesson:
+		return h.restoreLesson(c, id, raw)
+	case models.EntityCourse:
+		return h.restoreCourse(c, id, raw)
+	default:
+		return nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+}
+
+func (h *ContentWorkflowHandler) restoreLesson(c *gin.Context, id int, raw json.RawMessage) (any, error) {
+	var data lessonRevisionData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+
+	// байтов видео в ревизии нет: без ссылки на media оставляем текущее видео
+	current, err := h.lessonsRepo.FindById(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyHtml, _ := utils.RenderMarkdown(data.Body, nil)
+
+	lesson := models.Lesson{
+		Title:           data.Title,
+		Description:     data.Description,
+		Body:            data.Body,
+		Body_html:       bodyHtml,
+		Subject_id:      data.Subject_id,
+		Order:           data.Order,
+		Level:           data.Level,
+		Interest:        data.Interest,
+		Target_age_min:  data.Target_age_min,
+		Target_age_max:  data.Target_age_max,
+		Video_data:      current.Video_data,
+		Video_media_id:  data.Video_media_id,
+		Video_filename:  data.Video_filename,
+		Video_mime_type: data.Video_mime_type,
+		Duration_sec:    data.Duration_sec,
+	}
+
+	return data, h.lessonsRepo.Update(c, id, lesson)
+}
+
+func (h *ContentWorkflowHandler) restoreCourse(c *gin.Context, id int, raw json.RawMessage) (any, error) {
+	var data courseRevisionData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+
+	// в ревизии только содержимое, настройки записи на курс оставляем текущие
+	current, err := h.coursesRepo.FindById(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	course := models.Course{
+		Name:        data.Name,
+		Description: data.Description,
+		Enrollment:  current.Enrollment,
+		Capacity:    current.Capacity,
+	}
+
+	return data, h.coursesRepo.Update(c, id, course)
+}
+
+type scheduleRequest struct {
+	Publish_at   *time.Time `json:"publish_at"`
+	Unpublish_at *time.Time `json:"unpublish_at"`
+}
+
+// Schedule godoc
+// @Summary 	set publication window for lesson or course
+// @Description The background scheduler publishes content at publish_at and archives it at unpublish_at. Null clears the date.
+// @Description Each date fires once, the scheduler clears it after the transition.
+// @Tags 		workflow
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Lesson or course id"
+// @Param 		request body 		scheduleRequest 	true 	"RFC 3339 timestamps"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/schedule [put]
+// @Router 		/courses/{id}/schedule [put]
+func (h *ContentWorkflowHandler) Schedule(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, status, _, ok := h.findContent(c, entityType)
+		if !ok {
+			return
+		}
+
+		var request scheduleRequest
+		if err := c.BindJSON(&request); err != nil {
+			logger.Error("Failed JSON binding", zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+			return
+		}
+
+		if request.Publish_at != nil && request.Unpublish_at != nil && !request.Unpublish_at.After(*request.Publish_at) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("unpublish_at must be after publish_at"))
+			return
+		}
+		if status == models.StatusArchived && request.Publish_at != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Archived content must be moved to draft before scheduling"))
+			return
+		}
+
+		var err error
+		switch entityType {
+		case models.EntityLesson:
+			err = h.lessonsRepo.SetSchedule(c, id, request.Publish_at, request.Unpublish_at)
+		case models.EntityCourse:
+			err = h.coursesRepo.SetSchedule(c, id, request.Publish_at, request.Unpublish_at)
+		}
+		if err != nil {
+			logger.Error("Failed to set schedule", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		logger.Info("Publication scheduled",
+			zap.String("entity_type", entityType),
+			zap.Int("id", id),
+			zap.Timep("publish_at", request.Publish_at),
+			zap.Timep("unpublish_at", request.Unpublish_at))
+
+		c.Status(http.StatusOK)
+	}
+}
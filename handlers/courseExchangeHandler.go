@@ -0,0 +1,674 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	markdownMediaRefRegexp  = regexp.MustCompile(`media:(\d+)`)
+	markdownLinkTargetRegex = regexp.MustCompile(`(\]\()([^)\s]+)`)
+)
+
+// CourseExchangeHandler выгружает курсы в пакеты IMS Common Cartridge и загружает курсы из таких пакетов
+type CourseExchangeHandler struct {
+	coursesRepo   *repositories.Coursesrepository
+	lessonsRepo   *repositories.Lessonsrepository
+	subjectsRepo  *repositories.SubjectsRepository
+	mediaRepo     *repositories.MediaRepository
+	resourcesRepo *repositories.LessonResourcesRepository
+	revisionsRepo *repositories.RevisionsRepository
+}
+
+func NewCourseExchangeHandler(
+	coursesRepo *repositories.Coursesrepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	subjectsRepo *repositories.SubjectsRepository,
+	mediaRepo *repositories.MediaRepository,
+	resourcesRepo *repositories.LessonResourcesRepository,
+	revisionsRepo *repositories.RevisionsRepository) *CourseExchangeHandler {
+	return &CourseExchangeHandler{
+		coursesRepo:   coursesRepo,
+		lessonsRepo:   lessonsRepo,
+		subjectsRepo:  subjectsRepo,
+		mediaRepo:     mediaRepo,
+		resourcesRepo: resourcesRepo,
+		revisionsRepo: revisionsRepo,
+	}
+}
+
+// packageFile — файл пакета: либо готовые данные, либо blob из хранилища media
+type packageFile struct {
+	name     string
+	data     []byte
+	checksum string
+}
+
+func mediaPackagePath(media models.Media) string {
+	return fmt.Sprintf("web_resources/media-%d-%s", media.Id, path.Base(media.Filename))
+}
+
+// relativeFromLesson — ссылка на файл пакета из lessons/*.html или lessons/*.md
+func relativeFromLesson(packagePath string) string {
+	return "../" + (&url.URL{Path: packagePath}).EscapedPath()
+}
+
+// Export godoc
+// @Summary 	export course as IMS Common Cartridge 1.3 package
+// @Description Each lesson becomes a module with an HTML page (plus its Markdown source), attached files, links and video
+// @Tags 		courses
+// @Produce 	application/zip
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{file} 		file 	"imscc package"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/export [get]
+func (h *CourseExchangeHandler) Export(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	courseLessons, err := h.coursesRepo.FindLessons(c, course.Id, false)
+	if err != nil {
+		logger.Error("Failed to fetch course lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	builder := utils.NewCartridgeBuilder(fmt.Sprintf("course_%d", course.Id), course.Name, course.Description, models.DefaultLocale)
+	files := make([]packageFile, 0)
+	mediaCache := make(map[int]models.Media)
+
+	findMedia := func(id int) (models.Media, error) {
+		if media, ok := mediaCache[id]; ok {
+			return media, nil
+		}
+		media, err := h.mediaRepo.FindById(c, id)
+		if err != nil {
+			return models.Media{}, err
+		}
+		mediaCache[id] = media
+		files = append(files, packageFile{name: mediaPackagePath(media), checksum: media.Checksum})
+		return media, nil
+	}
+
+	// сначала собираем все из базы, чтобы ошибки вернулись обычным ответом, а не оборванным архивом
+	for _, courseLesson := range courseLessons {
+		lesson, err := h.lessonsRepo.FindById(c, courseLesson.Lesson_id)
+		if err != nil {
+			logger.Error("Failed to load lesson for export", zap.Int("lesson_id", courseLesson.Lesson_id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		_, mediaIds := utils.RenderMarkdown(lesson.Body, nil)
+		lessonFiles := make([]string, 0, len(mediaIds)+1)
+		for _, mediaId := range mediaIds {
+			media, err := findMedia(mediaId)
+			if err != nil {
+				logger.Error("Failed to load body media for export", zap.Int("media_id", mediaId), zap.Error(err))
+				c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+				return
+			}
+			lessonFiles = append(lessonFiles, mediaPackagePath(media))
+		}
+
+		resolve := func(mediaId int) string {
+			return relativeFromLesson(mediaPackagePath(mediaCache[mediaId]))
+		}
+		bodyHtml, _ := utils.RenderMarkdown(lesson.Body, resolve)
+		body := markdownMediaRefRegexp.ReplaceAllStringFunc(lesson.Body, func(ref string) string {
+			id, _ := strconv.Atoi(strings.TrimPrefix(ref, "media:"))
+			if _, ok := mediaCache[id]; !ok {
+				return ref
+			}
+			return resolve(id)
+		})
+
+		page := fmt.Sprintf("lessons/lesson-%d.html", lesson.Id)
+		source := fmt.Sprintf("lessons/lesson-%d.md", lesson.Id)
+		files = append(files,
+			packageFile{name: page, data: []byte(cartridgeLessonPage(lesson, bodyHtml))},
+			packageFile{name: source, data: []byte(body)})
+
+		module := builder.AddModule(lesson.Title)
+		builder.AddItem(module, lesson.Title, utils.CartridgeWebContent, page, append([]string{source}, lessonFiles...)...)
+
+		resources, err := h.resourcesRepo.FindByLessonId(c, lesson.Id)
+		if err != nil {
+			logger.Error("Failed to load lesson resources for export", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		for _, resource := range resources {
+			switch resource.Type {
+			case models.ResourceTypeFile:
+				media, err := findMedia(*resource.Media_id)
+				if err != nil {
+					logger.Error("Failed to load resource media for export", zap.Error(err))
+					c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+					return
+				}
+				builder.AddItem(module, resource.Title, utils.CartridgeWebContent, mediaPackagePath(media))
+			case models.ResourceTypeLink:
+				link, err := utils.CartridgeWebLinkXML(resource.Title, resource.Url)
+				if err != nil {
+					logger.Error("Failed to build web link", zap.Error(err))
+					c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+					return
+				}
+				name := fmt.Sprintf("weblinks/link-%d.xml", resource.Id)
+				files = append(files, packageFile{name: name, data: link})
+				builder.AddItem(module, resource.Title, utils.CartridgeWebLink, name)
+			}
+		}
+
+		if lesson.Video_media_id != nil {
+			media, err := findMedia(*lesson.Video_media_id)
+			if err != nil {
+				logger.Error("Failed to load lesson video for export", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+				return
+			}
+			builder.AddItem(module, lesson.Video_filename, utils.CartridgeWebContent, mediaPackagePath(media))
+		} else if len(lesson.Video_data) > 0 {
+			name := fmt.Sprintf("web_resources/lesson-%d-%s", lesson.Id, path.Base(lesson.Video_filename))
+			files = append(files, packageFile{name: name, data: lesson.Video_data})
+			builder.AddItem(module, lesson.Video_filename, utils.CartridgeWebContent, name)
+		}
+	}
+
+	manifest, err := builder.Manifest()
+	if err != nil {
+		logger.Error("Failed to build manifest", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="course-%d.imscc"`, course.Id))
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	if err := writePackageFile(archive, packageFile{name: utils.CartridgeManifestName, data: manifest}); err != nil {
+		logger.Error("Failed to write package", zap.Error(err))
+		return
+	}
+	for _, file := range files {
+		if err := writePackageFile(archive, file); err != nil {
+			// заголовки уже отправлены, клиент получит оборванный архив
+			logger.Error("Failed to write package", zap.String("file", file.name), zap.Error(err))
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		logger.Error("Failed to finish package", zap.Error(err))
+	}
+}
+
+func writePackageFile(archive *zip.Writer, file packageFile) error {
+	w, err := archive.Create(file.name)
+	if err != nil {
+		return err
+	}
+
+	if file.checksum == "" {
+		_, err = w.Write(file.data)
+		return err
+	}
+
+	blob, err := os.Open(utils.MediaFilePath(file.checksum))
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	_, err = io.Copy(w, blob)
+	return err
+}
+
+func cartridgeLessonPage(lesson models.Lesson, bodyHtml string) string {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+	return `<!DOCTYPE html>
+<html lang="` + models.DefaultLocale + `">
+<head>
+<meta charset="utf-8">
+<title>` + escape.Replace(lesson.Title) + `</title>
+<meta name="description" content="` + escape.Replace(lesson.Description) + `">
+</head>
+<body>
+` + bodyHtml + `
+</body>
+</html>
+`
+}
+
+// packageImporter переносит элементы пакета в уроки, файлы и ссылки
+type packageImporter struct {
+	h        *CourseExchangeHandler
+	c        *gin.Context
+	files    map[string]*zip.File
+	media    map[string]int
+	report   *models.PackageImportReport
+	template models.Lesson
+}
+
+func (p *packageImporter) unsupported(item utils.CartridgeItem, resourceType string, reason string) {
+	p.report.Unsupported = append(p.report.Unsupported, models.UnsupportedPackageItem{
+		Identifier: item.Identifier,
+		Title:      item.Title,
+		Type:       resourceType,
+		Reason:     reason,
+	})
+}
+
+func (p *packageImporter) read(name string, limit int64) ([]byte, error) {
+	file, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("file %s is missing in package", name)
+	}
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("file %s is too large", name)
+	}
+
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("file %s is too large", name)
+	}
+	return data, nil
+}
+
+// importMedia сохраняет файл пакета в хранилище один раз и возвращает id записи media
+func (p *packageImporter) importMedia(name string, limit int64) (int, error) {
+	if id, ok := p.media[name]; ok {
+		return id, nil
+	}
+
+	data, err := p.read(name, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	checksum, size, err := utils.StoreMedia(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := p.h.mediaRepo.Create(p.c, models.Media{
+		Filename:  path.Base(name),
+		Mime_type: utils.DetectMimeType(name, data[:min(len(data), 512)]),
+		Size:      size,
+		Checksum:  checksum,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	p.media[name] = id
+	p.report.Media_count++
+	return id, nil
+}
+
+// resolveLink заменяет ссылку на файл пакета ссылкой media:{id}; внешние ссылки не меняются
+func (p *packageImporter) resolveLink(fromFile string, ref string) string {
+	name, ok := utils.ResolvePackagePath(fromFile, ref)
+	if !ok {
+		return ref
+	}
+	if _, exists := p.files[name]; !exists {
+		return ref
+	}
+
+	id, err := p.importMedia(name, utils.MaxMediaSize)
+	if err != nil {
+		logger.GetLogger().Warn("Could not import linked file", zap.String("file", name), zap.Error(err))
+		return ref
+	}
+	return fmt.Sprintf("media:%d", id)
+}
+
+func (p *packageImporter) importLesson(item utils.CartridgeItem, resource utils.CartridgeResource) (int, error) {
+	page, err := p.read(resource.Href, utils.MaxMediaSize)
+	if err != nil {
+		return 0, err
+	}
+
+	lesson := p.template
+	lesson.Title = item.Title
+	if lesson.Title == "" {
+		lesson.Title = utils.HTMLTitle(string(page))
+	}
+	if lesson.Title == "" {
+		lesson.Title = path.Base(resource.Href)
+	}
+
+	// выгруженные нами пакеты содержат исходный Markdown рядом со страницей
+	for _, file := range resource.Files {
+		if strings.HasSuffix(file.Href, ".md") {
+			source, err := p.read(file.Href, utils.MaxMediaSize)
+			if err != nil {
+				return 0, err
+			}
+			lesson.Body = markdownLinkTargetRegex.ReplaceAllStringFunc(string(source), func(match string) string {
+				groups := markdownLinkTargetRegex.FindStringSubmatch(match)
+				return groups[1] + p.resolveLink(file.Href, groups[2])
+			})
+			break
+		}
+	}
+	if lesson.Body == "" {
+		lesson.Body = utils.HTMLToMarkdown(string(page), func(ref string) string {
+			return p.resolveLink(resource.Href, ref)
+		})
+	}
+	if len(lesson.Body) > utils.MaxMarkdownSize {
+		return 0, fmt.Errorf("lesson %s: body must not exceed %d KB", resource.Href, utils.MaxMarkdownSize>>10)
+	}
+	lesson.Body_html, _ = utils.RenderMarkdown(lesson.Body, nil)
+
+	id, err := p.h.lessonsRepo.Create(p.c, lesson)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := recordRevision(p.c, p.h.revisionsRepo, models.EntityLesson, id, models.StatusDraft, "imported", newLessonRevisionData(lesson)); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (p *packageImporter) importFile(item utils.CartridgeItem, resource utils.CartridgeResource, lessonId int) error {
+	limit, allowed := utils.ResourceSizeLimit(resource.Href)
+	if !allowed {
+		p.unsupported(item, resource.Type, "file type is not allowed for lesson resources")
+		return nil
+	}
+	if file, ok := p.files[resource.Href]; ok && file.UncompressedSize64 > uint64(limit) {
+		p.unsupported(item, resource.Type, "file is too large")
+		return nil
+	}
+
+	mediaId, err := p.importMedia(resource.Href, limit)
+	if err != nil {
+		return err
+	}
+
+	title := item.Title
+	if title == "" {
+		title = path.Base(resource.Href)
+	}
+	if _, err := p.h.resourcesRepo.Create(p.c, models.LessonResource{
+		Lesson_id: lessonId,
+		Type:      models.ResourceTypeFile,
+		Title:     title,
+		Media_id:  &mediaId,
+	}); err != nil {
+		return err
+	}
+
+	p.report.Resource_count++
+	return nil
+}
+
+func (p *packageImporter) importWebLink(item utils.CartridgeItem, resource utils.CartridgeResource, lessonId int) error {
+	if len(resource.Files) == 0 {
+		p.unsupported(item, resource.Type, "web link has no descriptor file")
+		return nil
+	}
+
+	data, err := p.read(resource.Files[0].Href, 1<<20)
+	if err != nil {
+		return err
+	}
+	link, err := utils.ParseCartridgeWebLink(data)
+	if err != nil {
+		p.unsupported(item, resource.Type, "invalid web link descriptor")
+		return nil
+	}
+
+	parsed, err := url.Parse(link.Url.Href)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		p.unsupported(item, resource.Type, "only http and https links are supported")
+		return nil
+	}
+
+	title := item.Title
+	if title == "" {
+		title = link.Title
+	}
+	if _, err := p.h.resourcesRepo.Create(p.c, models.LessonResource{
+		Lesson_id: lessonId,
+		Type:      models.ResourceTypeLink,
+		Title:     title,
+		Url:       link.Url.Href,
+	}); err != nil {
+		return err
+	}
+
+	p.report.Resource_count++
+	return nil
+}
+
+func isHTMLFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
+
+// Import godoc
+// @Summary 	import course from IMS Common Cartridge or SCORM package
+// @Description HTML pages become draft lessons, other files and web links become resources of the preceding lesson.
+// @Description Items that cannot be imported (quizzes, discussions, LTI tools, ...) are listed in the report.
+// @Tags 		courses
+// @Accept 		multipart/form-data
+// @Produce 	json
+// @Param 		package 	formData 	file 	true 	"imscc or zip package"
+// @Param 		subject_id 	formData 	int 	true 	"Subject for imported lessons"
+// @Param 		level 		formData 	string 	false 	"Level for imported lessons, Beginner by default"
+// @Success 	200 	{object} 	models.PackageImportReport "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/import [post]
+func (h *CourseExchangeHandler) Import(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	subjectId, err := strconv.Atoi(c.PostForm("subject_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("subject_id is required"))
+		return
+	}
+	if _, err := h.subjectsRepo.FindById(c, subjectId); errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Subject not found"))
+		return
+	} else if err != nil {
+		logger.Error("Failed to find subject", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	level := models.LevelBeginner
+	if value := c.PostForm("level"); value != "" {
+		normalized, ok := utils.NormalizeLevel(value)
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.NewApiError(invalidLevelMessage()))
+			return
+		}
+		level = normalized
+	}
+
+	fileHeader, err := c.FormFile("package")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Package file is required"))
+		return
+	}
+	if fileHeader.Size > utils.MaxPackageSize {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Package is too large"))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Error("Failed to open package", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not read package"))
+		return
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, fileHeader.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Package is not a valid zip archive"))
+		return
+	}
+
+	report := models.PackageImportReport{
+		Lesson_ids:  make([]int, 0),
+		Unsupported: make([]models.UnsupportedPackageItem, 0),
+	}
+	importer := &packageImporter{
+		h:      h,
+		c:      c,
+		files:  make(map[string]*zip.File, len(archive.File)),
+		media:  make(map[string]int),
+		report: &report,
+		template: models.Lesson{
+			Subject_id: subjectId,
+			Level:      level,
+		},
+	}
+	for _, f := range archive.File {
+		if !strings.HasSuffix(f.Name, "/") {
+			importer.files[path.Clean(f.Name)] = f
+		}
+	}
+
+	manifestData, err := importer.read(utils.CartridgeManifestName, 10<<20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+	manifest, err := utils.ParseCartridgeManifest(manifestData)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+
+	resources := make(map[string]utils.CartridgeResource, len(manifest.Resources))
+	for _, resource := range manifest.Resources {
+		resource.Href = path.Clean(resource.Href)
+		for i := range resource.Files {
+			resource.Files[i].Href = path.Clean(resource.Files[i].Href)
+		}
+		resources[resource.Identifier] = resource
+	}
+
+	items := make([]utils.CartridgeItem, 0)
+	var organizationTitle string
+	if len(manifest.Organizations) > 0 {
+		organizationTitle = manifest.Organizations[0].Title
+		items = manifest.Organizations[0].LeafItems()
+	} else {
+		// пакет без структуры — берем ресурсы в порядке манифеста
+		for _, resource := range manifest.Resources {
+			items = append(items, utils.CartridgeItem{Identifier: resource.Identifier, Identifierref: resource.Identifier})
+		}
+	}
+
+	course := models.Course{Name: manifest.Title, Description: manifest.Description}
+	if course.Name == "" {
+		course.Name = organizationTitle
+	}
+	if course.Name == "" {
+		course.Name = strings.TrimSuffix(path.Base(fileHeader.Filename), path.Ext(fileHeader.Filename))
+	}
+
+	courseId, err := h.coursesRepo.Create(c, course)
+	if err != nil {
+		logger.Error("Failed to create imported course", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+	if _, err := recordRevision(c, h.revisionsRepo, models.EntityCourse, courseId, models.StatusDraft, "imported", newCourseRevisionData(course)); err != nil {
+		logger.Error("Failed to record course revision", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+		return
+	}
+	report.Course_id = courseId
+
+	// ошибки отдельных элементов не прерывают импорт: созданное остается черновиком, причина попадает в отчет
+	currentLesson := 0
+	for _, item := range items {
+		resource, ok := resources[item.Identifierref]
+		if !ok {
+			importer.unsupported(item, "", "resource is not declared in manifest")
+			continue
+		}
+
+		var err error
+		switch {
+		case resource.Type == utils.CartridgeWebContent && isHTMLFile(resource.Href):
+			var lessonId int
+			lessonId, err = importer.importLesson(item, resource)
+			if err == nil {
+				importer.template.Order++
+				currentLesson = lessonId
+				report.Lesson_ids = append(report.Lesson_ids, lessonId)
+			}
+		case resource.Type == utils.CartridgeWebContent && resource.Href != "." && currentLesson != 0:
+			err = importer.importFile(item, resource, currentLesson)
+		case utils.IsCartridgeWebLink(resource.Type) && currentLesson != 0:
+			err = importer.importWebLink(item, resource, currentLesson)
+		case resource.Type == utils.CartridgeWebContent || utils.IsCartridgeWebLink(resource.Type):
+			importer.unsupported(item, resource.Type, "file or link outside of a lesson")
+		default:
+			importer.unsupported(item, resource.Type, "resource type is not supported")
+		}
+
+		if err != nil {
+			logger.Warn("Could not import package item", zap.String("identifier", item.Identifier), zap.Error(err))
+			importer.unsupported(item, resource.Type, err.Error())
+		}
+	}
+
+	if err := h.coursesRepo.SetLessons(c, courseId, report.Lesson_ids); err != nil {
+		logger.Error("Failed to attach imported lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Course imported",
+		zap.Int("course_id", courseId),
+		zap.Int("lessons", len(report.Lesson_ids)),
+		zap.Int("unsupported", len(report.Unsupported)))
+
+	c.JSON(http.StatusOK, report)
+}
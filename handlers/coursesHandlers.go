@@ -1,31 +1,48 @@
 package handlers
 
 import (
+	"errors"
 	"go-EdTech/logger"
 	"go-EdTech/models"
 	"go-EdTech/repositories"
+	"go-EdTech/utils"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
 type CoursesHandlers struct {
-	coursesRepo *repositories.Coursesrepository
+	coursesRepo      *repositories.Coursesrepository
+	revisionsRepo    *repositories.RevisionsRepository
+	tagsRepo         *repositories.TagsRepository
+	translationsRepo *repositories.TranslationsRepository
 }
 
 func NewCoursesHandler(
-	coursesRepo *repositories.Coursesrepository) *CoursesHandlers {
+	coursesRepo *repositories.Coursesrepository,
+	revisionsRepo *repositories.RevisionsRepository,
+	tagsRepo *repositories.TagsRepository,
+	translationsRepo *repositories.TranslationsRepository) *CoursesHandlers {
 	return &CoursesHandlers{
-		coursesRepo: coursesRepo,
+		coursesRepo:      coursesRepo,
+		revisionsRepo:    revisionsRepo,
+		tagsRepo:         tagsRepo,
+		translationsRepo: translationsRepo,
 	}
 }
 
 type courseRequest struct {
 	Course_title string `json:"title"`
 	Description  string `json:"description"`
-	Is_published bool   `json:"is_published"`
+	Enrollment   string `json:"enrollment"`
+	Capacity     *int   `json:"capacity"` // 0 — без ограничения, не передано — оставить как есть
+}
+
+type courseLessonsRequest struct {
+	Lesson_ids []int `json:"lesson_ids"`
 }
 
 // Create course	godoc
@@ -35,7 +52,8 @@ type courseRequest struct {
 // @Produce 		json
 // @Param 			title 			query 		string		true	"title"
 // @Param 			description 	query 		string 		true 	"description"
-// @Param 			is_published 	query 		boolean 	true 	"is_published"
+// @Param 			enrollment 		query 		string 		false 	"open (default) or closed"
+// @Param 			capacity 		query 		int 		false 	"Maximum of active students, 0 for unlimited"
 // @Success 		200 			{object} 	object{id=int} 		"OK"
 // @Failure 		400 			{object} 	models.ApiError		"error with json dinding"
 // @Failure 		500 			{object} 	models.ApiError
@@ -53,7 +71,10 @@ func (h *CoursesHandlers) Create(c *gin.Context) {
 	course := models.Course{
 		Name:         request.Course_title,
 		Description:  request.Description,
-		Is_published: request.Is_published,
+		Enrollment:   models.CourseEnrollmentOpen,
+	}
+	if !applyEnrollmentSettings(c, &course, request) {
+		return
 	}
 
 	id, err := h.coursesRepo.Create(c, course)
@@ -63,6 +84,13 @@ func (h *CoursesHandlers) Create(c *gin.Context) {
 		return
 	}
 
+	_, err = recordRevision(c, h.revisionsRepo, models.EntityCourse, id, models.StatusDraft, "created", newCourseRevisionData(course))
+	if err != nil {
+		logger.Error("Failed to record course revision", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"id": id,
 	})
@@ -76,57 +104,82 @@ func (h *CoursesHandlers) Create(c *gin.Context) {
 // @Param 		id 		path 		int 	true 	"id"
 // @Success 	200 	{object} 	models.Course "OK"
 // @Failure 	400 	{object} 	models.ApiError "invalid course id"
+// @Failure 	404 	{object} 	models.ApiError "Course not found or not published yet"
 // @Failure 	500 	{object} 	models.ApiError
 // @Router 		/courses/{id} [get]
 func (h *CoursesHandlers) FindById(c *gin.Context) {
 	logger := logger.GetLogger()
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		logger.Error("Invalid course ID format", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("invalid course id"))
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
 		return
 	}
 
-	course, err := h.coursesRepo.FindById(c, id)
-	if err != nil {
-		logger.Error("Course doesn't exist", zap.Error(err))
+	courses := []models.Course{course}
+	if err := localizeCourses(c, h.translationsRepo, courses); err != nil {
+		logger.Error("Failed to localize course", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
 		return
 	}
-	c.JSON(http.StatusOK, course)
+
+	c.JSON(http.StatusOK, courses[0])
 }
 
 // FindAll godoc
 // @Summary 	find all courses
+// @Description Learners only see published courses inside their publish_at/unpublish_at window
 // @Tags 		courses
 // @Accept 		json
 // @Produce 	json
+// @Param 		Accept-Language header 	string 	false 	"Preferred languages: ru, kk, en"
+// @Param 		tags 	query 	string 	false 	"Comma-separated tag slugs, course must match every tag or one of its children"
 // @Success 	200 	{object} []models.Course "OK"
+// @Failure 	400 	{object} models.ApiError
 // @Failure 	500 	{object} models.ApiError
 // @Router 		/courses [get]
 func (g *CoursesHandlers) FindAll(c *gin.Context) {
 	logger := logger.GetLogger()
 
-	courses, err := g.coursesRepo.FindAll(c)
+	filter := models.CourseFilter{
+		Visible_only: !utils.CanManageContent(c),
+	}
+
+	tagGroups, ok := parseTagFilter(c, g.tagsRepo)
+	if !ok {
+		return
+	}
+	filter.Tag_groups = tagGroups
+
+	courses, err := g.coursesRepo.FindAll(c, filter)
 	if err != nil {
 		logger.Error("Failed to fetch courses", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
 		return
 	}
 
+	if err := localizeCourses(c, g.translationsRepo, courses); err != nil {
+		logger.Error("Failed to localize courses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
 	c.JSON(http.StatusOK, courses)
 }
 
 // Update godoc
 // @Summary 	update course
+// @Description Updates content only, publication goes through PATCH /courses/{id}/status.
+// @Description For a published course whose title or description is edited by a teacher the new title and description are saved
+// @Description as a pending revision (202) until a content lead approves it, enrollment settings are applied at once.
 // @Tags 		courses
 // @Accept 		json
 // @Produce 	json
 // @Param 		title 			query 		string 		true 	"title"
 // @Param 		description 	query 		string 		true 	"description"
-// @Param 		is_published	query 		boolean		true 	"is_published"
+// @Param 		enrollment 		query 		string 		false 	"open or closed, unchanged if empty"
+// @Param 		capacity 		query 		int 		false 	"Maximum of active students, 0 for unlimited, unchanged if omitted"
 // @Success 	200  	"OK"
+// @Success 	202 			{object} 	object{revision=int,status=string} "Pending approval"
 // @Failure 	400 			{object} 	models.ApiError
 // @Failure 	500 			{object} 	models.ApiError
 // @Router 		/courses/{id} [put]
@@ -141,7 +194,7 @@ func (g *CoursesHandlers) Update(c *gin.Context) {
 		return
 	}
 
-	_, err = g.coursesRepo.FindById(c, id)
+	current, err := g.coursesRepo.FindById(c, id)
 	if err != nil {
 		logger.Error("Requested course not found", zap.Error(err))
 		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
@@ -160,7 +213,19 @@ func (g *CoursesHandlers) Update(c *gin.Context) {
 	updCourse := models.Course{
 		Name:         request.Course_title,
 		Description:  request.Description,
-		Is_published: request.Is_published,
+		Enrollment:   current.Enrollment,
+		Capacity:     current.Capacity,
+	}
+	if !applyEnrollmentSettings(c, &updCourse, request) {
+		return
+	}
+
+	// настройки записи на курс одобрения не требуют, только содержимое
+	pending := newCourseRevisionData(updCourse)
+	staged := pending != newCourseRevisionData(current) && needsApproval(c, current.Status)
+	if staged {
+		updCourse.Name = current.Name
+		updCourse.Description = current.Description
 	}
 
 	err = g.coursesRepo.Update(c, id, updCourse)
@@ -170,12 +235,25 @@ func (g *CoursesHandlers) Update(c *gin.Context) {
 		return
 	}
 
+	if staged {
+		stagePendingRevision(c, g.revisionsRepo, models.EntityCourse, id, "updated", pending)
+		return
+	}
+
+	_, err = recordRevision(c, g.revisionsRepo, models.EntityCourse, id, current.Status, "updated", newCourseRevisionData(updCourse))
+	if err != nil {
+		logger.Error("Failed to record course revision", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+		return
+	}
+
 	c.Status(http.StatusOK)
 
 }
 
 // Delete godoc
 // @Summary		delete course by id
+// @Description Moves to the trash, admins can restore it until the retention period ends
 // @Tags 		courses
 // @Accept 		json
 // @Produce 	json
@@ -211,3 +289,108 @@ func (g *CoursesHandlers) Delete(c *gin.Context) {
 	c.Status(http.StatusOK)
 
 }
+
+// FindLessons	godoc
+// @Summary 	lessons of the course in study order
+// @Tags 		courses
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	[]models.CourseLesson "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/lessons [get]
+func (h *CoursesHandlers) FindLessons(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	lessons, err := h.coursesRepo.FindLessons(c, course.Id, !utils.CanManageContent(c))
+	if err != nil {
+		logger.Error("Failed to fetch course lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, lessons)
+}
+
+// SetLessons	godoc
+// @Summary 	replace lessons of the course
+// @Description Lessons are stored in the given order
+// @Tags 		courses
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 					true 	"Course id"
+// @Param 		request body 		courseLessonsRequest 	true 	"Ordered lesson ids"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/lessons [put]
+func (h *CoursesHandlers) SetLessons(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	var request courseLessonsRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+
+	seen := make(map[int]bool, len(request.Lesson_ids))
+	for _, id := range request.Lesson_ids {
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Duplicate lesson id "+strconv.Itoa(id)))
+			return
+		}
+		seen[id] = true
+	}
+
+	err := h.coursesRepo.SetLessons(c, course.Id, request.Lesson_ids)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to set course lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// applyEnrollmentSettings переносит в курс способ записи и лимит мест из запроса
+func applyEnrollmentSettings(c *gin.Context, course *models.Course, request courseRequest) bool {
+	switch request.Enrollment {
+	case "":
+	case models.CourseEnrollmentOpen, models.CourseEnrollmentClosed:
+		course.Enrollment = request.Enrollment
+	default:
+		c.JSON(http.StatusBadRequest, models.NewApiError("Enrollment must be open or closed"))
+		return false
+	}
+
+	if request.Capacity != nil {
+		switch {
+		case *request.Capacity < 0:
+			c.JSON(http.StatusBadRequest, models.NewApiError("Capacity must not be negative"))
+			return false
+		case *request.Capacity == 0:
+			course.Capacity = nil
+		default:
+			course.Capacity = request.Capacity
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type EnrollmentHandler struct {
+	enrollmentsRepo *repositories.EnrollmentsRepository
+	coursesRepo     *repositories.Coursesrepository
+	usersRepo       *repositories.UsersRepository
+}
+
+func NewEnrollmentHandler(
+	enrollmentsRepo *repositories.EnrollmentsRepository,
+	coursesRepo *repositories.Coursesrepository,
+	usersRepo *repositories.UsersRepository) *EnrollmentHandler {
+	return &EnrollmentHandler{
+		enrollmentsRepo: enrollmentsRepo,
+		coursesRepo:     coursesRepo,
+		usersRepo:       usersRepo,
+	}
+}
+
+type enrollStudentRequest struct {
+	User_uuid string `json:"user_uuid"`
+}
+
+type enrollmentStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// enrollmentStatusFilter читает ?status=, пустая строка — все статусы
+func enrollmentStatusFilter(c *gin.Context) (string, bool) {
+	status := c.Query("status")
+	if status != "" && !slices.Contains(models.EnrollmentStatuses, status) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Status must be one of "+strings.Join(models.EnrollmentStatuses, ", ")))
+		return "", false
+	}
+	return status, true
+}
+
+// Enroll godoc
+// @Summary 	enroll yourself in an open course
+// @Description When the course is full the student is put on the waitlist and gets a seat as soon as one frees up
+// @Tags 		enrollments
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	models.Enrollment "active or waitlisted"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError "Course is closed"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/enroll [post]
+func (h *EnrollmentHandler) Enroll(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("unauthorized"))
+		return
+	}
+
+	if course.Enrollment == models.CourseEnrollmentClosed {
+		c.JSON(http.StatusForbidden, models.NewApiError("Course is closed, students are enrolled by teachers"))
+		return
+	}
+
+	enrollment, err := h.enrollmentsRepo.Enroll(c, course.Id, userUUID, nil)
+	if err != nil {
+		logger.Error("Failed to enroll", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Drop godoc
+// @Summary 	leave the course or its waitlist
+// @Tags 		enrollments
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	models.Enrollment "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError "Not enrolled"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/drop [post]
+func (h *EnrollmentHandler) Drop(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("unauthorized"))
+		return
+	}
+
+	enrollment, err := h.enrollmentsRepo.SetStatus(c, course.Id, userUUID, models.EnrollmentDropped)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("You are not enrolled in this course"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to drop course", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// MyCourses godoc
+// @Summary 	courses of the current user
+// @Tags 		enrollments
+// @Produce 	json
+// @Param 		status 	query 		string 	false 	"active, completed, dropped or waitlisted"
+// @Success 	200 	{object} 	[]models.Enrollment "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/courses [get]
+func (h *EnrollmentHandler) MyCourses(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("unauthorized"))
+		return
+	}
+
+	status, ok := enrollmentStatusFilter(c)
+	if !ok {
+		return
+	}
+
+	enrollments, err := h.enrollmentsRepo.FindByUser(c, userUUID, status)
+	if err != nil {
+		logger.Error("Failed to fetch user courses", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollments)
+}
+
+// Roster godoc
+// @Summary 	students of the course
+// @Tags 		enrollments
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Param 		status 	query 		string 	false 	"active, completed, dropped or waitlisted"
+// @Success 	200 	{object} 	[]models.Enrollment "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/enrollments [get]
+func (h *EnrollmentHandler) Roster(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	status, ok := enrollmentStatusFilter(c)
+	if !ok {
+		return
+	}
+
+	enrollments, err := h.enrollmentsRepo.FindByCourse(c, course.Id, status)
+	if err != nil {
+		logger.Error("Failed to fetch course roster", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollments)
+}
+
+// EnrollStudent godoc
+// @Summary 	enroll a student, works for open and closed courses
+// @Description Capacity still applies, a student over the limit is put on the waitlist
+// @Tags 		enrollments
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 					true 	"Course id"
+// @Param 		request body 		enrollStudentRequest 	true 	"Student uuid"
+// @Success 	200 	{object} 	models.Enrollment "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/enrollments [post]
+func (h *EnrollmentHandler) EnrollStudent(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	var request enrollStudentRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+
+	user, err := h.usersRepo.FindByUUID(c, request.User_uuid)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("User not found"))
+		return
+	}
+
+	var enrolledBy *uuid.UUID
+	if teacherUUID, ok := utils.CurrentUserUUID(c); ok {
+		enrolledBy = &teacherUUID
+	}
+
+	enrollment, err := h.enrollmentsRepo.Enroll(c, course.Id, user.UUID, enrolledBy)
+	if err != nil {
+		logger.Error("Failed to enroll student", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Student has been enrolled", zap.Int("course_id", course.Id), zap.String("user_uuid", user.UUID.String()), zap.String("status", enrollment.Status))
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// UpdateStatus godoc
+// @Summary 	change enrollment status of a student
+// @Description A freed seat goes to the first student on the waitlist
+// @Tags 		enrollments
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 						true 	"Course id"
+// @Param 		uuid 	path		string 						true 	"Student uuid"
+// @Param 		request body 		enrollmentStatusRequest 	true 	"active, completed, dropped or waitlisted"
+// @Success 	200 	{object} 	models.Enrollment "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	409 	{object}	models.ApiError "Course is full"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/enrollments/{uuid} [patch]
+func (h *EnrollmentHandler) UpdateStatus(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	userUUID, err := uuid.Parse(c.Param("uuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user uuid"))
+		return
+	}
+
+	var request enrollmentStatusRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+	if !slices.Contains(models.EnrollmentStatuses, request.Status) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Status must be one of "+strings.Join(models.EnrollmentStatuses, ", ")))
+		return
+	}
+
+	enrollment, err := h.enrollmentsRepo.SetStatus(c, course.Id, userUUID, request.Status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Student is not enrolled in this course"))
+		return
+	}
+	if errors.Is(err, repositories.ErrCourseFull) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update enrollment", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
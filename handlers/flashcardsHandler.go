@@ -0,0 +1,617 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDueFlashcardsLimit = 50
+	defaultNewFlashcardsLimit = 10
+	maxDueFlashcardsLimit     = 200
+)
+
+type FlashcardsHandler struct {
+	flashcardsRepo *repositories.FlashcardsRepository
+	lessonsRepo    *repositories.Lessonsrepository
+	subjectsRepo   *repositories.SubjectsRepository
+}
+
+func NewFlashcardsHandler(
+	flashcardsRepo *repositories.FlashcardsRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	subjectsRepo *repositories.SubjectsRepository) *FlashcardsHandler {
+	return &FlashcardsHandler{
+		flashcardsRepo: flashcardsRepo,
+		lessonsRepo:    lessonsRepo,
+		subjectsRepo:   subjectsRepo,
+	}
+}
+
+type flashcardDeckRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Subject_id  *int   `json:"subject_id"`
+	Lesson_id   *int   `json:"lesson_id"`
+}
+
+type flashcardRequest struct {
+	Front string `json:"front"`
+	Back  string `json:"back"`
+}
+
+type flashcardReviewRequest struct {
+	Grade *int `json:"grade"`
+}
+
+func (h *FlashcardsHandler) bindDeck(c *gin.Context) (models.FlashcardDeck, bool) {
+	logger := logger.GetLogger()
+
+	var request flashcardDeckRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return models.FlashcardDeck{}, false
+	}
+
+	deck := models.FlashcardDeck{
+		Title:       strings.TrimSpace(request.Title),
+		Description: strings.TrimSpace(request.Description),
+		Subject_id:  request.Subject_id,
+		Lesson_id:   request.Lesson_id,
+	}
+	if deck.Title == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Deck title is required"))
+		return models.FlashcardDeck{}, false
+	}
+
+	var err error
+	switch {
+	case (deck.Subject_id == nil) == (deck.Lesson_id == nil):
+		c.JSON(http.StatusBadRequest, models.NewApiError("Either subject_id or lesson_id is required"))
+		return models.FlashcardDeck{}, false
+	case deck.Lesson_id != nil:
+		_, err = h.lessonsRepo.FindById(c, *deck.Lesson_id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Lesson not found"))
+			return models.FlashcardDeck{}, false
+		}
+	default:
+		_, err = h.subjectsRepo.FindById(c, *deck.Subject_id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Subject not found"))
+			return models.FlashcardDeck{}, false
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to find deck subject or lesson", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.FlashcardDeck{}, false
+	}
+
+	return deck, true
+}
+
+func bindFlashcard(c *gin.Context) (models.Flashcard, bool) {
+	logger := logger.GetLogger()
+
+	var request flashcardRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return models.Flashcard{}, false
+	}
+
+	card := models.Flashcard{Front: strings.TrimSpace(request.Front), Back: strings.TrimSpace(request.Back)}
+	if card.Front == "" || card.Back == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Card needs front and back"))
+		return models.Flashcard{}, false
+	}
+	return card, true
+}
+
+// loadDeck загружает колоду; колоды неопубликованных уроков видят только авторы контента
+func (h *FlashcardsHandler) loadDeck(c *gin.Context, id int) (models.FlashcardDeck, bool) {
+	logger := logger.GetLogger()
+
+	deck, err := h.flashcardsRepo.FindDeck(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Deck not found"))
+		return models.FlashcardDeck{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find deck", zap.Int("deck_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.FlashcardDeck{}, false
+	}
+
+	if deck.Lesson_id != nil {
+		if _, ok := findAttachedContent(c, h.lessonsRepo, nil, deck.Lesson_id, nil, "Deck not found"); !ok {
+			return models.FlashcardDeck{}, false
+		}
+	}
+
+	return deck, true
+}
+
+func (h *FlashcardsHandler) findDeck(c *gin.Context) (models.FlashcardDeck, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid deck id"))
+		return models.FlashcardDeck{}, false
+	}
+	return h.loadDeck(c, id)
+}
+
+// findCard загружает карточку из параметра :id и проверяет, что ее колода видна пользователю
+func (h *FlashcardsHandler) findCard(c *gin.Context) (models.Flashcard, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid card id"))
+		return models.Flashcard{}, false
+	}
+
+	card, err := h.flashcardsRepo.FindCard(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Card not found"))
+		return models.Flashcard{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find card", zap.Int("card_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Flashcard{}, false
+	}
+
+	if _, ok := h.loadDeck(c, card.Deck_id); !ok {
+		return models.Flashcard{}, false
+	}
+	return card, true
+}
+
+// queryDeckId читает необязательный ?deck_id=
+func queryDeckId(c *gin.Context) (int, bool) {
+	raw := c.Query("deck_id")
+	if raw == "" {
+		return 0, true
+	}
+	deckId, err := strconv.Atoi(raw)
+	if err != nil || deckId <= 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid deck id"))
+		return 0, false
+	}
+	return deckId, true
+}
+
+// FindDecks godoc
+// @Summary 	list flashcard decks
+// @Description Decks of a subject include decks of its lessons. Students see decks of published lessons only.
+// @Tags 		flashcards
+// @Produce 	json
+// @Param 		subject_id 	query 		int 	false 	"Decks of the subject and its lessons"
+// @Param 		lesson_id 	query 		int 	false 	"Decks of the lesson"
+// @Success 	200 	{object} 	[]models.FlashcardDeck "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcard-decks [get]
+func (h *FlashcardsHandler) FindDecks(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	filter := models.FlashcardDeckFilter{Visible_only: !utils.CanManageContent(c)}
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"subject_id", &filter.Subject_id}, {"lesson_id", &filter.Lesson_id}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid "+param.name))
+			return
+		}
+		*param.target = value
+	}
+
+	decks, err := h.flashcardsRepo.FindDecks(c, filter)
+	if err != nil {
+		logger.Error("Failed to fetch decks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, decks)
+}
+
+// FindDeck godoc
+// @Summary 	get a flashcard deck with its cards
+// @Tags 		flashcards
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Deck id"
+// @Success 	200 	{object} 	models.FlashcardDeck "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcard-decks/{id} [get]
+func (h *FlashcardsHandler) FindDeck(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	deck, ok := h.findDeck(c)
+	if !ok {
+		return
+	}
+
+	cards, err := h.flashcardsRepo.FindCards(c, deck.Id)
+	if err != nil {
+		logger.Error("Failed to fetch cards", zap.Int("deck_id", deck.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+	deck.Cards = cards
+
+	c.JSON(http.StatusOK, deck)
+}
+
+// CreateDeck godoc
+// @Summary 	create a flashcard deck
+// @Description A deck belongs to either a subject or a lesson
+// @Tags 		flashcards
+// @Accept 		json
+// @Produce 	json
+// @Param 		request body 		flashcardDeckRequest 	true 	"Deck"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcard-decks [post]
+func (h *FlashcardsHandler) CreateDeck(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	deck, ok := h.bindDeck(c)
+	if !ok {
+		return
+	}
+	if userUUID, ok := utils.CurrentUserUUID(c); ok {
+		deck.Created_by = &userUUID
+	}
+
+	id, err := h.flashcardsRepo.CreateDeck(c, deck)
+	if err != nil {
+		logger.Error("Failed to create deck", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+// UpdateDeck godoc
+// @Summary 	update a flashcard deck
+// @Tags 		flashcards
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 					true 	"Deck id"
+// @Param 		request body 		flashcardDeckRequest 	true 	"Deck"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcard-decks/{id} [put]
+func (h *FlashcardsHandler) UpdateDeck(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid deck id"))
+		return
+	}
+
+	deck, ok := h.bindDeck(c)
+	if !ok {
+		return
+	}
+
+	err = h.flashcardsRepo.UpdateDeck(c, id, deck)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Deck not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update deck", zap.Int("deck_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DeleteDeck godoc
+// @Summary 	delete a flashcard deck
+// @Description Deletes the cards and the review history of every student
+// @Tags 		flashcards
+// @Param 		id 		path		int 	true 	"Deck id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcard-decks/{id} [delete]
+func (h *FlashcardsHandler) DeleteDeck(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid deck id"))
+		return
+	}
+
+	err = h.flashcardsRepo.DeleteDeck(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Deck not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete deck", zap.Int("deck_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// CreateCard godoc
+// @Summary 	add a card to the end of a deck
+// @Tags 		flashcards
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Deck id"
+// @Param 		request body 		flashcardRequest 	true 	"Card"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcard-decks/{id}/cards [post]
+func (h *FlashcardsHandler) CreateCard(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	deck, ok := h.findDeck(c)
+	if !ok {
+		return
+	}
+
+	card, ok := bindFlashcard(c)
+	if !ok {
+		return
+	}
+	card.Deck_id = deck.Id
+
+	id, err := h.flashcardsRepo.CreateCard(c, card)
+	if err != nil {
+		logger.Error("Failed to create card", zap.Int("deck_id", deck.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+// UpdateCard godoc
+// @Summary 	update a card
+// @Description The review schedule of students is kept
+// @Tags 		flashcards
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Card id"
+// @Param 		request body 		flashcardRequest 	true 	"Card"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcards/{id} [put]
+func (h *FlashcardsHandler) UpdateCard(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	existing, ok := h.findCard(c)
+	if !ok {
+		return
+	}
+
+	card, ok := bindFlashcard(c)
+	if !ok {
+		return
+	}
+
+	err := h.flashcardsRepo.UpdateCard(c, existing.Id, card)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Card not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update card", zap.Int("card_id", existing.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// DeleteCard godoc
+// @Summary 	delete a card
+// @Tags 		flashcards
+// @Param 		id 		path		int 	true 	"Card id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcards/{id} [delete]
+func (h *FlashcardsHandler) DeleteCard(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid card id"))
+		return
+	}
+
+	err = h.flashcardsRepo.DeleteCard(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Card not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete card", zap.Int("card_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Review godoc
+// @Summary 	review a card
+// @Description Grade how well the answer was recalled from 0 (blackout) to 5 (perfect).
+// @Description Grades from 3 count as recalled; the next review is scheduled by the SM-2 algorithm.
+// @Tags 		flashcards
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 					true 	"Card id"
+// @Param 		request body 		flashcardReviewRequest 	true 	"Grade"
+// @Success 	200 	{object} 	models.FlashcardState "Next review"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/flashcards/{id}/review [post]
+func (h *FlashcardsHandler) Review(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	card, ok := h.findCard(c)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	var request flashcardReviewRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+	if request.Grade == nil || *request.Grade < models.ReviewGradeMin || *request.Grade > models.ReviewGradeMax {
+		c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("grade must be between %d and %d", models.ReviewGradeMin, models.ReviewGradeMax)))
+		return
+	}
+
+	state, err := h.flashcardsRepo.Review(c, userUUID, card.Id, *request.Grade)
+	if err != nil {
+		logger.Error("Failed to save review", zap.Int("card_id", card.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// FindDue godoc
+// @Summary 	cards due for review
+// @Description Returns cards whose review is due, most overdue first, followed by new cards
+// @Description from decks the student has started (or from deck_id when it is given).
+// @Tags 		flashcards
+// @Produce 	json
+// @Param 		deck_id 	query 		int 	false 	"Only cards of the deck"
+// @Param 		limit 		query 		int 	false 	"Due cards to return, 50 by default"
+// @Param 		new 		query 		int 	false 	"New cards to add, 10 by default, 0 to skip"
+// @Success 	200 	{object} 	[]models.DueFlashcard "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/reviews/due [get]
+func (h *FlashcardsHandler) FindDue(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	deckId, ok := queryDeckId(c)
+	if !ok {
+		return
+	}
+
+	limit, newLimit := defaultDueFlashcardsLimit, defaultNewFlashcardsLimit
+	for _, param := range []struct {
+		name   string
+		target *int
+		min    int
+	}{{"limit", &limit, 1}, {"new", &newLimit, 0}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < param.min || value > maxDueFlashcardsLimit {
+			c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("%s must be between %d and %d", param.name, param.min, maxDueFlashcardsLimit)))
+			return
+		}
+		*param.target = value
+	}
+
+	cards, err := h.flashcardsRepo.FindDue(c, userUUID, deckId, limit, newLimit, !utils.CanManageContent(c))
+	if err != nil {
+		logger.Error("Failed to fetch due cards", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, cards)
+}
+
+// FindStats godoc
+// @Summary 	flashcard review statistics
+// @Description Without deck_id the statistics cover every deck the student has started.
+// @Description Mature cards have a review interval of 21 days or more.
+// @Tags 		flashcards
+// @Produce 	json
+// @Param 		deck_id 	query 		int 	false 	"Only cards of the deck"
+// @Success 	200 	{object} 	models.FlashcardStats "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/reviews/stats [get]
+func (h *FlashcardsHandler) FindStats(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	deckId, ok := queryDeckId(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.flashcardsRepo.FindStats(c, userUUID, deckId, !utils.CanManageContent(c))
+	if err != nil {
+		logger.Error("Failed to fetch review statistics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type GamificationHandler struct {
+	gamificationRepo *repositories.GamificationRepository
+}
+
+func NewGamificationHandler(gamificationRepo *repositories.GamificationRepository) *GamificationHandler {
+	return &GamificationHandler{gamificationRepo: gamificationRepo}
+}
+
+type badgeRequest struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Icon        string           `json:"icon"`
+	Rule        models.BadgeRule `json:"rule"`
+	Is_active   *bool            `json:"is_active"`
+}
+
+type timeZoneRequest struct {
+	Time_zone string `json:"time_zone"`
+}
+
+func bindBadge(c *gin.Context) (models.Badge, bool) {
+	logger := logger.GetLogger()
+
+	var request badgeRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return models.Badge{}, false
+	}
+
+	badge := models.Badge{
+		Name:        strings.TrimSpace(request.Name),
+		Description: strings.TrimSpace(request.Description),
+		Icon:        strings.TrimSpace(request.Icon),
+		Rule:        request.Rule,
+		Is_active:   request.Is_active == nil || *request.Is_active,
+	}
+	if badge.Name == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Badge name is required"))
+		return models.Badge{}, false
+	}
+	if err := utils.ValidateBadgeRule(badge.Rule); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return models.Badge{}, false
+	}
+
+	return badge, true
+}
+
+// MyAchievements godoc
+// @Summary 	XP, daily streak and badges of the current user
+// @Description XP is awarded for completing lessons and courses and for quiz scores (retakes earn only the improvement).
+// @Description The streak counts consecutive days with learning activity in the user's time zone.
+// @Tags 		gamification
+// @Produce 	json
+// @Success 	200 	{object} 	models.Achievements "OK"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/achievements [get]
+func (h *GamificationHandler) MyAchievements(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	achievements, err := h.gamificationRepo.FindAchievements(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch achievements", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, achievements)
+}
+
+// SetTimeZone godoc
+// @Summary 	set the time zone used for daily streaks
+// @Tags 		gamification
+// @Accept 		json
+// @Param 		request body 		timeZoneRequest 	true 	"IANA time zone, e.g. Asia/Almaty"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/time-zone [put]
+func (h *GamificationHandler) SetTimeZone(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	var request timeZoneRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+	// Local зависит от сервера, поэтому не принимается
+	if request.Time_zone == "" || request.Time_zone == "Local" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("time_zone is required"))
+		return
+	}
+	if _, err := time.LoadLocation(request.Time_zone); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Unknown time zone"))
+		return
+	}
+
+	if err := h.gamificationRepo.SetTimeZone(c, userUUID, request.Time_zone); err != nil {
+		logger.Error("Failed to save time zone", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// FindBadges godoc
+// @Summary 	list badges
+// @Description Learners see badges that can still be earned, admins see retired badges too
+// @Tags 		gamification
+// @Produce 	json
+// @Success 	200 	{object} 	[]models.Badge "OK"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/badges [get]
+func (h *GamificationHandler) FindBadges(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	badges, err := h.gamificationRepo.FindBadges(c, !utils.HasRole(c, models.RoleAdmin))
+	if err != nil {
+		logger.Error("Failed to fetch badges", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, badges)
+}
+
+// CreateBadge godoc
+// @Summary 	define a badge
+// @Description The rule names a metric and a threshold, optionally limited to a subject, for example
+// @Description {"metric": "lessons_completed", "subject_id": 3, "threshold": 5}. Metrics: lessons_completed,
+// @Description courses_completed, quizzes_passed, perfect_quizzes, xp, streak_days.
+// @Description Learners who already meet the rule receive the badge with their next learning activity.
+// @Tags 		gamification
+// @Accept 		json
+// @Produce 	json
+// @Param 		request body 		badgeRequest 	true 	"Badge"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/badges [post]
+func (h *GamificationHandler) CreateBadge(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	badge, ok := bindBadge(c)
+	if !ok {
+		return
+	}
+
+	id, err := h.gamificationRepo.CreateBadge(c, badge)
+	if err != nil {
+		logger.Error("Failed to create badge", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Badge has been created", zap.Int("badge_id", id), zap.String("metric", badge.Rule.Metric))
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+// UpdateBadge godoc
+// @Summary 	update a badge
+// @Description is_active=false retires the badge: nobody earns it anymore, learners keep the badges they earned
+// @Tags 		gamification
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 			true 	"Badge id"
+// @Param 		request body 		badgeRequest 	true 	"Badge"
+// @Success 	200 	{object} 	models.Badge "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/badges/{id} [put]
+func (h *GamificationHandler) UpdateBadge(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid badge id"))
+		return
+	}
+
+	badge, ok := bindBadge(c)
+	if !ok {
+		return
+	}
+
+	err = h.gamificationRepo.UpdateBadge(c, id, badge)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Badge not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update badge", zap.Int("badge_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	updated, err := h.gamificationRepo.FindBadge(c, id)
+	if err != nil {
+		logger.Error("Failed to find badge", zap.Int("badge_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
@@ -0,0 +1,528 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// GradebookHandler считает оценки учеников курса по взвешенным категориям с учетом ручных переопределений
+type GradebookHandler struct {
+	gradebookRepo   *repositories.GradebookRepository
+	enrollmentsRepo *repositories.EnrollmentsRepository
+	coursesRepo     *repositories.Coursesrepository
+}
+
+func NewGradebookHandler(
+	gradebookRepo *repositories.GradebookRepository,
+	enrollmentsRepo *repositories.EnrollmentsRepository,
+	coursesRepo *repositories.Coursesrepository) *GradebookHandler {
+	return &GradebookHandler{
+		gradebookRepo:   gradebookRepo,
+		enrollmentsRepo: enrollmentsRepo,
+		coursesRepo:     coursesRepo,
+	}
+}
+
+type gradebookSettingsRequest struct {
+	Categories    []models.GradeCategory `json:"categories"`
+	Letter_scheme []models.LetterGrade   `json:"letter_scheme"`
+}
+
+type gradeOverrideRequest struct {
+	User_uuid string   `json:"user_uuid"`
+	Item_type string   `json:"item_type"`
+	Item_id   int      `json:"item_id"`
+	Percent   *float64 `json:"percent"` // null снимает переопределение
+	Reason    string   `json:"reason"`
+}
+
+// gradedEnrollment — в журнал попадают активные ученики и завершившие курс
+func gradedEnrollment(enrollment models.Enrollment) bool {
+	return enrollment.Status == models.EnrollmentActive || enrollment.Status == models.EnrollmentCompleted
+}
+
+// currentOverrides сворачивает историю (новые первыми) в действующие переопределения по ученикам
+func currentOverrides(history []models.GradeOverride) map[uuid.UUID]map[utils.GradeKey]float64 {
+	seen := make(map[uuid.UUID]map[utils.GradeKey]bool)
+	overrides := make(map[uuid.UUID]map[utils.GradeKey]float64)
+	for _, override := range history {
+		key := utils.GradeKey{Type: override.Item_type, Id: override.Item_id}
+		if seen[override.User_uuid] == nil {
+			seen[override.User_uuid] = make(map[utils.GradeKey]bool)
+			overrides[override.User_uuid] = make(map[utils.GradeKey]float64)
+		}
+		if seen[override.User_uuid][key] {
+			continue
+		}
+		seen[override.User_uuid][key] = true
+		if override.Percent != nil {
+			overrides[override.User_uuid][key] = *override.Percent
+		}
+	}
+	return overrides
+}
+
+// gradebookData загружает настройки и элементы журнала курса, результаты и переопределения.
+// userUUID = nil — данные всех учеников.
+func (h *GradebookHandler) gradebookData(c *gin.Context, courseId int, userUUID *uuid.UUID) (
+	models.GradebookSettings,
+	[]models.GradebookItem,
+	map[uuid.UUID]map[utils.GradeKey]models.GradeScore,
+	map[uuid.UUID]map[utils.GradeKey]float64,
+	error) {
+	settings, err := h.gradebookRepo.FindSettings(c, courseId)
+	if err != nil {
+		return models.GradebookSettings{}, nil, nil, nil, err
+	}
+
+	items, err := h.gradebookRepo.FindItems(c, courseId)
+	if err != nil {
+		return models.GradebookSettings{}, nil, nil, nil, err
+	}
+	utils.AssignGradeCategories(items, settings.Categories)
+
+	scoreRows, err := h.gradebookRepo.FindScores(c, courseId, userUUID)
+	if err != nil {
+		return models.GradebookSettings{}, nil, nil, nil, err
+	}
+	scores := make(map[uuid.UUID]map[utils.GradeKey]models.GradeScore)
+	for _, score := range scoreRows {
+		if scores[score.User_uuid] == nil {
+			scores[score.User_uuid] = make(map[utils.GradeKey]models.GradeScore)
+		}
+		scores[score.User_uuid][utils.GradeKey{Type: score.Item_type, Id: score.Item_id}] = score
+	}
+
+	history, err := h.gradebookRepo.FindOverrides(c, courseId, userUUID)
+	if err != nil {
+		return models.GradebookSettings{}, nil, nil, nil, err
+	}
+
+	return settings, items, scores, currentOverrides(history), nil
+}
+
+// buildGradebook считает оценки всех учеников курса
+func (h *GradebookHandler) buildGradebook(c *gin.Context, course models.Course) (models.Gradebook, error) {
+	enrollments, err := h.enrollmentsRepo.FindByCourse(c, course.Id, "")
+	if err != nil {
+		return models.Gradebook{}, err
+	}
+
+	settings, items, scores, overrides, err := h.gradebookData(c, course.Id, nil)
+	if err != nil {
+		return models.Gradebook{}, err
+	}
+
+	gradebook := models.Gradebook{
+		Course_id:  course.Id,
+		Categories: settings.Categories,
+		Items:      items,
+		Students:   make([]models.StudentGrade, 0, len(enrollments)),
+	}
+	now := time.Now()
+	for _, enrollment := range enrollments {
+		if !gradedEnrollment(enrollment) {
+			continue
+		}
+		grade := utils.ComputeStudentGrade(course.Id, items, settings, scores[enrollment.User_uuid], overrides[enrollment.User_uuid], now)
+		grade.User_uuid = enrollment.User_uuid
+		grade.User_name = enrollment.User_name
+		gradebook.Students = append(gradebook.Students, grade)
+	}
+	slices.SortStableFunc(gradebook.Students, func(a, b models.StudentGrade) int {
+		return strings.Compare(a.User_name, b.User_name)
+	})
+
+	return gradebook, nil
+}
+
+// FindGradebook godoc
+// @Summary 	gradebook of the course
+// @Description Quizzes count with the best finished attempt, assignments with the latest graded submission.
+// @Description Missing work counts as 0 only for assignments past due_at. Category percent is points based,
+// @Description the course percent is the weighted average of categories that have grades. Overrides replace
+// @Description the computed percent of an item or of the whole course.
+// @Tags 		gradebook
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	models.Gradebook "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/gradebook [get]
+func (h *GradebookHandler) FindGradebook(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	gradebook, err := h.buildGradebook(c, course)
+	if err != nil {
+		logger.Error("Failed to build gradebook", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gradebook)
+}
+
+// formatPercent печатает процент для CSV, пустая ячейка — оценки нет
+func formatPercent(percent *float64) string {
+	if percent == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*percent, 'f', 2, 64)
+}
+
+// ExportGradebook godoc
+// @Summary 	export gradebook as CSV
+// @Description One row per student: item percents, category percents, final percent and letter
+// @Tags 		gradebook
+// @Produce 	text/csv
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{file} 		file 	"CSV"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/gradebook/export [get]
+func (h *GradebookHandler) ExportGradebook(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	gradebook, err := h.buildGradebook(c, course)
+	if err != nil {
+		logger.Error("Failed to build gradebook", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	header := []string{"student", "user_uuid"}
+	for _, item := range gradebook.Items {
+		header = append(header, fmt.Sprintf("%s %d: %s", item.Type, item.Id, item.Title))
+	}
+	for _, category := range gradebook.Categories {
+		header = append(header, category.Name)
+	}
+	header = append(header, "percent", "letter")
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="gradebook-course-%d.csv"`, course.Id))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	// имена учеников и названия заданий вводят пользователи, в таблице они не должны выполниться как формулы
+	writer.Write(utils.CSVSafeRow(header))
+	for _, student := range gradebook.Students {
+		row := []string{student.User_name, student.User_uuid.String()}
+		for _, item := range student.Items {
+			row = append(row, formatPercent(item.Percent))
+		}
+		for _, category := range student.Categories {
+			row = append(row, formatPercent(category.Percent))
+		}
+		row = append(row, formatPercent(student.Percent), student.Letter)
+		writer.Write(utils.CSVSafeRow(row))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		// заголовки уже отправлены, клиент получит оборванный файл
+		logger.Error("Failed to write gradebook", zap.Int("course_id", course.Id), zap.Error(err))
+	}
+}
+
+// MyGrades godoc
+// @Summary 	grades of the current user
+// @Description Grade in every active or completed course of the student
+// @Tags 		gradebook
+// @Produce 	json
+// @Success 	200 	{object} 	[]models.StudentGrade "OK"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/grades [get]
+func (h *GradebookHandler) MyGrades(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	enrollments, err := h.enrollmentsRepo.FindByUser(c, userUUID, "")
+	if err != nil {
+		logger.Error("Failed to fetch enrollments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	grades := make([]models.StudentGrade, 0, len(enrollments))
+	now := time.Now()
+	for _, enrollment := range enrollments {
+		if !gradedEnrollment(enrollment) {
+			continue
+		}
+
+		settings, items, scores, overrides, err := h.gradebookData(c, enrollment.Course_id, &userUUID)
+		if err != nil {
+			logger.Error("Failed to build grade", zap.Int("course_id", enrollment.Course_id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		grade := utils.ComputeStudentGrade(enrollment.Course_id, items, settings, scores[userUUID], overrides[userUUID], now)
+		grade.Course_title = enrollment.Course_title
+		grade.User_uuid = userUUID
+		grade.User_name = enrollment.User_name
+		grades = append(grades, grade)
+	}
+
+	c.JSON(http.StatusOK, grades)
+}
+
+// FindSettings godoc
+// @Summary 	gradebook settings of the course
+// @Description Courses without saved settings use Quizzes 40 / Assignments 60 and the A-F scheme
+// @Tags 		gradebook
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	models.GradebookSettings "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/gradebook/settings [get]
+func (h *GradebookHandler) FindSettings(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	settings, err := h.gradebookRepo.FindSettings(c, course.Id)
+	if err != nil {
+		logger.Error("Failed to fetch gradebook settings", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings godoc
+// @Summary 	replace gradebook settings of the course
+// @Description Categories need a unique name, a positive weight and item_type quiz or assignment. item_ids pins
+// @Description items to a category, a category without item_ids takes the remaining items of its type.
+// @Description The letter scheme needs unique letters and the lowest letter must start at 0 percent.
+// @Tags 		gradebook
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 						true 	"Course id"
+// @Param 		request body 		gradebookSettingsRequest 	true 	"Settings"
+// @Success 	200 	{object} 	models.GradebookSettings "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/gradebook/settings [put]
+func (h *GradebookHandler) UpdateSettings(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	var request gradebookSettingsRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+
+	settings := models.GradebookSettings{
+		Course_id:     course.Id,
+		Categories:    request.Categories,
+		Letter_scheme: request.Letter_scheme,
+	}
+	if err := utils.ValidateGradebookSettings(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+
+	if err := h.gradebookRepo.SaveSettings(c, settings); err != nil {
+		logger.Error("Failed to save gradebook settings", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	settings, err := h.gradebookRepo.FindSettings(c, course.Id)
+	if err != nil {
+		logger.Error("Failed to fetch gradebook settings", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// AddOverride godoc
+// @Summary 	override a grade
+// @Description Replaces the computed percent of a quiz, an assignment or, with item_type course, the whole course grade.
+// @Description Every change is kept in the history with its author and reason; percent null removes the override.
+// @Tags 		gradebook
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 					true 	"Course id"
+// @Param 		request body 		gradeOverrideRequest 	true 	"Override"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/gradebook/overrides [put]
+func (h *GradebookHandler) AddOverride(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	var request gradeOverrideRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+
+	studentUUID, err := uuid.Parse(request.User_uuid)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user uuid"))
+		return
+	}
+	if !slices.Contains(models.GradeItemTypes, request.Item_type) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Item type must be one of "+strings.Join(models.GradeItemTypes, ", ")))
+		return
+	}
+	if request.Percent != nil && (*request.Percent < 0 || *request.Percent > 100) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Percent must be between 0 and 100"))
+		return
+	}
+	request.Reason = strings.TrimSpace(request.Reason)
+	if request.Reason == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Reason is required"))
+		return
+	}
+
+	enrollment, err := h.enrollmentsRepo.Find(c, course.Id, studentUUID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !gradedEnrollment(enrollment)) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Student is not enrolled in the course"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to fetch enrollment", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	if request.Item_type == models.GradeItemCourse {
+		request.Item_id = course.Id
+	} else {
+		items, err := h.gradebookRepo.FindItems(c, course.Id)
+		if err != nil {
+			logger.Error("Failed to fetch gradebook items", zap.Int("course_id", course.Id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		if !slices.ContainsFunc(items, func(item models.GradebookItem) bool {
+			return item.Type == request.Item_type && item.Id == request.Item_id
+		}) {
+			c.JSON(http.StatusNotFound, models.NewApiError("Item not found in the course"))
+			return
+		}
+	}
+
+	var createdBy *uuid.UUID
+	if teacherUUID, ok := utils.CurrentUserUUID(c); ok {
+		createdBy = &teacherUUID
+	}
+
+	id, err := h.gradebookRepo.AddOverride(c, models.GradeOverride{
+		Course_id:  course.Id,
+		User_uuid:  studentUUID,
+		Item_type:  request.Item_type,
+		Item_id:    request.Item_id,
+		Percent:    request.Percent,
+		Reason:     request.Reason,
+		Created_by: createdBy,
+	})
+	if err != nil {
+		logger.Error("Failed to save grade override", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Grade has been overridden", zap.Int("course_id", course.Id), zap.String("user_uuid", studentUUID.String()),
+		zap.String("item_type", request.Item_type), zap.Int("item_id", request.Item_id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"id": id,
+	})
+}
+
+// FindOverrides godoc
+// @Summary 	history of grade overrides
+// @Tags 		gradebook
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Course id"
+// @Param 		user_uuid 	query 		string 	false 	"Only overrides of the student"
+// @Success 	200 	{object} 	[]models.GradeOverride "Newest first"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/gradebook/overrides [get]
+func (h *GradebookHandler) FindOverrides(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	var studentUUID *uuid.UUID
+	if raw := c.Query("user_uuid"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user uuid"))
+			return
+		}
+		studentUUID = &parsed
+	}
+
+	overrides, err := h.gradebookRepo.FindOverrides(c, course.Id, studentUUID)
+	if err != nil {
+		logger.Error("Failed to fetch grade overrides", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
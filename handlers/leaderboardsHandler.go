@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+type LeaderboardsHandler struct {
+	leaderboardsRepo *repositories.LeaderboardsRepository
+	coursesRepo      *repositories.Coursesrepository
+	enrollmentsRepo  *repositories.EnrollmentsRepository
+	classesRepo      *repositories.ClassesRepository
+}
+
+func NewLeaderboardsHandler(
+	leaderboardsRepo *repositories.LeaderboardsRepository,
+	coursesRepo *repositories.Coursesrepository,
+	enrollmentsRepo *repositories.EnrollmentsRepository,
+	classesRepo *repositories.ClassesRepository,
+) *LeaderboardsHandler {
+	return &LeaderboardsHandler{
+		leaderboardsRepo: leaderboardsRepo,
+		coursesRepo:      coursesRepo,
+		enrollmentsRepo:  enrollmentsRepo,
+		classesRepo:      classesRepo,
+	}
+}
+
+type leaderboardPrivacyRequest struct {
+	Visibility string `json:"visibility"`
+	Nickname   string `json:"nickname"`
+}
+
+// bindLeaderboardFilter разбирает ?metric=&period=&limit=; defaultMetric — метрика, если она не указана
+func bindLeaderboardFilter(c *gin.Context, defaultMetric string) (models.LeaderboardFilter, bool) {
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return models.LeaderboardFilter{}, false
+	}
+
+	filter := models.LeaderboardFilter{
+		Metric: c.DefaultQuery("metric", defaultMetric),
+		Period: c.DefaultQuery("period", models.LeaderboardPeriodWeek),
+		Limit:  defaultLeaderboardLimit,
+		Viewer: userUUID,
+	}
+	if !slices.Contains(models.LeaderboardMetrics, filter.Metric) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("metric must be one of: "+strings.Join(models.LeaderboardMetrics, ", ")))
+		return models.LeaderboardFilter{}, false
+	}
+	if !slices.Contains(models.LeaderboardPeriods, filter.Period) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("period must be one of: "+strings.Join(models.LeaderboardPeriods, ", ")))
+		return models.LeaderboardFilter{}, false
+	}
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, models.NewApiError("limit must be between 1 and "+strconv.Itoa(maxLeaderboardLimit)))
+			return models.LeaderboardFilter{}, false
+		}
+		filter.Limit = parsed
+	}
+	filter.Period_start = utils.LeaderboardPeriodStart(filter.Period, time.Now())
+
+	return filter, true
+}
+
+// respondLeaderboard собирает рейтинг с учетом приватности. lessonsTotal > 0 добавляет процент прохождения курса.
+func (h *LeaderboardsHandler) respondLeaderboard(c *gin.Context, filter models.LeaderboardFilter, lessonsTotal int) {
+	logger := logger.GetLogger()
+
+	rows, err := h.leaderboardsRepo.FindRows(c, filter)
+	if err != nil {
+		logger.Error("Failed to fetch leaderboard", zap.String("metric", filter.Metric), zap.Int("course_id", filter.Course_id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	leaderboard := models.Leaderboard{
+		Scope:        models.LeaderboardScopeOrganization,
+		Metric:       filter.Metric,
+		Period:       filter.Period,
+		Period_start: filter.Period_start.Format(time.DateOnly),
+	}
+	if filter.Course_id != 0 {
+		courseId := filter.Course_id
+		leaderboard.Scope = models.LeaderboardScopeCourse
+		leaderboard.Course_id = &courseId
+	}
+	if filter.Class_id != 0 {
+		classId := filter.Class_id
+		leaderboard.Scope = models.LeaderboardScopeClass
+		leaderboard.Class_id = &classId
+	}
+
+	leaderboard.Entries, leaderboard.Me = utils.LeaderboardEntries(rows, filter.Viewer, filter.Limit, lessonsTotal)
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// FindOrganization godoc
+// @Summary 	school leaderboard by XP
+// @Description Ranks all learners by XP earned in the current week or month (UTC, weeks start on Monday) or all time.
+// @Description Learners who opted out are not listed, minors are shown under a nickname. me is the current user's entry.
+// @Tags 		leaderboards
+// @Produce 	json
+// @Param 		period 	query 		string 	false 	"week (default), month or all"
+// @Param 		limit 	query 		int 	false 	"Number of places, 10 by default, at most 100"
+// @Success 	200 	{object} 	models.Leaderboard "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/leaderboard [get]
+func (h *LeaderboardsHandler) FindOrganization(c *gin.Context) {
+	filter, ok := bindLeaderboardFilter(c, models.LeaderboardMetricXP)
+	if !ok {
+		return
+	}
+	// прогресс считается только внутри курса
+	if filter.Metric != models.LeaderboardMetricXP {
+		c.JSON(http.StatusBadRequest, models.NewApiError("progress leaderboards are available for courses only"))
+		return
+	}
+
+	h.respondLeaderboard(c, filter, 0)
+}
+
+// FindCourse godoc
+// @Summary 	course leaderboard
+// @Description Ranks learners enrolled in the course by XP or by course progress (completed lessons).
+// @Description For progress over all time percent of the course is included. Visible to enrolled learners and content managers.
+// @Tags 		leaderboards
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Param 		metric 	query 		string 	false 	"progress (default) or xp"
+// @Param 		period 	query 		string 	false 	"week (default), month or all"
+// @Param 		limit 	query 		int 	false 	"Number of places, 10 by default, at most 100"
+// @Success 	200 	{object} 	models.Leaderboard "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/leaderboard [get]
+func (h *LeaderboardsHandler) FindCourse(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	filter, ok := bindLeaderboardFilter(c, models.LeaderboardMetricProgress)
+	if !ok {
+		return
+	}
+	filter.Course_id = course.Id
+
+	// рейтинг видят только участники курса и авторы контента
+	if !utils.CanManageContent(c) {
+		enrollment, err := h.enrollmentsRepo.Find(c, course.Id, filter.Viewer)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && !gradedEnrollment(enrollment)) {
+			c.JSON(http.StatusForbidden, models.NewApiError("You are not enrolled in the course"))
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to fetch enrollment", zap.Int("course_id", course.Id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+	}
+
+	lessonsTotal, ok := h.countLessonsForPercent(c, filter)
+	if !ok {
+		return
+	}
+
+	h.respondLeaderboard(c, filter, lessonsTotal)
+}
+
+// countLessonsForPercent возвращает число уроков курса, если рейтинг прогресса за все время показывает проценты, иначе 0
+func (h *LeaderboardsHandler) countLessonsForPercent(c *gin.Context, filter models.LeaderboardFilter) (int, bool) {
+	logger := logger.GetLogger()
+
+	if filter.Metric != models.LeaderboardMetricProgress || filter.Period != models.LeaderboardPeriodAll {
+		return 0, true
+	}
+
+	total, err := h.leaderboardsRepo.CountCourseLessons(c, filter.Course_id)
+	if err != nil {
+		logger.Error("Failed to count course lessons", zap.Int("course_id", filter.Course_id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return 0, false
+	}
+	return total, true
+}
+
+// FindClass godoc
+// @Summary 	class leaderboard
+// @Description Ranks members of the class by XP or, with course_id, by progress in that course among members enrolled in it.
+// @Description Visible to members of the class and content managers.
+// @Tags 		leaderboards
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Class id"
+// @Param 		metric 		query 		string 	false 	"xp (default) or progress"
+// @Param 		course_id 	query 		int 	false 	"Course for the progress metric"
+// @Param 		period 		query 		string 	false 	"week (default), month or all"
+// @Param 		limit 		query 		int 	false 	"Number of places, 10 by default, at most 100"
+// @Success 	200 	{object} 	models.Leaderboard "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/classes/{id}/leaderboard [get]
+func (h *LeaderboardsHandler) FindClass(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	class, ok := findClass(c, h.classesRepo)
+	if !ok {
+		return
+	}
+
+	filter, ok := bindLeaderboardFilter(c, models.LeaderboardMetricXP)
+	if !ok {
+		return
+	}
+	filter.Class_id = class.Id
+
+	if raw := c.Query("course_id"); raw != "" {
+		courseId, err := strconv.Atoi(raw)
+		if err != nil || courseId <= 0 {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid course id"))
+			return
+		}
+
+		course, err := h.coursesRepo.FindById(c, courseId)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && !utils.CanManageContent(c) &&
+			!utils.IsWithinPublicationWindow(course.Is_published, course.Publish_at, course.Unpublish_at, time.Now())) {
+			c.JSON(http.StatusNotFound, models.NewApiError("Course not found"))
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to find course", zap.Int("course_id", courseId), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		filter.Course_id = course.Id
+	}
+	// прогресс считается только внутри курса
+	if filter.Metric == models.LeaderboardMetricProgress && filter.Course_id == 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("course_id is required for the progress metric"))
+		return
+	}
+
+	lessonsTotal, ok := h.countLessonsForPercent(c, filter)
+	if !ok {
+		return
+	}
+
+	h.respondLeaderboard(c, filter, lessonsTotal)
+}
+
+// FindPrivacy godoc
+// @Summary 	leaderboard privacy settings of the current user
+// @Description An empty visibility means the default: adults are shown by first name and surname initial, minors by nickname
+// @Tags 		leaderboards
+// @Produce 	json
+// @Success 	200 	{object} 	models.LeaderboardPrivacy "OK"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/leaderboard-privacy [get]
+func (h *LeaderboardsHandler) FindPrivacy(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	privacy, _, err := h.leaderboardsRepo.FindPrivacy(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch leaderboard privacy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, privacy)
+}
+
+// UpdatePrivacy godoc
+// @Summary 	choose how the current user appears in leaderboards
+// @Description visibility: name, nickname or hidden (opt out of all leaderboards). Learners under 18 or without an age
+// @Description in their interests profile cannot appear by name. Without a nickname a generated one is shown.
+// @Tags 		leaderboards
+// @Accept 		json
+// @Produce 	json
+// @Param 		request body 		leaderboardPrivacyRequest 	true 	"Privacy settings"
+// @Success 	200 	{object} 	models.LeaderboardPrivacy "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/leaderboard-privacy [put]
+func (h *LeaderboardsHandler) UpdatePrivacy(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	var request leaderboardPrivacyRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+
+	privacy := models.LeaderboardPrivacy{
+		User_uuid:  userUUID,
+		Visibility: request.Visibility,
+		Nickname:   strings.TrimSpace(request.Nickname),
+	}
+	if !slices.Contains(models.LeaderboardVisibilities, privacy.Visibility) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("visibility must be one of: "+strings.Join(models.LeaderboardVisibilities, ", ")))
+		return
+	}
+	if privacy.Nickname != "" {
+		if err := utils.ValidateNickname(privacy.Nickname); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+			return
+		}
+	}
+
+	if privacy.Visibility == models.LeaderboardShowName {
+		_, age, err := h.leaderboardsRepo.FindPrivacy(c, userUUID)
+		if err != nil {
+			logger.Error("Failed to fetch leaderboard privacy", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		if utils.IsMinor(age) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Learners under 18 can appear in leaderboards only by nickname"))
+			return
+		}
+	}
+
+	if err := h.leaderboardsRepo.SavePrivacy(c, privacy); err != nil {
+		logger.Error("Failed to save leaderboard privacy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	saved, _, err := h.leaderboardsRepo.FindPrivacy(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch leaderboard privacy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
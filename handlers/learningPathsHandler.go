@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type LearningPathsHandler struct {
+	pathsRepo   *repositories.LearningPathsRepository
+	coursesRepo *repositories.Coursesrepository
+}
+
+func NewLearningPathsHandler(pathsRepo *repositories.LearningPathsRepository, coursesRepo *repositories.Coursesrepository) *LearningPathsHandler {
+	return &LearningPathsHandler{pathsRepo: pathsRepo, coursesRepo: coursesRepo}
+}
+
+type learningPathCourseRequest struct {
+	Course_id int  `json:"course_id"`
+	Required  bool `json:"required"`
+}
+
+type learningPathRequest struct {
+	Title        string                      `json:"title"`
+	Description  string                      `json:"description"`
+	Is_published bool                        `json:"is_published"`
+	Courses      []learningPathCourseRequest `json:"courses"`
+}
+
+// bindLearningPath читает и проверяет путь из тела запроса; курсы идут в порядке прохождения
+func (h *LearningPathsHandler) bindLearningPath(c *gin.Context) (models.LearningPath, bool) {
+	logger := logger.GetLogger()
+
+	var request learningPathRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request payload"))
+		return models.LearningPath{}, false
+	}
+
+	request.Title = strings.TrimSpace(request.Title)
+	if request.Title == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("title is required"))
+		return models.LearningPath{}, false
+	}
+	if len(request.Courses) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Learning path needs at least one course"))
+		return models.LearningPath{}, false
+	}
+
+	path := models.LearningPath{
+		Title:        request.Title,
+		Description:  request.Description,
+		Is_published: request.Is_published,
+		Courses:      make([]models.LearningPathCourse, 0, len(request.Courses)),
+	}
+	seen := make(map[int]bool)
+	for _, course := range request.Courses {
+		if seen[course.Course_id] {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Course "+strconv.Itoa(course.Course_id)+" is listed twice"))
+			return models.LearningPath{}, false
+		}
+		seen[course.Course_id] = true
+
+		found, err := h.coursesRepo.FindById(c, course.Course_id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Course "+strconv.Itoa(course.Course_id)+" not found"))
+			return models.LearningPath{}, false
+		}
+		if err != nil {
+			logger.Error("Failed to find course", zap.Int("course_id", course.Course_id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return models.LearningPath{}, false
+		}
+
+		path.Courses = append(path.Courses, models.LearningPathCourse{Course_id: found.Id, Title: found.Name, Required: course.Required})
+	}
+
+	return path, true
+}
+
+// findLearningPath загружает путь из параметра :id; ученики видят только опубликованные пути
+func (h *LearningPathsHandler) findLearningPath(c *gin.Context) (models.LearningPath, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid learning path id"))
+		return models.LearningPath{}, false
+	}
+
+	path, err := h.pathsRepo.FindById(c, id, !utils.CanManageContent(c))
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Learning path not found"))
+		return models.LearningPath{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find learning path", zap.Int("path_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.LearningPath{}, false
+	}
+
+	return path, true
+}
+
+// FindAll godoc
+// @Summary 	list learning paths
+// @Description Subjects and target age of a path are derived from the lessons of its courses.
+// @Description Students see only published paths and published courses in them.
+// @Tags 		learning-paths
+// @Produce 	json
+// @Param 		subject_id 	query 		int 	false 	"Paths with lessons of the subject"
+// @Param 		age 		query 		int 	false 	"Paths suitable for the age"
+// @Success 	200 	{object} 	[]models.LearningPath "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/learning-paths [get]
+func (h *LearningPathsHandler) FindAll(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	filter := models.LearningPathFilter{Visible_only: !utils.CanManageContent(c)}
+	if raw := c.Query("subject_id"); raw != "" {
+		subjectId, err := strconv.Atoi(raw)
+		if err != nil || subjectId <= 0 {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid subject id"))
+			return
+		}
+		filter.Subject_id = subjectId
+	}
+	if raw := c.Query("age"); raw != "" {
+		age, err := strconv.Atoi(raw)
+		if err != nil || age <= 0 {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid age"))
+			return
+		}
+		filter.Age = age
+	}
+
+	paths, err := h.pathsRepo.FindAll(c, filter)
+	if err != nil {
+		logger.Error("Failed to fetch learning paths", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, paths)
+}
+
+// FindById godoc
+// @Summary 	get a learning path with its courses
+// @Tags 		learning-paths
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Learning path id"
+// @Success 	200 	{object} 	models.LearningPath "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/learning-paths/{id} [get]
+func (h *LearningPathsHandler) FindById(c *gin.Context) {
+	path, ok := h.findLearningPath(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, path)
+}
+
+// Create godoc
+// @Summary 	create a learning path
+// @Description Courses are taken in the given order; required courses are enrolled automatically when a student joins the path
+// @Tags 		learning-paths
+// @Accept 		json
+// @Produce 	json
+// @Param 		request 	body 		learningPathRequest 	true 	"Learning path"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/learning-paths [post]
+func (h *LearningPathsHandler) Create(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	path, ok := h.bindLearningPath(c)
+	if !ok {
+		return
+	}
+	if userUUID, ok := utils.CurrentUserUUID(c); ok {
+		path.Created_by = &userUUID
+	}
+
+	id, err := h.pathsRepo.Create(c, path)
+	if err != nil {
+		logger.Error("Failed to create learning path", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Learning path has been created", zap.Int("path_id", id))
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+// Update godoc
+// @Summary 	update a learning path
+// @Description Replaces the course list. Existing course enrollments of students are kept.
+// @Tags 		learning-paths
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 			path		int 					true 	"Learning path id"
+// @Param 		request 	body 		learningPathRequest 	true 	"Learning path"
+// @Success 	200 	{object} 	models.LearningPath "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/learning-paths/{id} [put]
+func (h *LearningPathsHandler) Update(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid learning path id"))
+		return
+	}
+
+	path, ok := h.bindLearningPath(c)
+	if !ok {
+		return
+	}
+
+	err = h.pathsRepo.Update(c, id, path)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Learning path not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update learning path", zap.Int("path_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	updated, err := h.pathsRepo.FindById(c, id, false)
+	if err != nil {
+		logger.Error("Failed to find learning path", zap.Int("path_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// Delete godoc
+// @Summary 	delete a learning path
+// @Description Students stay enrolled in the courses of the path
+// @Tags 		learning-paths
+// @Param 		id 		path		int 	true 	"Learning path id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/learning-paths/{id} [delete]
+func (h *LearningPathsHandler) Delete(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid learning path id"))
+		return
+	}
+
+	err = h.pathsRepo.Delete(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Learning path not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete learning path", zap.Int("path_id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Learning path has been deleted", zap.Int("path_id", id))
+	c.Status(http.StatusOK)
+}
+
+// Enroll godoc
+// @Summary 	join a learning path
+// @Description Enrolls the student in every required open course of the path (or its waitlist when the course is full).
+// @Description Optional and closed courses are left to the student and the teacher.
+// @Tags 		learning-paths
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Learning path id"
+// @Success 	200 	{object} 	models.LearningPathProgress "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/learning-paths/{id}/enroll [post]
+func (h *LearningPathsHandler) Enroll(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	path, ok := h.findLearningPath(c)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("unauthorized"))
+		return
+	}
+
+	if err := h.pathsRepo.Enroll(c, path.Id, userUUID); err != nil {
+		logger.Error("Failed to enroll in learning path", zap.Int("path_id", path.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	progress, err := h.pathsRepo.FindProgress(c, path, userUUID, !utils.CanManageContent(c))
+	if err != nil {
+		logger.Error("Failed to fetch learning path progress", zap.Int("path_id", path.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// FindProgress godoc
+// @Summary 	progress through a learning path
+// @Description Percent and completion count required courses, or all courses when none are required.
+// @Description Students see their own progress, teachers may pass user_uuid to see a student's progress.
+// @Tags 		learning-paths
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Learning path id"
+// @Param 		user_uuid 	query 		string 	false 	"Student uuid, teachers only"
+// @Success 	200 	{object} 	models.LearningPathProgress "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/learning-paths/{id}/progress [get]
+func (h *LearningPathsHandler) FindProgress(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	path, ok := h.findLearningPath(c)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	if raw := c.Query("user_uuid"); raw != "" {
+		if !utils.CanManageContent(c) {
+			c.JSON(http.StatusForbidden, models.NewApiError("access denied"))
+			return
+		}
+		studentUUID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user uuid"))
+			return
+		}
+		userUUID = studentUUID
+	}
+
+	progress, err := h.pathsRepo.FindProgress(c, path, userUUID, !utils.CanManageContent(c))
+	if err != nil {
+		logger.Error("Failed to fetch learning path progress", zap.Int("path_id", path.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// MyLearningPaths godoc
+// @Summary 	learning paths of the current user with progress
+// @Tags 		learning-paths
+// @Produce 	json
+// @Success 	200 	{object} 	[]models.LearningPathProgress "Newest enrollment first"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/learning-paths [get]
+func (h *LearningPathsHandler) MyLearningPaths(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	pathIds, err := h.pathsRepo.FindEnrolledPathIds(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch learning paths", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	visibleOnly := !utils.CanManageContent(c)
+	result := make([]models.LearningPathProgress, 0, len(pathIds))
+	for _, pathId := range pathIds {
+		path, err := h.pathsRepo.FindById(c, pathId, visibleOnly)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// путь сняли с публикации
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to find learning path", zap.Int("path_id", pathId), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		progress, err := h.pathsRepo.FindProgress(c, path, userUUID, visibleOnly)
+		if err != nil {
+			logger.Error("Failed to fetch learning path progress", zap.Int("path_id", pathId), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		result = append(result, progress)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
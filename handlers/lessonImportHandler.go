@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const maxLessonAge = 100
+
+type LessonImportHandler struct {
+	lessonsRepo  *repositories.Lessonsrepository
+	subjectsRepo *repositories.SubjectsRepository
+	mediaRepo    *repositories.MediaRepository
+}
+
+func NewLessonImportHandler(
+	lessonsRepo *repositories.Lessonsrepository,
+	subjectsRepo *repositories.SubjectsRepository,
+	mediaRepo *repositories.MediaRepository) *LessonImportHandler {
+	return &LessonImportHandler{
+		lessonsRepo:  lessonsRepo,
+		subjectsRepo: subjectsRepo,
+		mediaRepo:    mediaRepo,
+	}
+}
+
+// lessonImportColumns — колонки таблицы, названия совпадают с полями lessonRequest.
+// Видео через таблицу не загружается.
+var lessonImportColumns = map[string]func(request *lessonRequest, value string) error{
+	"title":          func(r *lessonRequest, v string) error { r.Title = v; return nil },
+	"description":    func(r *lessonRequest, v string) error { r.Description = v; return nil },
+	"body":           func(r *lessonRequest, v string) error { r.Body = v; return nil },
+	"level":          func(r *lessonRequest, v string) error { r.Level = v; return nil },
+	"interest":       func(r *lessonRequest, v string) error { r.Interest = v; return nil },
+	"subject_id":     intColumn(func(r *lessonRequest, n int) { r.Subject_id = n }),
+	"order":          intColumn(func(r *lessonRequest, n int) { r.Order = n }),
+	"target_age_min": intColumn(func(r *lessonRequest, n int) { r.Target_age_min = n }),
+	"target_age_max": intColumn(func(r *lessonRequest, n int) { r.Target_age_max = n }),
+	"duration":       intColumn(func(r *lessonRequest, n int) { r.Duration_sec = n }),
+}
+
+func intColumn(set func(request *lessonRequest, n int)) func(request *lessonRequest, value string) error {
+	return func(request *lessonRequest, value string) error {
+		if value == "" {
+			return nil
+		}
+		// xlsx хранит целые числа как есть, но после формул бывает "12.0"
+		n, err := strconv.Atoi(strings.TrimSuffix(value, ".0"))
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		set(request, n)
+		return nil
+	}
+}
+
+// normalizeColumnName приводит заголовок "Target age min" к виду target_age_min
+func normalizeColumnName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+}
+
+// Import godoc
+// @Summary 	bulk import lessons from CSV or XLSX
+// @Description The first row holds column names matching lesson fields: title, description, body, subject_id, order, level, interest, target_age_min, target_age_max, duration.
+// @Description By default the file is only validated (dry_run=true). With dry_run=false lessons are created in one transaction, and only if every row is valid.
+// @Tags 		lessons
+// @Accept 		multipart/form-data
+// @Produce 	json
+// @Param 		file 		formData 	file 	true 	"csv or xlsx file"
+// @Param 		dry_run 	query 		bool 	false 	"Only validate, true by default"
+// @Success 	200 	{object} 	models.LessonImportReport "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	422 	{object} 	models.LessonImportReport "Some rows are invalid, nothing was created"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/import [post]
+func (h *LessonImportHandler) Import(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	dryRun := true
+	if value := c.Query("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("dry_run must be true or false"))
+			return
+		}
+		dryRun = parsed
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("File is required"))
+		return
+	}
+	if fileHeader.Size > utils.MaxSpreadsheetSize {
+		c.JSON(http.StatusBadRequest, models.NewApiError("File is too large"))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		logger.Error("Failed to open spreadsheet", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not read file"))
+		return
+	}
+	defer file.Close()
+
+	rows, err := utils.ReadSpreadsheet(fileHeader.Filename, file, fileHeader.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("File is empty"))
+		return
+	}
+
+	columns := make([]string, len(rows[0]))
+	unknown := make([]string, 0)
+	for i, name := range rows[0] {
+		columns[i] = normalizeColumnName(name)
+		if _, ok := lessonImportColumns[columns[i]]; !ok && columns[i] != "" {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Unknown columns: "+strings.Join(unknown, ", ")))
+		return
+	}
+
+	subjects, err := h.subjectsRepo.FindAll(c)
+	if err != nil {
+		logger.Error("Failed to fetch subjects", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+	subjectIds := make(map[int]bool, len(subjects))
+	for _, subject := range subjects {
+		subjectIds[subject.Id] = true
+	}
+
+	report := models.LessonImportReport{
+		Dry_run: dryRun,
+		Created: make([]int, 0),
+		Rows:    make([]models.LessonImportRow, 0, len(rows)-1),
+	}
+	lessons := make([]models.Lesson, 0, len(rows)-1)
+	mediaExists := make(map[int]bool)
+
+	for i, record := range rows[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row := models.LessonImportRow{Row: i + 2, Errors: make([]string, 0)}
+		var request lessonRequest
+		for j, value := range record {
+			if j >= len(columns) || columns[j] == "" {
+				continue
+			}
+			if err := lessonImportColumns[columns[j]](&request, strings.TrimSpace(value)); err != nil {
+				row.Errors = append(row.Errors, columns[j]+": "+err.Error())
+			}
+		}
+		row.Title = request.Title
+
+		lesson, errs, err := h.validateRow(c, request, subjectIds, mediaExists)
+		if err != nil {
+			logger.Error("Failed to validate lesson row", zap.Int("row", row.Row), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		row.Errors = append(row.Errors, errs...)
+
+		report.Total_rows++
+		if len(row.Errors) == 0 {
+			report.Valid_rows++
+			lessons = append(lessons, lesson)
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	if report.Valid_rows != report.Total_rows {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	revisions := make([]models.ContentRevision, 0, len(lessons))
+	for _, lesson := range lessons {
+		revision, err := newRevision(c, models.EntityLesson, 0, models.StatusDraft, "imported", newLessonRevisionData(lesson))
+		if err != nil {
+			logger.Error("Failed to build lesson revision", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+			return
+		}
+		revisions = append(revisions, revision)
+	}
+
+	ids, err := h.lessonsRepo.CreateBatch(c, lessons, revisions)
+	if err != nil {
+		logger.Error("Failed to import lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not import lessons, nothing was created"))
+		return
+	}
+	report.Created = ids
+
+	logger.Info("Lessons have been imported", zap.Int("count", len(ids)))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// validateRow проверяет строку так же, как POST /lessons, но собирает все ошибки вместо первой
+func (h *LessonImportHandler) validateRow(c *gin.Context, request lessonRequest, subjectIds map[int]bool, mediaExists map[int]bool) (models.Lesson, []string, error) {
+	errs := make([]string, 0)
+
+	lesson := models.Lesson{
+		Title:          request.Title,
+		Description:    request.Description,
+		Body:           request.Body,
+		Subject_id:     request.Subject_id,
+		Order:          request.Order,
+		Interest:       request.Interest,
+		Target_age_min: request.Target_age_min,
+		Target_age_max: request.Target_age_max,
+		Duration_sec:   request.Duration_sec,
+	}
+
+	if lesson.Title == "" {
+		errs = append(errs, "title is required")
+	}
+
+	if lesson.Subject_id == 0 {
+		errs = append(errs, "subject_id is required")
+	} else if !subjectIds[lesson.Subject_id] {
+		errs = append(errs, fmt.Sprintf("subject %d not found", lesson.Subject_id))
+	}
+
+	if level, ok := utils.NormalizeLevel(request.Level); ok {
+		lesson.Level = level
+	} else {
+		errs = append(errs, invalidLevelMessage())
+	}
+
+	if lesson.Target_age_min < 0 || lesson.Target_age_min > maxLessonAge ||
+		lesson.Target_age_max < 0 || lesson.Target_age_max > maxLessonAge {
+		errs = append(errs, fmt.Sprintf("target age must be between 0 and %d", maxLessonAge))
+	} else if lesson.Target_age_max != 0 && lesson.Target_age_min > lesson.Target_age_max {
+		errs = append(errs, "target_age_min is greater than target_age_max")
+	}
+
+	if lesson.Order < 0 {
+		errs = append(errs, "order must not be negative")
+	}
+	if lesson.Duration_sec < 0 {
+		errs = append(errs, "duration must not be negative")
+	}
+
+	if len(lesson.Body) > utils.MaxMarkdownSize {
+		errs = append(errs, fmt.Sprintf("body must not exceed %d KB", utils.MaxMarkdownSize>>10))
+		return lesson, errs, nil
+	}
+
+	bodyHtml, mediaIds := utils.RenderMarkdown(lesson.Body, nil)
+	for _, mediaId := range mediaIds {
+		exists, checked := mediaExists[mediaId]
+		if !checked {
+			_, err := h.mediaRepo.FindById(c, mediaId)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return models.Lesson{}, nil, err
+			}
+			exists = err == nil
+			mediaExists[mediaId] = exists
+		}
+		if !exists {
+			errs = append(errs, fmt.Sprintf("media %d referenced in body not found", mediaId))
+		}
+	}
+	lesson.Body_html = bodyHtml
+
+	return lesson, errs, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type LessonResourcesHandler struct {
+	resourcesRepo     *repositories.LessonResourcesRepository
+	lessonsRepo       *repositories.Lessonsrepository
+	mediaRepo         *repositories.MediaRepository
+	prerequisitesRepo *repositories.PrerequisitesRepository
+}
+
+func NewLessonResourcesHandler(
+	resourcesRepo *repositories.LessonResourcesRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	mediaRepo *repositories.MediaRepository,
+	prerequisitesRepo *repositories.PrerequisitesRepository) *LessonResourcesHandler {
+	return &LessonResourcesHandler{
+		resourcesRepo:     resourcesRepo,
+		lessonsRepo:       lessonsRepo,
+		mediaRepo:         mediaRepo,
+		prerequisitesRepo: prerequisitesRepo,
+	}
+}
+
+type reorderResourcesRequest struct {
+	Ids []int `json:"ids"`
+}
+
+// FindByLesson godoc
+// @Summary 	list resources attached to the lesson
+// @Tags 		lesson resources
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson_id"
+// @Success 	200 	{object}	[]models.LessonResource "OK"
+// @Failure 	400 	{object}	models.ApiError "Invalid id"
+// @Failure 	403 	{object}	models.LockedContentError "Prerequisites are not completed"
+// @Failure 	404 	{object}	models.ApiError "Lesson not found"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/resources [get]
+func (h *LessonResourcesHandler) FindByLesson(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok || !ensureLessonUnlocked(c, h.prerequisitesRepo, lesson) {
+		return
+	}
+
+	resources, err := h.resourcesRepo.FindByLessonId(c, lesson.Id)
+	if err != nil {
+		logger.Error("Failed to fetch lesson resources", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, resources)
+}
+
+// Create godoc
+// @Summary 	attach file or external link to the lesson
+// @Description Either file or url must be provided
+// @Tags 		lesson resources
+// @Accept 		multipart/form-data
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson_id"
+// @Param 		title 	formData	string 	true 	"Resource title"
+// @Param 		file 	formData	file 	false 	"Worksheet, slides, pdf..."
+// @Param 		url 	formData	string 	false 	"External link"
+// @Success 	200 	{object} 	object{id=int} 	"OK"
+// @Failure 	400 	{object}	models.ApiError "Invalid Payload"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/resources [post]
+func (h *LessonResourcesHandler) Create(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+
+	resource := models.LessonResource{
+		Lesson_id: lesson.Id,
+		Title:     c.PostForm("title"),
+	}
+
+	fileHeader, fileErr := c.FormFile("file")
+	link := c.PostForm("url")
+
+	switch {
+	case fileErr == nil && link != "":
+		c.JSON(http.StatusBadRequest, models.NewApiError("Provide either file or url, not both"))
+		return
+
+	case fileErr == nil:
+		limit, allowed := utils.ResourceSizeLimit(fileHeader.Filename)
+		if !allowed {
+			c.JSON(http.StatusBadRequest, models.NewApiError("File type is not allowed"))
+			return
+		}
+		if fileHeader.Size > limit {
+			c.JSON(http.StatusBadRequest, models.NewApiError("File is too large"))
+			return
+		}
+
+		media, err := utils.StoreUploadedMedia(fileHeader)
+		if err != nil {
+			logger.Error("Failed to store resource file", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not store file"))
+			return
+		}
+
+		mediaId, err := h.mediaRepo.Create(c, media)
+		if err != nil {
+			logger.Error("Failed to save media", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError("Could not store file"))
+			return
+		}
+
+		resource.Type = models.ResourceTypeFile
+		resource.Media_id = &mediaId
+		if resource.Title == "" {
+			resource.Title = media.Filename
+		}
+
+	case link != "":
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid url"))
+			return
+		}
+
+		resource.Type = models.ResourceTypeLink
+		resource.Url = parsed.String()
+		if resource.Title == "" {
+			resource.Title = parsed.Host
+		}
+
+	default:
+		c.JSON(http.StatusBadRequest, models.NewApiError("File or url is required"))
+		return
+	}
+
+	id, err := h.resourcesRepo.Create(c, resource)
+	if err != nil {
+		logger.Error("Failed to create lesson resource", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not create resource"))
+		return
+	}
+
+	logger.Info("Lesson resource has been created", zap.Int("lesson_id", lesson.Id), zap.Int("resource_id", id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"id": id,
+	})
+}
+
+// Reorder godoc
+// @Summary 	change order of lesson resources
+// @Tags 		lesson resources
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 						true 	"Lesson_id"
+// @Param 		request body 		reorderResourcesRequest 	true 	"All resource ids in the new order"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError "Invalid Payload"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/resources/order [put]
+func (h *LessonResourcesHandler) Reorder(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+
+	var request reorderResourcesRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+
+	err := h.resourcesRepo.Reorder(c, lesson.Id, request.Ids)
+	if errors.Is(err, repositories.ErrInvalidResourceOrder) {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to reorder lesson resources", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Download godoc
+// @Summary 	download resource file or follow external link
+// @Tags 		lesson resources
+// @Produce 	octet-stream
+// @Param 		id 			path		int 	true 	"Lesson_id"
+// @Param 		resourceId 	path		int 	true 	"Resource id"
+// @Success 	200 		{file}		file 	"File"
+// @Success 	302 		"Redirect to external link"
+// @Failure 	400 		{object}	models.ApiError "Invalid id"
+// @Failure 	404 		{object}	models.ApiError "Resource not found"
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/resources/{resourceId}/download [get]
+func (h *LessonResourcesHandler) Download(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+	if !ensureLessonUnlocked(c, h.prerequisitesRepo, lesson) {
+		return
+	}
+
+	resource, ok := h.findResource(c, lesson.Id)
+	if !ok {
+		return
+	}
+
+	err := h.resourcesRepo.IncrementDownloads(c, resource.Id)
+	if err != nil {
+		logger.Error("Failed to count download", zap.Error(err))
+	}
+
+	if resource.Type == models.ResourceTypeLink {
+		c.Redirect(http.StatusFound, resource.Url)
+		return
+	}
+
+	media, err := h.mediaRepo.FindById(c, *resource.Media_id)
+	if err != nil {
+		logger.Error("Failed to find resource media", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not load file"))
+		return
+	}
+
+	serveMedia(c, media, true)
+}
+
+// Delete godoc
+// @Summary 	remove resource from the lesson
+// @Tags 		lesson resources
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Lesson_id"
+// @Param 		resourceId 	path		int 	true 	"Resource id"
+// @Success 	200 		"OK"
+// @Failure 	400 		{object}	models.ApiError "Invalid id"
+// @Failure 	404 		{object}	models.ApiError "Resource not found"
+// @Failure 	500 		{object}	models.ApiError
+// @Router 		/lessons/{id}/resources/{resourceId} [delete]
+func (h *LessonResourcesHandler) Delete(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+
+	resource, ok := h.findResource(c, lesson.Id)
+	if !ok {
+		return
+	}
+
+	err := h.resourcesRepo.Delete(c, lesson.Id, resource.Id)
+	if err != nil {
+		logger.Error("Failed to delete lesson resource", zap.Error(err))
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *LessonResourcesHandler) findResource(c *gin.Context, lessonId int) (models.LessonResource, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("resourceId"))
+	if err != nil {
+		logger.Error("Invalid resource Id", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid resource id"))
+		return models.LessonResource{}, false
+	}
+
+	resource, err := h.resourcesRepo.FindById(c, lessonId, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Resource not found"))
+		return models.LessonResource{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find lesson resource", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.LessonResource{}, false
+	}
+
+	return resource, true
+}
@@ -1,23 +1,36 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"go-EdTech/logger"
 	"go-EdTech/models"
 	"go-EdTech/repositories"
+	"go-EdTech/utils"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
 )
 
 type LessonsHandler struct {
-	lessonsRepo *repositories.Lessonsrepository
+	lessonsRepo       *repositories.Lessonsrepository
+	mediaRepo         *repositories.MediaRepository
+	revisionsRepo     *repositories.RevisionsRepository
+	prerequisitesRepo *repositories.PrerequisitesRepository
+	tagsRepo          *repositories.TagsRepository
+	translationsRepo  *repositories.TranslationsRepository
+	xapiRepo          *repositories.XAPIRepository
 }
 
 type lessonRequest struct {
 	Title           string `json:"title"`
 	Description     string `json:"description"`
+	Body            string `json:"body"`
 	Subject_id      int    `json:"subject_id"`
 	Order           int    `json:"order"`
 	Level           string `json:"level"`
@@ -28,13 +41,24 @@ type lessonRequest struct {
 	Video_filename  string `json:"video_filename"`
 	Video_mime_type string `json:"video_mime_type"`
 	Duration_sec    int    `json:"duration"`
-	Is_published    bool   `json:"is_published"`
 }
 
 func NewLessonsHandler(
-	lessonsRepo *repositories.Lessonsrepository) *LessonsHandler {
+	lessonsRepo *repositories.Lessonsrepository,
+	mediaRepo *repositories.MediaRepository,
+	revisionsRepo *repositories.RevisionsRepository,
+	prerequisitesRepo *repositories.PrerequisitesRepository,
+	tagsRepo *repositories.TagsRepository,
+	translationsRepo *repositories.TranslationsRepository,
+	xapiRepo *repositories.XAPIRepository) *LessonsHandler {
 	return &LessonsHandler{
-		lessonsRepo: lessonsRepo,
+		lessonsRepo:       lessonsRepo,
+		mediaRepo:         mediaRepo,
+		revisionsRepo:     revisionsRepo,
+		prerequisitesRepo: prerequisitesRepo,
+		tagsRepo:          tagsRepo,
+		translationsRepo:  translationsRepo,
+		xapiRepo:          xapiRepo,
 	}
 }
 
@@ -44,38 +68,50 @@ func NewLessonsHandler(
 // @Accept 		json
 // @Produce 	json
 // @Param 		id 		path		int 	true 	"Lesson_id"
+// @Param 		Accept-Language header 	string 	false 	"Preferred languages: ru, kk, en"
 // @Success 	200 	{object}	models.Lesson "OK"
 // @Failure 	400 	{object}	models.ApiError "Invalid id"
+// @Failure 	403 	{object}	models.LockedContentError "Prerequisites are not completed"
+// @Failure 	404 	{object}	models.ApiError "Lesson not found or not published yet"
 // @Failure 	500 	{object}	models.ApiError
 // @Router 		/lessons/{id} [get]
 func (h *LessonsHandler) FindById(c *gin.Context) {
 	logger := logger.GetLogger()
 
-	idStr := c.Param("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		logger.Error("Invalid lesson Id", zap.Error(err))
-		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid Lesson Id"))
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+	if !ensureLessonUnlocked(c, h.prerequisitesRepo, lesson) {
 		return
 	}
 
-	lesson, err := h.lessonsRepo.FindById(c, id)
-
-	if err != nil {
-		logger.Error("Failed to find lesson", zap.Error(err))
+	lessons := []models.Lesson{lesson}
+	if err := localizeLessons(c, h.translationsRepo, lessons); err != nil {
+		logger.Error("Failed to localize lesson", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
 		return
 	}
 
-	c.JSON(http.StatusOK, lesson)
+	// авторы контента открывают урок для редактирования, а не для прохождения
+	if userUUID, ok := utils.CurrentUserUUID(c); ok && !utils.CanManageContent(c) {
+		recordXAPI(c, h.xapiRepo, utils.NewXAPIStatement(userUUID, models.XAPIVerbLaunched,
+			utils.XAPIActivity("lessons", lesson.Id, models.XAPIActivityLesson, lesson.Title), nil))
+	}
+
+	c.JSON(http.StatusOK, lessons[0])
 
 }
 
 // FindAll godoc
 // @Summary 	Find all lessons
+// @Description Learners only see published lessons inside their publish_at/unpublish_at window
 // @Tags 		lessons
 // @Accept 		json
 // @Produce 	json
+// @Param 		Accept-Language header 	string 	false 	"Preferred languages: ru, kk, en"
+// @Param 		level 	query 	string 	false 	"Beginner, Intermediate or Advanced"
+// @Param 		tags 	query 	string 	false 	"Comma-separated tag slugs, lesson must match every tag or one of its children"
 // @Success 	200 {object} []models.Lesson "OK"
 // @Failure 	400 {object} models.ApiError
 // @Failure 	500 {object} models.ApiError
@@ -83,13 +119,38 @@ func (h *LessonsHandler) FindById(c *gin.Context) {
 func (h *LessonsHandler) FindAll(c *gin.Context) {
 	logger := logger.GetLogger()
 
-	movies, err := h.lessonsRepo.FindAll(c)
+	filter := models.LessonFilter{
+		Visible_only: !utils.CanManageContent(c),
+	}
+
+	if level := c.Query("level"); level != "" {
+		normalized, ok := utils.NormalizeLevel(level)
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.NewApiError(invalidLevelMessage()))
+			return
+		}
+		filter.Level = normalized
+	}
+
+	tagGroups, ok := parseTagFilter(c, h.tagsRepo)
+	if !ok {
+		return
+	}
+	filter.Tag_groups = tagGroups
+
+	movies, err := h.lessonsRepo.FindAll(c, filter)
 	if err != nil {
 		logger.Error("Failed to fetch lessons", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, err)
 		return
 	}
 
+	if err := localizeLessons(c, h.translationsRepo, movies); err != nil {
+		logger.Error("Failed to localize lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
 	c.JSON(http.StatusOK, movies)
 }
 
@@ -100,6 +161,7 @@ func (h *LessonsHandler) FindAll(c *gin.Context) {
 // @Produce 	json
 // @Param		title 				query 		string 			true 	"Lesson_title"
 // @Param 		description 		query		string 			true 	"Lesson_description"
+// @Param 		body 				query		string 			false 	"Lesson body in Markdown, $...$ and $$...$$ for formulas, media:{id} for images"
 // @Param 		subject_id 			query		int 			true 	"Lesson_subject_id"
 // @Param 		order 				query		int 			true 	"Topic_order"
 // @Param 		level 				query		string 			true 	"Lessons_level" Enum('Beginner', 'Intermediate', 'Advanced')
@@ -110,7 +172,6 @@ func (h *LessonsHandler) FindAll(c *gin.Context) {
 // @Param 		video_filename 		query		string 			true 	"Lessons_video_filename"
 // @Param 		video_mime_type 	query		string 			true 	"Lessons_video_MIME-type"
 // @Param 		duration_sec 		query		int 			true 	"Lessons_video_duration_seconds"
-// @Param 		is_published 		query		boolean 		true 	"lessons_video_is_published"
 // @Success 	200 				{object} 	object{id=int} 	"OK"
 // @Failure 	400 				{object}	models.ApiError "Invalid Payload"
 // @Failure 	500 				{object} 	models.ApiError
@@ -130,6 +191,7 @@ func (h *LessonsHandler) Create(c *gin.Context) {
 	lesson := models.Lesson{
 		Title:           request.Title,
 		Description:     request.Description,
+		Body:            request.Body,
 		Subject_id:      request.Subject_id,
 		Order:           request.Order,
 		Level:           request.Level,
@@ -140,7 +202,13 @@ func (h *LessonsHandler) Create(c *gin.Context) {
 		Video_filename:  request.Video_filename,
 		Video_mime_type: request.Video_mime_type,
 		Duration_sec:    request.Duration_sec,
-		Is_published:    request.Is_published,
+	}
+
+	if !normalizeLessonLevel(c, &lesson) {
+		return
+	}
+	if !h.renderBody(c, &lesson) {
+		return
 	}
 
 	id, err := h.lessonsRepo.Create(c, lesson)
@@ -150,6 +218,13 @@ func (h *LessonsHandler) Create(c *gin.Context) {
 		return
 	}
 
+	_, err = recordRevision(c, h.revisionsRepo, models.EntityLesson, id, models.StatusDraft, "created", newLessonRevisionData(lesson))
+	if err != nil {
+		logger.Error("Failed to record lesson revision", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+		return
+	}
+
 	logger.Info("Lesson has been created", zap.Int("lesson_id", id))
 
 	c.JSON(http.StatusOK, gin.H{
@@ -159,12 +234,16 @@ func (h *LessonsHandler) Create(c *gin.Context) {
 
 // Update godoc
 // @Summary Update Lesson
+// @Description Updates content only, publication goes through PATCH /lessons/{id}/status.
+// @Description For a published lesson edited by a teacher the edit is saved as a pending revision (202) and the lesson
+// @Description stays as is until a content lead approves it through POST /lessons/{id}/revisions/{revision}/approve.
 // @Tags lessons
 // @Accept json
 // @Produce json
 // @Param 		id 					path 		int 			true 	"Lesson_id"
 // @Param		title 				query 		string 			true 	"Lesson_title"
 // @Param 		description 		query		string 			true 	"Lesson_description"
+// @Param 		body 				query		string 			false 	"Lesson body in Markdown, $...$ and $$...$$ for formulas, media:{id} for images"
 // @Param 		subject_id 			query		int 			true 	"Lesson_subject_id"
 // @Param 		order 				query		int 			true 	"Topic_order"
 // @Param 		level 				query		string 			true 	"Lessons_level" Enum('Beginner', 'Intermediate', 'Advanced')
@@ -175,8 +254,8 @@ func (h *LessonsHandler) Create(c *gin.Context) {
 // @Param 		video_filename 		query		string 			true 	"Lessons_video_filename"
 // @Param 		video_mime_type 	query		string 			true 	"Lessons_video_MIME-type"
 // @Param 		duration_sec 		query		int				true 	"Lessons_video_duration_seconds"
-// @Param 		is_published 		query		boolean 		true 	"lessons_video_is_published"
 // @Success 	200 			 	"OK"
+// @Success 	202 				{object} 	object{revision=int,status=string} "Pending approval"
 // @Failure 	400 				{object} 	models.ApiError "Invalid Payload"
 // @Failure 	500					{object} 	models.ApiError
 // @Router 		/lessons/{id} [put]
@@ -193,7 +272,7 @@ func (g *LessonsHandler) Update(c *gin.Context) {
 		return
 	}
 
-	_, err = g.lessonsRepo.FindById(c, id)
+	current, err := g.lessonsRepo.FindById(c, id)
 	if err != nil {
 		logger.Error("Failed to find lesson", zap.Error(err))
 		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
@@ -212,6 +291,7 @@ func (g *LessonsHandler) Update(c *gin.Context) {
 	updLesson := models.Lesson{
 		Title:           request.Title,
 		Description:     request.Description,
+		Body:            request.Body,
 		Subject_id:      request.Subject_id,
 		Order:           request.Order,
 		Level:           request.Level,
@@ -222,7 +302,30 @@ func (g *LessonsHandler) Update(c *gin.Context) {
 		Video_filename:  request.Video_filename,
 		Video_mime_type: request.Video_mime_type,
 		Duration_sec:    request.Duration_sec,
-		Is_published:    request.Is_published,
+	}
+
+	if !normalizeLessonLevel(c, &updLesson) {
+		return
+	}
+	if !g.renderBody(c, &updLesson) {
+		return
+	}
+
+	if needsApproval(c, current.Status) {
+		data := newLessonRevisionData(updLesson)
+		if len(updLesson.Video_data) > 0 {
+			// новое видео ждет одобрения в media, урок пока показывает прежнее
+			mediaId, err := g.stageVideo(c, updLesson)
+			if err != nil {
+				logger.Error("Failed to store pending video", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, models.NewApiError("Could not store video"))
+				return
+			}
+			data.Video_media_id = &mediaId
+		}
+
+		stagePendingRevision(c, g.revisionsRepo, models.EntityLesson, id, "updated", data)
+		return
 	}
 
 	err = g.lessonsRepo.Update(c, id, updLesson)
@@ -232,12 +335,20 @@ func (g *LessonsHandler) Update(c *gin.Context) {
 		return
 	}
 
+	_, err = recordRevision(c, g.revisionsRepo, models.EntityLesson, id, current.Status, "updated", newLessonRevisionData(updLesson))
+	if err != nil {
+		logger.Error("Failed to record lesson revision", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not record revision"))
+		return
+	}
+
 	c.Status(http.StatusOK)
 
 }
 
 // Delete godoc
 // @Summary 	delete lesson by id
+// @Description Moves to the trash, admins can restore it until the retention period ends
 // @Tags 		lessons
 // @Accept		json
 // @Produce 	json
@@ -274,3 +385,71 @@ func (g *LessonsHandler) Delete(c *gin.Context) {
 	c.Status(http.StatusOK)
 
 }
+
+// stageVideo сохраняет видео правки в хранилище media и возвращает id записи
+func (g *LessonsHandler) stageVideo(c *gin.Context, lesson models.Lesson) (int, error) {
+	checksum, size, err := utils.StoreMedia(bytes.NewReader(lesson.Video_data))
+	if err != nil {
+		return 0, err
+	}
+
+	return g.mediaRepo.Create(c, models.Media{
+		Filename:  lesson.Video_filename,
+		Mime_type: lesson.Video_mime_type,
+		Size:      size,
+		Checksum:  checksum,
+	})
+}
+
+func invalidLevelMessage() string {
+	return "Level must be one of " + strings.Join(models.Levels, ", ")
+}
+
+// normalizeLessonLevel проверяет, что уровень урока из списка models.Levels
+func normalizeLessonLevel(c *gin.Context, lesson *models.Lesson) bool {
+	level, ok := utils.NormalizeLevel(lesson.Level)
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.NewApiError(invalidLevelMessage()))
+		return false
+	}
+	lesson.Level = level
+	return true
+}
+
+// renderBody рендерит Markdown тело урока в HTML и проверяет, что все media:{id} существуют
+func (h *LessonsHandler) renderBody(c *gin.Context, lesson *models.Lesson) bool {
+	bodyHtml, ok := renderLessonBody(c, h.mediaRepo, lesson.Body)
+	if !ok {
+		return false
+	}
+
+	lesson.Body_html = bodyHtml
+	return true
+}
+
+// renderLessonBody — общая часть для урока и его переводов
+func renderLessonBody(c *gin.Context, mediaRepo *repositories.MediaRepository, body string) (string, bool) {
+	logger := logger.GetLogger()
+
+	if len(body) > utils.MaxMarkdownSize {
+		c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Lesson body must not exceed %d KB", utils.MaxMarkdownSize>>10)))
+		return "", false
+	}
+
+	bodyHtml, mediaIds := utils.RenderMarkdown(body, nil)
+
+	for _, mediaId := range mediaIds {
+		_, err := mediaRepo.FindById(c, mediaId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Media %d referenced in body not found", mediaId)))
+			return "", false
+		}
+		if err != nil {
+			logger.Error("Failed to resolve body media", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return "", false
+		}
+	}
+
+	return bodyHtml, true
+}
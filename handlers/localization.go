@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// findTranslations подбирает переводы по Accept-Language. Пустые поля перевода не подменяют исходный текст.
+func findTranslations(c *gin.Context, translationsRepo *repositories.TranslationsRepository, entityType string, ids []int) (map[int]models.Translation, error) {
+	c.Header("Vary", "Accept-Language")
+	return translationsRepo.FindBest(c, entityType, ids, utils.TranslationLocales(c.GetHeader("Accept-Language")))
+}
+
+// setContentLanguage указывает язык ответа с одним объектом
+func setContentLanguage(c *gin.Context, translations map[int]models.Translation, id int) {
+	locale := models.DefaultLocale
+	if translation, ok := translations[id]; ok {
+		locale = translation.Locale
+	}
+	c.Header("Content-Language", locale)
+}
+
+func overlay(target *string, value string) {
+	if value != "" {
+		*target = value
+	}
+}
+
+func localizeLessons(c *gin.Context, translationsRepo *repositories.TranslationsRepository, lessons []models.Lesson) error {
+	ids := make([]int, len(lessons))
+	for i, lesson := range lessons {
+		ids[i] = lesson.Id
+	}
+
+	translations, err := findTranslations(c, translationsRepo, models.EntityLesson, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range lessons {
+		translation, ok := translations[lessons[i].Id]
+		if !ok {
+			continue
+		}
+		overlay(&lessons[i].Title, translation.Title)
+		overlay(&lessons[i].Description, translation.Description)
+		if translation.Body != "" {
+			lessons[i].Body = translation.Body
+			lessons[i].Body_html = translation.Body_html
+		}
+	}
+
+	if len(lessons) == 1 {
+		setContentLanguage(c, translations, lessons[0].Id)
+	}
+	return nil
+}
+
+func localizeCourses(c *gin.Context, translationsRepo *repositories.TranslationsRepository, courses []models.Course) error {
+	ids := make([]int, len(courses))
+	for i, course := range courses {
+		ids[i] = course.Id
+	}
+
+	translations, err := findTranslations(c, translationsRepo, models.EntityCourse, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range courses {
+		translation, ok := translations[courses[i].Id]
+		if !ok {
+			continue
+		}
+		overlay(&courses[i].Name, translation.Title)
+		overlay(&courses[i].Description, translation.Description)
+	}
+
+	if len(courses) == 1 {
+		setContentLanguage(c, translations, courses[0].Id)
+	}
+	return nil
+}
+
+func localizeSubjects(c *gin.Context, translationsRepo *repositories.TranslationsRepository, subjects []models.Subject) error {
+	ids := make([]int, len(subjects))
+	for i, subject := range subjects {
+		ids[i] = subject.Id
+	}
+
+	translations, err := findTranslations(c, translationsRepo, models.EntitySubject, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range subjects {
+		if translation, ok := translations[subjects[i].Id]; ok {
+			overlay(&subjects[i].Name, translation.Title)
+		}
+	}
+
+	if len(subjects) == 1 {
+		setContentLanguage(c, translations, subjects[0].Id)
+	}
+	return nil
+}
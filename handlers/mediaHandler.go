@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type MediaHandler struct {
+	mediaRepo         *repositories.MediaRepository
+	lessonsRepo       *repositories.Lessonsrepository
+	prerequisitesRepo *repositories.PrerequisitesRepository
+}
+
+func NewMediaHandler(
+	mediaRepo *repositories.MediaRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	prerequisitesRepo *repositories.PrerequisitesRepository) *MediaHandler {
+	return &MediaHandler{
+		mediaRepo:         mediaRepo,
+		lessonsRepo:       lessonsRepo,
+		prerequisitesRepo: prerequisitesRepo,
+	}
+}
+
+// Upload godoc
+// @Summary 	upload media file
+// @Tags 		media
+// @Accept 		multipart/form-data
+// @Produce 	json
+// @Param 		file 	formData	file 	true 	"File"
+// @Success 	200 	{object} 	models.Media 	"OK"
+// @Failure 	400 	{object}	models.ApiError "Invalid Payload"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/media [post]
+func (h *MediaHandler) Upload(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		logger.Error("Media file missing", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("File is required"))
+		return
+	}
+	if fileHeader.Size > utils.MaxMediaSize {
+		c.JSON(http.StatusBadRequest, models.NewApiError("File is too large"))
+		return
+	}
+
+	media, err := utils.StoreUploadedMedia(fileHeader)
+	if err != nil {
+		logger.Error("Failed to store media", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not store file"))
+		return
+	}
+
+	media.Id, err = h.mediaRepo.Create(c, media)
+	if err != nil {
+		logger.Error("Failed to save media", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError("Could not store file"))
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// Serve godoc
+// @Summary 	download media file
+// @Description Content managers can download any file. Learners can download their own certificates and submission files
+// @Description and files used by lessons they can open (resources and images in the lesson text).
+// @Tags 		media
+// @Produce 	octet-stream
+// @Param 		id 		path		int 	true 	"Media id"
+// @Success 	200 	{file}		file 	"File"
+// @Failure 	400 	{object}	models.ApiError "Invalid id"
+// @Failure 	404 	{object}	models.ApiError "Media not found"
+// @Router 		/media/{id} [get]
+func (h *MediaHandler) Serve(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Error("Invalid media Id", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid media id"))
+		return
+	}
+
+	media, err := h.mediaRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Media not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to find media", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	if !utils.CanManageContent(c) {
+		allowed, err := h.canAccess(c, media.Id)
+		if err != nil {
+			logger.Error("Failed to check media access", zap.Int("media_id", media.Id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		// чужие файлы не отличаются от несуществующих
+		if !allowed {
+			c.JSON(http.StatusNotFound, models.NewApiError("Media not found"))
+			return
+		}
+	}
+
+	serveMedia(c, media, false)
+}
+
+// canAccess — файл принадлежит ученику или используется уроком, который ученик может открыть
+func (h *MediaHandler) canAccess(c *gin.Context, mediaId int) (bool, error) {
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		return false, nil
+	}
+
+	owned, lessonIds, err := h.mediaRepo.FindUsage(c, mediaId, userUUID)
+	if err != nil || owned {
+		return owned, err
+	}
+
+	for _, lessonId := range lessonIds {
+		lesson, err := h.lessonsRepo.FindById(c, lessonId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if !utils.IsWithinPublicationWindow(lesson.Is_published, lesson.Publish_at, lesson.Unpublish_at, time.Now()) {
+			continue
+		}
+
+		missingLessons, missingCourses, err := h.prerequisitesRepo.MissingForLesson(c, userUUID, lesson.Id)
+		if err != nil {
+			return false, err
+		}
+		if len(missingLessons) == 0 && len(missingCourses) == 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// inlineMedia — типы, которые браузер показывает сам и которые не могут выполнить скрипт.
+// SVG и HTML всегда отдаются как вложение, иначе загруженный файл стал бы XSS на домене API.
+func inlineMedia(mimeType string) bool {
+	mimeType = strings.ToLower(mimeType)
+	if strings.HasPrefix(mimeType, "image/svg") {
+		return false
+	}
+	return strings.HasPrefix(mimeType, "image/") || strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "audio/")
+}
+
+// serveMedia отдает blob из хранилища с именем и типом, сохраненными в таблице media
+func serveMedia(c *gin.Context, media models.Media, attachment bool) {
+	disposition := "inline"
+	if attachment || !inlineMedia(media.Mime_type) {
+		disposition = "attachment"
+	}
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Type", media.Mime_type)
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, media.Filename))
+	c.File(utils.MediaFilePath(media.Checksum))
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type NotificationsHandler struct {
+	notificationsRepo *repositories.NotificationsRepository
+}
+
+func NewNotificationsHandler(notificationsRepo *repositories.NotificationsRepository) *NotificationsHandler {
+	return &NotificationsHandler{notificationsRepo: notificationsRepo}
+}
+
+// FindMine godoc
+// @Summary 	notifications of the current user
+// @Description Newest first, at most 200
+// @Tags 		notifications
+// @Produce 	json
+// @Param 		unread 	query 		bool 	false 	"Only unread notifications"
+// @Success 	200 	{object} 	[]models.Notification "OK"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/notifications [get]
+func (h *NotificationsHandler) FindMine(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	unreadOnly, _ := strconv.ParseBool(c.Query("unread"))
+
+	notifications, err := h.notificationsRepo.FindByUser(c, userUUID, unreadOnly)
+	if err != nil {
+		logger.Error("Failed to fetch notifications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// MarkRead godoc
+// @Summary 	mark notification as read
+// @Tags 		notifications
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Notification id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/notifications/{id}/read [post]
+func (h *NotificationsHandler) MarkRead(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid notification id"))
+		return
+	}
+
+	err = h.notificationsRepo.MarkRead(c, userUUID, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Notification not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to mark notification read", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
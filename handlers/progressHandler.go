@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/config"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// ProgressHandler принимает heartbeat плеера и показывает прогресс просмотра уроков и курсов
+type ProgressHandler struct {
+	progressRepo      *repositories.ProgressRepository
+	lessonsRepo       *repositories.Lessonsrepository
+	coursesRepo       *repositories.Coursesrepository
+	prerequisitesRepo *repositories.PrerequisitesRepository
+	xapiRepo          *repositories.XAPIRepository
+}
+
+func NewProgressHandler(
+	progressRepo *repositories.ProgressRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository,
+	prerequisitesRepo *repositories.PrerequisitesRepository,
+	xapiRepo *repositories.XAPIRepository) *ProgressHandler {
+	return &ProgressHandler{
+		progressRepo:      progressRepo,
+		lessonsRepo:       lessonsRepo,
+		coursesRepo:       coursesRepo,
+		prerequisitesRepo: prerequisitesRepo,
+		xapiRepo:          xapiRepo,
+	}
+}
+
+type heartbeatRequest struct {
+	Position *int `json:"position"`
+}
+
+// Heartbeat godoc
+// @Summary 	report current player position
+// @Description The player sends the position every few seconds. Time between heartbeats counts as watched,
+// @Description seeks forward and back do not. The lesson is completed once the watched share of duration_sec
+// @Description reaches PROGRESS_COMPLETION_THRESHOLD, and course enrollments complete with their last lesson.
+// @Tags 		progress
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Lesson id"
+// @Param 		request body 		heartbeatRequest 	true 	"Position in seconds"
+// @Success 	200 	{object} 	models.LessonProgress "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.LockedContentError "Prerequisites are not completed"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/progress [post]
+func (h *ProgressHandler) Heartbeat(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+	if !ensureLessonUnlocked(c, h.prerequisitesRepo, lesson) {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	var request heartbeatRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+	if request.Position == nil || *request.Position < 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Position must be a non-negative number of seconds"))
+		return
+	}
+
+	previous, err := h.progressRepo.Find(c, userUUID, lesson.Id)
+	if err != nil {
+		logger.Error("Failed to fetch lesson progress", zap.Int("lesson_id", lesson.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	progress, err := h.progressRepo.RecordHeartbeat(c, userUUID, lesson, *request.Position, config.Config.ProgressCompletionThreshold)
+	if err != nil {
+		logger.Error("Failed to record lesson progress", zap.Int("lesson_id", lesson.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	// progressed отправляется на каждой четверти просмотра, а не на каждый heartbeat
+	activity := utils.XAPIActivity("lessons", lesson.Id, models.XAPIActivityLesson, lesson.Title)
+	statements := []models.XAPIStatement{}
+	if quarter := int(progress.Watched_percent / 25); quarter > int(previous.Watched_percent/25) {
+		statements = append(statements, utils.NewXAPIStatement(userUUID, models.XAPIVerbProgressed, activity, &models.XAPIResult{
+			Extensions: map[string]any{utils.XAPIProgressExtension: quarter * 25},
+		}))
+	}
+	if progress.Completed && !previous.Completed {
+		completion := true
+		statements = append(statements, utils.NewXAPIStatement(userUUID, models.XAPIVerbCompleted, activity, &models.XAPIResult{Completion: &completion}))
+	}
+	if len(statements) > 0 {
+		recordXAPI(c, h.xapiRepo, statements...)
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// FindLessonProgress godoc
+// @Summary 	progress of current user in the lesson
+// @Description position_sec is where the player should resume
+// @Tags 		progress
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson id"
+// @Success 	200 	{object} 	models.LessonProgress "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/progress [get]
+func (h *ProgressHandler) FindLessonProgress(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	progress, err := h.progressRepo.Find(c, userUUID, lesson.Id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Lesson not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to fetch lesson progress", zap.Int("lesson_id", lesson.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// FindCourseProgress godoc
+// @Summary 	completion of the course lessons
+// @Description Students see their own progress, teachers may pass user_uuid to see a student's progress
+// @Tags 		progress
+// @Produce 	json
+// @Param 		id 			path		int 	true 	"Course id"
+// @Param 		user_uuid 	query 		string 	false 	"Student uuid, teachers only"
+// @Success 	200 	{object} 	models.CourseProgress "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/progress [get]
+func (h *ProgressHandler) FindCourseProgress(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	if raw := c.Query("user_uuid"); raw != "" {
+		if !utils.CanManageContent(c) {
+			c.JSON(http.StatusForbidden, models.NewApiError("access denied"))
+			return
+		}
+		studentUUID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid user uuid"))
+			return
+		}
+		userUUID = studentUUID
+	}
+
+	progress, err := h.progressRepo.FindByCourse(c, userUUID, course.Id, !utils.CanManageContent(c))
+	if err != nil {
+		logger.Error("Failed to fetch course progress", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
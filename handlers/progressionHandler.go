@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// ProgressionHandler обслуживает предварительные требования уроков и курсов и прохождение уроков
+type ProgressionHandler struct {
+	lessonsRepo       *repositories.Lessonsrepository
+	coursesRepo       *repositories.Coursesrepository
+	prerequisitesRepo *repositories.PrerequisitesRepository
+	completionsRepo   *repositories.CompletionsRepository
+	xapiRepo          *repositories.XAPIRepository
+}
+
+func NewProgressionHandler(
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository,
+	prerequisitesRepo *repositories.PrerequisitesRepository,
+	completionsRepo *repositories.CompletionsRepository,
+	xapiRepo *repositories.XAPIRepository) *ProgressionHandler {
+	return &ProgressionHandler{
+		lessonsRepo:       lessonsRepo,
+		coursesRepo:       coursesRepo,
+		prerequisitesRepo: prerequisitesRepo,
+		completionsRepo:   completionsRepo,
+		xapiRepo:          xapiRepo,
+	}
+}
+
+type prerequisitesRequest struct {
+	Ids []int `json:"ids"`
+}
+
+// findEntityId проверяет, что урок или курс из :id существует и виден пользователю
+func (h *ProgressionHandler) findEntityId(c *gin.Context, entityType string) (int, bool) {
+	switch entityType {
+	case models.EntityLesson:
+		lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+		return lesson.Id, ok
+	case models.EntityCourse:
+		course, ok := findVisibleCourse(c, h.coursesRepo)
+		return course.Id, ok
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewApiError(fmt.Sprintf("unknown entity type %q", entityType)))
+		return 0, false
+	}
+}
+
+// FindPrerequisites godoc
+// @Summary 	prerequisites of lesson or course
+// @Tags 		prerequisites
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson or course id"
+// @Success 	200 	{object} 	[]models.PrerequisiteRef "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/prerequisites [get]
+// @Router 		/courses/{id}/prerequisites [get]
+func (h *ProgressionHandler) FindPrerequisites(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, ok := h.findEntityId(c, entityType)
+		if !ok {
+			return
+		}
+
+		prerequisites, err := h.prerequisitesRepo.FindByEntity(c, entityType, id)
+		if err != nil {
+			logger.Error("Failed to fetch prerequisites", zap.String("entity_type", entityType), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		c.JSON(http.StatusOK, prerequisites)
+	}
+}
+
+// ReplacePrerequisites godoc
+// @Summary 	replace prerequisites of lesson or course
+// @Description Lessons may depend only on lessons and courses only on courses. Changes that would create a cycle are rejected.
+// @Tags 		prerequisites
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 					true 	"Lesson or course id"
+// @Param 		request body 		prerequisitesRequest 	true 	"Prerequisite ids"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError "Unknown prerequisite or cycle"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/prerequisites [put]
+// @Router 		/courses/{id}/prerequisites [put]
+func (h *ProgressionHandler) ReplacePrerequisites(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, ok := h.findEntityId(c, entityType)
+		if !ok {
+			return
+		}
+
+		var request prerequisitesRequest
+		if err := c.BindJSON(&request); err != nil {
+			logger.Error("Failed JSON binding", zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+			return
+		}
+
+		seen := make(map[int]bool, len(request.Ids))
+		for _, prerequisiteId := range request.Ids {
+			if prerequisiteId == id {
+				c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("%s cannot be its own prerequisite", entityType)))
+				return
+			}
+			if seen[prerequisiteId] {
+				c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Duplicate prerequisite id %d", prerequisiteId)))
+				return
+			}
+			seen[prerequisiteId] = true
+		}
+
+		err := h.prerequisitesRepo.Replace(c, entityType, id, request.Ids, utils.ValidateAcyclic)
+		if errors.Is(err, utils.ErrDependencyCycle) || errors.Is(err, pgx.ErrNoRows) {
+			logger.Warn("Rejected prerequisites", zap.String("entity_type", entityType), zap.Int("id", id), zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to save prerequisites", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
+
+// CompleteLesson godoc
+// @Summary 	mark lesson as completed by current user
+// @Description Learners can mark only lessons without duration, lessons with video are completed by watching them (POST /lessons/{id}/progress)
+// @Tags 		prerequisites
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.LockedContentError "Prerequisites are not completed or lesson is completed by watching"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/complete [post]
+func (h *ProgressionHandler) CompleteLesson(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+	if !ensureLessonUnlocked(c, h.prerequisitesRepo, lesson) {
+		return
+	}
+	// урок с длительностью засчитывается только по просмотру через heartbeat
+	if lesson.Duration_sec > 0 && !utils.CanManageContent(c) {
+		c.JSON(http.StatusForbidden, models.NewApiError("Lesson is completed by watching it"))
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	completed, err := h.completionsRepo.FindCompletedLessons(c, userUUID, []int{lesson.Id})
+	if err != nil {
+		logger.Error("Failed to fetch completed lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	if err := h.completionsRepo.MarkLessonCompleted(c, userUUID, lesson.Id); err != nil {
+		logger.Error("Failed to mark lesson completed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	if !completed[lesson.Id] {
+		completion := true
+		recordXAPI(c, h.xapiRepo, utils.NewXAPIStatement(userUUID, models.XAPIVerbCompleted,
+			utils.XAPIActivity("lessons", lesson.Id, models.XAPIActivityLesson, lesson.Title), &models.XAPIResult{Completion: &completion}))
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// CourseUnlocks godoc
+// @Summary 	unlocked and locked lessons of the course for current user
+// @Tags 		prerequisites
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	models.CourseUnlockMap "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/unlocks [get]
+func (h *ProgressionHandler) CourseUnlocks(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	missingCourses, err := h.prerequisitesRepo.MissingForCourse(c, userUUID, course.Id)
+	if err != nil {
+		logger.Error("Failed to check course prerequisites", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	lessons, err := h.coursesRepo.FindLessons(c, course.Id, !utils.CanManageContent(c))
+	if err != nil {
+		logger.Error("Failed to fetch course lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	lessonIds := make([]int, len(lessons))
+	for i, lesson := range lessons {
+		lessonIds[i] = lesson.Lesson_id
+	}
+
+	edges, err := h.prerequisitesRepo.FindLessonEdges(c, lessonIds)
+	if err != nil {
+		logger.Error("Failed to fetch lesson prerequisites", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	// требования могут быть и из других курсов, поэтому проверяем прохождение и для них
+	checkIds := append([]int{}, lessonIds...)
+	for _, prerequisites := range edges {
+		checkIds = append(checkIds, prerequisites...)
+	}
+
+	completed, err := h.completionsRepo.FindCompletedLessons(c, userUUID, checkIds)
+	if err != nil {
+		logger.Error("Failed to fetch completed lessons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	unlockMap := models.CourseUnlockMap{
+		Course_id:       course.Id,
+		Unlocked:        len(missingCourses) == 0,
+		Missing_courses: missingCourses,
+		Lessons:         make([]models.LessonUnlockState, 0, len(lessons)),
+	}
+	for _, lesson := range lessons {
+		missing := make([]int, 0)
+		for _, prerequisiteId := range edges[lesson.Lesson_id] {
+			if !completed[prerequisiteId] {
+				missing = append(missing, prerequisiteId)
+			}
+		}
+
+		unlockMap.Lessons = append(unlockMap.Lessons, models.LessonUnlockState{
+			Lesson_id:       lesson.Lesson_id,
+			Title:           lesson.Title,
+			Position:        lesson.Position,
+			Completed:       completed[lesson.Lesson_id],
+			Unlocked:        unlockMap.Unlocked && len(missing) == 0,
+			Missing_lessons: missing,
+		})
+	}
+
+	c.JSON(http.StatusOK, unlockMap)
+}
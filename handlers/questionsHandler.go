@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// QuestionsHandler ведет банк вопросов предметов
+type QuestionsHandler struct {
+	questionsRepo *repositories.QuestionsRepository
+	subjectsRepo  *repositories.SubjectsRepository
+}
+
+func NewQuestionsHandler(questionsRepo *repositories.QuestionsRepository, subjectsRepo *repositories.SubjectsRepository) *QuestionsHandler {
+	return &QuestionsHandler{questionsRepo: questionsRepo, subjectsRepo: subjectsRepo}
+}
+
+type questionRequest struct {
+	Type               string                  `json:"type"`
+	Text               string                  `json:"text"`
+	Options            []models.QuestionOption `json:"options"`
+	Answer_bool        *bool                   `json:"answer_bool"`
+	Answer_number      *float64                `json:"answer_number"`
+	Tolerance          float64                 `json:"tolerance"`
+	Accepted_answers   []string                `json:"accepted_answers"`
+	Points             int                     `json:"points"`
+	Feedback_correct   string                  `json:"feedback_correct"`
+	Feedback_incorrect string                  `json:"feedback_incorrect"`
+}
+
+func bindQuestion(c *gin.Context) (models.Question, bool) {
+	logger := logger.GetLogger()
+
+	var request questionRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return models.Question{}, false
+	}
+
+	question := models.Question{
+		Type:               request.Type,
+		Text:               request.Text,
+		Options:            request.Options,
+		Answer_bool:        request.Answer_bool,
+		Answer_number:      request.Answer_number,
+		Tolerance:          request.Tolerance,
+		Accepted_answers:   request.Accepted_answers,
+		Points:             request.Points,
+		Feedback_correct:   strings.TrimSpace(request.Feedback_correct),
+		Feedback_incorrect: strings.TrimSpace(request.Feedback_incorrect),
+	}
+	if err := utils.NormalizeQuestion(&question); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return models.Question{}, false
+	}
+
+	return question, true
+}
+
+func (h *QuestionsHandler) findSubjectId(c *gin.Context) (int, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid subject id"))
+		return 0, false
+	}
+
+	_, err = h.subjectsRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Subject not found"))
+		return 0, false
+	}
+	if err != nil {
+		logger.Error("Failed to find subject", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (h *QuestionsHandler) findQuestion(c *gin.Context) (models.Question, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid question id"))
+		return models.Question{}, false
+	}
+
+	question, err := h.questionsRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Question not found"))
+		return models.Question{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find question", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Question{}, false
+	}
+
+	return question, true
+}
+
+// FindBySubject godoc
+// @Summary 	question bank of the subject
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Subject id"
+// @Param 		type 	query 		string 	false 	"single_choice, multiple_choice, true_false, numeric or short_answer"
+// @Success 	200 	{object} 	[]models.Question "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/subjects/{id}/questions [get]
+func (h *QuestionsHandler) FindBySubject(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	subjectId, ok := h.findSubjectId(c)
+	if !ok {
+		return
+	}
+
+	questionType := c.Query("type")
+	if questionType != "" && !slices.Contains(models.QuestionTypes, questionType) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Type must be one of "+strings.Join(models.QuestionTypes, ", ")))
+		return
+	}
+
+	questions, err := h.questionsRepo.FindBySubject(c, subjectId, questionType)
+	if err != nil {
+		logger.Error("Failed to fetch questions", zap.Int("subject_id", subjectId), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, questions)
+}
+
+// Create godoc
+// @Summary 	add question to the subject bank
+// @Description Choice questions take options with a correct flag, true_false takes answer_bool,
+// @Description numeric takes answer_number and tolerance, short_answer takes accepted_answers (case and spaces are ignored)
+// @Tags 		quizzes
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Subject id"
+// @Param 		request body 		questionRequest 	true 	"Question"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/subjects/{id}/questions [post]
+func (h *QuestionsHandler) Create(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	subjectId, ok := h.findSubjectId(c)
+	if !ok {
+		return
+	}
+
+	question, ok := bindQuestion(c)
+	if !ok {
+		return
+	}
+	question.Subject_id = subjectId
+
+	id, err := h.questionsRepo.Create(c, question)
+	if err != nil {
+		logger.Error("Failed to create question", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Question has been created", zap.Int("question_id", id), zap.Int("subject_id", subjectId))
+
+	c.JSON(http.StatusOK, gin.H{
+		"id": id,
+	})
+}
+
+// FindById godoc
+// @Summary 	find question by id
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Question id"
+// @Success 	200 	{object} 	models.Question "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/questions/{id} [get]
+func (h *QuestionsHandler) FindById(c *gin.Context) {
+	question, ok := h.findQuestion(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+// Update godoc
+// @Summary 	update question
+// @Description Attempts that are already graded keep their results
+// @Tags 		quizzes
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Question id"
+// @Param 		request body 		questionRequest 	true 	"Question"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/questions/{id} [put]
+func (h *QuestionsHandler) Update(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	existing, ok := h.findQuestion(c)
+	if !ok {
+		return
+	}
+
+	question, ok := bindQuestion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.questionsRepo.Update(c, existing.Id, question); err != nil {
+		logger.Error("Failed to update question", zap.Int("question_id", existing.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Summary 	delete question
+// @Description The question is also removed from every quiz that uses it
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Question id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/questions/{id} [delete]
+func (h *QuestionsHandler) Delete(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	question, ok := h.findQuestion(c)
+	if !ok {
+		return
+	}
+
+	if err := h.questionsRepo.Delete(c, question.Id); err != nil {
+		logger.Error("Failed to delete question", zap.Int("question_id", question.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,594 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// QuizzesHandler ведет квизы уроков и курсов и принимает попытки учеников
+type QuizzesHandler struct {
+	quizzesRepo       *repositories.QuizzesRepository
+	questionsRepo     *repositories.QuestionsRepository
+	lessonsRepo       *repositories.Lessonsrepository
+	coursesRepo       *repositories.Coursesrepository
+	prerequisitesRepo *repositories.PrerequisitesRepository
+	xapiRepo          *repositories.XAPIRepository
+}
+
+func NewQuizzesHandler(
+	quizzesRepo *repositories.QuizzesRepository,
+	questionsRepo *repositories.QuestionsRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository,
+	prerequisitesRepo *repositories.PrerequisitesRepository,
+	xapiRepo *repositories.XAPIRepository) *QuizzesHandler {
+	return &QuizzesHandler{
+		quizzesRepo:       quizzesRepo,
+		questionsRepo:     questionsRepo,
+		lessonsRepo:       lessonsRepo,
+		coursesRepo:       coursesRepo,
+		prerequisitesRepo: prerequisitesRepo,
+		xapiRepo:          xapiRepo,
+	}
+}
+
+type quizRequest struct {
+	Title             string  `json:"title"`
+	Description       string  `json:"description"`
+	Lesson_id         *int    `json:"lesson_id"`
+	Course_id         *int    `json:"course_id"`
+	Position          int     `json:"position"`
+	Time_limit_sec    *int    `json:"time_limit_sec"`
+	Max_attempts      *int    `json:"max_attempts"`
+	Shuffle_questions bool    `json:"shuffle_questions"`
+	Shuffle_options   bool    `json:"shuffle_options"`
+	Pass_percent      float64 `json:"pass_percent"`
+	Question_ids      []int   `json:"question_ids"`
+}
+
+type submitQuizRequest struct {
+	Answers []models.QuizAnswer `json:"answers"`
+}
+
+func (h *QuizzesHandler) bindQuiz(c *gin.Context) (models.Quiz, bool) {
+	logger := logger.GetLogger()
+
+	var request quizRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return models.Quiz{}, false
+	}
+
+	quiz := models.Quiz{
+		Title:             strings.TrimSpace(request.Title),
+		Description:       strings.TrimSpace(request.Description),
+		Lesson_id:         request.Lesson_id,
+		Course_id:         request.Course_id,
+		Position:          request.Position,
+		Time_limit_sec:    request.Time_limit_sec,
+		Max_attempts:      request.Max_attempts,
+		Shuffle_questions: request.Shuffle_questions,
+		Shuffle_options:   request.Shuffle_options,
+		Pass_percent:      request.Pass_percent,
+		Question_ids:      request.Question_ids,
+	}
+
+	switch {
+	case quiz.Title == "":
+		c.JSON(http.StatusBadRequest, models.NewApiError("Quiz title is required"))
+		return models.Quiz{}, false
+	case quiz.Time_limit_sec != nil && *quiz.Time_limit_sec <= 0:
+		c.JSON(http.StatusBadRequest, models.NewApiError("time_limit_sec must be positive"))
+		return models.Quiz{}, false
+	case quiz.Max_attempts != nil && *quiz.Max_attempts <= 0:
+		c.JSON(http.StatusBadRequest, models.NewApiError("max_attempts must be positive"))
+		return models.Quiz{}, false
+	case quiz.Pass_percent < 0 || quiz.Pass_percent > 100:
+		c.JSON(http.StatusBadRequest, models.NewApiError("pass_percent must be between 0 and 100"))
+		return models.Quiz{}, false
+	case len(quiz.Question_ids) == 0:
+		c.JSON(http.StatusBadRequest, models.NewApiError("Quiz needs at least one question"))
+		return models.Quiz{}, false
+	}
+
+	if !validateAttachment(c, h.lessonsRepo, h.coursesRepo, quiz.Lesson_id, quiz.Course_id) {
+		return models.Quiz{}, false
+	}
+
+	questions, err := h.questionsRepo.FindByIds(c, quiz.Question_ids)
+	if err != nil {
+		logger.Error("Failed to find questions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Quiz{}, false
+	}
+	seen := make(map[int]bool)
+	for _, id := range quiz.Question_ids {
+		if _, ok := questions[id]; !ok {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Question "+strconv.Itoa(id)+" not found"))
+			return models.Quiz{}, false
+		}
+		if seen[id] {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Question "+strconv.Itoa(id)+" is listed twice"))
+			return models.Quiz{}, false
+		}
+		seen[id] = true
+	}
+
+	return quiz, true
+}
+
+// findVisibleQuiz загружает квиз из параметра :id и проверяет, что его урок или курс виден пользователю.
+// Для квиза урока возвращает и сам урок, чтобы проверить предварительные требования.
+func (h *QuizzesHandler) findVisibleQuiz(c *gin.Context) (models.Quiz, *models.Lesson, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid quiz id"))
+		return models.Quiz{}, nil, false
+	}
+
+	quiz, err := h.quizzesRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Quiz not found"))
+		return models.Quiz{}, nil, false
+	}
+	if err != nil {
+		logger.Error("Failed to find quiz", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Quiz{}, nil, false
+	}
+
+	lesson, ok := findAttachedContent(c, h.lessonsRepo, h.coursesRepo, quiz.Lesson_id, quiz.Course_id, "Quiz not found")
+	if !ok {
+		return models.Quiz{}, nil, false
+	}
+
+	return quiz, lesson, true
+}
+
+// attemptQuestions собирает вопросы попытки в ее порядке, без правильных ответов
+func attemptQuestions(attempt models.QuizAttempt, questions map[int]models.Question) []models.QuizQuestion {
+	result := make([]models.QuizQuestion, 0, len(attempt.Question_order))
+	for _, id := range attempt.Question_order {
+		question, ok := questions[id]
+		if !ok {
+			continue
+		}
+
+		view := models.QuizQuestion{Id: question.Id, Type: question.Type, Text: question.Text, Points: question.Points}
+		options := make(map[string]string, len(question.Options))
+		for _, option := range question.Options {
+			options[option.Id] = option.Text
+		}
+		for _, optionId := range attempt.Option_order[id] {
+			if text, ok := options[optionId]; ok {
+				view.Options = append(view.Options, models.QuizOption{Id: optionId, Text: text})
+				delete(options, optionId)
+			}
+		}
+		// варианты, добавленные после начала попытки
+		for _, option := range question.Options {
+			if _, ok := options[option.Id]; ok {
+				view.Options = append(view.Options, models.QuizOption{Id: option.Id, Text: option.Text})
+			}
+		}
+
+		result = append(result, view)
+	}
+	return result
+}
+
+// FindByLesson godoc
+// @Summary 	quizzes of the lesson
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson id"
+// @Success 	200 	{object} 	[]models.Quiz "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/quizzes [get]
+func (h *QuizzesHandler) FindByLesson(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+	if !ok {
+		return
+	}
+
+	quizzes, err := h.quizzesRepo.FindByLesson(c, lesson.Id)
+	if err != nil {
+		logger.Error("Failed to fetch lesson quizzes", zap.Int("lesson_id", lesson.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, quizzes)
+}
+
+// FindByCourse godoc
+// @Summary 	quiz modules of the course
+// @Description A course quiz is a module placed after the lesson with the same position in the course order
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Course id"
+// @Success 	200 	{object} 	[]models.Quiz "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/courses/{id}/quizzes [get]
+func (h *QuizzesHandler) FindByCourse(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	course, ok := findVisibleCourse(c, h.coursesRepo)
+	if !ok {
+		return
+	}
+
+	quizzes, err := h.quizzesRepo.FindByCourse(c, course.Id)
+	if err != nil {
+		logger.Error("Failed to fetch course quizzes", zap.Int("course_id", course.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, quizzes)
+}
+
+// FindById godoc
+// @Summary 	find quiz by id
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Quiz id"
+// @Success 	200 	{object} 	models.Quiz "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/quizzes/{id} [get]
+func (h *QuizzesHandler) FindById(c *gin.Context) {
+	quiz, _, ok := h.findVisibleQuiz(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, quiz)
+}
+
+// Create godoc
+// @Summary 	create quiz for a lesson or a course
+// @Description Exactly one of lesson_id and course_id is required. Questions come from the question bank in the given order.
+// @Tags 		quizzes
+// @Accept 		json
+// @Produce 	json
+// @Param 		request body 		quizRequest 	true 	"Quiz"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/quizzes [post]
+func (h *QuizzesHandler) Create(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	quiz, ok := h.bindQuiz(c)
+	if !ok {
+		return
+	}
+
+	id, err := h.quizzesRepo.Create(c, quiz)
+	if err != nil {
+		logger.Error("Failed to create quiz", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Quiz has been created", zap.Int("quiz_id", id))
+
+	c.JSON(http.StatusOK, gin.H{
+		"id": id,
+	})
+}
+
+// Update godoc
+// @Summary 	update quiz
+// @Description Attempts in progress keep the questions they started with
+// @Tags 		quizzes
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 			true 	"Quiz id"
+// @Param 		request body 		quizRequest 	true 	"Quiz"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/quizzes/{id} [put]
+func (h *QuizzesHandler) Update(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	existing, _, ok := h.findVisibleQuiz(c)
+	if !ok {
+		return
+	}
+
+	quiz, ok := h.bindQuiz(c)
+	if !ok {
+		return
+	}
+
+	if err := h.quizzesRepo.Update(c, existing.Id, quiz); err != nil {
+		logger.Error("Failed to update quiz", zap.Int("quiz_id", existing.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Summary 	delete quiz
+// @Description Quizzes that students have already attempted can not be deleted
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Quiz id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	409 	{object}	models.ApiError "Quiz has attempts"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/quizzes/{id} [delete]
+func (h *QuizzesHandler) Delete(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	quiz, _, ok := h.findVisibleQuiz(c)
+	if !ok {
+		return
+	}
+
+	err := h.quizzesRepo.Delete(c, quiz.Id)
+	if errors.Is(err, repositories.ErrQuizHasAttempts) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete quiz", zap.Int("quiz_id", quiz.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// StartAttempt godoc
+// @Summary 	start quiz attempt or resume the unfinished one
+// @Description Questions and options are shuffled once per attempt when the quiz asks for it.
+// @Description deadline is set when the quiz has a time limit.
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Quiz id"
+// @Success 	200 	{object} 	models.QuizAttempt "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.LockedContentError "Prerequisites are not completed"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	409 	{object}	models.ApiError "No attempts left"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/quizzes/{id}/attempts [post]
+func (h *QuizzesHandler) StartAttempt(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	quiz, lesson, ok := h.findVisibleQuiz(c)
+	if !ok {
+		return
+	}
+	if lesson != nil && !ensureLessonUnlocked(c, h.prerequisitesRepo, *lesson) {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	questions, err := h.questionsRepo.FindByIds(c, quiz.Question_ids)
+	if err != nil {
+		logger.Error("Failed to fetch quiz questions", zap.Int("quiz_id", quiz.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	attempt := models.QuizAttempt{Option_order: make(map[int][]string)}
+	attempt.Question_order = quiz.Question_ids
+	if quiz.Shuffle_questions {
+		attempt.Question_order = utils.ShuffledIds(quiz.Question_ids)
+	}
+	for _, question := range questions {
+		attempt.Max_score += float64(question.Points)
+
+		optionIds := make([]string, len(question.Options))
+		for i, option := range question.Options {
+			optionIds[i] = option.Id
+		}
+		if quiz.Shuffle_options {
+			optionIds = utils.ShuffledIds(optionIds)
+		}
+		attempt.Option_order[question.Id] = optionIds
+	}
+
+	attempt, err = h.quizzesRepo.StartAttempt(c, quiz, userUUID, attempt, utils.QuizDeadlineGrace)
+	if errors.Is(err, repositories.ErrNoAttemptsLeft) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to start quiz attempt", zap.Int("quiz_id", quiz.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	// возобновленная попытка могла начаться с другим набором вопросов
+	questions, err = h.questionsRepo.FindByIds(c, attempt.Question_order)
+	if err != nil {
+		logger.Error("Failed to fetch attempt questions", zap.Int("attempt_id", attempt.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+	attempt.Questions = attemptQuestions(attempt, questions)
+
+	c.JSON(http.StatusOK, attempt)
+}
+
+// SubmitAttempt godoc
+// @Summary 	submit answers and get graded result
+// @Description Each question gets its points and feedback. Answers sent after the time limit are not graded.
+// @Tags 		quizzes
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 			path		int 				true 	"Quiz id"
+// @Param 		attemptId 	path		int 				true 	"Attempt id"
+// @Param 		request 	body 		submitQuizRequest 	true 	"Answers"
+// @Success 	200 	{object} 	models.QuizAttempt "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	409 	{object}	models.ApiError "Attempt is already finished"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/quizzes/{id}/attempts/{attemptId}/submit [post]
+func (h *QuizzesHandler) SubmitAttempt(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	quiz, _, ok := h.findVisibleQuiz(c)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	attemptId, err := strconv.Atoi(c.Param("attemptId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid attempt id"))
+		return
+	}
+
+	attempt, err := h.quizzesRepo.FindAttempt(c, attemptId)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (attempt.Quiz_id != quiz.Id || attempt.User_uuid != userUUID)) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Attempt not found"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to find quiz attempt", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+	if attempt.Status != models.AttemptInProgress {
+		c.JSON(http.StatusConflict, models.NewApiError(repositories.ErrAttemptClosed.Error()))
+		return
+	}
+
+	var request submitQuizRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return
+	}
+
+	status := models.AttemptSubmitted
+	score := 0.0
+	results := make([]models.QuestionResult, 0, len(attempt.Question_order))
+	statements := make([]models.XAPIStatement, 0, len(attempt.Question_order))
+	if attempt.Deadline != nil && time.Now().After(attempt.Deadline.Add(utils.QuizDeadlineGrace)) {
+		status = models.AttemptExpired
+	} else {
+		questions, err := h.questionsRepo.FindByIds(c, attempt.Question_order)
+		if err != nil {
+			logger.Error("Failed to fetch attempt questions", zap.Int("attempt_id", attempt.Id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		answers := make(map[int]models.QuizAnswer, len(request.Answers))
+		for _, answer := range request.Answers {
+			answers[answer.Question_id] = answer
+		}
+
+		// вопрос без ответа оценивается как неверный
+		for _, id := range attempt.Question_order {
+			question, ok := questions[id]
+			if !ok {
+				continue
+			}
+			result := utils.GradeAnswer(question, answers[id])
+			score += result.Earned
+			results = append(results, result)
+
+			statement := utils.NewXAPIStatement(userUUID, models.XAPIVerbAnswered,
+				utils.XAPIActivity("questions", question.Id, models.XAPIActivityInteraction, question.Text), utils.XAPIAnswerResult(answers[id], result))
+			statement.Context = xapiParentContext(utils.XAPIActivityId("quizzes", quiz.Id))
+			statements = append(statements, statement)
+		}
+	}
+
+	attempt, err = h.quizzesRepo.SubmitAttempt(c, attempt.Id, status, score, results)
+	if errors.Is(err, repositories.ErrAttemptClosed) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to submit quiz attempt", zap.Int("attempt_id", attemptId), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	if len(statements) > 0 {
+		recordXAPI(c, h.xapiRepo, statements...)
+	}
+
+	c.JSON(http.StatusOK, attempt)
+}
+
+// FindAttempts godoc
+// @Summary 	attempts of current user
+// @Tags 		quizzes
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Quiz id"
+// @Success 	200 	{object} 	[]models.QuizAttempt "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/quizzes/{id}/attempts [get]
+func (h *QuizzesHandler) FindAttempts(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	quiz, _, ok := h.findVisibleQuiz(c)
+	if !ok {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	attempts, err := h.quizzesRepo.FindAttempts(c, quiz.Id, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch quiz attempts", zap.Int("quiz_id", quiz.Id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
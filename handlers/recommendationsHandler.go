@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRecommendationsLimit = 10
+	maxRecommendationsLimit     = 50
+)
+
+type RecommendationsHandler struct {
+	recommendationsRepo *repositories.RecommendationsRepository
+}
+
+func NewRecommendationsHandler(recommendationsRepo *repositories.RecommendationsRepository) *RecommendationsHandler {
+	return &RecommendationsHandler{recommendationsRepo: recommendationsRepo}
+}
+
+type learnerProfileRequest struct {
+	Interests []string `json:"interests"`
+	Age       *int     `json:"age"`
+	Level     string   `json:"level"`
+}
+
+// MyRecommendations godoc
+// @Summary 	personalized lesson recommendations
+// @Description Ranks published lessons the learner has not completed by their interests, age and level,
+// @Description by lessons that learners with similar completion history took, and by subjects the learner studies.
+// @Description Every recommendation explains why it was chosen.
+// @Tags 		recommendations
+// @Produce 	json
+// @Param 		limit 	query 		int 	false 	"How many lessons to return, 10 by default, at most 50"
+// @Success 	200 	{object} 	[]models.Recommendation "Best first"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/recommendations [get]
+func (h *RecommendationsHandler) MyRecommendations(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	limit := defaultRecommendationsLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxRecommendationsLimit {
+			c.JSON(http.StatusBadRequest, models.NewApiError("limit must be between 1 and "+strconv.Itoa(maxRecommendationsLimit)))
+			return
+		}
+		limit = parsed
+	}
+
+	profile, err := h.recommendationsRepo.FindProfile(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch learner profile", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	candidates, err := h.recommendationsRepo.FindCandidates(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch recommendation candidates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.RankRecommendations(profile, candidates, limit))
+}
+
+// FindProfile godoc
+// @Summary 	interests profile of the current user
+// @Tags 		recommendations
+// @Produce 	json
+// @Success 	200 	{object} 	models.LearnerProfile "OK"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/interests [get]
+func (h *RecommendationsHandler) FindProfile(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	profile, err := h.recommendationsRepo.FindProfile(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch learner profile", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateProfile godoc
+// @Summary 	update interests profile of the current user
+// @Description Interests are compared with the interest of lessons case-insensitively. Empty level means any level.
+// @Tags 		recommendations
+// @Accept 		json
+// @Produce 	json
+// @Param 		request body 		learnerProfileRequest 	true 	"Profile"
+// @Success 	200 	{object} 	models.LearnerProfile "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/me/interests [put]
+func (h *RecommendationsHandler) UpdateProfile(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+
+	var request learnerProfileRequest
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid request payload"))
+		return
+	}
+
+	interests := utils.NormalizeInterests(request.Interests)
+	if len(interests) > utils.MaxLearnerInterests {
+		c.JSON(http.StatusBadRequest, models.NewApiError("At most "+strconv.Itoa(utils.MaxLearnerInterests)+" interests are allowed"))
+		return
+	}
+	if request.Age != nil && (*request.Age <= 0 || *request.Age > 120) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid age"))
+		return
+	}
+	if request.Level != "" && !slices.Contains(models.Levels, request.Level) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Level must be one of "+strings.Join(models.Levels, ", ")))
+		return
+	}
+
+	profile := models.LearnerProfile{User_uuid: userUUID, Interests: interests, Age: request.Age, Level: request.Level}
+	if err := h.recommendationsRepo.SaveProfile(c, profile); err != nil {
+		logger.Error("Failed to save learner profile", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	saved, err := h.recommendationsRepo.FindProfile(c, userUUID)
+	if err != nil {
+		logger.Error("Failed to fetch learner profile", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
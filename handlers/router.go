@@ -3,6 +3,7 @@ package handlers
 import (
 	"go-EdTech/docs"
 	"go-EdTech/middlewares"
+	"go-EdTech/models"
 	"go-EdTech/repositories"
 
 	"github.com/gin-gonic/gin"
@@ -19,12 +20,65 @@ func SetupRoutes(r *gin.Engine, conn *pgxpool.Pool) {
 	coursesRepository := repositories.NewCoursesRepository(conn)
 	roleRepository := repositories.NewRoleRepository(conn)
 	sessionsRepository := repositories.NewSessionsRepository(conn)
+	captionsRepository := repositories.NewCaptionsRepository(conn)
+	mediaRepository := repositories.NewMediaRepository(conn)
+	lessonResourcesRepository := repositories.NewLessonResourcesRepository(conn)
+	revisionsRepository := repositories.NewRevisionsRepository(conn)
+	prerequisitesRepository := repositories.NewPrerequisitesRepository(conn)
+	completionsRepository := repositories.NewCompletionsRepository(conn)
+	tagsRepository := repositories.NewTagsRepository(conn)
+	searchRepository := repositories.NewSearchRepository(conn)
+	translationsRepository := repositories.NewTranslationsRepository(conn)
+	lineageRepository := repositories.NewLineageRepository(conn)
+	trashRepository := repositories.NewTrashRepository(conn)
+	enrollmentsRepository := repositories.NewEnrollmentsRepository(conn)
+	progressRepository := repositories.NewProgressRepository(conn)
+	questionsRepository := repositories.NewQuestionsRepository(conn)
+	quizzesRepository := repositories.NewQuizzesRepository(conn)
+	assignmentsRepository := repositories.NewAssignmentsRepository(conn)
+	notificationsRepository := repositories.NewNotificationsRepository(conn)
+	gradebookRepository := repositories.NewGradebookRepository(conn)
+	certificatesRepository := repositories.NewCertificatesRepository(conn)
+	xapiRepository := repositories.NewXAPIRepository(conn)
+	learningPathsRepository := repositories.NewLearningPathsRepository(conn)
+	recommendationsRepository := repositories.NewRecommendationsRepository(conn)
+	flashcardsRepository := repositories.NewFlashcardsRepository(conn)
+	gamificationRepository := repositories.NewGamificationRepository(conn)
+	leaderboardsRepository := repositories.NewLeaderboardsRepository(conn)
+	classesRepository := repositories.NewClassesRepository(conn)
 
 	usersHandlers := NewUsersHandlers(usersRepository)
-	lessonsHandlers := NewLessonsHandler(lessonsRepository)
-	subjectsHandlers := NewSubjectsHandlers(subjectsRepository)
-	CoursesHandlers := NewCoursesHandler(coursesRepository)
+	lessonsHandlers := NewLessonsHandler(lessonsRepository, mediaRepository, revisionsRepository, prerequisitesRepository, tagsRepository, translationsRepository, xapiRepository)
+	subjectsHandlers := NewSubjectsHandlers(subjectsRepository, translationsRepository)
+	CoursesHandlers := NewCoursesHandler(coursesRepository, revisionsRepository, tagsRepository, translationsRepository)
 	authHandlers := NewAuthHandler(usersRepository, sessionsRepository, roleRepository)
+	captionsHandlers := NewCaptionsHandler(captionsRepository, lessonsRepository, prerequisitesRepository)
+	mediaHandlers := NewMediaHandler(mediaRepository, lessonsRepository, prerequisitesRepository)
+	lessonResourcesHandlers := NewLessonResourcesHandler(lessonResourcesRepository, lessonsRepository, mediaRepository, prerequisitesRepository)
+	workflowHandlers := NewContentWorkflowHandler(lessonsRepository, coursesRepository, revisionsRepository)
+	tagsHandlers := NewTagsHandler(tagsRepository, lessonsRepository, coursesRepository)
+	searchHandlers := NewSearchHandler(searchRepository, tagsRepository)
+	lessonImportHandlers := NewLessonImportHandler(lessonsRepository, subjectsRepository, mediaRepository)
+	courseExchangeHandlers := NewCourseExchangeHandler(coursesRepository, lessonsRepository, subjectsRepository, mediaRepository, lessonResourcesRepository, revisionsRepository)
+	translationsHandlers := NewTranslationsHandler(translationsRepository, lessonsRepository, coursesRepository, subjectsRepository, mediaRepository)
+	trashHandlers := NewTrashHandler(trashRepository)
+	enrollmentHandlers := NewEnrollmentHandler(enrollmentsRepository, coursesRepository, usersRepository)
+	cloneHandlers := NewCloneHandler(coursesRepository, lessonsRepository, lineageRepository, revisionsRepository)
+	progressionHandlers := NewProgressionHandler(lessonsRepository, coursesRepository, prerequisitesRepository, completionsRepository, xapiRepository)
+	progressHandlers := NewProgressHandler(progressRepository, lessonsRepository, coursesRepository, prerequisitesRepository, xapiRepository)
+	questionsHandlers := NewQuestionsHandler(questionsRepository, subjectsRepository)
+	quizzesHandlers := NewQuizzesHandler(quizzesRepository, questionsRepository, lessonsRepository, coursesRepository, prerequisitesRepository, xapiRepository)
+	assignmentsHandlers := NewAssignmentsHandler(assignmentsRepository, lessonsRepository, coursesRepository, prerequisitesRepository, mediaRepository)
+	notificationsHandlers := NewNotificationsHandler(notificationsRepository)
+	gradebookHandlers := NewGradebookHandler(gradebookRepository, enrollmentsRepository, coursesRepository)
+	certificatesHandlers := NewCertificatesHandler(certificatesRepository)
+	xapiHandlers := NewXAPIHandler(xapiRepository)
+	learningPathsHandlers := NewLearningPathsHandler(learningPathsRepository, coursesRepository)
+	recommendationsHandlers := NewRecommendationsHandler(recommendationsRepository)
+	flashcardsHandlers := NewFlashcardsHandler(flashcardsRepository, lessonsRepository, subjectsRepository)
+	gamificationHandlers := NewGamificationHandler(gamificationRepository)
+	leaderboardsHandlers := NewLeaderboardsHandler(leaderboardsRepository, coursesRepository, enrollmentsRepository, classesRepository)
+	classesHandlers := NewClassesHandler(classesRepository)
 
 	unauthorized := r.Group("")
 
@@ -48,11 +102,122 @@ func SetupRoutes(r *gin.Engine, conn *pgxpool.Pool) {
 	authorized.PATCH("/users/:uuid/deactivate", usersHandlers.Deactivate) //no functionality 
 	authorized.PATCH("/users/:uuid/activate", usersHandlers.Activate) //no functionality
 
+	contentManagers := middlewares.RoleMiddleware(models.RoleAdmin, models.RoleTeacher, models.RoleContentLead)
+	publishers := middlewares.RoleMiddleware(models.RoleAdmin, models.RoleContentLead)
+	admins := middlewares.RoleMiddleware(models.RoleAdmin)
+	translators := middlewares.RoleMiddleware(models.RoleAdmin, models.RoleContentLead, models.RoleTranslator)
+
 	authorized.GET("/lessons/:id", lessonsHandlers.FindById)
 	authorized.GET("/lessons", lessonsHandlers.FindAll)
-	authorized.POST("/lessons", lessonsHandlers.Create)
-	authorized.PUT("lessons/:id", lessonsHandlers.Update)
-	authorized.DELETE("lessons/:id", lessonsHandlers.Delete)
+	authorized.POST("/lessons", contentManagers, lessonsHandlers.Create)
+	authorized.PUT("lessons/:id", contentManagers, lessonsHandlers.Update)
+	authorized.DELETE("lessons/:id", contentManagers, lessonsHandlers.Delete)
+
+	authorized.GET("/lessons/:id/captions", captionsHandlers.FindByLesson)
+	authorized.POST("/lessons/:id/captions", contentManagers, captionsHandlers.Upload)
+	authorized.GET("/lessons/:id/captions/:language", captionsHandlers.Serve)
+	authorized.DELETE("/lessons/:id/captions/:language", contentManagers, captionsHandlers.Delete)
+
+	authorized.POST("/lessons/import", contentManagers, lessonImportHandlers.Import)
+
+	authorized.GET("/lessons/:id/resources", lessonResourcesHandlers.FindByLesson)
+	authorized.GET("/lessons/:id/resources/:resourceId/download", lessonResourcesHandlers.Download)
+	authorized.POST("/lessons/:id/resources", contentManagers, lessonResourcesHandlers.Create)
+	authorized.PUT("/lessons/:id/resources/order", contentManagers, lessonResourcesHandlers.Reorder)
+	authorized.DELETE("/lessons/:id/resources/:resourceId", contentManagers, lessonResourcesHandlers.Delete)
+
+	for _, entity := range []struct{ path, entityType string }{
+		{"/lessons/:id", models.EntityLesson},
+		{"/courses/:id", models.EntityCourse},
+	} {
+		authorized.PATCH(entity.path+"/status", contentManagers, workflowHandlers.UpdateStatus(entity.entityType))
+		authorized.PUT(entity.path+"/schedule", publishers, workflowHandlers.Schedule(entity.entityType))
+		authorized.GET(entity.path+"/revisions", contentManagers, workflowHandlers.FindRevisions(entity.entityType))
+		authorized.GET(entity.path+"/revisions/diff", contentManagers, workflowHandlers.Diff(entity.entityType))
+		authorized.GET(entity.path+"/revisions/:revision", contentManagers, workflowHandlers.FindRevision(entity.entityType))
+		authorized.POST(entity.path+"/revisions/:revision/restore", contentManagers, workflowHandlers.Restore(entity.entityType))
+		authorized.POST(entity.path+"/revisions/:revision/approve", publishers, workflowHandlers.Approve(entity.entityType))
+		authorized.POST(entity.path+"/revisions/:revision/reject", publishers, workflowHandlers.Reject(entity.entityType))
+		authorized.GET(entity.path+"/prerequisites", progressionHandlers.FindPrerequisites(entity.entityType))
+		authorized.PUT(entity.path+"/prerequisites", contentManagers, progressionHandlers.ReplacePrerequisites(entity.entityType))
+		authorized.GET(entity.path+"/tags", tagsHandlers.FindForEntity(entity.entityType))
+		authorized.PUT(entity.path+"/tags", contentManagers, tagsHandlers.ReplaceForEntity(entity.entityType))
+	}
+
+	for _, entity := range []struct{ path, entityType string }{
+		{"/lessons/:id", models.EntityLesson},
+		{"/courses/:id", models.EntityCourse},
+		{"/subjects/:id", models.EntitySubject},
+	} {
+		authorized.GET(entity.path+"/translations", translators, translationsHandlers.FindForEntity(entity.entityType))
+		authorized.PUT(entity.path+"/translations/:locale", translators, translationsHandlers.Upsert(entity.entityType))
+		authorized.DELETE(entity.path+"/translations/:locale", translators, translationsHandlers.Delete(entity.entityType))
+	}
+	authorized.GET("/translations/missing", translators, translationsHandlers.Missing)
+
+	authorized.POST("/lessons/:id/complete", progressionHandlers.CompleteLesson)
+	authorized.GET("/courses/:id/unlocks", progressionHandlers.CourseUnlocks)
+	authorized.POST("/lessons/:id/progress", progressHandlers.Heartbeat)
+	authorized.GET("/lessons/:id/progress", progressHandlers.FindLessonProgress)
+	authorized.GET("/courses/:id/progress", progressHandlers.FindCourseProgress)
+
+	authorized.GET("/subjects/:id/questions", contentManagers, questionsHandlers.FindBySubject)
+	authorized.POST("/subjects/:id/questions", contentManagers, questionsHandlers.Create)
+	authorized.GET("/questions/:id", contentManagers, questionsHandlers.FindById)
+	authorized.PUT("/questions/:id", contentManagers, questionsHandlers.Update)
+	authorized.DELETE("/questions/:id", contentManagers, questionsHandlers.Delete)
+
+	authorized.GET("/lessons/:id/quizzes", quizzesHandlers.FindByLesson)
+	authorized.GET("/courses/:id/quizzes", quizzesHandlers.FindByCourse)
+	authorized.GET("/quizzes/:id", quizzesHandlers.FindById)
+	authorized.POST("/quizzes", contentManagers, quizzesHandlers.Create)
+	authorized.PUT("/quizzes/:id", contentManagers, quizzesHandlers.Update)
+	authorized.DELETE("/quizzes/:id", contentManagers, quizzesHandlers.Delete)
+	authorized.POST("/quizzes/:id/attempts", quizzesHandlers.StartAttempt)
+	authorized.GET("/quizzes/:id/attempts", quizzesHandlers.FindAttempts)
+	authorized.POST("/quizzes/:id/attempts/:attemptId/submit", quizzesHandlers.SubmitAttempt)
+
+	authorized.GET("/lessons/:id/assignments", assignmentsHandlers.FindByLesson)
+	authorized.GET("/courses/:id/assignments", assignmentsHandlers.FindByCourse)
+	authorized.GET("/assignments/:id", assignmentsHandlers.FindById)
+	authorized.POST("/assignments", contentManagers, assignmentsHandlers.Create)
+	authorized.PUT("/assignments/:id", contentManagers, assignmentsHandlers.Update)
+	authorized.DELETE("/assignments/:id", contentManagers, assignmentsHandlers.Delete)
+	authorized.POST("/assignments/:id/submissions", assignmentsHandlers.Submit)
+	authorized.GET("/assignments/:id/submissions", assignmentsHandlers.FindSubmissions)
+	authorized.PUT("/assignments/:id/submissions/:submissionId/grade", contentManagers, assignmentsHandlers.GradeSubmission)
+
+	authorized.GET("/courses/:id/gradebook", contentManagers, gradebookHandlers.FindGradebook)
+	authorized.GET("/courses/:id/gradebook/export", contentManagers, gradebookHandlers.ExportGradebook)
+	authorized.GET("/courses/:id/gradebook/settings", contentManagers, gradebookHandlers.FindSettings)
+	authorized.PUT("/courses/:id/gradebook/settings", contentManagers, gradebookHandlers.UpdateSettings)
+	authorized.GET("/courses/:id/gradebook/overrides", contentManagers, gradebookHandlers.FindOverrides)
+	authorized.PUT("/courses/:id/gradebook/overrides", contentManagers, gradebookHandlers.AddOverride)
+	authorized.GET("/me/grades", gradebookHandlers.MyGrades)
+
+	authorized.GET("/me/certificates", certificatesHandlers.FindMine)
+	unauthorized.GET("/certificates/verify/:serial", certificatesHandlers.Verify)
+
+	authorized.POST("/xapi/statements", xapiHandlers.PostStatements)
+	authorized.PUT("/xapi/statements", xapiHandlers.PutStatement)
+	authorized.GET("/xapi/statements", xapiHandlers.GetStatements)
+
+	authorized.GET("/me/notifications", notificationsHandlers.FindMine)
+	authorized.POST("/me/notifications/:id/read", notificationsHandlers.MarkRead)
+
+	authorized.GET("/media/:id", mediaHandlers.Serve)
+	authorized.POST("/media", contentManagers, mediaHandlers.Upload)
+
+	authorized.GET("/search", searchHandlers.Search)
+
+	authorized.GET("/tags", tagsHandlers.FindAll)
+	authorized.GET("/tags/:id", tagsHandlers.FindById)
+	authorized.POST("/tags", admins, tagsHandlers.Create)
+	authorized.PUT("/tags/:id", admins, tagsHandlers.Update)
+	authorized.DELETE("/tags/:id", admins, tagsHandlers.Delete)
+
+	authorized.GET("/trash", admins, trashHandlers.FindAll)
+	authorized.POST("/trash/:type/:id/restore", admins, trashHandlers.Restore)
 
 	authorized.GET("/subjects/:id", subjectsHandlers.FindById)
 	authorized.GET("/subjects", subjectsHandlers.FindAll)
@@ -62,9 +227,66 @@ func SetupRoutes(r *gin.Engine, conn *pgxpool.Pool) {
 
 	authorized.GET("/courses/:id", CoursesHandlers.FindById)
 	authorized.GET("/courses", CoursesHandlers.FindAll)
-	authorized.POST("/courses", CoursesHandlers.Create)
-	authorized.PUT("courses/:id", CoursesHandlers.Update)
-	authorized.DELETE("courses/:id", CoursesHandlers.Delete)
+	authorized.POST("/courses", contentManagers, CoursesHandlers.Create)
+	authorized.PUT("courses/:id", contentManagers, CoursesHandlers.Update)
+	authorized.DELETE("courses/:id", contentManagers, CoursesHandlers.Delete)
+	authorized.GET("/courses/:id/export", contentManagers, courseExchangeHandlers.Export)
+	authorized.POST("/courses/import", contentManagers, courseExchangeHandlers.Import)
+	authorized.POST("/courses/:id/clone", contentManagers, cloneHandlers.CloneCourse)
+	authorized.GET("/courses/:id/lineage", contentManagers, cloneHandlers.FindLineage)
+	authorized.POST("/courses/:id/lineage/pull", contentManagers, cloneHandlers.PullUpdates)
+	authorized.GET("/courses/:id/lessons", CoursesHandlers.FindLessons)
+	authorized.PUT("/courses/:id/lessons", contentManagers, CoursesHandlers.SetLessons)
+
+	authorized.POST("/courses/:id/enroll", enrollmentHandlers.Enroll)
+	authorized.POST("/courses/:id/drop", enrollmentHandlers.Drop)
+	authorized.GET("/me/courses", enrollmentHandlers.MyCourses)
+	authorized.GET("/courses/:id/enrollments", contentManagers, enrollmentHandlers.Roster)
+	authorized.POST("/courses/:id/enrollments", contentManagers, enrollmentHandlers.EnrollStudent)
+	authorized.PATCH("/courses/:id/enrollments/:uuid", contentManagers, enrollmentHandlers.UpdateStatus)
+
+	authorized.GET("/learning-paths", learningPathsHandlers.FindAll)
+	authorized.GET("/learning-paths/:id", learningPathsHandlers.FindById)
+	authorized.POST("/learning-paths", contentManagers, learningPathsHandlers.Create)
+	authorized.PUT("/learning-paths/:id", contentManagers, learningPathsHandlers.Update)
+	authorized.DELETE("/learning-paths/:id", contentManagers, learningPathsHandlers.Delete)
+	authorized.POST("/learning-paths/:id/enroll", learningPathsHandlers.Enroll)
+	authorized.GET("/learning-paths/:id/progress", learningPathsHandlers.FindProgress)
+	authorized.GET("/me/learning-paths", learningPathsHandlers.MyLearningPaths)
+
+	authorized.GET("/me/recommendations", recommendationsHandlers.MyRecommendations)
+	authorized.GET("/me/interests", recommendationsHandlers.FindProfile)
+	authorized.PUT("/me/interests", recommendationsHandlers.UpdateProfile)
+
+	authorized.GET("/flashcard-decks", flashcardsHandlers.FindDecks)
+	authorized.GET("/flashcard-decks/:id", flashcardsHandlers.FindDeck)
+	authorized.POST("/flashcard-decks", contentManagers, flashcardsHandlers.CreateDeck)
+	authorized.PUT("/flashcard-decks/:id", contentManagers, flashcardsHandlers.UpdateDeck)
+	authorized.DELETE("/flashcard-decks/:id", contentManagers, flashcardsHandlers.DeleteDeck)
+	authorized.POST("/flashcard-decks/:id/cards", contentManagers, flashcardsHandlers.CreateCard)
+	authorized.PUT("/flashcards/:id", contentManagers, flashcardsHandlers.UpdateCard)
+	authorized.DELETE("/flashcards/:id", contentManagers, flashcardsHandlers.DeleteCard)
+	authorized.POST("/flashcards/:id/review", flashcardsHandlers.Review)
+	authorized.GET("/me/reviews/due", flashcardsHandlers.FindDue)
+	authorized.GET("/me/reviews/stats", flashcardsHandlers.FindStats)
+
+	authorized.GET("/me/achievements", gamificationHandlers.MyAchievements)
+	authorized.PUT("/me/time-zone", gamificationHandlers.SetTimeZone)
+	authorized.GET("/badges", gamificationHandlers.FindBadges)
+	authorized.POST("/badges", admins, gamificationHandlers.CreateBadge)
+	authorized.PUT("/badges/:id", admins, gamificationHandlers.UpdateBadge)
+
+	authorized.GET("/classes", classesHandlers.FindAll)
+	authorized.GET("/classes/:id", classesHandlers.FindById)
+	authorized.POST("/classes", contentManagers, classesHandlers.Create)
+	authorized.PUT("/classes/:id", contentManagers, classesHandlers.Update)
+	authorized.DELETE("/classes/:id", contentManagers, classesHandlers.Delete)
+
+	authorized.GET("/leaderboard", leaderboardsHandlers.FindOrganization)
+	authorized.GET("/courses/:id/leaderboard", leaderboardsHandlers.FindCourse)
+	authorized.GET("/classes/:id/leaderboard", leaderboardsHandlers.FindClass)
+	authorized.GET("/me/leaderboard-privacy", leaderboardsHandlers.FindPrivacy)
+	authorized.PUT("/me/leaderboard-privacy", leaderboardsHandlers.UpdatePrivacy)
 
 	// Swagger
 	docs.SwaggerInfo.BasePath = "/"
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type SearchHandler struct {
+	searchRepo *repositories.SearchRepository
+	tagsRepo   *repositories.TagsRepository
+}
+
+func NewSearchHandler(searchRepo *repositories.SearchRepository, tagsRepo *repositories.TagsRepository) *SearchHandler {
+	return &SearchHandler{
+		searchRepo: searchRepo,
+		tagsRepo:   tagsRepo,
+	}
+}
+
+// queryInt читает необязательный целочисленный query-параметр
+func queryInt(c *gin.Context, name string, fallback int) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, true
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Invalid %s", name)))
+		return 0, false
+	}
+	return number, true
+}
+
+// Search godoc
+// @Summary 	full-text search across lessons, courses and subjects
+// @Description Every word is matched by prefix, so partial input works for typeahead.
+// @Description Russian and English are stemmed, other languages are matched as is.
+// @Description Translations are indexed together with the original text.
+// @Description Snippets are HTML-escaped with matches wrapped in <mark>. Facets are counted over all matches.
+// @Tags 		search
+// @Produce 	json
+// @Param 		q 			query 	string 	true 	"Search text"
+// @Param 		type 		query 	string 	false 	"Comma-separated types: lesson, course, subject"
+// @Param 		subject_id 	query 	int 	false 	"Subject id"
+// @Param 		level 		query 	string 	false 	"Beginner, Intermediate or Advanced"
+// @Param 		tags 		query 	string 	false 	"Comma-separated tag slugs"
+// @Param 		limit 		query 	int 	false 	"Page size, 20 by default, at most 100"
+// @Param 		offset 		query 	int 	false 	"Offset"
+// @Success 	200 	{object} 	models.SearchResponse "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	filter := models.SearchFilter{
+		Query:        utils.BuildPrefixQuery(c.Query("q")),
+		Types:        utils.ParseList(c.Query("type")),
+		Visible_only: !utils.CanManageContent(c),
+	}
+	if filter.Query == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Search query is required"))
+		return
+	}
+
+	for _, entityType := range filter.Types {
+		if entityType != models.EntityLesson && entityType != models.EntityCourse && entityType != models.EntitySubject {
+			c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Unknown type %q", entityType)))
+			return
+		}
+	}
+
+	if value := c.Query("subject_id"); value != "" {
+		subjectId, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid subject_id"))
+			return
+		}
+		filter.Subject_id = &subjectId
+	}
+
+	if level := c.Query("level"); level != "" {
+		normalized, ok := utils.NormalizeLevel(level)
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.NewApiError(invalidLevelMessage()))
+			return
+		}
+		filter.Level = normalized
+	}
+
+	tagGroups, ok := parseTagFilter(c, h.tagsRepo)
+	if !ok {
+		return
+	}
+	filter.Tag_groups = tagGroups
+
+	if filter.Limit, ok = queryInt(c, "limit", defaultSearchLimit); !ok {
+		return
+	}
+	if filter.Limit == 0 || filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+	if filter.Offset, ok = queryInt(c, "offset", 0); !ok {
+		return
+	}
+
+	response, err := h.searchRepo.Search(c, filter)
+	if err != nil {
+		logger.Error("Search failed", zap.String("query", filter.Query), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -12,11 +12,12 @@ import (
 )
 
 type SubjectsHandlers struct {
-	repo *repositories.SubjectsRepository
+	repo             *repositories.SubjectsRepository
+	translationsRepo *repositories.TranslationsRepository
 }
 
-func NewSubjectsHandlers(repo *repositories.SubjectsRepository) *SubjectsHandlers {
-	return &SubjectsHandlers{repo: repo}
+func NewSubjectsHandlers(repo *repositories.SubjectsRepository, translationsRepo *repositories.TranslationsRepository) *SubjectsHandlers {
+	return &SubjectsHandlers{repo: repo, translationsRepo: translationsRepo}
 }
 
 // FindById godoc
@@ -25,6 +26,7 @@ func NewSubjectsHandlers(repo *repositories.SubjectsRepository) *SubjectsHandler
 // @Accept		json
 // @Produce 	json
 // @Param 		id 		path 		int 	true 	"Subject_id"
+// @Param 		Accept-Language header 	string 	false 	"Preferred languages: ru, kk, en"
 // @Success 	200 	{object} 	models.Subject 	"OK"
 // @Failure 	400 	{object} 	models.ApiError "Invalid Payload"
 // @Failure 	500 	{object} 	models.ApiError
@@ -46,7 +48,14 @@ func (g *SubjectsHandlers) FindById(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, subject)
+	subjects := []models.Subject{subject}
+	if err := localizeSubjects(c, g.translationsRepo, subjects); err != nil {
+		logger.Error("Failed to localize subject", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, subjects[0])
 
 }
 
@@ -55,6 +64,7 @@ func (g *SubjectsHandlers) FindById(c *gin.Context) {
 // @Tags 		subjects
 // @Accept 		json
 // @Produce 	json
+// @Param 		Accept-Language header 	string 	false 	"Preferred languages: ru, kk, en"
 // @Success 	200 {object} []models.Subject "OK"
 // @Failure 	500 {object} models.ApiError
 // @Router 		/subjects [get]
@@ -68,6 +78,12 @@ func (g *SubjectsHandlers) FindAll(c *gin.Context) {
 		return
 	}
 
+	if err := localizeSubjects(c, g.translationsRepo, subjects); err != nil {
+		logger.Error("Failed to localize subjects", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
 	c.JSON(http.StatusOK, subjects)
 }
 
@@ -164,6 +180,7 @@ func (g *SubjectsHandlers) Update(c *gin.Context) {
 
 // Delete godoc
 // @Summary 	delete subject by id
+// @Description Moves to the trash, admins can restore it until the retention period ends
 // @Tags 		subjects
 // @Accept 		json
 // @Produce 	json
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type TagsHandler struct {
+	tagsRepo    *repositories.TagsRepository
+	lessonsRepo *repositories.Lessonsrepository
+	coursesRepo *repositories.Coursesrepository
+}
+
+func NewTagsHandler(
+	tagsRepo *repositories.TagsRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository) *TagsHandler {
+	return &TagsHandler{
+		tagsRepo:    tagsRepo,
+		lessonsRepo: lessonsRepo,
+		coursesRepo: coursesRepo,
+	}
+}
+
+type tagRequest struct {
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	Parent_id *int   `json:"parent_id"`
+}
+
+type entityTagsRequest struct {
+	Tag_ids []int `json:"tag_ids"`
+}
+
+// parseTagFilter превращает ?tags=slug1,slug2 в группы id: каждый тег вместе с его потомками.
+// Неизвестный slug — ошибка 400, чтобы опечатка не возвращала пустой список молча.
+func parseTagFilter(c *gin.Context, tagsRepo *repositories.TagsRepository) ([][]int, bool) {
+	logger := logger.GetLogger()
+
+	slugs := utils.ParseList(c.Query("tags"))
+	if len(slugs) == 0 {
+		return nil, true
+	}
+
+	expanded, err := tagsRepo.ExpandSlugs(c, slugs)
+	if err != nil {
+		logger.Error("Failed to expand tag filter", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return nil, false
+	}
+
+	groups := make([][]int, 0, len(slugs))
+	for _, slug := range slugs {
+		ids, ok := expanded[slug]
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Unknown tag %q", slug)))
+			return nil, false
+		}
+		groups = append(groups, ids)
+	}
+
+	return groups, true
+}
+
+func (h *TagsHandler) bindTag(c *gin.Context) (models.Tag, bool) {
+	logger := logger.GetLogger()
+
+	var request tagRequest
+	if err := c.BindJSON(&request); err != nil {
+		logger.Error("Failed JSON binding", zap.Error(err))
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return models.Tag{}, false
+	}
+
+	tag := models.Tag{
+		Name:      strings.TrimSpace(request.Name),
+		Slug:      utils.Slugify(request.Slug),
+		Parent_id: request.Parent_id,
+	}
+	if tag.Slug == "" {
+		tag.Slug = utils.Slugify(tag.Name)
+	}
+	if tag.Name == "" || tag.Slug == "" {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Tag name is required"))
+		return models.Tag{}, false
+	}
+
+	if tag.Parent_id != nil {
+		_, err := h.tagsRepo.FindById(c, *tag.Parent_id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Parent tag not found"))
+			return models.Tag{}, false
+		}
+		if err != nil {
+			logger.Error("Failed to find parent tag", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return models.Tag{}, false
+		}
+	}
+
+	return tag, true
+}
+
+func (h *TagsHandler) findTag(c *gin.Context) (models.Tag, bool) {
+	logger := logger.GetLogger()
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid tag id"))
+		return models.Tag{}, false
+	}
+
+	tag, err := h.tagsRepo.FindById(c, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Tag not found"))
+		return models.Tag{}, false
+	}
+	if err != nil {
+		logger.Error("Failed to find tag", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return models.Tag{}, false
+	}
+
+	return tag, true
+}
+
+// FindAll godoc
+// @Summary 	list all tags
+// @Description Flat list, the hierarchy is given by parent_id
+// @Tags 		tags
+// @Produce 	json
+// @Success 	200 	{object} 	[]models.Tag "OK"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/tags [get]
+func (h *TagsHandler) FindAll(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	tags, err := h.tagsRepo.FindAll(c)
+	if err != nil {
+		logger.Error("Failed to fetch tags", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// FindById godoc
+// @Summary 	find tag by id
+// @Tags 		tags
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Tag id"
+// @Success 	200 	{object} 	models.Tag "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/tags/{id} [get]
+func (h *TagsHandler) FindById(c *gin.Context) {
+	tag, ok := h.findTag(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// Create godoc
+// @Summary 	create tag
+// @Description Slug is generated from the name when omitted
+// @Tags 		tags
+// @Accept 		json
+// @Produce 	json
+// @Param 		request body 		tagRequest 	true 	"Tag"
+// @Success 	200 	{object} 	object{id=int} "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	409 	{object}	models.ApiError "Slug already exists"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/tags [post]
+func (h *TagsHandler) Create(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	tag, ok := h.bindTag(c)
+	if !ok {
+		return
+	}
+
+	id, err := h.tagsRepo.Create(c, tag)
+	if errors.Is(err, repositories.ErrDuplicateSlug) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create tag", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id": id,
+	})
+}
+
+// Update godoc
+// @Summary 	update tag
+// @Description Moving a tag under one of its own descendants is rejected
+// @Tags 		tags
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 		true 	"Tag id"
+// @Param 		request body 		tagRequest 	true 	"Tag"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	409 	{object}	models.ApiError "Slug already exists"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/tags/{id} [put]
+func (h *TagsHandler) Update(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	current, ok := h.findTag(c)
+	if !ok {
+		return
+	}
+
+	tag, ok := h.bindTag(c)
+	if !ok {
+		return
+	}
+
+	err := h.tagsRepo.Update(c, current.Id, tag, utils.ValidateAcyclic)
+	if errors.Is(err, repositories.ErrDuplicateSlug) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return
+	}
+	if errors.Is(err, utils.ErrDependencyCycle) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Tag cannot be moved under its own descendant"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update tag", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Delete godoc
+// @Summary 	delete tag
+// @Description Child tags are moved to the parent of the deleted tag
+// @Tags 		tags
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Tag id"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/tags/{id} [delete]
+func (h *TagsHandler) Delete(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	tag, ok := h.findTag(c)
+	if !ok {
+		return
+	}
+
+	if err := h.tagsRepo.Delete(c, tag.Id); err != nil {
+		logger.Error("Failed to delete tag", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *TagsHandler) findEntityId(c *gin.Context, entityType string) (int, bool) {
+	switch entityType {
+	case models.EntityLesson:
+		lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+		return lesson.Id, ok
+	case models.EntityCourse:
+		course, ok := findVisibleCourse(c, h.coursesRepo)
+		return course.Id, ok
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewApiError(fmt.Sprintf("unknown entity type %q", entityType)))
+		return 0, false
+	}
+}
+
+// FindForEntity godoc
+// @Summary 	tags of lesson or course
+// @Tags 		tags
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson or course id"
+// @Success 	200 	{object} 	[]models.Tag "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/tags [get]
+// @Router 		/courses/{id}/tags [get]
+func (h *TagsHandler) FindForEntity(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, ok := h.findEntityId(c, entityType)
+		if !ok {
+			return
+		}
+
+		tags, err := h.tagsRepo.FindByEntity(c, entityType, id)
+		if err != nil {
+			logger.Error("Failed to fetch tags", zap.String("entity_type", entityType), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		c.JSON(http.StatusOK, tags)
+	}
+}
+
+// ReplaceForEntity godoc
+// @Summary 	replace tags of lesson or course
+// @Tags 		tags
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Lesson or course id"
+// @Param 		request body 		entityTagsRequest 	true 	"Tag ids"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError "Unknown tag"
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/tags [put]
+// @Router 		/courses/{id}/tags [put]
+func (h *TagsHandler) ReplaceForEntity(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, ok := h.findEntityId(c, entityType)
+		if !ok {
+			return
+		}
+
+		var request entityTagsRequest
+		if err := c.BindJSON(&request); err != nil {
+			logger.Error("Failed JSON binding", zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+			return
+		}
+
+		seen := make(map[int]bool, len(request.Tag_ids))
+		tagIds := make([]int, 0, len(request.Tag_ids))
+		for _, tagId := range request.Tag_ids {
+			if !seen[tagId] {
+				seen[tagId] = true
+				tagIds = append(tagIds, tagId)
+			}
+		}
+
+		err := h.tagsRepo.ReplaceForEntity(c, entityType, id, tagIds)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to save tags", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type TranslationsHandler struct {
+	translationsRepo *repositories.TranslationsRepository
+	lessonsRepo      *repositories.Lessonsrepository
+	coursesRepo      *repositories.Coursesrepository
+	subjectsRepo     *repositories.SubjectsRepository
+	mediaRepo        *repositories.MediaRepository
+}
+
+func NewTranslationsHandler(
+	translationsRepo *repositories.TranslationsRepository,
+	lessonsRepo *repositories.Lessonsrepository,
+	coursesRepo *repositories.Coursesrepository,
+	subjectsRepo *repositories.SubjectsRepository,
+	mediaRepo *repositories.MediaRepository) *TranslationsHandler {
+	return &TranslationsHandler{
+		translationsRepo: translationsRepo,
+		lessonsRepo:      lessonsRepo,
+		coursesRepo:      coursesRepo,
+		subjectsRepo:     subjectsRepo,
+		mediaRepo:        mediaRepo,
+	}
+}
+
+type translationRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Body        string `json:"body"`
+}
+
+func (h *TranslationsHandler) findEntityId(c *gin.Context, entityType string) (int, bool) {
+	logger := logger.GetLogger()
+
+	switch entityType {
+	case models.EntityLesson:
+		lesson, ok := findVisibleLesson(c, h.lessonsRepo)
+		return lesson.Id, ok
+	case models.EntityCourse:
+		course, ok := findVisibleCourse(c, h.coursesRepo)
+		return course.Id, ok
+	case models.EntitySubject:
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid subject id"))
+			return 0, false
+		}
+		_, err = h.subjectsRepo.FindById(c, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, models.NewApiError("Subject not found"))
+			return 0, false
+		}
+		if err != nil {
+			logger.Error("Failed to find subject", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return 0, false
+		}
+		return id, true
+	default:
+		c.JSON(http.StatusInternalServerError, models.NewApiError(fmt.Sprintf("unknown entity type %q", entityType)))
+		return 0, false
+	}
+}
+
+// translationLocale проверяет :locale — перевод возможен на любой поддерживаемый язык, кроме исходного
+func translationLocale(c *gin.Context) (string, bool) {
+	locale := strings.ToLower(c.Param("locale"))
+	if !utils.IsSupportedLocale(locale) || locale == models.DefaultLocale {
+		c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Unsupported translation locale %q", locale)))
+		return "", false
+	}
+	return locale, true
+}
+
+// FindForEntity godoc
+// @Summary 	translations of lesson, course or subject
+// @Tags 		translations
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson, course or subject id"
+// @Success 	200 	{object} 	[]models.Translation "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/translations [get]
+// @Router 		/courses/{id}/translations [get]
+// @Router 		/subjects/{id}/translations [get]
+func (h *TranslationsHandler) FindForEntity(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, ok := h.findEntityId(c, entityType)
+		if !ok {
+			return
+		}
+
+		translations, err := h.translationsRepo.FindByEntity(c, entityType, id)
+		if err != nil {
+			logger.Error("Failed to fetch translations", zap.String("entity_type", entityType), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		c.JSON(http.StatusOK, translations)
+	}
+}
+
+// Upsert godoc
+// @Summary 	create or replace translation
+// @Description Lessons use title, description and Markdown body, courses title and description, subjects only title
+// @Tags 		translations
+// @Accept 		json
+// @Produce 	json
+// @Param 		id 		path		int 				true 	"Lesson, course or subject id"
+// @Param 		locale 	path		string 				true 	"kk or en"
+// @Param 		request body 		translationRequest 	true 	"Translated texts"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/translations/{locale} [put]
+// @Router 		/courses/{id}/translations/{locale} [put]
+// @Router 		/subjects/{id}/translations/{locale} [put]
+func (h *TranslationsHandler) Upsert(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, ok := h.findEntityId(c, entityType)
+		if !ok {
+			return
+		}
+		locale, ok := translationLocale(c)
+		if !ok {
+			return
+		}
+
+		var request translationRequest
+		if err := c.BindJSON(&request); err != nil {
+			logger.Error("Failed JSON binding", zap.Error(err))
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+			return
+		}
+		if strings.TrimSpace(request.Title) == "" {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Title is required"))
+			return
+		}
+
+		translation := models.Translation{
+			Entity_type: entityType,
+			Entity_id:   id,
+			Locale:      locale,
+			Title:       request.Title,
+		}
+		switch entityType {
+		case models.EntityLesson:
+			translation.Description = request.Description
+			translation.Body = request.Body
+			bodyHtml, ok := renderLessonBody(c, h.mediaRepo, request.Body)
+			if !ok {
+				return
+			}
+			translation.Body_html = bodyHtml
+		case models.EntityCourse:
+			translation.Description = request.Description
+		}
+
+		if err := h.translationsRepo.Upsert(c, translation); err != nil {
+			logger.Error("Failed to save translation", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
+
+// Delete godoc
+// @Summary 	delete translation
+// @Tags 		translations
+// @Produce 	json
+// @Param 		id 		path		int 	true 	"Lesson, course or subject id"
+// @Param 		locale 	path		string 	true 	"kk or en"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/lessons/{id}/translations/{locale} [delete]
+// @Router 		/courses/{id}/translations/{locale} [delete]
+// @Router 		/subjects/{id}/translations/{locale} [delete]
+func (h *TranslationsHandler) Delete(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logger.GetLogger()
+
+		id, ok := h.findEntityId(c, entityType)
+		if !ok {
+			return
+		}
+		locale, ok := translationLocale(c)
+		if !ok {
+			return
+		}
+
+		deleted, err := h.translationsRepo.Delete(c, entityType, id, locale)
+		if err != nil {
+			logger.Error("Failed to delete translation", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+		if !deleted {
+			c.JSON(http.StatusNotFound, models.NewApiError("Translation not found"))
+			return
+		}
+
+		c.Status(http.StatusOK)
+	}
+}
+
+// Missing godoc
+// @Summary 	report of missing and outdated translations
+// @Description A translation is outdated when the source text changed after it was saved
+// @Tags 		translations
+// @Produce 	json
+// @Param 		locale 	query 	string 	false 	"Comma-separated locales, all translation locales by default"
+// @Param 		type 	query 	string 	false 	"Comma-separated types: lesson, course, subject"
+// @Success 	200 	{object} 	[]models.MissingTranslation "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/translations/missing [get]
+func (h *TranslationsHandler) Missing(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	locales := utils.ParseList(strings.ToLower(c.Query("locale")))
+	if len(locales) == 0 {
+		for _, locale := range models.SupportedLocales {
+			if locale != models.DefaultLocale {
+				locales = append(locales, locale)
+			}
+		}
+	}
+	for _, locale := range locales {
+		if !utils.IsSupportedLocale(locale) || locale == models.DefaultLocale {
+			c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Unsupported translation locale %q", locale)))
+			return
+		}
+	}
+
+	entityTypes := utils.ParseList(c.Query("type"))
+	if len(entityTypes) == 0 {
+		entityTypes = []string{models.EntityLesson, models.EntityCourse, models.EntitySubject}
+	}
+	for _, entityType := range entityTypes {
+		if entityType != models.EntityLesson && entityType != models.EntityCourse && entityType != models.EntitySubject {
+			c.JSON(http.StatusBadRequest, models.NewApiError(fmt.Sprintf("Unknown type %q", entityType)))
+			return
+		}
+	}
+
+	report, err := h.translationsRepo.FindMissing(c, entityTypes, locales)
+	if err != nil {
+		logger.Error("Failed to build translations report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
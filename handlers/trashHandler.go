@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"go-EdTech/config"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type TrashHandler struct {
+	trashRepo *repositories.TrashRepository
+}
+
+func NewTrashHandler(trashRepo *repositories.TrashRepository) *TrashHandler {
+	return &TrashHandler{trashRepo: trashRepo}
+}
+
+// FindAll godoc
+// @Summary 	list deleted lessons, courses, subjects and users
+// @Description Items are purged permanently at purge_at
+// @Description Lessons, courses and users with learner history (completions, enrollments, certificates, submissions) stay in the trash
+// @Tags 		trash
+// @Produce 	json
+// @Param 		type 	query 		string 	false 	"Comma-separated types: lesson, course, subject, user"
+// @Success 	200 	{object} 	[]models.TrashItem "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/trash [get]
+func (h *TrashHandler) FindAll(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	types := utils.ParseList(c.Query("type"))
+	if len(types) == 0 {
+		types = models.TrashItems
+	}
+	for _, entityType := range types {
+		if !slices.Contains(models.TrashItems, entityType) {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Type must be one of "+strings.Join(models.TrashItems, ", ")))
+			return
+		}
+	}
+
+	items, err := h.trashRepo.FindAll(c, types)
+	if err != nil {
+		logger.Error("Failed to fetch trash", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	for i := range items {
+		items[i].Purge_at = items[i].Deleted_at.Add(config.Config.TrashRetention)
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// Restore godoc
+// @Summary 	restore deleted item from the trash
+// @Tags 		trash
+// @Produce 	json
+// @Param 		type 	path		string 	true 	"lesson, course, subject or user"
+// @Param 		id 		path		string 	true 	"Id, uuid for users"
+// @Success 	200 	"OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError "Item is not in the trash"
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/trash/{type}/{id}/restore [post]
+func (h *TrashHandler) Restore(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	entityType := c.Param("type")
+	if !slices.Contains(models.TrashItems, entityType) {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Type must be one of "+strings.Join(models.TrashItems, ", ")))
+		return
+	}
+
+	var id any
+	var err error
+	if entityType == models.EntityUser {
+		id, err = uuid.Parse(c.Param("id"))
+	} else {
+		id, err = strconv.Atoi(c.Param("id"))
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid id"))
+		return
+	}
+
+	err = h.trashRepo.Restore(c, entityType, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, models.NewApiError("Item is not in the trash"))
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to restore from trash", zap.String("type", entityType), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	logger.Info("Restored from trash", zap.String("type", entityType), zap.Any("id", id))
+
+	c.Status(http.StatusOK)
+}
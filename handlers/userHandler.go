@@ -216,6 +216,7 @@ func (h *UsersHandlers) ChangePassword(c *gin.Context) {
 
 // Delete godoc
 // @Summary 	Delete User
+// @Description Moves to the trash, admins can restore it until the retention period ends
 // @Tags 		users
 // @Accept		json
 // @Produce 	json
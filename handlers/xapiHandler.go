@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	xapiDefaultLimit = 100
+	xapiMaxLimit     = 500
+	// xapiMaxBody — предел тела запроса с утверждениями
+	xapiMaxBody = 1 << 20
+)
+
+// XAPIHandler — минимальный ресурс statements Learning Record Store для внешнего контента
+type XAPIHandler struct {
+	xapiRepo *repositories.XAPIRepository
+}
+
+func NewXAPIHandler(xapiRepo *repositories.XAPIRepository) *XAPIHandler {
+	return &XAPIHandler{xapiRepo: xapiRepo}
+}
+
+// recordXAPI сохраняет утверждения о действиях пользователя. Ошибка только логируется —
+// аналитика не должна мешать ученику.
+func recordXAPI(c *gin.Context, xapiRepo *repositories.XAPIRepository, statements ...models.XAPIStatement) {
+	logger := logger.GetLogger()
+
+	now := time.Now()
+	records := make([]models.XAPIRecord, 0, len(statements))
+	for _, statement := range statements {
+		raw, err := json.Marshal(statement)
+		if err != nil {
+			logger.Error("Failed to encode xAPI statement", zap.Error(err))
+			return
+		}
+		record, err := utils.PrepareXAPIStatement(raw, "", utils.XAPIPlatformAgent(), nil, now)
+		if err != nil {
+			logger.Error("Invalid xAPI statement", zap.String("verb", statement.Verb.Id), zap.Error(err))
+			return
+		}
+		records = append(records, record)
+	}
+
+	if err := xapiRepo.Store(c, records, false); err != nil {
+		logger.Error("Failed to store xAPI statements", zap.Error(err))
+	}
+}
+
+// xapiParentContext указывает родительскую активность утверждения, например квиз для ответа на вопрос
+func xapiParentContext(parentId string) json.RawMessage {
+	data, _ := json.Marshal(map[string]any{
+		"contextActivities": map[string]any{
+			"parent": []models.XAPIObject{{Object_type: "Activity", Id: parentId}},
+		},
+	})
+	return data
+}
+
+// checkXAPIVersion требует заголовок X-Experience-API-Version 1.0.x и выставляет версию в ответе
+func checkXAPIVersion(c *gin.Context) bool {
+	c.Header("X-Experience-API-Version", models.XAPIVersion)
+	if !strings.HasPrefix(c.GetHeader("X-Experience-API-Version"), "1.0") {
+		c.JSON(http.StatusBadRequest, models.NewApiError("X-Experience-API-Version header 1.0.x is required"))
+		return false
+	}
+	return true
+}
+
+// prepareStatements читает одно утверждение или массив и проверяет их.
+// Ученики записывают утверждения только о себе: actor должен совпадать с текущим пользователем.
+func prepareStatements(c *gin.Context, statementId string) ([]models.XAPIRecord, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, xapiMaxBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, models.NewApiError("Statements must not exceed "+strconv.Itoa(xapiMaxBody>>10)+" KB"))
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+		return nil, false
+	}
+
+	raws := []json.RawMessage{}
+	body = bytes.TrimSpace(body)
+	if statementId == "" && bytes.HasPrefix(body, []byte("[")) {
+		if err := json.Unmarshal(body, &raws); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("Invalid payload"))
+			return nil, false
+		}
+	} else {
+		raws = append(raws, body)
+	}
+	if len(raws) == 0 {
+		c.JSON(http.StatusBadRequest, models.NewApiError("No statements"))
+		return nil, false
+	}
+
+	var userUUID *uuid.UUID
+	authority := utils.XAPIPlatformAgent()
+	if current, ok := utils.CurrentUserUUID(c); ok {
+		userUUID = &current
+		authority = utils.XAPIUserAgent(current)
+	}
+
+	// пустой ключ не совпадет ни с одним actor, поэтому без пользователя ученик ничего не запишет
+	ownActorKey := ""
+	manager := utils.CanManageContent(c)
+	if userUUID != nil && !manager {
+		ownActorKey, _ = utils.XAPIActorKey(authority)
+	}
+
+	now := time.Now()
+	records := make([]models.XAPIRecord, 0, len(raws))
+	for i, raw := range raws {
+		record, err := utils.PrepareXAPIStatement(raw, statementId, authority, userUUID, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("statement "+strconv.Itoa(i+1)+": "+err.Error()))
+			return nil, false
+		}
+		if !manager && record.Actor_key != ownActorKey {
+			c.JSON(http.StatusForbidden, models.NewApiError("statement "+strconv.Itoa(i+1)+": actor must be the current user"))
+			return nil, false
+		}
+		records = append(records, record)
+	}
+	return records, true
+}
+
+// storeStatements сохраняет утверждения и отвечает 409 при конфликте id.
+// Аннулировать чужие утверждения могут только те, кто видит все утверждения.
+func (h *XAPIHandler) storeStatements(c *gin.Context, records []models.XAPIRecord) bool {
+	logger := logger.GetLogger()
+
+	err := h.xapiRepo.Store(c, records, utils.CanManageContent(c))
+	if errors.Is(err, repositories.ErrStatementConflict) {
+		c.JSON(http.StatusConflict, models.NewApiError(err.Error()))
+		return false
+	}
+	if errors.Is(err, repositories.ErrVoidTargetMissing) {
+		c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+		return false
+	}
+	if err != nil {
+		logger.Error("Failed to store xAPI statements", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return false
+	}
+	return true
+}
+
+// PostStatements godoc
+// @Summary 	store xAPI statements
+// @Description Accepts one statement or an array, up to 1 MB. Statements without id get one, authority is the current user.
+// @Description Students can only record statements about themselves and void only statements about themselves or stored by them.
+// @Tags 		xapi
+// @Accept 		json
+// @Produce 	json
+// @Param 		X-Experience-API-Version 	header 	string 	true 	"1.0.3"
+// @Param 		request body 		models.XAPIStatement 	true 	"Statement or array of statements"
+// @Success 	200 	{object} 	[]string "Statement ids"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError "Actor is another learner"
+// @Failure 	409 	{object}	models.ApiError
+// @Failure 	413 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/xapi/statements [post]
+func (h *XAPIHandler) PostStatements(c *gin.Context) {
+	if !checkXAPIVersion(c) {
+		return
+	}
+
+	records, ok := prepareStatements(c, "")
+	if !ok {
+		return
+	}
+	if !h.storeStatements(c, records) {
+		return
+	}
+
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.Id.String())
+	}
+	c.JSON(http.StatusOK, ids)
+}
+
+// PutStatement godoc
+// @Summary 	store xAPI statement with the given id
+// @Tags 		xapi
+// @Accept 		json
+// @Param 		X-Experience-API-Version 	header 	string 	true 	"1.0.3"
+// @Param 		statementId 	query 	string 	true 	"Statement id"
+// @Param 		request body 		models.XAPIStatement 	true 	"Statement"
+// @Success 	204 	"No Content"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	403 	{object}	models.ApiError "Actor is another learner"
+// @Failure 	409 	{object}	models.ApiError
+// @Failure 	413 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/xapi/statements [put]
+func (h *XAPIHandler) PutStatement(c *gin.Context) {
+	if !checkXAPIVersion(c) {
+		return
+	}
+
+	statementId := c.Query("statementId")
+	if _, err := uuid.Parse(statementId); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiError("statementId must be a UUID"))
+		return
+	}
+
+	records, ok := prepareStatements(c, statementId)
+	if !ok {
+		return
+	}
+	if !h.storeStatements(c, records) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetStatements godoc
+// @Summary 	query xAPI statements
+// @Description statementId or voidedStatementId return a single statement, otherwise a StatementResult filtered
+// @Description by agent (JSON agent), verb, activity, since and until. Students only see statements about themselves
+// @Description or stored by them, teachers see everything.
+// @Tags 		xapi
+// @Produce 	json
+// @Param 		X-Experience-API-Version 	header 	string 	true 	"1.0.3"
+// @Param 		statementId 		query 	string 	false 	"Statement id"
+// @Param 		voidedStatementId 	query 	string 	false 	"Voided statement id"
+// @Param 		agent 		query 	string 	false 	"Agent JSON"
+// @Param 		verb 		query 	string 	false 	"Verb IRI"
+// @Param 		activity 	query 	string 	false 	"Activity IRI"
+// @Param 		since 		query 	string 	false 	"Stored after, RFC 3339"
+// @Param 		until 		query 	string 	false 	"Stored at or before, RFC 3339"
+// @Param 		limit 		query 	int 	false 	"Default 100, at most 500"
+// @Param 		ascending 	query 	bool 	false 	"Oldest first"
+// @Success 	200 	{object} 	models.XAPIStatementResult "OK"
+// @Failure 	400 	{object}	models.ApiError
+// @Failure 	404 	{object}	models.ApiError
+// @Failure 	500 	{object}	models.ApiError
+// @Router 		/xapi/statements [get]
+func (h *XAPIHandler) GetStatements(c *gin.Context) {
+	logger := logger.GetLogger()
+
+	if !checkXAPIVersion(c) {
+		return
+	}
+
+	userUUID, ok := utils.CurrentUserUUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.NewApiError("user is not authenticated"))
+		return
+	}
+	ownKey, _ := utils.XAPIActorKey(utils.XAPIUserAgent(userUUID))
+	manager := utils.CanManageContent(c)
+
+	for _, lookup := range []struct {
+		param  string
+		voided bool
+	}{{"statementId", false}, {"voidedStatementId", true}} {
+		param := lookup.param
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError(param+" must be a UUID"))
+			return
+		}
+
+		record, err := h.xapiRepo.FindById(c, id, lookup.voided)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && !manager && record.Actor_key != ownKey &&
+			(record.User_uuid == nil || *record.User_uuid != userUUID)) {
+			c.JSON(http.StatusNotFound, models.NewApiError("Statement not found"))
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to fetch xAPI statement", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+			return
+		}
+
+		c.Data(http.StatusOK, "application/json", record.Statement)
+		return
+	}
+
+	filter := models.XAPIFilter{
+		Verb_id:     c.Query("verb"),
+		Activity_id: c.Query("activity"),
+		Limit:       xapiDefaultLimit,
+	}
+	if raw := c.Query("agent"); raw != "" {
+		var agent models.XAPIAgent
+		if err := json.Unmarshal([]byte(raw), &agent); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError("agent must be an Agent JSON object"))
+			return
+		}
+		key, err := utils.XAPIActorKey(agent)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError(err.Error()))
+			return
+		}
+		filter.Actor_key = key
+	}
+	for param, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewApiError(param+" must be an RFC 3339 timestamp"))
+			return
+		}
+		*target = &parsed
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, models.NewApiError("limit must be a non-negative number"))
+			return
+		}
+		// 0 по спецификации — максимум, который разрешает сервер
+		if limit > 0 && limit < xapiMaxLimit {
+			filter.Limit = limit
+		} else {
+			filter.Limit = xapiMaxLimit
+		}
+	}
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, models.NewApiError("offset must be a non-negative number"))
+			return
+		}
+		filter.Offset = offset
+	}
+	filter.Ascending, _ = strconv.ParseBool(c.Query("ascending"))
+	if !manager {
+		filter.Owner_uuid = &userUUID
+		filter.Owner_key = ownKey
+	}
+
+	statements, err := h.xapiRepo.Find(c, filter)
+	if err != nil {
+		logger.Error("Failed to fetch xAPI statements", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.NewApiError(err.Error()))
+		return
+	}
+
+	result := models.XAPIStatementResult{Statements: statements}
+	if len(statements) == filter.Limit {
+		query := c.Request.URL.Query()
+		query.Set("offset", strconv.Itoa(filter.Offset+filter.Limit))
+		query.Set("limit", strconv.Itoa(filter.Limit))
+		result.More = c.Request.URL.Path + "?" + query.Encode()
+	}
+
+	c.Header("X-Experience-API-Consistent-Through", time.Now().UTC().Format(time.RFC3339Nano))
+	c.JSON(http.StatusOK, result)
+}
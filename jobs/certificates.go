@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/repositories"
+	"go-EdTech/utils"
+
+	"go.uber.org/zap"
+)
+
+// RenderCertificates генерирует PDF выданных сертификатов и сохраняет их в media.
+// Макет читается на каждом проходе, поэтому изменения применяются без перезапуска.
+// Сертификаты, текст которых шрифт макета не отображает, откладываются до смены шрифта; отметка хранится
+// в строке сертификата, поэтому переживает перезапуск и общая для всех реплик.
+func RenderCertificates(certificatesRepo *repositories.CertificatesRepository, templatePath string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		logger := logger.GetLogger()
+
+		certificateTemplate, fontData, err := utils.LoadCertificateTemplate(templatePath)
+		if err != nil {
+			return err
+		}
+		fontChecksum := fmt.Sprintf("%x", sha256.Sum256(fontData))
+
+		rendered, acquired, err := certificatesRepo.RenderPending(ctx, fontChecksum, func(certificate models.Certificate) (models.Media, error) {
+			pdf, err := utils.RenderCertificate(certificateTemplate, fontData, certificate)
+			if errors.Is(err, utils.ErrUnencodableText) {
+				logger.Warn("Certificate font can not render certificate text, configure a template with a Unicode font",
+					zap.String("serial", certificate.Serial), zap.Error(err))
+				return models.Media{}, repositories.ErrRenderSkipped
+			}
+			if err != nil {
+				return models.Media{}, err
+			}
+			checksum, size, err := utils.StoreMedia(bytes.NewReader(pdf))
+			if err != nil {
+				return models.Media{}, err
+			}
+			return models.Media{
+				Filename:  "certificate-" + certificate.Serial + ".pdf",
+				Mime_type: "application/pdf",
+				Size:      size,
+				Checksum:  checksum,
+			}, nil
+		})
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			logger.Debug("Certificate rendering is handled by another replica")
+			return nil
+		}
+
+		if rendered > 0 {
+			logger.Info("Certificates rendered", zap.Int("rendered", rendered))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+	"go-EdTech/config"
+	"go-EdTech/logger"
+	"go-EdTech/repositories"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// RunEvery запускает job сразу и затем каждые interval, пока не отменен ctx.
+// Ошибки только логируются — следующая попытка будет на следующем тике.
+func RunEvery(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	logger := logger.GetLogger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info("Background job started", zap.String("job", name), zap.Duration("interval", interval))
+
+	for {
+		if err := job(ctx); err != nil {
+			logger.Error("Background job failed", zap.String("job", name), zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Background job stopped", zap.String("job", name))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce запускает job при старте и повторяет его каждые interval, пока job не сообщит, что работа сделана.
+// Так разовая задача не теряется, если первая попытка упала или ее выполняет другая реплика.
+func RunOnce(ctx context.Context, name string, interval time.Duration, job func(context.Context) (bool, error)) {
+	logger := logger.GetLogger()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info("One-off job started", zap.String("job", name))
+
+	for {
+		done, err := job(ctx)
+		if err != nil {
+			logger.Error("One-off job failed", zap.String("job", name), zap.Error(err))
+		}
+		if done {
+			logger.Info("One-off job finished", zap.String("job", name))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("One-off job stopped", zap.String("job", name))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SetupJobs запускает все фоновые задачи сервера
+func SetupJobs(ctx context.Context, conn *pgxpool.Pool) {
+	schedulingRepository := repositories.NewSchedulingRepository(conn)
+	searchRepository := repositories.NewSearchRepository(conn)
+	trashRepository := repositories.NewTrashRepository(conn)
+	certificatesRepository := repositories.NewCertificatesRepository(conn)
+	recommendationsRepository := repositories.NewRecommendationsRepository(conn)
+	leaderboardsRepository := repositories.NewLeaderboardsRepository(conn)
+
+	go RunEvery(ctx, "publication-scheduler", config.Config.SchedulerInterval, PublishScheduled(schedulingRepository))
+	go RunEvery(ctx, "search-indexer", config.Config.SchedulerInterval, IndexSearch(searchRepository))
+	go RunEvery(ctx, "trash-purge", config.Config.SchedulerInterval, PurgeTrash(trashRepository, config.Config.TrashRetention))
+	go RunEvery(ctx, "certificate-renderer", config.Config.SchedulerInterval, RenderCertificates(certificatesRepository, config.Config.CertificateTemplate))
+	go RunEvery(ctx, "lesson-cooccurrence", config.Config.RecommendationsInterval, ComputeCooccurrence(recommendationsRepository))
+	go RunOnce(ctx, "leaderboard-backfill", config.Config.SchedulerInterval, BackfillLeaderboards(leaderboardsRepository))
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/repositories"
+
+	"go.uber.org/zap"
+)
+
+// BackfillLeaderboards один раз заполняет рейтинги по уже накопленной истории; дальше очки обновляются вместе с событиями.
+// Сообщает true, когда заполнение отмечено в базе — этой репликой или другой.
+func BackfillLeaderboards(leaderboardsRepo *repositories.LeaderboardsRepository) func(context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		logger := logger.GetLogger()
+
+		stored, done, err := leaderboardsRepo.Backfill(ctx)
+		if err != nil {
+			return false, err
+		}
+		if !done {
+			logger.Debug("Leaderboards are backfilled by another replica")
+			return false, nil
+		}
+
+		if stored > 0 {
+			logger.Info("Leaderboards backfilled", zap.Int("scores", stored))
+		}
+		return true, nil
+	}
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/repositories"
+
+	"go.uber.org/zap"
+)
+
+// PublishScheduled переводит уроки и курсы в published/archived по publish_at и unpublish_at
+func PublishScheduled(schedulingRepo *repositories.SchedulingRepository) func(context.Context) error {
+	return func(ctx context.Context) error {
+		logger := logger.GetLogger()
+
+		result, acquired, err := schedulingRepo.ApplySchedules(ctx)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			logger.Debug("Publication schedule is handled by another replica")
+			return nil
+		}
+
+		if result.Published > 0 || result.Unpublished > 0 {
+			logger.Info("Scheduled publications applied",
+				zap.Int("published", result.Published),
+				zap.Int("unpublished", result.Unpublished))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/repositories"
+
+	"go.uber.org/zap"
+)
+
+// ComputeCooccurrence пересчитывает похожие уроки по истории прохождения для рекомендаций
+func ComputeCooccurrence(recommendationsRepo *repositories.RecommendationsRepository) func(context.Context) error {
+	return func(ctx context.Context) error {
+		logger := logger.GetLogger()
+
+		stored, acquired, err := recommendationsRepo.RecomputeCooccurrence(ctx)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			logger.Debug("Lesson co-occurrence is computed by another replica")
+			return nil
+		}
+
+		logger.Info("Lesson co-occurrence recomputed", zap.Int("pairs", stored))
+		return nil
+	}
+}
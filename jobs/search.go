@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/repositories"
+
+	"go.uber.org/zap"
+)
+
+// IndexSearch заполняет search_vector у записей, созданных до появления поиска или не проиндексированных из-за ошибки
+func IndexSearch(searchRepo *repositories.SearchRepository) func(context.Context) error {
+	return func(ctx context.Context) error {
+		logger := logger.GetLogger()
+
+		indexed, acquired, err := searchRepo.IndexMissing(ctx)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			logger.Debug("Search indexing is handled by another replica")
+			return nil
+		}
+
+		if indexed > 0 {
+			logger.Info("Search index updated", zap.Int("indexed", indexed))
+		}
+		return nil
+	}
+}
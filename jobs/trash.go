@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/repositories"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PurgeTrash окончательно удаляет записи, которые пролежали в корзине дольше retention
+func PurgeTrash(trashRepo *repositories.TrashRepository, retention time.Duration) func(context.Context) error {
+	return func(ctx context.Context) error {
+		logger := logger.GetLogger()
+
+		purged, acquired, err := trashRepo.Purge(ctx, retention)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			logger.Debug("Trash purge is handled by another replica")
+			return nil
+		}
+
+		if purged > 0 {
+			logger.Info("Trash purged", zap.Int("purged", purged), zap.Duration("retention", retention))
+		}
+		return nil
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go-EdTech/config"
 	"go-EdTech/handlers"
+	"go-EdTech/jobs"
 	"go-EdTech/logger"
 	"time"
 
@@ -90,6 +91,10 @@ func main() {
 
 	handlers.SetupRoutes(r, conn)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	jobs.SetupJobs(jobsCtx, conn)
+
 	logger.Info("Application starting...", zap.String("host", config.Config.AppHost))
 	if err := r.Run(config.Config.AppHost); err != nil {
 		logger.Fatal("Server failed to start", zap.Error(err))
@@ -110,6 +115,13 @@ func loadConfig() error {
 	viper.BindEnv("DB_CONNECTION_STRING")
 	viper.BindEnv("JWT_SECRET_KEY")
 	viper.BindEnv("JWT_EXPIRE_DURATION")
+	viper.BindEnv("MEDIA_PATH")
+	viper.BindEnv("SCHEDULER_INTERVAL")
+	viper.BindEnv("TRASH_RETENTION")
+	viper.BindEnv("PROGRESS_COMPLETION_THRESHOLD")
+	viper.BindEnv("CERTIFICATE_TEMPLATE")
+	viper.BindEnv("XAPI_HOMEPAGE")
+	viper.BindEnv("RECOMMENDATIONS_INTERVAL")
 
 	// Мапим переменные в структуру
 	var mapConfig config.MapConfig
@@ -128,6 +140,24 @@ func loadConfig() error {
 	if mapConfig.AppHost == "" {
 		mapConfig.AppHost = ":8081" // Default value
 	}
+	if mapConfig.MediaPath == "" {
+		mapConfig.MediaPath = "uploads"
+	}
+	if mapConfig.SchedulerInterval <= 0 {
+		mapConfig.SchedulerInterval = time.Minute
+	}
+	if mapConfig.TrashRetention <= 0 {
+		mapConfig.TrashRetention = 30 * 24 * time.Hour
+	}
+	if mapConfig.ProgressCompletionThreshold <= 0 || mapConfig.ProgressCompletionThreshold > 1 {
+		mapConfig.ProgressCompletionThreshold = 0.9
+	}
+	if mapConfig.XAPIHomePage == "" {
+		mapConfig.XAPIHomePage = "https://ilessons.cloud"
+	}
+	if mapConfig.RecommendationsInterval <= 0 {
+		mapConfig.RecommendationsInterval = time.Hour
+	}
 
 	config.Config = &mapConfig
 	return nil
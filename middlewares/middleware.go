@@ -6,6 +6,7 @@ import (
 	"go-EdTech/models"
 	"go-EdTech/repositories"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -118,11 +119,11 @@ func AuthMiddleware(sessionsRepo *repositories.SessionsRepository, usersRepo *re
 }
 
 
-func RoleMiddleware(requiredRole string) gin.HandlerFunc {
+func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		logger := logger.GetLogger()
 
-		roleObj, exists := c.Get("role")
+		roleObj, exists := c.Get("userRole")
 		if !exists {
 			logger.Warn("Role missing - access denied")
 			c.JSON(http.StatusForbidden, models.NewApiError("access denied"))
@@ -139,8 +140,8 @@ func RoleMiddleware(requiredRole string) gin.HandlerFunc {
 		}
 
 		// Проверка роли
-		if role.Name != requiredRole {
-			logger.Warn("Access denied", zap.String("role", role.Name), zap.String("required", requiredRole))
+		if !slices.Contains(requiredRoles, role.Name) {
+			logger.Warn("Access denied", zap.String("role", role.Name), zap.Strings("required", requiredRoles))
 			c.JSON(http.StatusForbidden, models.NewApiError("forbidden"))
 			c.Abort()
 			return
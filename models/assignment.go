@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SubmissionSubmitted = "submitted"
+	SubmissionGraded    = "graded"
+)
+
+// Assignment — домашнее задание урока или курса. Max_submissions = nil — пересдавать можно без ограничений.
+type Assignment struct {
+	Id              int               `json:"id"`
+	Title           string            `json:"title"`
+	Instructions    string            `json:"instructions"`
+	Lesson_id       *int              `json:"lesson_id"`
+	Course_id       *int              `json:"course_id"`
+	Due_at          *time.Time        `json:"due_at"`
+	Accept_late     bool              `json:"accept_late"`
+	Max_submissions *int              `json:"max_submissions"`
+	Max_score       float64           `json:"max_score"`
+	Rubric          []RubricCriterion `json:"rubric"`
+	Created_by      *uuid.UUID        `json:"created_by"`
+	Created_at      time.Time         `json:"created_at"`
+	Updated_at      time.Time         `json:"updated_at"`
+}
+
+type RubricCriterion struct {
+	Id          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"`
+	Max_points  float64 `json:"max_points"`
+}
+
+type RubricScore struct {
+	Criterion_id string  `json:"criterion_id"`
+	Points       float64 `json:"points"`
+	Comment      string  `json:"comment,omitempty"`
+}
+
+type SubmissionFile struct {
+	Media_id  int    `json:"media_id"`
+	Filename  string `json:"filename"`
+	Mime_type string `json:"mime_type"`
+	Size      int64  `json:"size"`
+}
+
+type AssignmentSubmission struct {
+	Id            int              `json:"id"`
+	Assignment_id int              `json:"assignment_id"`
+	User_uuid     uuid.UUID        `json:"user_uuid"`
+	User_name     string           `json:"user_name"`
+	Number        int              `json:"number"`
+	Text          string           `json:"text"`
+	Files         []SubmissionFile `json:"files"`
+	Late          bool             `json:"late"`
+	Status        string           `json:"status"`
+	Score         *float64         `json:"score"`
+	Rubric_scores []RubricScore    `json:"rubric_scores"`
+	Feedback      string           `json:"feedback"`
+	Graded_by     *uuid.UUID       `json:"graded_by"`
+	Graded_at     *time.Time       `json:"graded_at"`
+	Submitted_at  time.Time        `json:"submitted_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type LessonCaption struct {
+	Id         int       `json:"id"`
+	Lesson_id  int       `json:"lesson_id"`
+	Language   string    `json:"language"`
+	Label      string    `json:"label"`
+	Is_default bool      `json:"is_default"`
+	Content    string    `json:"-"`
+	Created_at time.Time `json:"created_at"`
+	Updated_at time.Time `json:"updated_at"`
+}
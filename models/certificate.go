@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Certificate — сертификат о прохождении курса. Имя ученика и название курса сохраняются на момент выдачи,
+// Media_id появляется, когда PDF сгенерирован.
+type Certificate struct {
+	Id           int       `json:"id"`
+	Serial       string    `json:"serial"`
+	User_uuid    uuid.UUID `json:"user_uuid"`
+	Student_name string    `json:"student_name"`
+	Course_id    int       `json:"course_id"`
+	Course_title string    `json:"course_title"`
+	Media_id     *int      `json:"media_id"`
+	Issued_at    time.Time `json:"issued_at"`
+}
+
+// CertificateVerification — ответ публичной проверки, без uuid ученика
+type CertificateVerification struct {
+	Valid        bool      `json:"valid"`
+	Serial       string    `json:"serial"`
+	Student_name string    `json:"student_name"`
+	Course_title string    `json:"course_title"`
+	Issued_at    time.Time `json:"issued_at"`
+}
+
+// CertificateTemplate — макет PDF, размеры и координаты в пунктах от левого нижнего угла
+type CertificateTemplate struct {
+	Width  float64           `json:"width"`
+	Height float64           `json:"height"`
+	Font   string            `json:"font"` // путь к TrueType шрифту; пусто — встроенный Go Regular (латиница, кириллица, греческий)
+	Border bool              `json:"border"`
+	Lines  []CertificateLine `json:"lines"`
+}
+
+// CertificateLine — строка макета. Text — text/template с полями .Student, .Course, .Date и .Serial.
+type CertificateLine struct {
+	Text  string  `json:"text"`
+	Size  float64 `json:"size"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Align string  `json:"align"` // left от X, right до X, center — по центру страницы
+}
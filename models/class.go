@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Class — учебная группа учеников, например класс школы. По ней строится рейтинг класса.
+type Class struct {
+	Id           int           `json:"id"`
+	Name         string        `json:"name"`
+	Member_count int           `json:"member_count"`
+	Members      []ClassMember `json:"members,omitempty"`
+	Created_by   *uuid.UUID    `json:"created_by"`
+	Created_at   time.Time     `json:"created_at"`
+	Updated_at   time.Time     `json:"updated_at"`
+}
+
+type ClassMember struct {
+	User_uuid uuid.UUID `json:"user_uuid"`
+	Name      string    `json:"name"`
+	Surname   string    `json:"surname"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type CourseLesson struct {
+	Course_id    int    `json:"course_id"`
+	Lesson_id    int    `json:"lesson_id"`
+	Title        string `json:"title"`
+	Position     int    `json:"position"`
+	Is_published bool   `json:"is_published"`
+}
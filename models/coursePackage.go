@@ -0,0 +1,18 @@
+package models
+
+// PackageImportReport — результат импорта пакета IMS Common Cartridge / SCORM
+type PackageImportReport struct {
+	Course_id      int                      `json:"course_id"`
+	Lesson_ids     []int                    `json:"lesson_ids"`
+	Media_count    int                      `json:"media_count"`
+	Resource_count int                      `json:"resource_count"`
+	Unsupported    []UnsupportedPackageItem `json:"unsupported"`
+}
+
+// UnsupportedPackageItem — элемент пакета, который не удалось перенести
+type UnsupportedPackageItem struct {
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	Type       string `json:"type"`
+	Reason     string `json:"reason"`
+}
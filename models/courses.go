@@ -6,7 +6,19 @@ type Course struct {
 	Id           int
 	Name         string
 	Description  string
+	Enrollment   string // open — ученики записываются сами, closed — записывает учитель
+	Capacity     *int   // максимум активных учеников, nil — без ограничения
 	Is_published bool
+	Status       string
+	Publish_at   *time.Time
+	Unpublish_at *time.Time
 	Created_at   time.Time
 	Updated_at   time.Time
 }
+
+type CourseFilter struct {
+	// только опубликованные курсы внутри окна publish_at/unpublish_at
+	Visible_only bool
+	// курс должен иметь тег из каждой группы; группа — тег вместе с дочерними
+	Tag_groups [][]int
+}
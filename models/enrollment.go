@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CourseEnrollmentOpen   = "open"
+	CourseEnrollmentClosed = "closed"
+)
+
+const (
+	EnrollmentActive     = "active"
+	EnrollmentCompleted  = "completed"
+	EnrollmentDropped    = "dropped"
+	EnrollmentWaitlisted = "waitlisted"
+)
+
+var EnrollmentStatuses = []string{EnrollmentActive, EnrollmentCompleted, EnrollmentDropped, EnrollmentWaitlisted}
+
+type Enrollment struct {
+	Course_id         int        `json:"course_id"`
+	Course_title      string     `json:"course_title"`
+	User_uuid         uuid.UUID  `json:"user_uuid"`
+	User_name         string     `json:"user_name"`
+	Status            string     `json:"status"`
+	Waitlist_position *int       `json:"waitlist_position,omitempty"` // место в очереди, только для waitlisted
+	Enrolled_by       *uuid.UUID `json:"enrolled_by,omitempty"`
+	Enrolled_at       time.Time  `json:"enrolled_at"`
+	Updated_at        time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// оценка ответа при повторении карточки по шкале SM-2: 0 — не вспомнил, 5 — вспомнил сразу
+const (
+	ReviewGradeMin  = 0
+	ReviewGradePass = 3
+	ReviewGradeMax  = 5
+)
+
+// FlashcardDeck — колода карточек предмета или урока
+type FlashcardDeck struct {
+	Id          int         `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Subject_id  *int        `json:"subject_id"`
+	Lesson_id   *int        `json:"lesson_id"`
+	Cards_count int         `json:"cards_count"`
+	Cards       []Flashcard `json:"cards,omitempty"`
+	Created_by  *uuid.UUID  `json:"created_by"`
+	Created_at  time.Time   `json:"created_at"`
+	Updated_at  time.Time   `json:"updated_at"`
+}
+
+type FlashcardDeckFilter struct {
+	// только колоды предметов и видимых ученикам уроков
+	Visible_only bool
+	Subject_id   int
+	Lesson_id    int
+}
+
+type Flashcard struct {
+	Id         int       `json:"id"`
+	Deck_id    int       `json:"deck_id"`
+	Front      string    `json:"front"`
+	Back       string    `json:"back"`
+	Position   int       `json:"position"`
+	Created_at time.Time `json:"created_at"`
+	Updated_at time.Time `json:"updated_at"`
+}
+
+// FlashcardState — расписание повторения карточки учеником
+type FlashcardState struct {
+	User_uuid        uuid.UUID  `json:"user_uuid"`
+	Card_id          int        `json:"card_id"`
+	Repetitions      int        `json:"repetitions"` // успешных повторений подряд
+	Interval_days    int        `json:"interval_days"`
+	Ease             float64    `json:"ease"`
+	Lapses           int        `json:"lapses"` // сколько раз карточка была забыта
+	Due_at           time.Time  `json:"due_at"`
+	Last_reviewed_at *time.Time `json:"last_reviewed_at"`
+}
+
+// DueFlashcard — карточка к повторению; Is_new — ученик видит ее впервые
+type DueFlashcard struct {
+	Card       Flashcard       `json:"card"`
+	Deck_title string          `json:"deck_title"`
+	Is_new     bool            `json:"is_new"`
+	State      *FlashcardState `json:"state"`
+}
+
+// FlashcardStats — статистика повторений ученика. Retention — процент успешных повторений
+// уже изученных карточек за 30 дней, первые показы не учитываются.
+type FlashcardStats struct {
+	Deck_id       *int     `json:"deck_id"`
+	Cards_total   int      `json:"cards_total"`
+	Cards_studied int      `json:"cards_studied"`
+	Cards_mature  int      `json:"cards_mature"`
+	Due_now       int      `json:"due_now"`
+	Reviews_total int      `json:"reviews_total"`
+	Reviews_30d   int      `json:"reviews_30d"`
+	Retention_30d *float64 `json:"retention_30d"`
+	Average_ease  *float64 `json:"average_ease"`
+	Lapses        int      `json:"lapses"`
+}
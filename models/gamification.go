@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// события обучения, за которые начисляется опыт и проверяются бейджи
+const (
+	GamificationLessonCompleted = "lesson_completed"
+	GamificationCourseCompleted = "course_completed"
+	GamificationQuizSubmitted   = "quiz_submitted"
+)
+
+// показатели ученика, на которых строятся правила бейджей
+const (
+	BadgeMetricLessonsCompleted = "lessons_completed"
+	BadgeMetricCoursesCompleted = "courses_completed"
+	BadgeMetricQuizzesPassed    = "quizzes_passed"
+	BadgeMetricPerfectQuizzes   = "perfect_quizzes"
+	BadgeMetricXP               = "xp"
+	BadgeMetricStreakDays       = "streak_days"
+)
+
+var BadgeMetrics = []string{
+	BadgeMetricLessonsCompleted, BadgeMetricCoursesCompleted, BadgeMetricQuizzesPassed,
+	BadgeMetricPerfectQuizzes, BadgeMetricXP, BadgeMetricStreakDays,
+}
+
+// показатели, которые можно ограничить предметом
+var BadgeSubjectMetrics = []string{BadgeMetricLessonsCompleted, BadgeMetricQuizzesPassed, BadgeMetricPerfectQuizzes}
+
+// GamificationEvent — событие обучения ученика; Xp — сколько опыта оно приносит.
+// Серию продлевают только действия самого ученика, а не учителя.
+type GamificationEvent struct {
+	User_uuid    uuid.UUID
+	Type         string
+	Entity_id    int
+	Xp           int
+	Learner_made bool
+}
+
+// BadgeRule — бейдж выдается, когда показатель достигает Threshold,
+// например {"metric": "lessons_completed", "subject_id": 3, "threshold": 5}
+type BadgeRule struct {
+	Metric     string `json:"metric"`
+	Subject_id *int   `json:"subject_id,omitempty"`
+	Threshold  int    `json:"threshold"`
+}
+
+type Badge struct {
+	Id          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Icon        string    `json:"icon"`
+	Rule        BadgeRule `json:"rule"`
+	Is_active   bool      `json:"is_active"`
+	Created_at  time.Time `json:"created_at"`
+	Updated_at  time.Time `json:"updated_at"`
+}
+
+type EarnedBadge struct {
+	Badge     Badge     `json:"badge"`
+	Earned_at time.Time `json:"earned_at"`
+}
+
+type XPEvent struct {
+	Type        string    `json:"type"`
+	Entity_id   int       `json:"entity_id"`
+	Xp          int       `json:"xp"`
+	Occurred_at time.Time `json:"occurred_at"`
+}
+
+// GamificationState — накопленный опыт и серия дней с активностью ученика
+type GamificationState struct {
+	Xp               int
+	Current_streak   int
+	Longest_streak   int
+	Last_active_date *time.Time
+	Time_zone        string
+}
+
+// Achievements — опыт, серия и бейджи ученика. Серия прерывается, если ученик пропустил день в своем часовом поясе.
+type Achievements struct {
+	User_uuid        uuid.UUID     `json:"user_uuid"`
+	Xp               int           `json:"xp"`
+	Current_streak   int           `json:"current_streak"`
+	Longest_streak   int           `json:"longest_streak"`
+	Last_active_date *string       `json:"last_active_date"`
+	Time_zone        string        `json:"time_zone"`
+	Badges           []EarnedBadge `json:"badges"`
+	Recent_xp        []XPEvent     `json:"recent_xp"`
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	GradeItemQuiz       = "quiz"
+	GradeItemAssignment = "assignment"
+	// переопределение итоговой оценки за курс, Item_id — id курса
+	GradeItemCourse = "course"
+)
+
+var GradeItemTypes = []string{GradeItemQuiz, GradeItemAssignment, GradeItemCourse}
+
+// GradeCategory — взвешенная категория журнала. Элемент попадает в первую категорию,
+// где он указан в Item_ids, иначе в первую категорию своего типа с пустым Item_ids.
+type GradeCategory struct {
+	Name      string  `json:"name"`
+	Weight    float64 `json:"weight"`
+	Item_type string  `json:"item_type"`
+	Item_ids  []int   `json:"item_ids,omitempty"`
+}
+
+type LetterGrade struct {
+	Letter      string  `json:"letter"`
+	Min_percent float64 `json:"min_percent"`
+}
+
+type GradebookSettings struct {
+	Course_id     int             `json:"course_id"`
+	Categories    []GradeCategory `json:"categories"`
+	Letter_scheme []LetterGrade   `json:"letter_scheme"`
+	Updated_at    *time.Time      `json:"updated_at"`
+}
+
+// GradebookItem — квиз или задание курса или его уроков
+type GradebookItem struct {
+	Type      string     `json:"type"`
+	Id        int        `json:"id"`
+	Title     string     `json:"title"`
+	Category  string     `json:"category"`
+	Max_score float64    `json:"max_score"`
+	Due_at    *time.Time `json:"due_at,omitempty"`
+}
+
+// GradeScore — результат ученика по элементу из попыток и проверенных работ
+type GradeScore struct {
+	User_uuid uuid.UUID
+	Item_type string
+	Item_id   int
+	Score     float64
+	Max_score float64
+}
+
+type GradeOverride struct {
+	Id         int        `json:"id"`
+	Course_id  int        `json:"course_id"`
+	User_uuid  uuid.UUID  `json:"user_uuid"`
+	Item_type  string     `json:"item_type"`
+	Item_id    int        `json:"item_id"`
+	Percent    *float64   `json:"percent"` // nil снимает переопределение
+	Reason     string     `json:"reason"`
+	Created_by *uuid.UUID `json:"created_by"`
+	Created_at time.Time  `json:"created_at"`
+}
+
+type ItemGrade struct {
+	Type       string   `json:"type"`
+	Id         int      `json:"id"`
+	Percent    *float64 `json:"percent"`
+	Overridden bool     `json:"overridden"`
+}
+
+type CategoryGrade struct {
+	Name    string   `json:"name"`
+	Weight  float64  `json:"weight"`
+	Percent *float64 `json:"percent"`
+}
+
+type StudentGrade struct {
+	Course_id    int             `json:"course_id"`
+	Course_title string          `json:"course_title,omitempty"`
+	User_uuid    uuid.UUID       `json:"user_uuid"`
+	User_name    string          `json:"user_name"`
+	Percent      *float64        `json:"percent"`
+	Letter       string          `json:"letter"`
+	Overridden   bool            `json:"overridden"`
+	Categories   []CategoryGrade `json:"categories"`
+	Items        []ItemGrade     `json:"items"`
+}
+
+type Gradebook struct {
+	Course_id  int             `json:"course_id"`
+	Categories []GradeCategory `json:"categories"`
+	Items      []GradebookItem `json:"items"`
+	Students   []StudentGrade  `json:"students"`
+}
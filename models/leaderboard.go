@@ -0,0 +1,87 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	LeaderboardMetricXP = "xp"
+	// пройденные уроки курса
+	LeaderboardMetricProgress = "progress"
+
+	LeaderboardPeriodWeek  = "week"
+	LeaderboardPeriodMonth = "month"
+	LeaderboardPeriodAll   = "all"
+
+	LeaderboardScopeOrganization = "organization"
+	LeaderboardScopeCourse       = "course"
+	LeaderboardScopeClass        = "class"
+)
+
+var (
+	LeaderboardMetrics = []string{LeaderboardMetricXP, LeaderboardMetricProgress}
+	LeaderboardPeriods = []string{LeaderboardPeriodWeek, LeaderboardPeriodMonth, LeaderboardPeriodAll}
+)
+
+// как ученик показывается в рейтингах: под именем, под ником или не показывается вовсе
+const (
+	LeaderboardShowName     = "name"
+	LeaderboardShowNickname = "nickname"
+	LeaderboardHidden       = "hidden"
+)
+
+var LeaderboardVisibilities = []string{LeaderboardShowName, LeaderboardShowNickname, LeaderboardHidden}
+
+// LeaderboardPrivacy — настройки ученика. Пустой Visibility — по умолчанию: взрослые под именем, остальные под ником.
+type LeaderboardPrivacy struct {
+	User_uuid  uuid.UUID  `json:"user_uuid"`
+	Visibility string     `json:"visibility"`
+	Nickname   string     `json:"nickname"`
+	Updated_at *time.Time `json:"updated_at"`
+}
+
+type LeaderboardFilter struct {
+	Metric string
+	// 0 — рейтинг всей школы
+	Course_id int
+	// 0 — без ограничения по классу, иначе в рейтинге только ученики класса
+	Class_id     int
+	Period       string
+	Period_start time.Time
+	Limit        int
+	// зритель попадает в Me, даже если он вне первых Limit мест
+	Viewer uuid.UUID
+}
+
+// LeaderboardRow — строка рейтинга до применения настроек приватности
+type LeaderboardRow struct {
+	Rank       int
+	User_uuid  uuid.UUID
+	Score      int
+	Name       string
+	Surname    string
+	Visibility string
+	Nickname   string
+	Age        *int
+}
+
+type LeaderboardEntry struct {
+	Rank         int      `json:"rank"`
+	Display_name string   `json:"display_name"`
+	Score        int      `json:"score"`
+	Percent      *float64 `json:"percent,omitempty"` // прогресс по курсу за все время
+	Is_me        bool     `json:"is_me"`
+}
+
+type Leaderboard struct {
+	Scope        string             `json:"scope"`
+	Course_id    *int               `json:"course_id,omitempty"`
+	Class_id     *int               `json:"class_id,omitempty"`
+	Metric       string             `json:"metric"`
+	Period       string             `json:"period"`
+	Period_start string             `json:"period_start"`
+	Entries      []LeaderboardEntry `json:"entries"`
+	Me           *LeaderboardEntry  `json:"me"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LearningPath — последовательность курсов. Предметы и возраст выводятся из уроков этих курсов.
+type LearningPath struct {
+	Id             int                  `json:"id"`
+	Title          string               `json:"title"`
+	Description    string               `json:"description"`
+	Is_published   bool                 `json:"is_published"`
+	Subject_ids    []int                `json:"subject_ids"`
+	Target_age_min *int                 `json:"target_age_min"`
+	Target_age_max *int                 `json:"target_age_max"`
+	Courses        []LearningPathCourse `json:"courses"`
+	Created_by     *uuid.UUID           `json:"created_by"`
+	Created_at     time.Time            `json:"created_at"`
+	Updated_at     time.Time            `json:"updated_at"`
+}
+
+type LearningPathCourse struct {
+	Course_id int    `json:"course_id"`
+	Title     string `json:"title"`
+	Position  int    `json:"position"`
+	Required  bool   `json:"required"`
+}
+
+type LearningPathFilter struct {
+	// только опубликованные пути и видимые ученикам курсы
+	Visible_only bool
+	Subject_id   int
+	// возраст попадает в диапазон target_age_min..target_age_max пути
+	Age int
+}
+
+type LearningPathCourseProgress struct {
+	Course_id         int     `json:"course_id"`
+	Title             string  `json:"title"`
+	Required          bool    `json:"required"`
+	Status            string  `json:"status"` // статус записи на курс, пусто — не записан
+	Total_lessons     int     `json:"total_lessons"`
+	Completed_lessons int     `json:"completed_lessons"`
+	Percent           float64 `json:"percent"`
+}
+
+// LearningPathProgress — прогресс по пути считается по обязательным курсам, если их нет — по всем
+type LearningPathProgress struct {
+	Path_id            int                          `json:"path_id"`
+	Title              string                       `json:"title"`
+	User_uuid          uuid.UUID                    `json:"user_uuid"`
+	Enrolled_at        *time.Time                   `json:"enrolled_at"`
+	Total_courses      int                          `json:"total_courses"`
+	Completed_courses  int                          `json:"completed_courses"`
+	Required_courses   int                          `json:"required_courses"`
+	Completed_required int                          `json:"completed_required"`
+	Percent            float64                      `json:"percent"`
+	Completed          bool                         `json:"completed"`
+	Courses            []LearningPathCourseProgress `json:"courses"`
+}
@@ -3,20 +3,36 @@ package models
 import "time"
 
 type Lesson struct {
-	Id              int
-	Title           string
-	Description     string
-	Subject_id      int //under question
-	Order           int
-	Level           string
-	Interest        string
-	Target_age_min  int
-	Target_age_max  int
-	Video_data      []byte
+	Id             int
+	Title          string
+	Description    string
+	Body           string
+	Body_html      string
+	Subject_id     int //under question
+	Order          int
+	Level          string
+	Interest       string
+	Target_age_min int
+	Target_age_max int
+	Video_data     []byte
+	// видео в хранилище media вместо Video_data; у копий курса оно общее с исходником
+	Video_media_id  *int
 	Video_filename  string
 	Video_mime_type string
 	Duration_sec    int
 	Is_published    bool
+	Status          string
+	Publish_at      *time.Time
+	Unpublish_at    *time.Time
 	Created_at      time.Time
 	Updated_at      time.Time
 }
+
+type LessonFilter struct {
+	// только опубликованные уроки внутри окна publish_at/unpublish_at
+	Visible_only bool
+	// пусто — любой уровень
+	Level string
+	// урок должен иметь тег из каждой группы; группа — тег вместе с дочерними
+	Tag_groups [][]int
+}
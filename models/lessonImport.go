@@ -0,0 +1,17 @@
+package models
+
+// LessonImportReport — результат проверки или импорта уроков из таблицы
+type LessonImportReport struct {
+	Dry_run    bool              `json:"dry_run"`
+	Total_rows int               `json:"total_rows"`
+	Valid_rows int               `json:"valid_rows"`
+	Created    []int             `json:"created"`
+	Rows       []LessonImportRow `json:"rows"`
+}
+
+// LessonImportRow — строка таблицы; Row считается как в Excel, заголовок — строка 1
+type LessonImportRow struct {
+	Row    int      `json:"row"`
+	Title  string   `json:"title"`
+	Errors []string `json:"errors"`
+}
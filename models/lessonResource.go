@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+const (
+	ResourceTypeFile = "file"
+	ResourceTypeLink = "link"
+)
+
+type LessonResource struct {
+	Id             int       `json:"id"`
+	Lesson_id      int       `json:"lesson_id"`
+	Type           string    `json:"type"`
+	Title          string    `json:"title"`
+	Url            string    `json:"url,omitempty"`
+	Media_id       *int      `json:"media_id,omitempty"`
+	Filename       string    `json:"filename,omitempty"`
+	Mime_type      string    `json:"mime_type,omitempty"`
+	Size           int64     `json:"size,omitempty"`
+	Position       int       `json:"position"`
+	Download_count int       `json:"download_count"`
+	Created_at     time.Time `json:"created_at"`
+}
@@ -0,0 +1,9 @@
+package models
+
+const (
+	LevelBeginner     = "Beginner"
+	LevelIntermediate = "Intermediate"
+	LevelAdvanced     = "Advanced"
+)
+
+var Levels = []string{LevelBeginner, LevelIntermediate, LevelAdvanced}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ContentLineage связывает копию с исходником, из которого она была склонирована.
+// Source_revision — последняя ревизия исходника на момент клонирования,
+// Source_latest_revision — последняя ревизия исходника сейчас (0, если исходник удален).
+type ContentLineage struct {
+	Entity_type            string `json:"entity_type"`
+	Entity_id              int    `json:"entity_id"`
+	Source_id              int    `json:"source_id"`
+	Source_revision        int    `json:"source_revision"`
+	Source_latest_revision int    `json:"source_latest_revision"`
+	Source_updated         bool   `json:"source_updated"`
+	// обновление сохранено ревизией pending и ждет одобрения; только в ответе pull
+	Pending    bool      `json:"pending,omitempty"`
+	Created_at time.Time `json:"created_at"`
+}
+
+// CloneResult — id копии курса и соответствие исходных уроков их копиям
+type CloneResult struct {
+	Course_id int            `json:"course_id"`
+	Lessons   []ClonedLesson `json:"lessons"`
+}
+
+type ClonedLesson struct {
+	Source_id int `json:"source_id"`
+	Id        int `json:"id"`
+}
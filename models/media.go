@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type Media struct {
+	Id         int       `json:"id"`
+	Filename   string    `json:"filename"`
+	Mime_type  string    `json:"mime_type"`
+	Size       int64     `json:"size"`
+	Checksum   string    `json:"checksum"`
+	Created_at time.Time `json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EntityAssignment  = "assignment"
+	EntityCertificate = "certificate"
+	EntityBadge       = "badge"
+
+	NotificationAssignmentGraded  = "assignment_graded"
+	NotificationCertificateIssued = "certificate_issued"
+	NotificationBadgeEarned       = "badge_earned"
+)
+
+// Notification — уведомление внутри приложения. Entity_type и Entity_id указывают, к чему оно относится.
+type Notification struct {
+	Id          int        `json:"id"`
+	User_uuid   uuid.UUID  `json:"user_uuid"`
+	Type        string     `json:"type"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	Entity_type string     `json:"entity_type"`
+	Entity_id   int        `json:"entity_id"`
+	Read_at     *time.Time `json:"read_at"`
+	Created_at  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+type PrerequisiteRef struct {
+	Id    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// LockedContentError возвращается с 403, когда у ученика не пройдены предварительные требования
+type LockedContentError struct {
+	Error           string
+	Missing_lessons []PrerequisiteRef `json:"missing_lessons"`
+	Missing_courses []PrerequisiteRef `json:"missing_courses"`
+}
+
+type LessonUnlockState struct {
+	Lesson_id       int    `json:"lesson_id"`
+	Title           string `json:"title"`
+	Position        int    `json:"position"`
+	Completed       bool   `json:"completed"`
+	Unlocked        bool   `json:"unlocked"`
+	Missing_lessons []int  `json:"missing_lessons"`
+}
+
+type CourseUnlockMap struct {
+	Course_id       int                 `json:"course_id"`
+	Unlocked        bool                `json:"unlocked"`
+	Missing_courses []PrerequisiteRef   `json:"missing_courses"`
+	Lessons         []LessonUnlockState `json:"lessons"`
+}
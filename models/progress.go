@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LessonProgress struct {
+	Lesson_id       int        `json:"lesson_id"`
+	Title           string     `json:"title,omitempty"`
+	Position_sec    int        `json:"position_sec"`
+	Duration_sec    int        `json:"duration_sec"`
+	Watched_sec     int        `json:"watched_sec"`
+	Watched_percent float64    `json:"watched_percent"`
+	Completed       bool       `json:"completed"`
+	Completed_at    *time.Time `json:"completed_at,omitempty"`
+	Updated_at      *time.Time `json:"updated_at,omitempty"`
+}
+
+type CourseProgress struct {
+	Course_id         int              `json:"course_id"`
+	User_uuid         uuid.UUID        `json:"user_uuid"`
+	Total_lessons     int              `json:"total_lessons"`
+	Completed_lessons int              `json:"completed_lessons"`
+	Percent           float64          `json:"percent"`
+	Lessons           []LessonProgress `json:"lessons"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	QuestionSingleChoice   = "single_choice"
+	QuestionMultipleChoice = "multiple_choice"
+	QuestionTrueFalse      = "true_false"
+	QuestionNumeric        = "numeric"
+	QuestionShortAnswer    = "short_answer"
+)
+
+var QuestionTypes = []string{QuestionSingleChoice, QuestionMultipleChoice, QuestionTrueFalse, QuestionNumeric, QuestionShortAnswer}
+
+const (
+	AttemptInProgress = "in_progress"
+	AttemptSubmitted  = "submitted"
+	AttemptExpired    = "expired"
+)
+
+// Question — вопрос банка вопросов предмета. Какие поля ответа заполнены, зависит от типа.
+type Question struct {
+	Id                 int              `json:"id"`
+	Subject_id         int              `json:"subject_id"`
+	Type               string           `json:"type"`
+	Text               string           `json:"text"`
+	Options            []QuestionOption `json:"options,omitempty"`          // single_choice и multiple_choice
+	Answer_bool        *bool            `json:"answer_bool,omitempty"`      // true_false
+	Answer_number      *float64         `json:"answer_number,omitempty"`    // numeric
+	Tolerance          float64          `json:"tolerance,omitempty"`        // numeric, допустимое отклонение
+	Accepted_answers   []string         `json:"accepted_answers,omitempty"` // short_answer, без учета регистра и пробелов
+	Points             int              `json:"points"`
+	Feedback_correct   string           `json:"feedback_correct,omitempty"`
+	Feedback_incorrect string           `json:"feedback_incorrect,omitempty"`
+	Created_at         time.Time        `json:"created_at"`
+	Updated_at         time.Time        `json:"updated_at"`
+}
+
+type QuestionOption struct {
+	Id      string `json:"id"`
+	Text    string `json:"text"`
+	Correct bool   `json:"correct"`
+}
+
+// Quiz прикрепляется к уроку или к курсу. Квиз курса стоит модулем после урока с позицией Position.
+type Quiz struct {
+	Id                int       `json:"id"`
+	Title             string    `json:"title"`
+	Description       string    `json:"description"`
+	Lesson_id         *int      `json:"lesson_id"`
+	Course_id         *int      `json:"course_id"`
+	Position          int       `json:"position"`
+	Time_limit_sec    *int      `json:"time_limit_sec"`
+	Max_attempts      *int      `json:"max_attempts"`
+	Shuffle_questions bool      `json:"shuffle_questions"`
+	Shuffle_options   bool      `json:"shuffle_options"`
+	Pass_percent      float64   `json:"pass_percent"`
+	Question_ids      []int     `json:"question_ids"`
+	Created_at        time.Time `json:"created_at"`
+	Updated_at        time.Time `json:"updated_at"`
+}
+
+// QuizQuestion — вопрос в том виде, в котором его видит ученик: без правильных ответов
+type QuizQuestion struct {
+	Id      int          `json:"id"`
+	Type    string       `json:"type"`
+	Text    string       `json:"text"`
+	Points  int          `json:"points"`
+	Options []QuizOption `json:"options,omitempty"`
+}
+
+type QuizOption struct {
+	Id   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// QuizAnswer — ответ ученика, заполняется поле, соответствующее типу вопроса
+type QuizAnswer struct {
+	Question_id int      `json:"question_id"`
+	Option_ids  []string `json:"option_ids,omitempty"`
+	Bool        *bool    `json:"bool,omitempty"`
+	Number      *float64 `json:"number,omitempty"`
+	Text        string   `json:"text,omitempty"`
+}
+
+type QuestionResult struct {
+	Question_id int     `json:"question_id"`
+	Correct     bool    `json:"correct"`
+	Points      int     `json:"points"`
+	Earned      float64 `json:"earned"`
+	Feedback    string  `json:"feedback,omitempty"`
+}
+
+type QuizAttempt struct {
+	Id             int              `json:"id"`
+	Quiz_id        int              `json:"quiz_id"`
+	User_uuid      uuid.UUID        `json:"user_uuid"`
+	Attempt_number int              `json:"attempt_number"`
+	Status         string           `json:"status"`
+	Started_at     time.Time        `json:"started_at"`
+	Deadline       *time.Time       `json:"deadline"`
+	Submitted_at   *time.Time       `json:"submitted_at"`
+	Score          float64          `json:"score"`
+	Max_score      float64          `json:"max_score"`
+	Percent        float64          `json:"percent"`
+	Passed         bool             `json:"passed"`
+	Questions      []QuizQuestion   `json:"questions,omitempty"`
+	Results        []QuestionResult `json:"results,omitempty"`
+
+	// порядок, в котором ученик видит вопросы и варианты ответа
+	Question_order []int            `json:"-"`
+	Option_order   map[int][]string `json:"-"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LearnerProfile — интересы, возраст и желаемый уровень, которые ученик указал сам
+type LearnerProfile struct {
+	User_uuid  uuid.UUID  `json:"user_uuid"`
+	Interests  []string   `json:"interests"`
+	Age        *int       `json:"age"`
+	Level      string     `json:"level"`
+	Updated_at *time.Time `json:"updated_at"`
+}
+
+// RecommendationCandidate — непройденный видимый урок с сигналами для ранжирования
+type RecommendationCandidate struct {
+	Lesson_id      int
+	Title          string
+	Subject_id     int
+	Level          string
+	Interest       string
+	Target_age_min int
+	Target_age_max int
+	// сколько учеников прошли урок
+	Learners int
+	// сумма сходства с уроками, которые ученик уже прошел
+	Similarity float64
+	// самый похожий пройденный урок, для объяснения
+	Similar_lesson string
+	// сколько уроков этого предмета ученик уже прошел
+	Subject_completed int
+}
+
+type Recommendation struct {
+	Lesson_id   int      `json:"lesson_id"`
+	Title       string   `json:"title"`
+	Subject_id  int      `json:"subject_id"`
+	Level       string   `json:"level"`
+	Score       float64  `json:"score"`
+	Reasons     []string `json:"reasons"`
+	Explanation string   `json:"explanation"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EntityLesson  = "lesson"
+	EntityCourse  = "course"
+	EntitySubject = "subject"
+)
+
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+	// StatusPending бывает только у ревизий: правка опубликованного контента без права публикации.
+	// В сущность она попадает, только когда publisher ее одобрит.
+	StatusPending = "pending"
+)
+
+type ContentRevision struct {
+	Id          int             `json:"id"`
+	Entity_type string          `json:"entity_type"`
+	Entity_id   int             `json:"entity_id"`
+	Revision    int             `json:"revision"`
+	Status      string          `json:"status"`
+	Comment     string          `json:"comment,omitempty"`
+	Data        json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+	Author_uuid *uuid.UUID      `json:"author_uuid,omitempty"`
+	Created_at  time.Time       `json:"created_at"`
+}
+
+type RevisionDiff struct {
+	Entity_type string        `json:"entity_type"`
+	Entity_id   int           `json:"entity_id"`
+	From        int           `json:"from"`
+	To          int           `json:"to"`
+	Changes     []FieldChange `json:"changes"`
+}
+
+type FieldChange struct {
+	Field string     `json:"field"`
+	Old   any        `json:"old"`
+	New   any        `json:"new"`
+	Lines []DiffLine `json:"lines,omitempty"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op"` // "=", "-" или "+"
+	Text string `json:"text"`
+}
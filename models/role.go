@@ -4,3 +4,14 @@ type Role struct {
 	Id          int             `json:"id"`
 	Name        string          `json:"name"`
 }
+
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+	RoleStudent = "student"
+
+	// проверяет и публикует материалы
+	RoleContentLead = "content_lead"
+	// ведет переводы уроков, курсов и предметов
+	RoleTranslator = "translator"
+)
@@ -0,0 +1,36 @@
+package models
+
+type SearchFilter struct {
+	// tsquery с префиксами, собирается utils.BuildPrefixQuery
+	Query string
+	// пусто — все типы: lesson, course, subject
+	Types        []string
+	Subject_id   *int
+	Level        string
+	Tag_groups   [][]int
+	Visible_only bool
+	Limit        int
+	Offset       int
+}
+
+type SearchResult struct {
+	Type       string  `json:"type"`
+	Id         int     `json:"id"`
+	Title      string  `json:"title"`
+	Snippet    string  `json:"snippet"`
+	Rank       float32 `json:"rank"`
+	Subject_id *int    `json:"subject_id"`
+	Level      *string `json:"level"`
+}
+
+type SearchFacet struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+type SearchResponse struct {
+	Total   int                      `json:"total"`
+	Results []SearchResult           `json:"results"`
+	Facets  map[string][]SearchFacet `json:"facets"`
+}
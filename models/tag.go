@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Tag — элемент таксономии: интерес, тема или категория. Parent_id задает иерархию.
+type Tag struct {
+	Id         int       `json:"id"`
+	Name       string    `json:"name"`
+	Slug       string    `json:"slug"`
+	Parent_id  *int      `json:"parent_id"`
+	Created_at time.Time `json:"created_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	LocaleRussian = "ru"
+	LocaleKazakh  = "kk"
+	LocaleEnglish = "en"
+
+	// язык, на котором авторы пишут исходный контент
+	DefaultLocale = LocaleRussian
+)
+
+var SupportedLocales = []string{LocaleRussian, LocaleKazakh, LocaleEnglish}
+
+const (
+	TranslationMissing  = "missing"
+	TranslationOutdated = "outdated"
+)
+
+// Translation — перевод урока, курса или предмета; для предмета заполняется только Title
+type Translation struct {
+	Entity_type string    `json:"entity_type"`
+	Entity_id   int       `json:"entity_id"`
+	Locale      string    `json:"locale"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Body        string    `json:"body"`
+	Body_html   string    `json:"body_html"`
+	Updated_at  time.Time `json:"updated_at"`
+}
+
+// MissingTranslation — строка отчета: перевода нет или он старше исходного текста
+type MissingTranslation struct {
+	Entity_type string `json:"entity_type"`
+	Entity_id   int    `json:"entity_id"`
+	Title       string `json:"title"`
+	Locale      string `json:"locale"`
+	Status      string `json:"status"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// EntityUser — тип для корзины; у остального контента типы из revision.go
+const EntityUser = "user"
+
+// TrashItems — типы, которые можно удалить в корзину и восстановить
+var TrashItems = []string{EntityLesson, EntityCourse, EntitySubject, EntityUser}
+
+// TrashItem — удаленная запись в корзине. Id строкой, потому что у пользователей uuid.
+type TrashItem struct {
+	Type       string    `json:"type"`
+	Id         string    `json:"id"`
+	Title      string    `json:"title"`
+	Deleted_at time.Time `json:"deleted_at"`
+	Purge_at   time.Time `json:"purge_at"`
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const XAPIVersion = "1.0.3"
+
+const (
+	XAPIVerbLaunched   = "http://adlnet.gov/expapi/verbs/launched"
+	XAPIVerbProgressed = "http://adlnet.gov/expapi/verbs/progressed"
+	XAPIVerbCompleted  = "http://adlnet.gov/expapi/verbs/completed"
+	XAPIVerbAnswered   = "http://adlnet.gov/expapi/verbs/answered"
+	XAPIVerbVoided     = "http://adlnet.gov/expapi/verbs/voided"
+)
+
+const (
+	XAPIActivityLesson      = "http://adlnet.gov/expapi/activities/lesson"
+	XAPIActivityAssessment  = "http://adlnet.gov/expapi/activities/assessment"
+	XAPIActivityInteraction = "http://adlnet.gov/expapi/activities/cmi.interaction"
+)
+
+type XAPIAccount struct {
+	Home_page string `json:"homePage"`
+	Name      string `json:"name"`
+}
+
+// XAPIAgent — агент xAPI; должен быть ровно один идентификатор: mbox, mbox_sha1sum, openid или account
+type XAPIAgent struct {
+	Object_type  string       `json:"objectType,omitempty"`
+	Name         string       `json:"name,omitempty"`
+	Mbox         string       `json:"mbox,omitempty"`
+	Mbox_sha1sum string       `json:"mbox_sha1sum,omitempty"`
+	Openid       string       `json:"openid,omitempty"`
+	Account      *XAPIAccount `json:"account,omitempty"`
+}
+
+type XAPIVerb struct {
+	Id      string            `json:"id"`
+	Display map[string]string `json:"display,omitempty"`
+}
+
+type XAPIActivityDefinition struct {
+	Name map[string]string `json:"name,omitempty"`
+	Type string            `json:"type,omitempty"`
+}
+
+// XAPIObject — объект утверждения; из него индексируются только Activity и StatementRef
+type XAPIObject struct {
+	Object_type string                  `json:"objectType,omitempty"`
+	Id          string                  `json:"id,omitempty"`
+	Definition  *XAPIActivityDefinition `json:"definition,omitempty"`
+}
+
+type XAPIScore struct {
+	Scaled *float64 `json:"scaled,omitempty"`
+	Raw    *float64 `json:"raw,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+	Max    *float64 `json:"max,omitempty"`
+}
+
+type XAPIResult struct {
+	Score      *XAPIScore     `json:"score,omitempty"`
+	Success    *bool          `json:"success,omitempty"`
+	Completion *bool          `json:"completion,omitempty"`
+	Response   string         `json:"response,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// XAPIStatement — поля утверждения, которые сервер проверяет и индексирует.
+// Остальные поля присланного утверждения хранятся как есть.
+type XAPIStatement struct {
+	Id        string          `json:"id,omitempty"`
+	Actor     XAPIAgent       `json:"actor"`
+	Verb      XAPIVerb        `json:"verb"`
+	Object    XAPIObject      `json:"object"`
+	Result    *XAPIResult     `json:"result,omitempty"`
+	Context   json.RawMessage `json:"context,omitempty"`
+	Timestamp *time.Time      `json:"timestamp,omitempty"`
+	Stored    *time.Time      `json:"stored,omitempty"`
+	Authority *XAPIAgent      `json:"authority,omitempty"`
+	Version   string          `json:"version,omitempty"`
+}
+
+// XAPIRecord — утверждение в хранилище вместе с полями для фильтрации
+type XAPIRecord struct {
+	Id          uuid.UUID
+	Statement   json.RawMessage
+	Actor_key   string
+	Verb_id     string
+	Object_type string
+	Object_id   string
+	Timestamp   time.Time
+	Stored      time.Time
+	User_uuid   *uuid.UUID // кто записал утверждение
+}
+
+// XAPIFilter — параметры GET /xapi/statements
+type XAPIFilter struct {
+	Actor_key   string
+	Verb_id     string
+	Activity_id string
+	Since       *time.Time
+	Until       *time.Time
+	Limit       int
+	Offset      int
+	Ascending   bool
+	// ученик видит только утверждения о себе и записанные им самим
+	Owner_uuid *uuid.UUID
+	Owner_key  string
+}
+
+type XAPIStatementResult struct {
+	Statements []json.RawMessage `json:"statements"`
+	More       string            `json:"more"`
+}
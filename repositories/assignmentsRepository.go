@@ -0,0 +1,334 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrAssignmentHasSubmissions = errors.New("assignment already has submissions")
+	ErrNoSubmissionsLeft        = errors.New("no resubmissions left")
+)
+
+type AssignmentsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAssignmentsRepository(conn *pgxpool.Pool) *AssignmentsRepository {
+	return &AssignmentsRepository{db: conn}
+}
+
+const assignmentColumns = `
+	id, title, instructions, lesson_id, course_id, due_at, accept_late, max_submissions, max_score, rubric,
+	created_by, created_at, updated_at
+	from assignments
+	`
+
+func scanAssignments(rows pgx.Rows) ([]models.Assignment, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	assignments := make([]models.Assignment, 0)
+	for rows.Next() {
+		var a models.Assignment
+		err := rows.Scan(&a.Id, &a.Title, &a.Instructions, &a.Lesson_id, &a.Course_id, &a.Due_at, &a.Accept_late, &a.Max_submissions, &a.Max_score, &a.Rubric,
+			&a.Created_by, &a.Created_at, &a.Updated_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		assignments = append(assignments, a)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+func (r *AssignmentsRepository) FindById(c context.Context, id int) (models.Assignment, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+assignmentColumns+` where id = $1`, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Assignment{}, err
+	}
+
+	assignments, err := scanAssignments(rows)
+	if err != nil {
+		return models.Assignment{}, err
+	}
+	if len(assignments) == 0 {
+		return models.Assignment{}, pgx.ErrNoRows
+	}
+	return assignments[0], nil
+}
+
+func (r *AssignmentsRepository) FindByLesson(c context.Context, lessonId int) ([]models.Assignment, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+assignmentColumns+` where lesson_id = $1 order by due_at nulls last, id`, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanAssignments(rows)
+}
+
+func (r *AssignmentsRepository) FindByCourse(c context.Context, courseId int) ([]models.Assignment, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+assignmentColumns+` where course_id = $1 order by due_at nulls last, id`, courseId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanAssignments(rows)
+}
+
+func (r *AssignmentsRepository) Create(c context.Context, assignment models.Assignment) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	err := r.db.QueryRow(c,
+		`
+	insert into assignments (title, instructions, lesson_id, course_id, due_at, accept_late, max_submissions, max_score, rubric, created_by)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	returning id
+	`,
+		assignment.Title, assignment.Instructions, assignment.Lesson_id, assignment.Course_id, assignment.Due_at,
+		assignment.Accept_late, assignment.Max_submissions, assignment.Max_score, assignment.Rubric, assignment.Created_by,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update меняет задание. Уже выставленные оценки не пересчитываются.
+func (r *AssignmentsRepository) Update(c context.Context, id int, assignment models.Assignment) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c,
+		`
+	update assignments
+	set title = $1, instructions = $2, lesson_id = $3, course_id = $4, due_at = $5, accept_late = $6,
+		max_submissions = $7, max_score = $8, rubric = $9, updated_at = now()
+	where id = $10
+	`,
+		assignment.Title, assignment.Instructions, assignment.Lesson_id, assignment.Course_id, assignment.Due_at,
+		assignment.Accept_late, assignment.Max_submissions, assignment.Max_score, assignment.Rubric, id,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Delete удаляет задание без сданных работ. ErrAssignmentHasSubmissions, если работы уже есть.
+func (r *AssignmentsRepository) Delete(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	tag, err := r.db.Exec(c, "delete from assignments where id = $1 and not exists (select 1 from assignment_submissions where assignment_id = $1)", id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAssignmentHasSubmissions
+	}
+	return nil
+}
+
+const submissionColumns = `
+	s.id, s.assignment_id, s.user_uuid, concat_ws(' ', u.user_name, u.user_surname), s.number, s.text,
+	coalesce((
+		select json_agg(json_build_object('media_id', m.id, 'filename', m.filename, 'mime_type', m.mime_type, 'size', m.size) order by sf.position)
+		from submission_files sf
+		join media m on m.id = sf.media_id
+		where sf.submission_id = s.id
+	), '[]'),
+	s.late, s.status, s.score, coalesce(s.rubric_scores, '[]'), s.feedback, s.graded_by, s.graded_at, s.submitted_at
+	from assignment_submissions s
+	join users u on u.uuid = s.user_uuid
+	`
+
+func scanSubmissions(rows pgx.Rows) ([]models.AssignmentSubmission, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	submissions := make([]models.AssignmentSubmission, 0)
+	for rows.Next() {
+		var s models.AssignmentSubmission
+		err := rows.Scan(&s.Id, &s.Assignment_id, &s.User_uuid, &s.User_name, &s.Number, &s.Text, &s.Files,
+			&s.Late, &s.Status, &s.Score, &s.Rubric_scores, &s.Feedback, &s.Graded_by, &s.Graded_at, &s.Submitted_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		submissions = append(submissions, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return submissions, nil
+}
+
+func (r *AssignmentsRepository) FindSubmission(c context.Context, id int) (models.AssignmentSubmission, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+submissionColumns+` where s.id = $1`, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+
+	submissions, err := scanSubmissions(rows)
+	if err != nil {
+		return models.AssignmentSubmission{}, err
+	}
+	if len(submissions) == 0 {
+		return models.AssignmentSubmission{}, pgx.ErrNoRows
+	}
+	return submissions[0], nil
+}
+
+// FindSubmissions возвращает работы по заданию; userUUID = nil — работы всех учеников
+func (r *AssignmentsRepository) FindSubmissions(c context.Context, assignmentId int, userUUID *uuid.UUID) ([]models.AssignmentSubmission, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+submissionColumns+`
+	where s.assignment_id = $1 and ($2::uuid is null or s.user_uuid = $2)
+	order by u.user_surname, u.user_name, s.number
+	`, assignmentId, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanSubmissions(rows)
+}
+
+// CountSubmissions возвращает, сколько работ ученик уже отправил по заданию
+func (r *AssignmentsRepository) CountSubmissions(c context.Context, assignmentId int, userUUID uuid.UUID) (int, error) {
+	logger := logger.GetLogger()
+
+	var submitted int
+	err := r.db.QueryRow(c, "select count(*) from assignment_submissions where assignment_id = $1 and user_uuid = $2", assignmentId, userUUID).Scan(&submitted)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return submitted, nil
+}
+
+// Submit сохраняет новую работу ученика с уже загруженными файлами.
+// ErrNoSubmissionsLeft, если лимит пересдач исчерпан.
+func (r *AssignmentsRepository) Submit(c context.Context, assignment models.Assignment, submission models.AssignmentSubmission) (models.AssignmentSubmission, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+	defer tx.Rollback(c)
+
+	// параллельная отправка двух работ обошла бы лимит пересдач
+	if _, err := tx.Exec(c, "select pg_advisory_xact_lock($1, hashtext($2))", assignment.Id, submission.User_uuid.String()); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+
+	var submitted int
+	err = tx.QueryRow(c, "select count(*) from assignment_submissions where assignment_id = $1 and user_uuid = $2", assignment.Id, submission.User_uuid).Scan(&submitted)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+	if assignment.Max_submissions != nil && submitted >= *assignment.Max_submissions {
+		return models.AssignmentSubmission{}, ErrNoSubmissionsLeft
+	}
+
+	var id int
+	err = tx.QueryRow(c,
+		`
+	insert into assignment_submissions (assignment_id, user_uuid, number, text, late, status)
+	values ($1, $2, $3, $4, $5, 'submitted')
+	returning id
+	`,
+		assignment.Id, submission.User_uuid, submitted+1, submission.Text, submission.Late,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+
+	for position, file := range submission.Files {
+		_, err := tx.Exec(c, "insert into submission_files (submission_id, media_id, position) values ($1, $2, $3)", id, file.Media_id, position)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return models.AssignmentSubmission{}, err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+
+	return r.FindSubmission(c, id)
+}
+
+// Grade выставляет или исправляет оценку и в той же транзакции уведомляет ученика
+func (r *AssignmentsRepository) Grade(c context.Context, submission models.AssignmentSubmission, notification models.Notification) (models.AssignmentSubmission, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c,
+		`
+	update assignment_submissions
+	set status = 'graded', score = $2, rubric_scores = $3, feedback = $4, graded_by = $5, graded_at = now()
+	where id = $1
+	`,
+		submission.Id, submission.Score, submission.Rubric_scores, submission.Feedback, submission.Graded_by,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+
+	if err := notifyUser(c, tx, notification); err != nil {
+		return models.AssignmentSubmission{}, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.AssignmentSubmission{}, err
+	}
+
+	return r.FindSubmission(c, submission.Id)
+}
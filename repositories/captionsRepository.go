@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type CaptionsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCaptionsRepository(conn *pgxpool.Pool) *CaptionsRepository {
+	return &CaptionsRepository{db: conn}
+}
+
+func (r *CaptionsRepository) Create(c context.Context, caption models.LessonCaption) (int, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	// у урока может быть только одна дорожка по умолчанию
+	if caption.Is_default {
+		_, err = tx.Exec(c, "update lesson_captions set is_default = false where lesson_id = $1", caption.Lesson_id)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return 0, err
+		}
+	}
+
+	var id int
+	err = tx.QueryRow(c,
+		`
+	insert into lesson_captions (lesson_id, language, label, is_default, content)
+	values ($1, $2, $3, $4, $5)
+	on conflict (lesson_id, language) do update
+	set label = excluded.label, is_default = excluded.is_default, content = excluded.content, updated_at = now()
+	returning id
+	`,
+		caption.Lesson_id, caption.Language, caption.Label, caption.Is_default, caption.Content,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *CaptionsRepository) FindByLessonId(c context.Context, lessonId int) ([]models.LessonCaption, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c,
+		`
+	select id, lesson_id, language, label, is_default, created_at, updated_at
+	from lesson_captions
+	where lesson_id = $1
+	order by is_default desc, language
+	`, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	captions := make([]models.LessonCaption, 0)
+	for rows.Next() {
+		var caption models.LessonCaption
+		err := rows.Scan(&caption.Id, &caption.Lesson_id, &caption.Language, &caption.Label, &caption.Is_default, &caption.Created_at, &caption.Updated_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		captions = append(captions, caption)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return captions, nil
+}
+
+func (r *CaptionsRepository) FindByLanguage(c context.Context, lessonId int, language string) (models.LessonCaption, error) {
+	logger := logger.GetLogger()
+
+	var caption models.LessonCaption
+	row := r.db.QueryRow(c,
+		`
+	select id, lesson_id, language, label, is_default, content, created_at, updated_at
+	from lesson_captions
+	where lesson_id = $1 and language = $2
+	`, lessonId, language)
+	err := row.Scan(&caption.Id, &caption.Lesson_id, &caption.Language, &caption.Label, &caption.Is_default, &caption.Content, &caption.Created_at, &caption.Updated_at)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.LessonCaption{}, err
+	}
+
+	return caption, nil
+}
+
+func (r *CaptionsRepository) Delete(c context.Context, lessonId int, language string) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c, "delete from lesson_captions where lesson_id = $1 and language = $2", lessonId, language)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// сколько сертификатов генерируется за один проход фоновой задачи
+const certificateRenderBatch = 50
+
+// ErrRenderSkipped — render отказался генерировать сертификат, он остается без файла до следующей попытки
+var ErrRenderSkipped = errors.New("certificate rendering skipped")
+
+type CertificatesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCertificatesRepository(conn *pgxpool.Pool) *CertificatesRepository {
+	return &CertificatesRepository{db: conn}
+}
+
+const certificateColumns = `
+	id, serial, user_uuid, student_name, course_id, course_title, media_id, issued_at
+	from certificates
+	`
+
+func scanCertificates(rows pgx.Rows) ([]models.Certificate, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	certificates := make([]models.Certificate, 0)
+	for rows.Next() {
+		var cert models.Certificate
+		err := rows.Scan(&cert.Id, &cert.Serial, &cert.User_uuid, &cert.Student_name, &cert.Course_id, &cert.Course_title, &cert.Media_id, &cert.Issued_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		certificates = append(certificates, cert)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return certificates, nil
+}
+
+func (r *CertificatesRepository) FindByUser(c context.Context, userUUID uuid.UUID) ([]models.Certificate, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+certificateColumns+` where user_uuid = $1 order by issued_at desc`, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanCertificates(rows)
+}
+
+func (r *CertificatesRepository) FindBySerial(c context.Context, serial string) (models.Certificate, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+certificateColumns+` where serial = $1`, serial)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Certificate{}, err
+	}
+
+	certificates, err := scanCertificates(rows)
+	if err != nil {
+		return models.Certificate{}, err
+	}
+	if len(certificates) == 0 {
+		return models.Certificate{}, pgx.ErrNoRows
+	}
+	return certificates[0], nil
+}
+
+// RenderPending генерирует PDF для выданных сертификатов без файла и уведомляет учеников.
+// render сохраняет файл и возвращает метаданные для таблицы media; ErrRenderSkipped пропускает сертификат,
+// не прерывая проход. У пропущенного сертификата запоминается контрольная сумма шрифта fontChecksum:
+// с тем же шрифтом он больше не выбирается, после смены шрифта попадет в очередь снова.
+func (r *CertificatesRepository) RenderPending(c context.Context, fontChecksum string, render func(models.Certificate) (models.Media, error)) (int, bool, error) {
+	logger := logger.GetLogger()
+
+	rendered := 0
+	acquired, err := WithAdvisoryLock(c, r.db, AdvisoryLockCertificates, func(tx pgx.Tx) error {
+		rows, err := tx.Query(c, `select `+certificateColumns+` where media_id is null and skipped_font is distinct from $2 order by issued_at limit $1`,
+			certificateRenderBatch, fontChecksum)
+		if err != nil {
+			logger.Error("could not query database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		certificates, err := scanCertificates(rows)
+		if err != nil {
+			return err
+		}
+
+		for _, certificate := range certificates {
+			media, err := render(certificate)
+			if errors.Is(err, ErrRenderSkipped) {
+				if _, err := tx.Exec(c, "update certificates set skipped_font = $2 where id = $1", certificate.Id, fontChecksum); err != nil {
+					logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("certificate %s: %w", certificate.Serial, err)
+			}
+
+			var mediaId int
+			err = tx.QueryRow(c,
+				"insert into media (filename, mime_type, size, checksum) values ($1, $2, $3, $4) returning id",
+				media.Filename, media.Mime_type, media.Size, media.Checksum,
+			).Scan(&mediaId)
+			if err != nil {
+				logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+				return err
+			}
+
+			if _, err := tx.Exec(c, "update certificates set media_id = $2, skipped_font = null where id = $1", certificate.Id, mediaId); err != nil {
+				logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+				return err
+			}
+
+			err = notifyUser(c, tx, models.Notification{
+				User_uuid:   certificate.User_uuid,
+				Type:        models.NotificationCertificateIssued,
+				Title:       "Certificate issued",
+				Body:        fmt.Sprintf("Your certificate for %q is ready, serial %s", certificate.Course_title, certificate.Serial),
+				Entity_type: models.EntityCertificate,
+				Entity_id:   certificate.Id,
+			})
+			if err != nil {
+				return err
+			}
+			rendered++
+		}
+		return nil
+	})
+
+	return rendered, acquired, err
+}
+
+// issueCertificate выдает сертификат о прохождении курса в транзакции завершения курса.
+// Повторное завершение сертификат не меняет; PDF генерирует фоновая задача.
+func issueCertificate(c context.Context, tx pgx.Tx, courseId int, userUUID uuid.UUID) error {
+	logger := logger.GetLogger()
+
+	serial, err := utils.GenerateCertificateSerial()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(c,
+		`
+	insert into certificates (serial, user_uuid, student_name, course_id, course_title)
+	select $1, u.uuid, concat_ws(' ', u.user_name, u.user_surname), co.id, co.name
+	from users u, courses co
+	where u.uuid = $2 and co.id = $3
+	on conflict (user_uuid, course_id) do nothing
+	`,
+		serial, userUUID, courseId,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
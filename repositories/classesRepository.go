@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrUnknownClassMember = errors.New("class member does not exist")
+
+type ClassesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewClassesRepository(conn *pgxpool.Pool) *ClassesRepository {
+	return &ClassesRepository{db: conn}
+}
+
+// удаленные пользователи в составе класса не считаются
+const classColumns = `
+	cl.id, cl.name,
+	(select count(*) from class_members cm join users u on u.uuid = cm.user_uuid and u.deleted_at is null where cm.class_id = cl.id),
+	cl.created_by, cl.created_at, cl.updated_at
+	from classes cl
+	`
+
+func scanClasses(rows pgx.Rows) ([]models.Class, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	classes := make([]models.Class, 0)
+	for rows.Next() {
+		var class models.Class
+		if err := rows.Scan(&class.Id, &class.Name, &class.Member_count, &class.Created_by, &class.Created_at, &class.Updated_at); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		classes = append(classes, class)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return classes, nil
+}
+
+// FindAll возвращает классы по имени; member ограничивает список классами, в которых состоит ученик
+func (r *ClassesRepository) FindAll(c context.Context, member *uuid.UUID) ([]models.Class, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+classColumns+`
+	where $1::uuid is null or exists (select 1 from class_members cm where cm.class_id = cl.id and cm.user_uuid = $1)
+	order by cl.name, cl.id`, member)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanClasses(rows)
+}
+
+// FindById возвращает класс вместе с составом
+func (r *ClassesRepository) FindById(c context.Context, id int) (models.Class, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+classColumns+` where cl.id = $1`, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Class{}, err
+	}
+
+	classes, err := scanClasses(rows)
+	if err != nil {
+		return models.Class{}, err
+	}
+	if len(classes) == 0 {
+		return models.Class{}, pgx.ErrNoRows
+	}
+	class := classes[0]
+
+	rows, err = r.db.Query(c, `
+	select u.uuid, u.user_name, u.user_surname
+	from class_members cm
+	join users u on u.uuid = cm.user_uuid and u.deleted_at is null
+	where cm.class_id = $1
+	order by u.user_surname, u.user_name, u.uuid`, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Class{}, err
+	}
+	defer rows.Close()
+
+	class.Members = make([]models.ClassMember, 0)
+	for rows.Next() {
+		var member models.ClassMember
+		if err := rows.Scan(&member.User_uuid, &member.Name, &member.Surname); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.Class{}, err
+		}
+
+		class.Members = append(class.Members, member)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return models.Class{}, err
+	}
+
+	return class, nil
+}
+
+// IsMember проверяет, что ученик состоит в классе
+func (r *ClassesRepository) IsMember(c context.Context, classId int, userUUID uuid.UUID) (bool, error) {
+	logger := logger.GetLogger()
+
+	var member bool
+	err := r.db.QueryRow(c,
+		"select exists (select 1 from class_members where class_id = $1 and user_uuid = $2)",
+		classId, userUUID).Scan(&member)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return false, err
+	}
+	return member, nil
+}
+
+// Create создает класс с составом. ErrUnknownClassMember, если кого-то из учеников нет.
+func (r *ClassesRepository) Create(c context.Context, class models.Class, members []uuid.UUID) (int, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var id int
+	err = tx.QueryRow(c,
+		"insert into classes (name, created_by) values ($1, $2) returning id",
+		class.Name, class.Created_by,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	if err := replaceClassMembers(c, tx, id, members); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update переименовывает класс и заменяет его состав
+func (r *ClassesRepository) Update(c context.Context, id int, class models.Class, members []uuid.UUID) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c, "update classes set name = $1, updated_at = now() where id = $2", class.Name, id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := replaceClassMembers(c, tx, id, members); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// replaceClassMembers сохраняет состав класса; members без повторов
+func replaceClassMembers(c context.Context, tx pgx.Tx, classId int, members []uuid.UUID) error {
+	logger := logger.GetLogger()
+
+	if _, err := tx.Exec(c, "delete from class_members where class_id = $1", classId); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	tag, err := tx.Exec(c, `
+	insert into class_members (class_id, user_uuid)
+	select $1, u.uuid from users u
+	where u.uuid = any($2) and u.deleted_at is null`,
+		classId, members)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if int(tag.RowsAffected()) != len(members) {
+		return ErrUnknownClassMember
+	}
+	return nil
+}
+
+// Delete удаляет класс вместе с составом; сами ученики и их очки остаются
+func (r *ClassesRepository) Delete(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err := tx.Exec(c, "delete from class_members where class_id = $1", id); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	tag, err := tx.Exec(c, "delete from classes where id = $1", id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type CompletionsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCompletionsRepository(conn *pgxpool.Pool) *CompletionsRepository {
+	return &CompletionsRepository{db: conn}
+}
+
+// MarkLessonCompleted отмечает урок пройденным, повторная отметка не меняет дату прохождения.
+// Активные записи на курсы, где пройдены все уроки, переводятся в completed.
+func (r *CompletionsRepository) MarkLessonCompleted(c context.Context, userUUID uuid.UUID, lessonId int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if err := completeLesson(c, tx, userUUID, lessonId); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// completeLesson отмечает урок и закрывает пройденные курсы в транзакции вызывающего.
+// За пройденный курс выдается сертификат, место выпускника получает первый из очереди курса.
+// Опыт начисляется только за первое прохождение урока.
+func completeLesson(c context.Context, tx pgx.Tx, userUUID uuid.UUID, lessonId int) error {
+	logger := logger.GetLogger()
+
+	// курсы блокируются первыми и по возрастанию id, как при записи и смене статуса записи,
+	// иначе встречная запись на курс ловит deadlock на enrollments
+	rows, err := tx.Query(c, `
+	select co.id from courses co
+	join enrollments e on e.course_id = co.id and e.user_uuid = $1 and e.status = 'active'
+	where co.deleted_at is null
+		and co.id in (select course_id from course_lessons where lesson_id = $2)
+	order by co.id
+	for update of co`,
+		userUUID, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	lockedIds, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	tag, err := tx.Exec(c,
+		`insert into lesson_completions (user_uuid, lesson_id) values ($1, $2)
+		on conflict (user_uuid, lesson_id) do nothing`,
+		userUUID, lessonId)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		err := applyGamificationEvent(c, tx, models.GamificationEvent{
+			User_uuid:    userUUID,
+			Type:         models.GamificationLessonCompleted,
+			Entity_id:    lessonId,
+			Xp:           utils.XPLessonCompleted,
+			Learner_made: true,
+		})
+		if err != nil {
+			return err
+		}
+		if err := bumpLessonProgress(c, tx, userUUID, lessonId, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if len(lockedIds) == 0 {
+		return nil
+	}
+
+	rows, err = tx.Query(c, `
+	update enrollments e
+	set status = 'completed', updated_at = now()
+	where e.user_uuid = $1 and e.status = 'active'
+		and e.course_id = any($2)
+		and `+fmt.Sprintf(courseCompletedCondition, "e.course_id")+`
+	returning e.course_id`,
+		userUUID, lockedIds)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	courseIds, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	for _, courseId := range courseIds {
+		if err := issueCertificate(c, tx, courseId, userUUID); err != nil {
+			return err
+		}
+		if err := applyGamificationEvent(c, tx, courseCompletedEvent(userUUID, courseId, true)); err != nil {
+			return err
+		}
+		if err := promoteWaitlist(c, tx, courseId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindCompletedLessons возвращает множество пройденных учеником уроков среди переданных
+func (r *CompletionsRepository) FindCompletedLessons(c context.Context, userUUID uuid.UUID, lessonIds []int) (map[int]bool, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, "select lesson_id from lesson_completions where user_uuid = $1 and lesson_id = any($2)", userUUID, lessonIds)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	completed := make(map[int]bool)
+	for rows.Next() {
+		var lessonId int
+		if err := rows.Scan(&lessonId); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		completed[lessonId] = true
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return completed, nil
+}
@@ -2,9 +2,12 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"go-EdTech/logger"
 	"go-EdTech/models"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -13,6 +16,11 @@ type Coursesrepository struct {
 	db *pgxpool.Pool
 }
 
+// курс виден ученикам, если опубликован и текущее время внутри окна публикации
+const visibleCourseCondition = `is_published
+	and (publish_at is null or publish_at <= now())
+	and (unpublish_at is null or unpublish_at > now())`
+
 func NewCoursesRepository(conn *pgxpool.Pool) *Coursesrepository {
 	return &Coursesrepository{db: conn}
 }
@@ -20,12 +28,14 @@ func NewCoursesRepository(conn *pgxpool.Pool) *Coursesrepository {
 func (r *Coursesrepository) Create(c context.Context, course models.Course) (int, error) {
 	logger := logger.GetLogger()
 
-	row := r.db.QueryRow(c, `insert into courses (name, description, is_published) values ($1, $2, $3) returning id`, course.Name, course.Description, course.Is_published)
+	row := r.db.QueryRow(c, `insert into courses (name, description, enrollment, capacity, is_published, status) values ($1, $2, coalesce(nullif($3, ''), 'open'), $4, false, 'draft') returning id`, course.Name, course.Description, course.Enrollment, course.Capacity)
 	err := row.Scan(&course.Id)
 	if err != nil {
 		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
 		return 0, err
 	}
+
+	refreshSearchVector(c, r.db, models.EntityCourse, course.Id)
 	return course.Id, nil
 }
 
@@ -33,18 +43,28 @@ func (r *Coursesrepository) FindById(c context.Context, courseId int) (models.Co
 	logger := logger.GetLogger()
 
 	var course models.Course
-	row := r.db.QueryRow(c, `select id, name, description, is_published, created_at, updated_at from courses where id = $1`, courseId)
-	if err := row.Scan(&course.Id, &course.Name, &course.Description, &course.Is_published, &course.Created_at, &course.Updated_at); err != nil {
+	row := r.db.QueryRow(c, `select id, name, description, enrollment, capacity, is_published, status, publish_at, unpublish_at, created_at, updated_at from courses where id = $1 and deleted_at is null`, courseId)
+	if err := row.Scan(&course.Id, &course.Name, &course.Description, &course.Enrollment, &course.Capacity, &course.Is_published, &course.Status, &course.Publish_at, &course.Unpublish_at, &course.Created_at, &course.Updated_at); err != nil {
 		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
 		return models.Course{}, err
 	}
 	return course, nil
 }
 
-func (r *Coursesrepository) FindAll(c context.Context) ([]models.Course, error) {
+func (r *Coursesrepository) FindAll(c context.Context, filter models.CourseFilter) ([]models.Course, error) {
 	logger := logger.GetLogger()
 
-	rows, err := r.db.Query(c, `select id, name, description, is_published, created_at, updated_at from courses`)
+	sql := `select id, name, description, enrollment, capacity, is_published, status, publish_at, unpublish_at, created_at, updated_at from courses where deleted_at is null`
+	args := make([]any, 0)
+	if filter.Visible_only {
+		sql += ` and ` + visibleCourseCondition
+	}
+	for _, group := range filter.Tag_groups {
+		args = append(args, group)
+		sql += fmt.Sprintf(` and exists (select 1 from course_tags ct where ct.entity_id = courses.id and ct.tag_id = any($%d))`, len(args))
+	}
+
+	rows, err := r.db.Query(c, sql, args...)
 	if err != nil {
 		logger.Error("could not query database", zap.String("db_msg", err.Error()))
 		return []models.Course{}, err
@@ -55,7 +75,7 @@ func (r *Coursesrepository) FindAll(c context.Context) ([]models.Course, error)
 
 	for rows.Next() {
 		var course models.Course
-		err := rows.Scan(&course.Id, &course.Name, &course.Description, &course.Is_published, &course.Created_at, &course.Updated_at)
+		err := rows.Scan(&course.Id, &course.Name, &course.Description, &course.Enrollment, &course.Capacity, &course.Is_published, &course.Status, &course.Publish_at, &course.Unpublish_at, &course.Created_at, &course.Updated_at)
 		if err != nil {
 			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
 			return nil, err
@@ -67,24 +87,147 @@ func (r *Coursesrepository) FindAll(c context.Context) ([]models.Course, error)
 	return courses, nil
 }
 
+// Update меняет курс; если мест стало больше, они сразу достаются ученикам из очереди
 func (r *Coursesrepository) Update(c context.Context, id int, Updcourse models.Course) error {
 	logger := logger.GetLogger()
 
-	_, err := r.db.Exec(c, "update courses set name = $1, description = $2, is_published = $3 where id = $4", Updcourse.Name, Updcourse.Description, Updcourse.Is_published, id)
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c,
+		"update courses set name = $1, description = $2, enrollment = $3, capacity = $4, search_vector = null, updated_at = now() where id = $5",
+		Updcourse.Name, Updcourse.Description, Updcourse.Enrollment, Updcourse.Capacity, id)
 	if err != nil {
 		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
 		return err
 	}
+
+	if err := promoteWaitlist(c, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	refreshSearchVector(c, r.db, models.EntityCourse, id)
 	return nil
 }
 
+// UpdateStatus меняет статус публикации, is_published остается для старых клиентов
+func (r *Coursesrepository) UpdateStatus(c context.Context, id int, status string) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c, "update courses set status = $1, is_published = ($1 = 'published'), updated_at = now() where id = $2", status, id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// SetSchedule задает окно публикации, переходы статусов выполняет фоновый планировщик
+func (r *Coursesrepository) SetSchedule(c context.Context, id int, publishAt *time.Time, unpublishAt *time.Time) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c, "update courses set publish_at = $1, unpublish_at = $2, updated_at = now() where id = $3", publishAt, unpublishAt, id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Delete переносит курс в корзину, окончательно его удалит фоновая очистка корзины
 func (r *Coursesrepository) Delete(c context.Context, id int) error {
 	logger := logger.GetLogger()
 
-	_, err := r.db.Exec(c, "delete from courses where id = $1", id)
+	_, err := r.db.Exec(c, "update courses set deleted_at = now() where id = $1 and deleted_at is null", id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// FindLessons возвращает уроки курса в порядке прохождения
+func (r *Coursesrepository) FindLessons(c context.Context, courseId int, visibleOnly bool) ([]models.CourseLesson, error) {
+	logger := logger.GetLogger()
+
+	sql := `
+	select cl.course_id, cl.lesson_id, l.lesson_title, cl.position, l.is_published
+	from course_lessons cl
+	join lessons l on l.lesson_id = cl.lesson_id
+	where cl.course_id = $1 and l.deleted_at is null`
+	if visibleOnly {
+		sql += ` and ` + visibleLessonCondition
+	}
+	sql += ` order by cl.position`
+
+	rows, err := r.db.Query(c, sql, courseId)
 	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	lessons := make([]models.CourseLesson, 0)
+	for rows.Next() {
+		var lesson models.CourseLesson
+		err := rows.Scan(&lesson.Course_id, &lesson.Lesson_id, &lesson.Title, &lesson.Position, &lesson.Is_published)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		lessons = append(lessons, lesson)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return lessons, nil
+}
+
+// SetLessons заменяет состав курса, позиции выставляются в порядке переданных id
+func (r *Coursesrepository) SetLessons(c context.Context, courseId int, lessonIds []int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err := tx.Exec(c, "delete from course_lessons where course_id = $1", courseId); err != nil {
 		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
 		return err
 	}
+
+	for i, lessonId := range lessonIds {
+		tag, err := tx.Exec(c,
+			`insert into course_lessons (course_id, lesson_id, position)
+			select $1, lesson_id, $3 from lessons where lesson_id = $2 and deleted_at is null`,
+			courseId, lessonId, i+1)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("lesson %d not found: %w", lessonId, pgx.ErrNoRows)
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,323 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrCourseFull = errors.New("course has no free seats")
+
+type EnrollmentsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEnrollmentsRepository(conn *pgxpool.Pool) *EnrollmentsRepository {
+	return &EnrollmentsRepository{db: conn}
+}
+
+// место в очереди считается по времени постановки в очередь
+const enrollmentColumns = `
+	e.course_id,
+	co.name,
+	e.user_uuid,
+	concat_ws(' ', u.user_name, u.user_surname),
+	e.status,
+	case when e.status = 'waitlisted' then
+		(select count(*) from enrollments w where w.course_id = e.course_id and w.status = 'waitlisted' and w.enrolled_at <= e.enrolled_at)
+	end,
+	e.enrolled_by,
+	e.enrolled_at,
+	e.updated_at
+	from enrollments e
+	join courses co on co.id = e.course_id
+	join users u on u.uuid = e.user_uuid
+	`
+
+func scanEnrollments(rows pgx.Rows) ([]models.Enrollment, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	enrollments := make([]models.Enrollment, 0)
+	for rows.Next() {
+		var e models.Enrollment
+		err := rows.Scan(&e.Course_id, &e.Course_title, &e.User_uuid, &e.User_name, &e.Status, &e.Waitlist_position, &e.Enrolled_by, &e.Enrolled_at, &e.Updated_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		enrollments = append(enrollments, e)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return enrollments, nil
+}
+
+// FindByUser возвращает курсы ученика; пустой status — все статусы
+func (r *EnrollmentsRepository) FindByUser(c context.Context, userUUID uuid.UUID, status string) ([]models.Enrollment, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+enrollmentColumns+`
+	where e.user_uuid = $1 and co.deleted_at is null and ($2 = '' or e.status = $2)
+	order by e.enrolled_at desc
+	`, userUUID, status)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanEnrollments(rows)
+}
+
+// FindByCourse возвращает список учеников курса, очередь — в порядке записи
+func (r *EnrollmentsRepository) FindByCourse(c context.Context, courseId int, status string) ([]models.Enrollment, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+enrollmentColumns+`
+	where e.course_id = $1 and u.deleted_at is null and ($2 = '' or e.status = $2)
+	order by e.status, e.enrolled_at
+	`, courseId, status)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanEnrollments(rows)
+}
+
+func (r *EnrollmentsRepository) Find(c context.Context, courseId int, userUUID uuid.UUID) (models.Enrollment, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+enrollmentColumns+`
+	where e.course_id = $1 and e.user_uuid = $2
+	`, courseId, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Enrollment{}, err
+	}
+
+	enrollments, err := scanEnrollments(rows)
+	if err != nil {
+		return models.Enrollment{}, err
+	}
+	if len(enrollments) == 0 {
+		return models.Enrollment{}, pgx.ErrNoRows
+	}
+	return enrollments[0], nil
+}
+
+// Enroll записывает ученика на курс или ставит в очередь, если мест нет.
+// Повторная запись не меняет активную, завершенную запись или место в очереди.
+func (r *EnrollmentsRepository) Enroll(c context.Context, courseId int, userUUID uuid.UUID, enrolledBy *uuid.UUID) (models.Enrollment, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.Enrollment{}, err
+	}
+	defer tx.Rollback(c)
+
+	if err := enrollInCourse(c, tx, courseId, userUUID, enrolledBy); err != nil {
+		return models.Enrollment{}, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.Enrollment{}, err
+	}
+
+	return r.Find(c, courseId, userUUID)
+}
+
+// SetStatus меняет статус записи. Освободившееся место сразу получает первый из очереди,
+// при переводе в completed ученику выдается сертификат.
+// ErrCourseFull, если ученика переводят в active, а мест нет; pgx.ErrNoRows, если записи нет.
+func (r *EnrollmentsRepository) SetStatus(c context.Context, courseId int, userUUID uuid.UUID, status string) (models.Enrollment, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.Enrollment{}, err
+	}
+	defer tx.Rollback(c)
+
+	capacity, err := lockCourseSeats(c, tx, courseId)
+	if err != nil {
+		return models.Enrollment{}, err
+	}
+
+	current, err := currentEnrollmentStatus(c, tx, courseId, userUUID)
+	if err != nil {
+		return models.Enrollment{}, err
+	}
+	if current == "" {
+		return models.Enrollment{}, pgx.ErrNoRows
+	}
+
+	if current != status {
+		if status == models.EnrollmentActive {
+			free, err := hasFreeSeat(c, tx, courseId, capacity)
+			if err != nil {
+				return models.Enrollment{}, err
+			}
+			if !free {
+				return models.Enrollment{}, ErrCourseFull
+			}
+		}
+
+		// в очередь ставим в конец
+		_, err = tx.Exec(c,
+			`
+		update enrollments
+		set status = $3,
+			enrolled_at = case when $3 = 'waitlisted' then now() else enrolled_at end,
+			updated_at = now()
+		where course_id = $1 and user_uuid = $2
+		`,
+			courseId, userUUID, status,
+		)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return models.Enrollment{}, err
+		}
+
+		if current == models.EnrollmentActive {
+			if err := promoteWaitlist(c, tx, courseId); err != nil {
+				return models.Enrollment{}, err
+			}
+		}
+		if status == models.EnrollmentCompleted {
+			if err := issueCertificate(c, tx, courseId, userUUID); err != nil {
+				return models.Enrollment{}, err
+			}
+			if err := applyGamificationEvent(c, tx, courseCompletedEvent(userUUID, courseId, false)); err != nil {
+				return models.Enrollment{}, err
+			}
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.Enrollment{}, err
+	}
+
+	return r.Find(c, courseId, userUUID)
+}
+
+// enrollInCourse записывает ученика на курс в транзакции вызывающего или ставит в очередь, если мест нет.
+// Повторная запись не меняет активную, завершенную запись или место в очереди.
+func enrollInCourse(c context.Context, tx pgx.Tx, courseId int, userUUID uuid.UUID, enrolledBy *uuid.UUID) error {
+	logger := logger.GetLogger()
+
+	capacity, err := lockCourseSeats(c, tx, courseId)
+	if err != nil {
+		return err
+	}
+
+	current, err := currentEnrollmentStatus(c, tx, courseId, userUUID)
+	if err != nil {
+		return err
+	}
+
+	if current == "" || current == models.EnrollmentDropped {
+		status := models.EnrollmentWaitlisted
+		free, err := hasFreeSeat(c, tx, courseId, capacity)
+		if err != nil {
+			return err
+		}
+		if free {
+			status = models.EnrollmentActive
+		}
+
+		_, err = tx.Exec(c,
+			`
+		insert into enrollments (course_id, user_uuid, status, enrolled_by)
+		values ($1, $2, $3, $4)
+		on conflict (course_id, user_uuid) do update
+		set status = excluded.status, enrolled_by = excluded.enrolled_by, enrolled_at = now(), updated_at = now()
+		`,
+			courseId, userUUID, status, enrolledBy,
+		)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
+// lockCourseSeats блокирует курс до конца транзакции, чтобы параллельные записи не превысили лимит мест
+func lockCourseSeats(c context.Context, tx pgx.Tx, courseId int) (*int, error) {
+	var capacity *int
+	err := tx.QueryRow(c, "select capacity from courses where id = $1 and deleted_at is null for update", courseId).Scan(&capacity)
+	if err != nil {
+		logger.GetLogger().Error("could not scan query row", zap.String("db_msg", err.Error()))
+	}
+	return capacity, err
+}
+
+// currentEnrollmentStatus возвращает пустую строку, если ученик на курс не записывался
+func currentEnrollmentStatus(c context.Context, tx pgx.Tx, courseId int, userUUID uuid.UUID) (string, error) {
+	var status string
+	err := tx.QueryRow(c, "select status from enrollments where course_id = $1 and user_uuid = $2", courseId, userUUID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		logger.GetLogger().Error("could not scan query row", zap.String("db_msg", err.Error()))
+	}
+	return status, err
+}
+
+func hasFreeSeat(c context.Context, tx pgx.Tx, courseId int, capacity *int) (bool, error) {
+	if capacity == nil {
+		return true, nil
+	}
+
+	var active int
+	err := tx.QueryRow(c, "select count(*) from enrollments where course_id = $1 and status = 'active'", courseId).Scan(&active)
+	if err != nil {
+		logger.GetLogger().Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return false, err
+	}
+	return active < *capacity, nil
+}
+
+// promoteWaitlist переводит учеников из очереди в active, пока есть свободные места.
+// Курс должен быть заблокирован в этой транзакции.
+func promoteWaitlist(c context.Context, tx pgx.Tx, courseId int) error {
+	_, err := tx.Exec(c,
+		`
+	update enrollments
+	set status = 'active', updated_at = now()
+	where course_id = $1 and user_uuid in (
+		select w.user_uuid from enrollments w
+		where w.course_id = $1 and w.status = 'waitlisted'
+		order by w.enrolled_at
+		limit (
+			select case when co.capacity is null then null
+				else greatest(co.capacity - (select count(*) from enrollments a where a.course_id = $1 and a.status = 'active'), 0)
+			end
+			from courses co where co.id = $1
+		)
+	)
+	`,
+		courseId,
+	)
+	if err != nil {
+		logger.GetLogger().Error("could not execute in database", zap.String("db_msg", err.Error()))
+	}
+	return err
+}
@@ -0,0 +1,486 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type FlashcardsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFlashcardsRepository(conn *pgxpool.Pool) *FlashcardsRepository {
+	return &FlashcardsRepository{db: conn}
+}
+
+const flashcardDeckColumns = `
+	d.id, d.title, d.description, d.subject_id, d.lesson_id,
+	(select count(*) from flashcards f where f.deck_id = d.id),
+	d.created_by, d.created_at, d.updated_at
+	from flashcard_decks d
+	`
+
+// flashcardDeckCondition скрывает колоды удаленных уроков, а при visibleOnly — и неопубликованных
+func flashcardDeckCondition(visibleOnly bool) string {
+	lessonCondition := `l.deleted_at is null`
+	if visibleOnly {
+		lessonCondition += ` and ` + visibleLessonCondition
+	}
+	return `(d.lesson_id is null or exists (select 1 from lessons l where l.lesson_id = d.lesson_id and ` + lessonCondition + `))`
+}
+
+func scanFlashcardDecks(rows pgx.Rows) ([]models.FlashcardDeck, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	decks := make([]models.FlashcardDeck, 0)
+	for rows.Next() {
+		var d models.FlashcardDeck
+		err := rows.Scan(&d.Id, &d.Title, &d.Description, &d.Subject_id, &d.Lesson_id, &d.Cards_count, &d.Created_by, &d.Created_at, &d.Updated_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		decks = append(decks, d)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return decks, nil
+}
+
+func (r *FlashcardsRepository) FindDecks(c context.Context, filter models.FlashcardDeckFilter) ([]models.FlashcardDeck, error) {
+	logger := logger.GetLogger()
+
+	sql := `select ` + flashcardDeckColumns + ` where ` + flashcardDeckCondition(filter.Visible_only)
+	args := make([]any, 0)
+	if filter.Subject_id > 0 {
+		args = append(args, filter.Subject_id)
+		sql += fmt.Sprintf(` and (d.subject_id = $%d or d.lesson_id in (select lesson_id from lessons where subject_id = $%d))`, len(args), len(args))
+	}
+	if filter.Lesson_id > 0 {
+		args = append(args, filter.Lesson_id)
+		sql += fmt.Sprintf(` and d.lesson_id = $%d`, len(args))
+	}
+	sql += ` order by d.title, d.id`
+
+	rows, err := r.db.Query(c, sql, args...)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanFlashcardDecks(rows)
+}
+
+func (r *FlashcardsRepository) FindDeck(c context.Context, id int) (models.FlashcardDeck, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+flashcardDeckColumns+` where d.id = $1 and `+flashcardDeckCondition(false), id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.FlashcardDeck{}, err
+	}
+
+	decks, err := scanFlashcardDecks(rows)
+	if err != nil {
+		return models.FlashcardDeck{}, err
+	}
+	if len(decks) == 0 {
+		return models.FlashcardDeck{}, pgx.ErrNoRows
+	}
+	return decks[0], nil
+}
+
+func (r *FlashcardsRepository) CreateDeck(c context.Context, deck models.FlashcardDeck) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	err := r.db.QueryRow(c,
+		"insert into flashcard_decks (title, description, subject_id, lesson_id, created_by) values ($1, $2, $3, $4, $5) returning id",
+		deck.Title, deck.Description, deck.Subject_id, deck.Lesson_id, deck.Created_by,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *FlashcardsRepository) UpdateDeck(c context.Context, id int, deck models.FlashcardDeck) error {
+	logger := logger.GetLogger()
+
+	tag, err := r.db.Exec(c,
+		"update flashcard_decks set title = $1, description = $2, subject_id = $3, lesson_id = $4, updated_at = now() where id = $5",
+		deck.Title, deck.Description, deck.Subject_id, deck.Lesson_id, id,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteDeck удаляет колоду вместе с карточками и историей повторений учеников
+func (r *FlashcardsRepository) DeleteDeck(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	for _, sql := range []string{
+		"delete from flashcard_reviews where card_id in (select id from flashcards where deck_id = $1)",
+		"delete from flashcard_states where card_id in (select id from flashcards where deck_id = $1)",
+		"delete from flashcards where deck_id = $1",
+	} {
+		if _, err := tx.Exec(c, sql, id); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+	tag, err := tx.Exec(c, "delete from flashcard_decks where id = $1", id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+const flashcardColumns = `f.id, f.deck_id, f.front, f.back, f.position, f.created_at, f.updated_at`
+
+func scanFlashcard(row pgx.Row, card *models.Flashcard, extra ...any) error {
+	return row.Scan(append([]any{&card.Id, &card.Deck_id, &card.Front, &card.Back, &card.Position, &card.Created_at, &card.Updated_at}, extra...)...)
+}
+
+func (r *FlashcardsRepository) FindCards(c context.Context, deckId int) ([]models.Flashcard, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+flashcardColumns+` from flashcards f where f.deck_id = $1 order by f.position, f.id`, deckId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := make([]models.Flashcard, 0)
+	for rows.Next() {
+		var card models.Flashcard
+		if err := scanFlashcard(rows, &card); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		cards = append(cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return cards, nil
+}
+
+func (r *FlashcardsRepository) FindCard(c context.Context, id int) (models.Flashcard, error) {
+	logger := logger.GetLogger()
+
+	var card models.Flashcard
+	err := scanFlashcard(r.db.QueryRow(c, `select `+flashcardColumns+` from flashcards f where f.id = $1`, id), &card)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		}
+		return models.Flashcard{}, err
+	}
+	return card, nil
+}
+
+// CreateCard добавляет карточку в конец колоды
+func (r *FlashcardsRepository) CreateCard(c context.Context, card models.Flashcard) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	err := r.db.QueryRow(c,
+		`
+	insert into flashcards (deck_id, front, back, position)
+	select $1, $2, $3, coalesce(max(position), 0) + 1 from flashcards where deck_id = $1
+	returning id
+	`,
+		card.Deck_id, card.Front, card.Back,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *FlashcardsRepository) UpdateCard(c context.Context, id int, card models.Flashcard) error {
+	logger := logger.GetLogger()
+
+	tag, err := r.db.Exec(c, "update flashcards set front = $1, back = $2, updated_at = now() where id = $3", card.Front, card.Back, id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *FlashcardsRepository) DeleteCard(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	for _, sql := range []string{
+		"delete from flashcard_reviews where card_id = $1",
+		"delete from flashcard_states where card_id = $1",
+	} {
+		if _, err := tx.Exec(c, sql, id); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+	tag, err := tx.Exec(c, "delete from flashcards where id = $1", id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+const flashcardStateColumns = `s.user_uuid, s.card_id, s.repetitions, s.interval_days, s.ease, s.lapses, s.due_at, s.last_reviewed_at`
+
+func flashcardStateTargets(state *models.FlashcardState) []any {
+	return []any{&state.User_uuid, &state.Card_id, &state.Repetitions, &state.Interval_days, &state.Ease, &state.Lapses, &state.Due_at, &state.Last_reviewed_at}
+}
+
+// Review записывает оценку ученика и переносит следующее повторение карточки по SM-2
+func (r *FlashcardsRepository) Review(c context.Context, userUUID uuid.UUID, cardId int, grade int) (models.FlashcardState, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.FlashcardState{}, err
+	}
+	defer tx.Rollback(c)
+
+	now := time.Now()
+	state := utils.NewFlashcardState(userUUID, cardId, now)
+	err = tx.QueryRow(c,
+		`select `+flashcardStateColumns+` from flashcard_states s where s.user_uuid = $1 and s.card_id = $2 for update`,
+		userUUID, cardId,
+	).Scan(flashcardStateTargets(&state)...)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.FlashcardState{}, err
+	}
+	isNew := errors.Is(err, pgx.ErrNoRows)
+
+	state = utils.ScheduleReview(state, grade, now)
+
+	_, err = tx.Exec(c,
+		`
+	insert into flashcard_states (user_uuid, card_id, repetitions, interval_days, ease, lapses, due_at, last_reviewed_at)
+	values ($1, $2, $3, $4, $5, $6, $7, $8)
+	on conflict (user_uuid, card_id) do update
+	set repetitions = excluded.repetitions, interval_days = excluded.interval_days, ease = excluded.ease,
+		lapses = excluded.lapses, due_at = excluded.due_at, last_reviewed_at = excluded.last_reviewed_at
+	`,
+		state.User_uuid, state.Card_id, state.Repetitions, state.Interval_days, state.Ease, state.Lapses, state.Due_at, state.Last_reviewed_at,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return models.FlashcardState{}, err
+	}
+
+	_, err = tx.Exec(c,
+		"insert into flashcard_reviews (user_uuid, card_id, grade, is_first, interval_days, ease, reviewed_at) values ($1, $2, $3, $4, $5, $6, $7)",
+		userUUID, cardId, grade, isNew, state.Interval_days, state.Ease, now,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return models.FlashcardState{}, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.FlashcardState{}, err
+	}
+	return state, nil
+}
+
+// FindDue возвращает карточки, срок повторения которых наступил, самые просроченные первыми,
+// а затем до newLimit новых карточек из колод, которые ученик уже начал (или из deckId).
+func (r *FlashcardsRepository) FindDue(c context.Context, userUUID uuid.UUID, deckId int, limit, newLimit int, visibleOnly bool) ([]models.DueFlashcard, error) {
+	logger := logger.GetLogger()
+
+	deckFilter := ``
+	args := []any{userUUID}
+	if deckId > 0 {
+		args = append(args, deckId)
+		deckFilter = fmt.Sprintf(` and d.id = $%d`, len(args))
+	}
+
+	args = append(args, limit)
+	dueSQL := `
+	select ` + flashcardColumns + `, d.title, ` + flashcardStateColumns + `
+	from flashcard_states s
+	join flashcards f on f.id = s.card_id
+	join flashcard_decks d on d.id = f.deck_id
+	where s.user_uuid = $1 and s.due_at <= now() and ` + flashcardDeckCondition(visibleOnly) + deckFilter + `
+	order by s.due_at, f.id
+	limit $` + fmt.Sprint(len(args))
+
+	due := make([]models.DueFlashcard, 0)
+	rows, err := r.db.Query(c, dueSQL, args...)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var card models.DueFlashcard
+		state := &models.FlashcardState{}
+		if err := scanFlashcard(rows, &card.Card, append([]any{&card.Deck_title}, flashcardStateTargets(state)...)...); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+		card.State = state
+
+		due = append(due, card)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	if newLimit <= 0 {
+		return due, nil
+	}
+
+	startedCondition := ``
+	if deckId <= 0 {
+		startedCondition = ` and d.id in (select f2.deck_id from flashcard_states s2 join flashcards f2 on f2.id = s2.card_id where s2.user_uuid = $1)`
+	}
+	args[len(args)-1] = newLimit
+	newSQL := `
+	select ` + flashcardColumns + `, d.title
+	from flashcards f
+	join flashcard_decks d on d.id = f.deck_id
+	where not exists (select 1 from flashcard_states s where s.user_uuid = $1 and s.card_id = f.id)
+		and ` + flashcardDeckCondition(visibleOnly) + deckFilter + startedCondition + `
+	order by d.id, f.position, f.id
+	limit $` + fmt.Sprint(len(args))
+
+	rows, err = r.db.Query(c, newSQL, args...)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		card := models.DueFlashcard{Is_new: true}
+		if err := scanFlashcard(rows, &card.Card, &card.Deck_title); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		due = append(due, card)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return due, nil
+}
+
+// FindStats считает статистику повторений ученика по всем колодам или по одной
+func (r *FlashcardsRepository) FindStats(c context.Context, userUUID uuid.UUID, deckId int, visibleOnly bool) (models.FlashcardStats, error) {
+	logger := logger.GetLogger()
+
+	stats := models.FlashcardStats{}
+	args := []any{userUUID, utils.FlashcardMatureInterval}
+	cardsCondition := `f.deck_id in (select f2.deck_id from flashcard_states s2 join flashcards f2 on f2.id = s2.card_id where s2.user_uuid = $1)`
+	if deckId > 0 {
+		stats.Deck_id = &deckId
+		args = append(args, deckId)
+		cardsCondition = `f.deck_id = $3`
+	}
+
+	err := r.db.QueryRow(c, `
+	with cards as (
+		select f.id from flashcards f
+		join flashcard_decks d on d.id = f.deck_id
+		where `+cardsCondition+` and `+flashcardDeckCondition(visibleOnly)+`
+	),
+	states as (
+		select s.* from flashcard_states s join cards on cards.id = s.card_id where s.user_uuid = $1
+	),
+	reviews as (
+		select r.* from flashcard_reviews r join cards on cards.id = r.card_id where r.user_uuid = $1
+	)
+	select
+		(select count(*) from cards),
+		(select count(*) from states),
+		(select count(*) from states where interval_days >= $2),
+		(select count(*) from states where due_at <= now()),
+		(select coalesce(sum(lapses), 0) from states),
+		(select avg(ease) from states),
+		(select count(*) from reviews),
+		(select count(*) from reviews where reviewed_at > now() - interval '30 days'),
+		(select avg(case when grade >= `+fmt.Sprint(models.ReviewGradePass)+` then 100.0 else 0.0 end)
+			from reviews where not is_first and reviewed_at > now() - interval '30 days')
+	`, args...).Scan(&stats.Cards_total, &stats.Cards_studied, &stats.Cards_mature, &stats.Due_now, &stats.Lapses,
+		&stats.Average_ease, &stats.Reviews_total, &stats.Reviews_30d, &stats.Retention_30d)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.FlashcardStats{}, err
+	}
+
+	return stats, nil
+}
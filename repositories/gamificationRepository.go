@@ -0,0 +1,406 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// сколько последних начислений опыта показывать в достижениях
+const recentXPEvents = 20
+
+type GamificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGamificationRepository(conn *pgxpool.Pool) *GamificationRepository {
+	return &GamificationRepository{db: conn}
+}
+
+// badgeMetricQueries считают показатель ученика $1; $2 — предмет или null
+var badgeMetricQueries = map[string]string{
+	models.BadgeMetricLessonsCompleted: `
+	select count(*) from lesson_completions lc
+	join lessons l on l.lesson_id = lc.lesson_id
+	where lc.user_uuid = $1 and ($2::int is null or l.subject_id = $2)`,
+	models.BadgeMetricCoursesCompleted: `
+	select count(*) from enrollments where user_uuid = $1 and status = 'completed' and $2::int is null`,
+	models.BadgeMetricQuizzesPassed: `
+	select count(distinct qa.quiz_id) from quiz_attempts qa
+	join quizzes q on q.id = qa.quiz_id
+	left join lessons l on l.lesson_id = q.lesson_id
+	where qa.user_uuid = $1 and qa.status <> 'in_progress' and qa.max_score > 0
+		and qa.score / qa.max_score * 100 >= q.pass_percent and ($2::int is null or l.subject_id = $2)`,
+	models.BadgeMetricPerfectQuizzes: `
+	select count(distinct qa.quiz_id) from quiz_attempts qa
+	join quizzes q on q.id = qa.quiz_id
+	left join lessons l on l.lesson_id = q.lesson_id
+	where qa.user_uuid = $1 and qa.status <> 'in_progress' and qa.max_score > 0
+		and qa.score >= qa.max_score and ($2::int is null or l.subject_id = $2)`,
+}
+
+// applyGamificationEvent обрабатывает событие обучения в транзакции вызывающего:
+// начисляет опыт, продлевает серию в часовом поясе ученика и выдает бейджи, правила которых выполнены.
+// Новые бейджи достаются ученикам при их следующем событии.
+func applyGamificationEvent(c context.Context, tx pgx.Tx, event models.GamificationEvent) error {
+	logger := logger.GetLogger()
+
+	_, err := tx.Exec(c, "insert into user_gamification (user_uuid) values ($1) on conflict (user_uuid) do nothing", event.User_uuid)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	var state models.GamificationState
+	err = tx.QueryRow(c,
+		"select xp, current_streak, longest_streak, last_active_date, time_zone from user_gamification where user_uuid = $1 for update",
+		event.User_uuid,
+	).Scan(&state.Xp, &state.Current_streak, &state.Longest_streak, &state.Last_active_date, &state.Time_zone)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	// за курс опыт начисляется один раз, даже если учитель снова переведет запись в completed
+	if event.Type == models.GamificationCourseCompleted && event.Xp > 0 {
+		var awarded bool
+		err := tx.QueryRow(c,
+			"select exists (select 1 from xp_events where user_uuid = $1 and type = $2 and entity_id = $3)",
+			event.User_uuid, event.Type, event.Entity_id,
+		).Scan(&awarded)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return err
+		}
+		if awarded {
+			event.Xp = 0
+		}
+	}
+
+	now := time.Now()
+	if event.Learner_made {
+		state = utils.AdvanceStreak(state, utils.LocalDate(now, utils.LoadUserLocation(state.Time_zone)))
+	}
+	state.Xp += event.Xp
+
+	_, err = tx.Exec(c,
+		`
+	update user_gamification
+	set xp = $2, current_streak = $3, longest_streak = $4, last_active_date = $5, updated_at = now()
+	where user_uuid = $1
+	`,
+		event.User_uuid, state.Xp, state.Current_streak, state.Longest_streak, state.Last_active_date,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	if event.Xp > 0 {
+		_, err = tx.Exec(c,
+			"insert into xp_events (user_uuid, type, entity_id, xp, occurred_at) values ($1, $2, $3, $4, $5)",
+			event.User_uuid, event.Type, event.Entity_id, event.Xp, now,
+		)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		if err := bumpLeaderboard(c, tx, models.LeaderboardMetricXP, 0, event.User_uuid, event.Xp, now); err != nil {
+			return err
+		}
+	}
+
+	return awardBadges(c, tx, event.User_uuid, state)
+}
+
+func courseCompletedEvent(userUUID uuid.UUID, courseId int, learnerMade bool) models.GamificationEvent {
+	return models.GamificationEvent{
+		User_uuid:    userUUID,
+		Type:         models.GamificationCourseCompleted,
+		Entity_id:    courseId,
+		Xp:           utils.XPCourseCompleted,
+		Learner_made: learnerMade,
+	}
+}
+
+// awardBadges выдает ученику активные бейджи, которых у него еще нет и правила которых выполнены
+func awardBadges(c context.Context, tx pgx.Tx, userUUID uuid.UUID, state models.GamificationState) error {
+	logger := logger.GetLogger()
+
+	rows, err := tx.Query(c, `select `+badgeColumns+`
+	where b.is_active and not exists (select 1 from user_badges ub where ub.badge_id = b.id and ub.user_uuid = $1)
+	order by b.id`, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	badges, err := scanBadges(rows)
+	if err != nil {
+		return err
+	}
+
+	// одинаковые показатели считаются один раз
+	values := make(map[string]int)
+	for _, badge := range badges {
+		key := badge.Rule.Metric
+		if badge.Rule.Subject_id != nil {
+			key += "|" + strconv.Itoa(*badge.Rule.Subject_id)
+		}
+		value, ok := values[key]
+		if !ok {
+			value, err = badgeMetricValue(c, tx, userUUID, badge.Rule, state)
+			if err != nil {
+				return err
+			}
+			values[key] = value
+		}
+		if value < badge.Rule.Threshold {
+			continue
+		}
+
+		_, err := tx.Exec(c, "insert into user_badges (user_uuid, badge_id) values ($1, $2) on conflict (user_uuid, badge_id) do nothing", userUUID, badge.Id)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+
+		err = notifyUser(c, tx, models.Notification{
+			User_uuid:   userUUID,
+			Type:        models.NotificationBadgeEarned,
+			Title:       "New badge",
+			Body:        fmt.Sprintf("You earned the %q badge", badge.Name),
+			Entity_type: models.EntityBadge,
+			Entity_id:   badge.Id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func badgeMetricValue(c context.Context, tx pgx.Tx, userUUID uuid.UUID, rule models.BadgeRule, state models.GamificationState) (int, error) {
+	switch rule.Metric {
+	case models.BadgeMetricXP:
+		return state.Xp, nil
+	case models.BadgeMetricStreakDays:
+		return state.Longest_streak, nil
+	}
+
+	sql, ok := badgeMetricQueries[rule.Metric]
+	if !ok {
+		return 0, nil
+	}
+	var value int
+	if err := tx.QueryRow(c, sql, userUUID, rule.Subject_id).Scan(&value); err != nil {
+		logger.GetLogger().Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return value, nil
+}
+
+const badgeColumns = `
+	b.id, b.name, b.description, b.icon, b.rule, b.is_active, b.created_at, b.updated_at
+	from badges b
+	`
+
+func scanBadges(rows pgx.Rows) ([]models.Badge, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	badges := make([]models.Badge, 0)
+	for rows.Next() {
+		var b models.Badge
+		if err := rows.Scan(&b.Id, &b.Name, &b.Description, &b.Icon, &b.Rule, &b.Is_active, &b.Created_at, &b.Updated_at); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		badges = append(badges, b)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return badges, nil
+}
+
+// FindBadges возвращает бейджи; activeOnly — только те, которые еще можно получить
+func (r *GamificationRepository) FindBadges(c context.Context, activeOnly bool) ([]models.Badge, error) {
+	logger := logger.GetLogger()
+
+	sql := `select ` + badgeColumns
+	if activeOnly {
+		sql += ` where b.is_active`
+	}
+	sql += ` order by b.id`
+
+	rows, err := r.db.Query(c, sql)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanBadges(rows)
+}
+
+func (r *GamificationRepository) FindBadge(c context.Context, id int) (models.Badge, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+badgeColumns+` where b.id = $1`, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Badge{}, err
+	}
+
+	badges, err := scanBadges(rows)
+	if err != nil {
+		return models.Badge{}, err
+	}
+	if len(badges) == 0 {
+		return models.Badge{}, pgx.ErrNoRows
+	}
+	return badges[0], nil
+}
+
+func (r *GamificationRepository) CreateBadge(c context.Context, badge models.Badge) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	err := r.db.QueryRow(c,
+		"insert into badges (name, description, icon, rule, is_active) values ($1, $2, $3, $4, $5) returning id",
+		badge.Name, badge.Description, badge.Icon, badge.Rule, badge.Is_active,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpdateBadge меняет бейдж; уже выданные бейджи остаются у учеников
+func (r *GamificationRepository) UpdateBadge(c context.Context, id int, badge models.Badge) error {
+	logger := logger.GetLogger()
+
+	tag, err := r.db.Exec(c,
+		"update badges set name = $1, description = $2, icon = $3, rule = $4, is_active = $5, updated_at = now() where id = $6",
+		badge.Name, badge.Description, badge.Icon, badge.Rule, badge.Is_active, id,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// SetTimeZone сохраняет часовой пояс, в котором считаются дни серии
+func (r *GamificationRepository) SetTimeZone(c context.Context, userUUID uuid.UUID, timeZone string) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c,
+		`
+	insert into user_gamification (user_uuid, time_zone) values ($1, $2)
+	on conflict (user_uuid) do update set time_zone = excluded.time_zone, updated_at = now()
+	`,
+		userUUID, timeZone,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// FindAchievements собирает опыт, серию, бейджи и последние начисления опыта ученика
+func (r *GamificationRepository) FindAchievements(c context.Context, userUUID uuid.UUID) (models.Achievements, error) {
+	logger := logger.GetLogger()
+
+	var state models.GamificationState
+	err := r.db.QueryRow(c,
+		"select xp, current_streak, longest_streak, last_active_date, time_zone from user_gamification where user_uuid = $1",
+		userUUID,
+	).Scan(&state.Xp, &state.Current_streak, &state.Longest_streak, &state.Last_active_date, &state.Time_zone)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.Achievements{}, err
+	}
+
+	today := utils.LocalDate(time.Now(), utils.LoadUserLocation(state.Time_zone))
+	achievements := models.Achievements{
+		User_uuid:      userUUID,
+		Xp:             state.Xp,
+		Current_streak: utils.CurrentStreak(state, today),
+		Longest_streak: state.Longest_streak,
+		Time_zone:      state.Time_zone,
+		Badges:         make([]models.EarnedBadge, 0),
+		Recent_xp:      make([]models.XPEvent, 0),
+	}
+	if achievements.Time_zone == "" {
+		achievements.Time_zone = "UTC"
+	}
+	if state.Last_active_date != nil {
+		date := state.Last_active_date.Format(time.DateOnly)
+		achievements.Last_active_date = &date
+	}
+
+	rows, err := r.db.Query(c, `select b.id, b.name, b.description, b.icon, b.rule, b.is_active, b.created_at, b.updated_at, ub.earned_at
+	from user_badges ub
+	join badges b on b.id = ub.badge_id
+	where ub.user_uuid = $1
+	order by ub.earned_at desc`, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Achievements{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var earned models.EarnedBadge
+		b := &earned.Badge
+		if err := rows.Scan(&b.Id, &b.Name, &b.Description, &b.Icon, &b.Rule, &b.Is_active, &b.Created_at, &b.Updated_at, &earned.Earned_at); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.Achievements{}, err
+		}
+		achievements.Badges = append(achievements.Badges, earned)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return models.Achievements{}, err
+	}
+
+	rows, err = r.db.Query(c,
+		"select type, entity_id, xp, occurred_at from xp_events where user_uuid = $1 order by occurred_at desc, id desc limit $2",
+		userUUID, recentXPEvents)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Achievements{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var event models.XPEvent
+		if err := rows.Scan(&event.Type, &event.Entity_id, &event.Xp, &event.Occurred_at); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.Achievements{}, err
+		}
+		achievements.Recent_xp = append(achievements.Recent_xp, event)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return models.Achievements{}, err
+	}
+
+	return achievements, nil
+}
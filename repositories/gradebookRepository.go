@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type GradebookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGradebookRepository(conn *pgxpool.Pool) *GradebookRepository {
+	return &GradebookRepository{db: conn}
+}
+
+// уроки курса, квизы и задания которых входят в журнал
+const gradebookLessons = `(
+	select cl.lesson_id from course_lessons cl
+	join lessons l on l.lesson_id = cl.lesson_id
+	where cl.course_id = $1 and l.deleted_at is null
+	)`
+
+// FindSettings возвращает настройки журнала курса; если их не сохраняли — настройки по умолчанию
+func (r *GradebookRepository) FindSettings(c context.Context, courseId int) (models.GradebookSettings, error) {
+	logger := logger.GetLogger()
+
+	settings := models.GradebookSettings{Course_id: courseId}
+	err := r.db.QueryRow(c,
+		"select categories, letter_scheme, updated_at from course_gradebook_settings where course_id = $1", courseId,
+	).Scan(&settings.Categories, &settings.Letter_scheme, &settings.Updated_at)
+	if errors.Is(err, pgx.ErrNoRows) {
+		settings.Categories = utils.DefaultGradeCategories
+		settings.Letter_scheme = utils.DefaultLetterScheme
+		return settings, nil
+	}
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.GradebookSettings{}, err
+	}
+	return settings, nil
+}
+
+func (r *GradebookRepository) SaveSettings(c context.Context, settings models.GradebookSettings) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c,
+		`
+	insert into course_gradebook_settings (course_id, categories, letter_scheme, updated_at)
+	values ($1, $2, $3, now())
+	on conflict (course_id) do update
+	set categories = excluded.categories, letter_scheme = excluded.letter_scheme, updated_at = now()
+	`,
+		settings.Course_id, settings.Categories, settings.Letter_scheme,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// FindItems возвращает квизы и задания курса и его уроков. Максимум квиза — сумма баллов его вопросов.
+func (r *GradebookRepository) FindItems(c context.Context, courseId int) ([]models.GradebookItem, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	select 'quiz', q.id, q.title,
+		coalesce((select sum(qn.points) from quiz_questions qq join questions qn on qn.id = qq.question_id where qq.quiz_id = q.id), 0)::float8,
+		null::timestamptz
+	from quizzes q
+	where q.course_id = $1 or q.lesson_id in `+gradebookLessons+`
+	union all
+	select 'assignment', a.id, a.title, a.max_score::float8, a.due_at
+	from assignments a
+	where a.course_id = $1 or a.lesson_id in `+gradebookLessons+`
+	order by 1 desc, 2
+	`, courseId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.GradebookItem, 0)
+	for rows.Next() {
+		var item models.GradebookItem
+		if err := rows.Scan(&item.Type, &item.Id, &item.Title, &item.Max_score, &item.Due_at); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// FindScores возвращает результаты учеников по элементам курса: лучшую завершенную попытку квиза
+// и последнюю проверенную работу по заданию. userUUID = nil — результаты всех учеников.
+func (r *GradebookRepository) FindScores(c context.Context, courseId int, userUUID *uuid.UUID) ([]models.GradeScore, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	select * from (
+		select distinct on (qa.quiz_id, qa.user_uuid)
+			qa.user_uuid, 'quiz', qa.quiz_id, qa.score::float8, qa.max_score::float8
+		from quiz_attempts qa
+		join quizzes q on q.id = qa.quiz_id
+		where (q.course_id = $1 or q.lesson_id in `+gradebookLessons+`)
+			and qa.status <> 'in_progress' and ($2::uuid is null or qa.user_uuid = $2)
+		order by qa.quiz_id, qa.user_uuid, qa.score / nullif(qa.max_score, 0) desc nulls last
+	) quiz_scores
+	union all
+	select * from (
+		select distinct on (s.assignment_id, s.user_uuid)
+			s.user_uuid, 'assignment', s.assignment_id, s.score::float8, a.max_score::float8
+		from assignment_submissions s
+		join assignments a on a.id = s.assignment_id
+		where (a.course_id = $1 or a.lesson_id in `+gradebookLessons+`)
+			and s.status = 'graded' and ($2::uuid is null or s.user_uuid = $2)
+		order by s.assignment_id, s.user_uuid, s.number desc
+	) assignment_scores
+	`, courseId, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	scores := make([]models.GradeScore, 0)
+	for rows.Next() {
+		var s models.GradeScore
+		if err := rows.Scan(&s.User_uuid, &s.Item_type, &s.Item_id, &s.Score, &s.Max_score); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		scores = append(scores, s)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return scores, nil
+}
+
+// FindOverrides возвращает историю переопределений оценок курса, новые первыми.
+// userUUID = nil — история всех учеников.
+func (r *GradebookRepository) FindOverrides(c context.Context, courseId int, userUUID *uuid.UUID) ([]models.GradeOverride, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	select id, course_id, user_uuid, item_type, item_id, percent, reason, created_by, created_at
+	from grade_overrides
+	where course_id = $1 and ($2::uuid is null or user_uuid = $2)
+	order by created_at desc, id desc
+	`, courseId, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make([]models.GradeOverride, 0)
+	for rows.Next() {
+		var o models.GradeOverride
+		err := rows.Scan(&o.Id, &o.Course_id, &o.User_uuid, &o.Item_type, &o.Item_id, &o.Percent, &o.Reason, &o.Created_by, &o.Created_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		overrides = append(overrides, o)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+// AddOverride дописывает переопределение в историю; записи не меняются и не удаляются,
+// действует последняя запись по элементу, percent = null снимает переопределение
+func (r *GradebookRepository) AddOverride(c context.Context, override models.GradeOverride) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	err := r.db.QueryRow(c,
+		`
+	insert into grade_overrides (course_id, user_uuid, item_type, item_id, percent, reason, created_by)
+	values ($1, $2, $3, $4, $5, $6, $7)
+	returning id
+	`,
+		override.Course_id, override.User_uuid, override.Item_type, override.Item_id, override.Percent, override.Reason, override.Created_by,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
@@ -0,0 +1,263 @@
+package repositories
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type LeaderboardsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLeaderboardsRepository(conn *pgxpool.Pool) *LeaderboardsRepository {
+	return &LeaderboardsRepository{db: conn}
+}
+
+// bumpLeaderboard прибавляет delta к очкам ученика сразу во всех окнах рейтинга (неделя, месяц, все время),
+// чтобы рейтинг читался из leaderboard_scores, а не пересчитывался по всей истории обучения.
+// Опыт хранится с course_id = 0, прогресс — по каждому курсу отдельно.
+func bumpLeaderboard(c context.Context, tx pgx.Tx, metric string, courseId int, userUUID uuid.UUID, delta int, at time.Time) error {
+	logger := logger.GetLogger()
+
+	periods := models.LeaderboardPeriods
+	starts := make([]time.Time, len(periods))
+	for i, period := range periods {
+		starts[i] = utils.LeaderboardPeriodStart(period, at)
+	}
+
+	_, err := tx.Exec(c, `
+	insert into leaderboard_scores (metric, course_id, user_uuid, period, period_start, score)
+	select $1, $2, $3, p.period, p.period_start, $4
+	from unnest($5::text[], $6::date[]) as p(period, period_start)
+	on conflict (metric, course_id, user_uuid, period, period_start)
+	do update set score = leaderboard_scores.score + excluded.score
+	`, metric, courseId, userUUID, delta, periods, starts)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// bumpLessonProgress засчитывает пройденный урок в рейтинги прогресса всех курсов, в которые он входит
+func bumpLessonProgress(c context.Context, tx pgx.Tx, userUUID uuid.UUID, lessonId int, at time.Time) error {
+	logger := logger.GetLogger()
+
+	rows, err := tx.Query(c, `
+	select cl.course_id from course_lessons cl
+	join courses co on co.id = cl.course_id and co.deleted_at is null
+	where cl.lesson_id = $1`, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	courseIds, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	for _, courseId := range courseIds {
+		if err := bumpLeaderboard(c, tx, models.LeaderboardMetricProgress, courseId, userUUID, 1, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindRows возвращает первые filter.Limit мест рейтинга и строку зрителя, если он есть в рейтинге.
+// Скрытые ученики в рейтинг не попадают и места не занимают. В рейтинге курса участвуют только записанные на курс,
+// в рейтинге класса — только ученики класса.
+func (r *LeaderboardsRepository) FindRows(c context.Context, filter models.LeaderboardFilter) ([]models.LeaderboardRow, error) {
+	logger := logger.GetLogger()
+
+	// опыт общий для всей школы, в рейтинге курса он только фильтруется по записям
+	scoreCourseId := filter.Course_id
+	if filter.Metric == models.LeaderboardMetricXP {
+		scoreCourseId = 0
+	}
+
+	rows, err := r.db.Query(c, `
+	with ranked as (
+		select s.user_uuid, s.score, u.user_name, u.user_surname,
+			coalesce(p.visibility, '') as visibility, coalesce(p.nickname, '') as nickname, lp.age,
+			rank() over (order by s.score desc) as rank,
+			row_number() over (order by s.score desc, s.user_uuid) as position
+		from leaderboard_scores s
+		join users u on u.uuid = s.user_uuid and u.deleted_at is null
+		left join leaderboard_privacy p on p.user_uuid = s.user_uuid
+		left join learner_profiles lp on lp.user_uuid = s.user_uuid
+		where s.metric = $1 and s.course_id = $2 and s.period = $3 and s.period_start = $4 and s.score > 0
+			and coalesce(p.visibility, '') <> $5
+			and ($6 = 0 or exists (
+				select 1 from enrollments e
+				where e.course_id = $6 and e.user_uuid = s.user_uuid and e.status in ($7, $8)
+			))
+			and ($11 = 0 or exists (
+				select 1 from class_members cm where cm.class_id = $11 and cm.user_uuid = s.user_uuid
+			))
+	)
+	select rank, user_uuid, score, user_name, user_surname, visibility, nickname, age
+	from ranked
+	where position <= $9 or user_uuid = $10
+	order by position
+	`,
+		filter.Metric, scoreCourseId, filter.Period, filter.Period_start, models.LeaderboardHidden,
+		filter.Course_id, models.EnrollmentActive, models.EnrollmentCompleted,
+		filter.Limit, filter.Viewer, filter.Class_id,
+	)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.LeaderboardRow, 0)
+	for rows.Next() {
+		var row models.LeaderboardRow
+		err := rows.Scan(&row.Rank, &row.User_uuid, &row.Score, &row.Name, &row.Surname, &row.Visibility, &row.Nickname, &row.Age)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CountCourseLessons — число уроков курса, от которого считается процент прогресса
+func (r *LeaderboardsRepository) CountCourseLessons(c context.Context, courseId int) (int, error) {
+	logger := logger.GetLogger()
+
+	var count int
+	err := r.db.QueryRow(c, `
+	select count(*) from course_lessons cl
+	join lessons l on l.lesson_id = cl.lesson_id and l.deleted_at is null
+	where cl.course_id = $1`, courseId).Scan(&count)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindPrivacy возвращает настройки ученика вместе с возрастом из профиля
+func (r *LeaderboardsRepository) FindPrivacy(c context.Context, userUUID uuid.UUID) (models.LeaderboardPrivacy, *int, error) {
+	logger := logger.GetLogger()
+
+	privacy := models.LeaderboardPrivacy{User_uuid: userUUID}
+	var age *int
+	err := r.db.QueryRow(c, `
+	select coalesce(p.visibility, ''), coalesce(p.nickname, ''), p.updated_at, lp.age
+	from (select $1::uuid as user_uuid) me
+	left join leaderboard_privacy p on p.user_uuid = me.user_uuid
+	left join learner_profiles lp on lp.user_uuid = me.user_uuid
+	`, userUUID).Scan(&privacy.Visibility, &privacy.Nickname, &privacy.Updated_at, &age)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.LeaderboardPrivacy{}, nil, err
+	}
+	return privacy, age, nil
+}
+
+func (r *LeaderboardsRepository) SavePrivacy(c context.Context, privacy models.LeaderboardPrivacy) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c, `
+	insert into leaderboard_privacy (user_uuid, visibility, nickname, updated_at)
+	values ($1, $2, $3, now())
+	on conflict (user_uuid)
+	do update set visibility = excluded.visibility, nickname = excluded.nickname, updated_at = now()
+	`, privacy.User_uuid, privacy.Visibility, privacy.Nickname)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// leaderboardBackfillJob — строка job_markers, которая отмечает, что история уже перенесена в рейтинги
+const leaderboardBackfillJob = "leaderboard-backfill"
+
+// Backfill один раз пересчитывает leaderboard_scores по истории опыта и пройденных уроков и отмечает это в job_markers.
+// Дальше очки обновляются вместе с событиями обучения. Возвращает число записанных строк и true,
+// если заполнение уже отмечено; false без ошибки — заполнение сейчас выполняет другая реплика.
+func (r *LeaderboardsRepository) Backfill(c context.Context) (int, bool, error) {
+	logger := logger.GetLogger()
+
+	stored := 0
+	acquired, err := WithAdvisoryLock(c, r.db, AdvisoryLockLeaderboards, func(tx pgx.Tx) error {
+		var done bool
+		if err := tx.QueryRow(c, "select exists (select 1 from job_markers where name = $1)", leaderboardBackfillJob).Scan(&done); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		// очки, начисленные до заполнения, уже есть в истории: пересчитываем с нуля. Блокировка задерживает
+		// начисления параллельных событий до конца пересчета, чтобы ни одно не попало в очки дважды
+		if _, err := tx.Exec(c, "lock table leaderboard_scores in exclusive mode"); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		if _, err := tx.Exec(c, "delete from leaderboard_scores"); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+
+		// date_trunc('week') начинает неделю с понедельника, как и utils.LeaderboardPeriodStart
+		tag, err := tx.Exec(c, `
+		insert into leaderboard_scores (metric, course_id, user_uuid, period, period_start, score)
+		select events.metric, events.course_id, events.user_uuid, p.period, p.period_start, sum(events.score)
+		from (
+			select $1::text as metric, 0 as course_id, x.user_uuid, x.occurred_at as at, x.xp as score
+			from xp_events x
+			union all
+			select $2::text, cl.course_id, lc.user_uuid, lc.completed_at, 1
+			from lesson_completions lc
+			join course_lessons cl on cl.lesson_id = lc.lesson_id
+			join courses co on co.id = cl.course_id and co.deleted_at is null
+		) events
+		cross join lateral (values
+			($3::text, date_trunc('week', events.at at time zone 'UTC')::date),
+			($4::text, date_trunc('month', events.at at time zone 'UTC')::date),
+			($5::text, date '1970-01-01')
+		) as p(period, period_start)
+		group by events.metric, events.course_id, events.user_uuid, p.period, p.period_start
+		`,
+			models.LeaderboardMetricXP, models.LeaderboardMetricProgress,
+			models.LeaderboardPeriodWeek, models.LeaderboardPeriodMonth, models.LeaderboardPeriodAll,
+		)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		stored = int(tag.RowsAffected())
+
+		if _, err := tx.Exec(c, "insert into job_markers (name, completed_at) values ($1, now())", leaderboardBackfillJob); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		return nil
+	})
+	if err != nil || !acquired {
+		return 0, false, err
+	}
+
+	return stored, true, nil
+}
@@ -0,0 +1,467 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type LearningPathsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLearningPathsRepository(conn *pgxpool.Pool) *LearningPathsRepository {
+	return &LearningPathsRepository{db: conn}
+}
+
+// предметы и возраст пути выводятся из неудаленных уроков его курсов, 0 в возрасте урока — не задан
+const learningPathColumns = `
+	p.id, p.title, p.description, p.is_published,
+	coalesce(d.subject_ids, '{}'), d.target_age_min, d.target_age_max,
+	p.created_by, p.created_at, p.updated_at
+	from learning_paths p
+	left join lateral (
+		select array_agg(distinct l.subject_id) as subject_ids,
+			min(nullif(l.target_age_min, 0)) as target_age_min,
+			max(nullif(l.target_age_max, 0)) as target_age_max
+		from learning_path_courses pc
+		join courses co on co.id = pc.course_id and co.deleted_at is null
+		join course_lessons cl on cl.course_id = pc.course_id
+		join lessons l on l.lesson_id = cl.lesson_id and l.deleted_at is null
+		where pc.path_id = p.id
+	) d on true
+	`
+
+func scanLearningPaths(rows pgx.Rows) ([]models.LearningPath, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	paths := make([]models.LearningPath, 0)
+	for rows.Next() {
+		var p models.LearningPath
+		err := rows.Scan(&p.Id, &p.Title, &p.Description, &p.Is_published,
+			&p.Subject_ids, &p.Target_age_min, &p.Target_age_max,
+			&p.Created_by, &p.Created_at, &p.Updated_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		paths = append(paths, p)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// FindAll возвращает пути по фильтру вместе с их курсами
+func (r *LearningPathsRepository) FindAll(c context.Context, filter models.LearningPathFilter) ([]models.LearningPath, error) {
+	logger := logger.GetLogger()
+
+	sql := `select ` + learningPathColumns + ` where true`
+	args := make([]any, 0)
+	if filter.Visible_only {
+		sql += ` and p.is_published`
+	}
+	if filter.Subject_id > 0 {
+		args = append(args, filter.Subject_id)
+		sql += fmt.Sprintf(` and $%d = any(d.subject_ids)`, len(args))
+	}
+	if filter.Age > 0 {
+		args = append(args, filter.Age)
+		sql += fmt.Sprintf(` and coalesce(d.target_age_min, 0) <= $%d and coalesce(d.target_age_max, $%d) >= $%d`, len(args), len(args), len(args))
+	}
+	sql += ` order by p.title, p.id`
+
+	rows, err := r.db.Query(c, sql, args...)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	paths, err := scanLearningPaths(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadCourses(c, paths, filter.Visible_only); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func (r *LearningPathsRepository) FindById(c context.Context, id int, visibleOnly bool) (models.LearningPath, error) {
+	logger := logger.GetLogger()
+
+	sql := `select ` + learningPathColumns + ` where p.id = $1`
+	if visibleOnly {
+		sql += ` and p.is_published`
+	}
+
+	rows, err := r.db.Query(c, sql, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.LearningPath{}, err
+	}
+
+	paths, err := scanLearningPaths(rows)
+	if err != nil {
+		return models.LearningPath{}, err
+	}
+	if len(paths) == 0 {
+		return models.LearningPath{}, pgx.ErrNoRows
+	}
+	if err := r.loadCourses(c, paths, visibleOnly); err != nil {
+		return models.LearningPath{}, err
+	}
+	return paths[0], nil
+}
+
+// loadCourses заполняет курсы путей в порядке пути; visibleOnly скрывает неопубликованные курсы
+func (r *LearningPathsRepository) loadCourses(c context.Context, paths []models.LearningPath, visibleOnly bool) error {
+	logger := logger.GetLogger()
+
+	ids := make([]int, 0, len(paths))
+	for _, path := range paths {
+		ids = append(ids, path.Id)
+	}
+
+	sql := `
+	select pc.path_id, pc.course_id, courses.name, pc.position, pc.required
+	from learning_path_courses pc
+	join courses on courses.id = pc.course_id and courses.deleted_at is null
+	where pc.path_id = any($1)`
+	if visibleOnly {
+		sql += ` and ` + visibleCourseCondition
+	}
+	sql += ` order by pc.path_id, pc.position`
+
+	rows, err := r.db.Query(c, sql, ids)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer rows.Close()
+
+	courses := make(map[int][]models.LearningPathCourse)
+	for rows.Next() {
+		var pathId int
+		var course models.LearningPathCourse
+		if err := rows.Scan(&pathId, &course.Course_id, &course.Title, &course.Position, &course.Required); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return err
+		}
+
+		courses[pathId] = append(courses[pathId], course)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return err
+	}
+
+	for i := range paths {
+		paths[i].Courses = courses[paths[i].Id]
+		if paths[i].Courses == nil {
+			paths[i].Courses = make([]models.LearningPathCourse, 0)
+		}
+	}
+	return nil
+}
+
+func (r *LearningPathsRepository) Create(c context.Context, path models.LearningPath) (int, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var id int
+	err = tx.QueryRow(c,
+		"insert into learning_paths (title, description, is_published, created_by) values ($1, $2, $3, $4) returning id",
+		path.Title, path.Description, path.Is_published, path.Created_by,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	if err := replacePathCourses(c, tx, id, path.Courses); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update меняет путь и его курсы. Уже созданные записи на курсы не трогает.
+func (r *LearningPathsRepository) Update(c context.Context, id int, path models.LearningPath) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c,
+		"update learning_paths set title = $1, description = $2, is_published = $3, updated_at = now() where id = $4",
+		path.Title, path.Description, path.Is_published, id,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := replacePathCourses(c, tx, id, path.Courses); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// replacePathCourses сохраняет курсы пути в переданном порядке
+func replacePathCourses(c context.Context, tx pgx.Tx, pathId int, courses []models.LearningPathCourse) error {
+	logger := logger.GetLogger()
+
+	if _, err := tx.Exec(c, "delete from learning_path_courses where path_id = $1", pathId); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	for i, course := range courses {
+		_, err := tx.Exec(c,
+			"insert into learning_path_courses (path_id, course_id, position, required) values ($1, $2, $3, $4)",
+			pathId, course.Course_id, i+1, course.Required,
+		)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete удаляет путь и записи на него; записи на курсы остаются
+func (r *LearningPathsRepository) Delete(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	for _, sql := range []string{
+		"delete from learning_path_enrollments where path_id = $1",
+		"delete from learning_path_courses where path_id = $1",
+	} {
+		if _, err := tx.Exec(c, sql, id); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+	tag, err := tx.Exec(c, "delete from learning_paths where id = $1", id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Enroll записывает ученика на путь и на его обязательные открытые опубликованные курсы.
+// На необязательные курсы ученик записывается сам, на закрытые — записывает учитель.
+func (r *LearningPathsRepository) Enroll(c context.Context, pathId int, userUUID uuid.UUID) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c,
+		"insert into learning_path_enrollments (path_id, user_uuid) values ($1, $2) on conflict (path_id, user_uuid) do nothing",
+		pathId, userUUID,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	// курсы блокируются по возрастанию id, чтобы параллельные записи на разные пути не взаимоблокировались
+	rows, err := tx.Query(c, `
+	select pc.course_id
+	from learning_path_courses pc
+	join courses on courses.id = pc.course_id and courses.deleted_at is null
+	where pc.path_id = $1 and pc.required and courses.enrollment = 'open' and `+visibleCourseCondition+`
+	order by pc.course_id`,
+		pathId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	courseIds, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	for _, courseId := range courseIds {
+		if err := enrollInCourse(c, tx, courseId, userUUID, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// FindEnrolledPathIds возвращает пути, на которые записан ученик, новые записи первыми
+func (r *LearningPathsRepository) FindEnrolledPathIds(c context.Context, userUUID uuid.UUID) ([]int, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, "select path_id from learning_path_enrollments where user_uuid = $1 order by enrolled_at desc", userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	pathIds, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	return pathIds, nil
+}
+
+// FindProgress собирает прогресс ученика по курсам пути. Курс пройден, когда запись на него завершена.
+func (r *LearningPathsRepository) FindProgress(c context.Context, path models.LearningPath, userUUID uuid.UUID, visibleOnly bool) (models.LearningPathProgress, error) {
+	logger := logger.GetLogger()
+
+	progress := models.LearningPathProgress{Path_id: path.Id, Title: path.Title, User_uuid: userUUID}
+	err := r.db.QueryRow(c, "select enrolled_at from learning_path_enrollments where path_id = $1 and user_uuid = $2", path.Id, userUUID).Scan(&progress.Enrolled_at)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.LearningPathProgress{}, err
+	}
+
+	lessonCondition := ``
+	if visibleOnly {
+		lessonCondition = ` and ` + visibleLessonCondition
+	}
+	rows, err := r.db.Query(c, `
+	select pc.course_id, co.name, pc.required, coalesce(e.status, ''), count(l.lesson_id), count(lc.lesson_id)
+	from learning_path_courses pc
+	join courses co on co.id = pc.course_id and co.deleted_at is null
+	left join enrollments e on e.course_id = pc.course_id and e.user_uuid = $2
+	left join course_lessons cl on cl.course_id = pc.course_id
+	left join lessons l on l.lesson_id = cl.lesson_id and l.deleted_at is null`+lessonCondition+`
+	left join lesson_completions lc on lc.lesson_id = l.lesson_id and lc.user_uuid = $2
+	where pc.path_id = $1 and pc.course_id = any($3)
+	group by pc.course_id, co.name, pc.required, e.status, pc.position
+	order by pc.position
+	`, path.Id, userUUID, pathCourseIds(path))
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.LearningPathProgress{}, err
+	}
+	defer rows.Close()
+
+	progress.Courses = make([]models.LearningPathCourseProgress, 0)
+	for rows.Next() {
+		var course models.LearningPathCourseProgress
+		err := rows.Scan(&course.Course_id, &course.Title, &course.Required, &course.Status, &course.Total_lessons, &course.Completed_lessons)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.LearningPathProgress{}, err
+		}
+		if course.Total_lessons > 0 {
+			course.Percent = float64(course.Completed_lessons) / float64(course.Total_lessons) * 100
+		}
+
+		progress.Courses = append(progress.Courses, course)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return models.LearningPathProgress{}, err
+	}
+
+	summarizePathProgress(&progress)
+	return progress, nil
+}
+
+func pathCourseIds(path models.LearningPath) []int {
+	ids := make([]int, 0, len(path.Courses))
+	for _, course := range path.Courses {
+		ids = append(ids, course.Course_id)
+	}
+	return ids
+}
+
+// summarizePathProgress считает итог по обязательным курсам, а если их нет — по всем
+func summarizePathProgress(progress *models.LearningPathProgress) {
+	var totalLessons, completedLessons int
+	for _, course := range progress.Courses {
+		progress.Total_courses++
+		completed := course.Status == models.EnrollmentCompleted
+		if completed {
+			progress.Completed_courses++
+		}
+		if course.Required {
+			progress.Required_courses++
+			if completed {
+				progress.Completed_required++
+			}
+		}
+	}
+
+	for _, course := range progress.Courses {
+		if course.Required || progress.Required_courses == 0 {
+			totalLessons += course.Total_lessons
+			completedLessons += course.Completed_lessons
+		}
+	}
+	if totalLessons > 0 {
+		progress.Percent = float64(completedLessons) / float64(totalLessons) * 100
+	}
+
+	if progress.Required_courses > 0 {
+		progress.Completed = progress.Completed_required == progress.Required_courses
+	} else {
+		progress.Completed = progress.Total_courses > 0 && progress.Completed_courses == progress.Total_courses
+	}
+}
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidResourceOrder — порядок не перечисляет каждый ресурс урока ровно один раз
+var ErrInvalidResourceOrder = errors.New("invalid resource order")
+
+type LessonResourcesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLessonResourcesRepository(conn *pgxpool.Pool) *LessonResourcesRepository {
+	return &LessonResourcesRepository{db: conn}
+}
+
+const lessonResourceColumns = `
+	r.id,
+	r.lesson_id,
+	r.type,
+	r.title,
+	coalesce(r.url, ''),
+	r.media_id,
+	coalesce(m.filename, ''),
+	coalesce(m.mime_type, ''),
+	coalesce(m.size, 0),
+	r.position,
+	r.download_count,
+	r.created_at
+	`
+
+func scanLessonResource(row interface{ Scan(...any) error }) (models.LessonResource, error) {
+	var resource models.LessonResource
+	err := row.Scan(
+		&resource.Id,
+		&resource.Lesson_id,
+		&resource.Type,
+		&resource.Title,
+		&resource.Url,
+		&resource.Media_id,
+		&resource.Filename,
+		&resource.Mime_type,
+		&resource.Size,
+		&resource.Position,
+		&resource.Download_count,
+		&resource.Created_at,
+	)
+	return resource, err
+}
+
+func (r *LessonResourcesRepository) FindByLessonId(c context.Context, lessonId int) ([]models.LessonResource, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	select `+lessonResourceColumns+`
+	from lesson_resources r
+	left join media m on m.id = r.media_id
+	where r.lesson_id = $1
+	order by r.position, r.id
+	`, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	resources := make([]models.LessonResource, 0)
+	for rows.Next() {
+		resource, err := scanLessonResource(rows)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		resources = append(resources, resource)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+func (r *LessonResourcesRepository) FindById(c context.Context, lessonId int, id int) (models.LessonResource, error) {
+	logger := logger.GetLogger()
+
+	row := r.db.QueryRow(c, `
+	select `+lessonResourceColumns+`
+	from lesson_resources r
+	left join media m on m.id = r.media_id
+	where r.lesson_id = $1 and r.id = $2
+	`, lessonId, id)
+
+	resource, err := scanLessonResource(row)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.LessonResource{}, err
+	}
+
+	return resource, nil
+}
+
+func (r *LessonResourcesRepository) Create(c context.Context, resource models.LessonResource) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	row := r.db.QueryRow(c,
+		`
+	insert into lesson_resources (lesson_id, type, title, url, media_id, position)
+	values ($1, $2, $3, nullif($4, ''), $5,
+		(select coalesce(max(position), 0) + 1 from lesson_resources where lesson_id = $1))
+	returning id
+	`,
+		resource.Lesson_id, resource.Type, resource.Title, resource.Url, resource.Media_id,
+	)
+	if err := row.Scan(&id); err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// Reorder выставляет позиции в порядке переданных id. Список должен содержать все ресурсы урока.
+func (r *LessonResourcesRepository) Reorder(c context.Context, lessonId int, ids []int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	rows, err := tx.Query(c, "select id from lesson_resources where lesson_id = $1 for update", lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if len(existing) != len(ids) {
+		return fmt.Errorf("%w: expected %d resource ids, got %d", ErrInvalidResourceOrder, len(existing), len(ids))
+	}
+
+	// каждый ресурс урока должен встретиться ровно один раз
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("%w: resource %d is listed more than once", ErrInvalidResourceOrder, id)
+		}
+		seen[id] = true
+	}
+	for _, id := range existing {
+		if !seen[id] {
+			return fmt.Errorf("%w: resource %d of lesson %d is missing from the order", ErrInvalidResourceOrder, id, lessonId)
+		}
+	}
+
+	for i, id := range ids {
+		tag, err := tx.Exec(c, "update lesson_resources set position = $1 where id = $2 and lesson_id = $3", i+1, id, lessonId)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%w: resource %d does not belong to lesson %d", ErrInvalidResourceOrder, id, lessonId)
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (r *LessonResourcesRepository) IncrementDownloads(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c, "update lesson_resources set download_count = download_count + 1 where id = $1", id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (r *LessonResourcesRepository) Delete(c context.Context, lessonId int, id int) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c, "delete from lesson_resources where lesson_id = $1 and id = $2", lessonId, id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -2,10 +2,12 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 	"go-EdTech/logger"
 	"go-EdTech/models"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -14,6 +16,11 @@ type Lessonsrepository struct {
 	db *pgxpool.Pool
 }
 
+// урок виден ученикам, если опубликован и текущее время внутри окна публикации
+const visibleLessonCondition = `l.is_published
+	and (l.publish_at is null or l.publish_at <= now())
+	and (l.unpublish_at is null or l.unpublish_at > now())`
+
 func NewLessonsRepository(conn *pgxpool.Pool) *Lessonsrepository {
 	return &Lessonsrepository{db: conn}
 }
@@ -25,6 +32,8 @@ func (r *Lessonsrepository) FindById(c context.Context, id int) (models.Lesson,
 	l.lesson_id,
 	l.lesson_title,
 	l.description,
+	l.body,
+	l.body_html,
 	l.subject_id,
 	l.order,
 	l.level,
@@ -32,10 +41,14 @@ func (r *Lessonsrepository) FindById(c context.Context, id int) (models.Lesson,
 	l.target_age_min,
 	l.target_age_max,
 	l.video_data,
+	l.video_media_id,
 	l.video_filename,
 	l.video_mime_type,
 	l.duration_sec,
 	l.is_published,
+	l.status,
+	l.publish_at,
+	l.unpublish_at,
 	l.created_at,
 	l.updated_at,
 	s.subject_id,
@@ -44,7 +57,7 @@ func (r *Lessonsrepository) FindById(c context.Context, id int) (models.Lesson,
 	join subjects s on l.subject_id = s.subject_id,
 	extract(epoch from l.created_at) as created_at,
 	extract(epoch from l.updated_at) as updated_at
-	where l.lesson_id = $1
+	where l.lesson_id = $1 and l.deleted_at is null
 	`
 
 	logger := logger.GetLogger()
@@ -66,6 +79,8 @@ func (r *Lessonsrepository) FindById(c context.Context, id int) (models.Lesson,
 			&les.Id,
 			&les.Title,
 			&les.Description,
+			&les.Body,
+			&les.Body_html,
 			&les.Subject_id,
 			&les.Order,
 			&les.Level,
@@ -73,10 +88,14 @@ func (r *Lessonsrepository) FindById(c context.Context, id int) (models.Lesson,
 			&les.Target_age_min,
 			&les.Target_age_max,
 			&les.Video_data,
+			&les.Video_media_id,
 			&les.Video_filename,
 			&les.Video_mime_type,
 			&les.Duration_sec,
 			&les.Is_published,
+			&les.Status,
+			&les.Publish_at,
+			&les.Unpublish_at,
 			&les.Created_at,
 			&les.Updated_at,
 			&sub.Id,
@@ -102,17 +121,23 @@ func (r *Lessonsrepository) FindById(c context.Context, id int) (models.Lesson,
 		return models.Lesson{}, err
 	}
 
+	if lesson == nil {
+		return models.Lesson{}, pgx.ErrNoRows
+	}
+
 	return *lesson, nil
 
 }
 
-func (r *Lessonsrepository) FindAll(c context.Context) ([]models.Lesson, error) {
+func (r *Lessonsrepository) FindAll(c context.Context, filter models.LessonFilter) ([]models.Lesson, error) {
 	sql :=
 		`
 	select 
 	l.lesson_id,
 	l.lesson_title,
 	l.description,
+	l.body,
+	l.body_html,
 	l.subject_id,
 	l.order,
 	l.level,
@@ -120,10 +145,14 @@ func (r *Lessonsrepository) FindAll(c context.Context) ([]models.Lesson, error)
 	l.target_age_min,
 	l.target_age_max,
 	l.video_data,
+	l.video_media_id,
 	l.video_filename,
 	l.video_mime_type,
 	l.duration_sec,
 	l.is_published,
+	l.status,
+	l.publish_at,
+	l.unpublish_at,
 	l.created_at,
 	l.updated_at,
 	s.subject_id,
@@ -132,11 +161,25 @@ func (r *Lessonsrepository) FindAll(c context.Context) ([]models.Lesson, error)
 	join subjects s on l.subject_id = s.subject_id,
 	extract(epoch from l.created_at) as created_at,
 	extract(epoch from l.updated_at) as updated_at
+	where l.deleted_at is null
 	`
 
+	args := make([]any, 0)
+	if filter.Visible_only {
+		sql += ` and ` + visibleLessonCondition
+	}
+	if filter.Level != "" {
+		args = append(args, filter.Level)
+		sql += fmt.Sprintf(` and l.level = $%d`, len(args))
+	}
+	for _, group := range filter.Tag_groups {
+		args = append(args, group)
+		sql += fmt.Sprintf(` and exists (select 1 from lesson_tags lt where lt.entity_id = l.lesson_id and lt.tag_id = any($%d))`, len(args))
+	}
+
 	logger := logger.GetLogger()
 
-	rows, err := r.db.Query(c, sql)
+	rows, err := r.db.Query(c, sql, args...)
 
 	if err != nil {
 		logger.Error("could not query database", zap.String("db_msg", err.Error()))
@@ -153,6 +196,8 @@ func (r *Lessonsrepository) FindAll(c context.Context) ([]models.Lesson, error)
 			&les.Id,
 			&les.Title,
 			&les.Description,
+			&les.Body,
+			&les.Body_html,
 			&les.Subject_id,
 			&les.Order,
 			&les.Level,
@@ -160,10 +205,14 @@ func (r *Lessonsrepository) FindAll(c context.Context) ([]models.Lesson, error)
 			&les.Target_age_min,
 			&les.Target_age_max,
 			&les.Video_data,
+			&les.Video_media_id,
 			&les.Video_filename,
 			&les.Video_mime_type,
 			&les.Duration_sec,
 			&les.Is_published,
+			&les.Status,
+			&les.Publish_at,
+			&les.Unpublish_at,
 			&les.Created_at,
 			&les.Updated_at,
 			&sub.Id,
@@ -187,17 +236,13 @@ func (r *Lessonsrepository) FindAll(c context.Context) ([]models.Lesson, error)
 
 }
 
-func (r *Lessonsrepository) Create(c context.Context, lesson models.Lesson) (int, error) {
-	var id int
-
-	logger := logger.GetLogger()
-
-	row := r.db.QueryRow(c,
-		`
+const insertLessonQuery = `
 	insert into lessons
 	(
 	lesson_title, 
 	description, 
+	body, 
+	body_html, 
 	subject_id, 
 	"order", 
 	"level", 
@@ -208,14 +253,19 @@ func (r *Lessonsrepository) Create(c context.Context, lesson models.Lesson) (int
 	video_filename, 
 	video_mime_type, 
 	duration_sec, 
-	is_published
+	is_published,
+	status
 	) 
-	values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, false, 'draft')
 	returning lesson_id
-	`,
+	`
 
+func insertLessonArgs(lesson models.Lesson) []any {
+	return []any{
 		lesson.Title,
 		lesson.Description,
+		lesson.Body,
+		lesson.Body_html,
 		lesson.Subject_id,
 		lesson.Order,
 		lesson.Level,
@@ -226,8 +276,15 @@ func (r *Lessonsrepository) Create(c context.Context, lesson models.Lesson) (int
 		lesson.Video_filename,
 		lesson.Video_mime_type,
 		lesson.Duration_sec,
-		lesson.Is_published,
-	)
+	}
+}
+
+func (r *Lessonsrepository) Create(c context.Context, lesson models.Lesson) (int, error) {
+	var id int
+
+	logger := logger.GetLogger()
+
+	row := r.db.QueryRow(c, insertLessonQuery, insertLessonArgs(lesson)...)
 
 	err := row.Scan(&id)
 	if err != nil {
@@ -235,9 +292,57 @@ func (r *Lessonsrepository) Create(c context.Context, lesson models.Lesson) (int
 		return 0, err
 	}
 
+	refreshSearchVector(c, r.db, models.EntityLesson, id)
 	return id, nil
 }
 
+// CreateBatch создает уроки вместе с их первыми ревизиями в одной транзакции: либо все, либо ничего.
+// revisions[i] записывается для lessons[i], Entity_id заполняется после вставки урока.
+func (r *Lessonsrepository) CreateBatch(c context.Context, lessons []models.Lesson, revisions []models.ContentRevision) ([]int, error) {
+	logger := logger.GetLogger()
+
+	if len(lessons) != len(revisions) {
+		return nil, fmt.Errorf("got %d lessons and %d revisions", len(lessons), len(revisions))
+	}
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer tx.Rollback(c)
+
+	ids := make([]int, 0, len(lessons))
+	for i, lesson := range lessons {
+		var id int
+		if err := tx.QueryRow(c, insertLessonQuery, insertLessonArgs(lesson)...).Scan(&id); err != nil {
+			logger.Error("could not query database", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		revision := revisions[i]
+		revision.Entity_id = id
+		if _, err := tx.Exec(c, insertRevisionQuery, insertRevisionArgs(revision)...); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	for _, id := range ids {
+		refreshSearchVector(c, r.db, models.EntityLesson, id)
+	}
+	return ids, nil
+}
+
+// Update заменяет содержимое урока. Видео из media остается, пока не загружено новое или не очищено имя файла;
+// заданный Video_media_id заменяет видео файлом из media.
 func (r *Lessonsrepository) Update(c context.Context, id int, updLesson models.Lesson) error {
 	logger := logger.GetLogger()
 
@@ -248,22 +353,27 @@ func (r *Lessonsrepository) Update(c context.Context, id int, updLesson models.L
 	set 
 	lesson_title = $1, 
 	description = $2, 
-	subject_id = $3, 
-	"order" = $4, 
-	"level" = $5, 
-	interest = $6, 
-	target_age_min = $7, 
-	target_age_max = $8, 
-	video_data = $9, 
-	video_filename = $10, 
-	video_mime_type = $11, 
-	duration_sec = $12, 
-	is_published = $13,
+	body = $3, 
+	body_html = $4, 
+	subject_id = $5, 
+	"order" = $6, 
+	"level" = $7, 
+	interest = $8, 
+	target_age_min = $9, 
+	target_age_max = $10, 
+	video_data = case when $16::int is not null then null else $11 end, 
+	video_media_id = case when $16::int is not null then $16 when coalesce(length($11::bytea), 0) > 0 or $12 = '' then null else video_media_id end,
+	video_filename = $12, 
+	video_mime_type = $13, 
+	duration_sec = $14, 
+	search_vector = null,
 	updated_at = now()
-	where lesson_id = $14
+	where lesson_id = $15
 		`,
 		updLesson.Title,
 		updLesson.Description,
+		updLesson.Body,
+		updLesson.Body_html,
 		updLesson.Subject_id,
 		updLesson.Order,
 		updLesson.Level,
@@ -274,21 +384,49 @@ func (r *Lessonsrepository) Update(c context.Context, id int, updLesson models.L
 		updLesson.Video_filename,
 		updLesson.Video_mime_type,
 		updLesson.Duration_sec,
-		updLesson.Is_published,
 		id,
+		updLesson.Video_media_id,
 	)
 	if err != nil {
 		logger.Error("could not query database", zap.String("db_msg", err.Error()))
 		return err
 	}
 
+	refreshSearchVector(c, r.db, models.EntityLesson, id)
+	return nil
+}
+
+// UpdateStatus меняет статус публикации, is_published остается для старых клиентов
+func (r *Lessonsrepository) UpdateStatus(c context.Context, id int, status string) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c,
+		"update lessons set status = $1, is_published = ($1 = 'published'), updated_at = now() where lesson_id = $2",
+		status, id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// SetSchedule задает окно публикации, переходы статусов выполняет фоновый планировщик
+func (r *Lessonsrepository) SetSchedule(c context.Context, id int, publishAt *time.Time, unpublishAt *time.Time) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c, "update lessons set publish_at = $1, unpublish_at = $2, updated_at = now() where lesson_id = $3", publishAt, unpublishAt, id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
 	return nil
 }
 
+// Delete переносит урок в корзину, окончательно его удалит фоновая очистка корзины
 func (r *Lessonsrepository) Delete(c context.Context, id int) error {
 	logger := logger.GetLogger()
 
-	_, err := r.db.Exec(c, "delete from lessons where lesson_id = $1", id)
+	_, err := r.db.Exec(c, "update lessons set deleted_at = now() where lesson_id = $1 and deleted_at is null", id)
 	if err != nil {
 		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
 		return err
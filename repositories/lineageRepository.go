@@ -0,0 +1,451 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type LineageRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLineageRepository(conn *pgxpool.Pool) *LineageRepository {
+	return &LineageRepository{db: conn}
+}
+
+// CloneOptions — параметры копии курса. Пустое название — оставить исходное.
+type CloneOptions struct {
+	Name    string
+	Publish bool
+}
+
+// CloneCourse копирует курс, его состав, уроки, вложения, субтитры, теги, переводы и требования в одной транзакции.
+// Файлы из media не дублируются: копии ссылаются на те же media_id. Встроенное видео исходного урока
+// один раз переносится в media, и дальше исходник и все копии ссылаются на один файл.
+// Требования между уроками курса переносятся на копии, требования к урокам вне курса остаются как есть.
+func (r *LineageRepository) CloneCourse(c context.Context, sourceId int, options CloneOptions) (models.CloneResult, error) {
+	logger := logger.GetLogger()
+
+	status := models.StatusDraft
+	if options.Publish {
+		status = models.StatusPublished
+	}
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.CloneResult{}, err
+	}
+	defer tx.Rollback(c)
+
+	result := models.CloneResult{Lessons: make([]models.ClonedLesson, 0)}
+	err = tx.QueryRow(c,
+		`
+	insert into courses (name, description, enrollment, capacity, is_published, status)
+	select coalesce(nullif($2, ''), name), description, enrollment, capacity, $3 = 'published', $3
+	from courses where id = $1
+	returning id
+	`,
+		sourceId, options.Name, status,
+	).Scan(&result.Course_id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.CloneResult{}, err
+	}
+
+	rows, err := tx.Query(c, `
+	select cl.lesson_id from course_lessons cl
+	join lessons l on l.lesson_id = cl.lesson_id and l.deleted_at is null
+	where cl.course_id = $1
+	order by cl.position
+	`, sourceId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.CloneResult{}, err
+	}
+	sourceLessons, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.CloneResult{}, err
+	}
+
+	lessonIds := make(map[int]int, len(sourceLessons))
+	for i, sourceLesson := range sourceLessons {
+		if err := shareLessonVideo(c, tx, sourceLesson); err != nil {
+			return models.CloneResult{}, err
+		}
+
+		var id int
+		err := tx.QueryRow(c,
+			`
+		insert into lessons
+		(lesson_title, description, body, body_html, subject_id, "order", "level", interest,
+		target_age_min, target_age_max, video_media_id, video_filename, video_mime_type, duration_sec, is_published, status)
+		select lesson_title, description, body, body_html, subject_id, "order", "level", interest,
+		target_age_min, target_age_max, video_media_id, video_filename, video_mime_type, duration_sec, $2 = 'published', $2
+		from lessons where lesson_id = $1
+		returning lesson_id
+		`,
+			sourceLesson, status,
+		).Scan(&id)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.CloneResult{}, err
+		}
+
+		lessonIds[sourceLesson] = id
+		result.Lessons = append(result.Lessons, models.ClonedLesson{Source_id: sourceLesson, Id: id})
+
+		_, err = tx.Exec(c, "insert into course_lessons (course_id, lesson_id, position) values ($1, $2, $3)", result.Course_id, id, i+1)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return models.CloneResult{}, err
+		}
+
+		statements := []string{
+			`insert into lesson_resources (lesson_id, type, title, url, media_id, position)
+			select $2, type, title, url, media_id, position from lesson_resources where lesson_id = $1`,
+			`insert into lesson_captions (lesson_id, language, label, is_default, content)
+			select $2, language, label, is_default, content from lesson_captions where lesson_id = $1`,
+			`insert into lesson_tags (entity_id, tag_id)
+			select $2, tag_id from lesson_tags where entity_id = $1`,
+			`insert into content_translations (entity_type, entity_id, locale, title, description, body, body_html, updated_at)
+			select entity_type, $2, locale, title, description, body, body_html, updated_at
+			from content_translations where entity_type = 'lesson' and entity_id = $1`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(c, statement, sourceLesson, id); err != nil {
+				logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+				return models.CloneResult{}, err
+			}
+		}
+
+		if err := insertLineage(c, tx, models.EntityLesson, id, sourceLesson); err != nil {
+			return models.CloneResult{}, err
+		}
+	}
+
+	// требования копируются после всех уроков, чтобы связи внутри курса указывали на копии
+	for sourceLesson, id := range lessonIds {
+		rows, err := tx.Query(c, "select prerequisite_id from lesson_prerequisites where entity_id = $1", sourceLesson)
+		if err != nil {
+			logger.Error("could not query database", zap.String("db_msg", err.Error()))
+			return models.CloneResult{}, err
+		}
+		prerequisites, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.CloneResult{}, err
+		}
+
+		for _, prerequisite := range prerequisites {
+			if cloned, ok := lessonIds[prerequisite]; ok {
+				prerequisite = cloned
+			}
+			_, err := tx.Exec(c, "insert into lesson_prerequisites (entity_id, prerequisite_id) values ($1, $2)", id, prerequisite)
+			if err != nil {
+				logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+				return models.CloneResult{}, err
+			}
+		}
+	}
+
+	courseStatements := []string{
+		`insert into course_tags (entity_id, tag_id)
+		select $2, tag_id from course_tags where entity_id = $1`,
+		`insert into course_prerequisites (entity_id, prerequisite_id)
+		select $2, prerequisite_id from course_prerequisites where entity_id = $1`,
+		`insert into content_translations (entity_type, entity_id, locale, title, description, body, body_html, updated_at)
+		select entity_type, $2, locale, title, description, body, body_html, updated_at
+		from content_translations where entity_type = 'course' and entity_id = $1`,
+	}
+	for _, statement := range courseStatements {
+		if _, err := tx.Exec(c, statement, sourceId, result.Course_id); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return models.CloneResult{}, err
+		}
+	}
+
+	if err := insertLineage(c, tx, models.EntityCourse, result.Course_id, sourceId); err != nil {
+		return models.CloneResult{}, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.CloneResult{}, err
+	}
+
+	refreshSearchVector(c, r.db, models.EntityCourse, result.Course_id)
+	for _, lesson := range result.Lessons {
+		refreshSearchVector(c, r.db, models.EntityLesson, lesson.Id)
+	}
+
+	return result, nil
+}
+
+// shareLessonVideo переносит встроенное видео урока в хранилище media, чтобы копии ссылались на файл, а не копировали байты
+func shareLessonVideo(c context.Context, tx pgx.Tx, lessonId int) error {
+	logger := logger.GetLogger()
+
+	var (
+		data     []byte
+		filename string
+		mimeType string
+	)
+	err := tx.QueryRow(c, `
+	select video_data, video_filename, video_mime_type from lessons
+	where lesson_id = $1 and video_media_id is null and length(video_data) > 0
+	for update`,
+		lessonId,
+	).Scan(&data, &filename, &mimeType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	checksum, size, err := utils.StoreMedia(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	var mediaId int
+	err = tx.QueryRow(c,
+		"insert into media (filename, mime_type, size, checksum) values ($1, $2, $3, $4) returning id",
+		filename, mimeType, size, checksum,
+	).Scan(&mediaId)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	_, err = tx.Exec(c, "update lessons set video_media_id = $2, video_data = null where lesson_id = $1", lessonId, mediaId)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+	}
+	return err
+}
+
+// insertLineage запоминает исходник копии и его последнюю ревизию, от которой потом считаются обновления
+func insertLineage(c context.Context, tx pgx.Tx, entityType string, id int, sourceId int) error {
+	_, err := tx.Exec(c,
+		`
+	insert into content_lineage (entity_type, entity_id, source_id, source_revision)
+	values ($1, $2, $3,
+		(select coalesce(max(revision), 0) from content_revisions
+			where entity_type = $1 and entity_id = $3 and status <> 'pending'))
+	`,
+		entityType, id, sourceId,
+	)
+	if err != nil {
+		logger.GetLogger().Error("could not execute in database", zap.String("db_msg", err.Error()))
+	}
+	return err
+}
+
+// FindByCourse возвращает происхождение курса и его уроков; пустой список, если курс не клонировался
+func (r *LineageRepository) FindByCourse(c context.Context, courseId int) ([]models.ContentLineage, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c,
+		`
+	select cl.entity_type, cl.entity_id, cl.source_id, cl.source_revision,
+		coalesce((select max(revision) from content_revisions cr
+			where cr.entity_type = cl.entity_type and cr.entity_id = cl.source_id and cr.status <> 'pending'), 0),
+		cl.created_at
+	from content_lineage cl
+	where (cl.entity_type = 'course' and cl.entity_id = $1)
+		or (cl.entity_type = 'lesson' and cl.entity_id in (select lesson_id from course_lessons where course_id = $1))
+	order by cl.entity_type, cl.entity_id
+	`,
+		courseId,
+	)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	lineage := make([]models.ContentLineage, 0)
+	for rows.Next() {
+		var item models.ContentLineage
+		err := rows.Scan(&item.Entity_type, &item.Entity_id, &item.Source_id, &item.Source_revision, &item.Source_latest_revision, &item.Created_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		item.Source_updated = item.Source_latest_revision > item.Source_revision
+		lineage = append(lineage, item)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return lineage, nil
+}
+
+// PullLesson переносит в копию урока текущее содержимое исходника и отмечает, до какой ревизии исходника она обновлена.
+// Видео исходника становится общим, как при клонировании. pgx.ErrNoRows — если исходник удален.
+func (r *LineageRepository) PullLesson(c context.Context, id int, sourceId int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if err := shareLessonVideo(c, tx, sourceId); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(c,
+		`
+	update lessons l
+	set lesson_title = s.lesson_title, description = s.description, body = s.body, body_html = s.body_html,
+		subject_id = s.subject_id, "order" = s."order", "level" = s."level", interest = s.interest,
+		target_age_min = s.target_age_min, target_age_max = s.target_age_max,
+		video_data = null, video_media_id = s.video_media_id, video_filename = s.video_filename,
+		video_mime_type = s.video_mime_type, duration_sec = s.duration_sec,
+		search_vector = null, updated_at = now()
+	from lessons s
+	where l.lesson_id = $1 and l.deleted_at is null and s.lesson_id = $2 and s.deleted_at is null
+	`,
+		id, sourceId,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := markPulled(c, tx, models.EntityLesson, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	refreshSearchVector(c, r.db, models.EntityLesson, id)
+	return nil
+}
+
+// PullCourse переносит в копию курса описание исходника; название остается своим, копии обычно переименовывают.
+// pgx.ErrNoRows — если исходник удален.
+func (r *LineageRepository) PullCourse(c context.Context, id int, sourceId int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	tag, err := tx.Exec(c,
+		`
+	update courses co
+	set description = s.description, search_vector = null, updated_at = now()
+	from courses s
+	where co.id = $1 and co.deleted_at is null and s.id = $2 and s.deleted_at is null
+	`,
+		id, sourceId,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := markPulled(c, tx, models.EntityCourse, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	refreshSearchVector(c, r.db, models.EntityCourse, id)
+	return nil
+}
+
+// ShareLessonVideo переносит видео урока в хранилище media, чтобы на него могла сослаться ревизия копии
+func (r *LineageRepository) ShareLessonVideo(c context.Context, lessonId int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if err := shareLessonVideo(c, tx, lessonId); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// MarkPulled отмечает копию обновленной до последней ревизии исходника, когда обновление ждет одобрения в ревизии pending
+func (r *LineageRepository) MarkPulled(c context.Context, entityType string, id int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if err := markPulled(c, tx, entityType, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// markPulled сдвигает ревизию исходника, от которой считаются обновления, на его последнюю ревизию
+func markPulled(c context.Context, tx pgx.Tx, entityType string, id int) error {
+	_, err := tx.Exec(c,
+		`
+	update content_lineage cl
+	set source_revision = (select coalesce(max(revision), 0) from content_revisions
+		where entity_type = cl.entity_type and entity_id = cl.source_id and status <> 'pending')
+	where cl.entity_type = $1 and cl.entity_id = $2
+	`,
+		entityType, id,
+	)
+	if err != nil {
+		logger.GetLogger().Error("could not execute in database", zap.String("db_msg", err.Error()))
+	}
+	return err
+}
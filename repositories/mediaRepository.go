@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type MediaRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMediaRepository(conn *pgxpool.Pool) *MediaRepository {
+	return &MediaRepository{db: conn}
+}
+
+func (r *MediaRepository) Create(c context.Context, media models.Media) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	row := r.db.QueryRow(c,
+		"insert into media (filename, mime_type, size, checksum) values ($1, $2, $3, $4) returning id",
+		media.Filename, media.Mime_type, media.Size, media.Checksum)
+	if err := row.Scan(&id); err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *MediaRepository) FindById(c context.Context, id int) (models.Media, error) {
+	logger := logger.GetLogger()
+
+	var media models.Media
+	row := r.db.QueryRow(c, "select id, filename, mime_type, size, checksum, created_at from media where id = $1", id)
+	err := row.Scan(&media.Id, &media.Filename, &media.Mime_type, &media.Size, &media.Checksum, &media.Created_at)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.Media{}, err
+	}
+
+	return media, nil
+}
+
+// FindUsage показывает, к чему прикреплен файл: принадлежит ли он ученику (сертификат, файл ответа на задание)
+// и в каких уроках он используется — как ресурс или как media:{id} в тексте урока и его переводов.
+func (r *MediaRepository) FindUsage(c context.Context, mediaId int, userUUID uuid.UUID) (bool, []int, error) {
+	logger := logger.GetLogger()
+
+	var (
+		owned     bool
+		lessonIds []int
+	)
+	err := r.db.QueryRow(c, `
+	select
+		exists (select 1 from certificates where media_id = $1 and user_uuid = $2)
+		or exists (
+			select 1 from submission_files sf
+			join assignment_submissions s on s.id = sf.submission_id
+			where sf.media_id = $1 and s.user_uuid = $2
+		),
+		array(
+			select lesson_id from lesson_resources where media_id = $1
+			union
+			select lesson_id from lessons where deleted_at is null and video_media_id = $1
+			union
+			select lesson_id from lessons where deleted_at is null and body ~ $3
+			union
+			select entity_id from content_translations where entity_type = $4 and body ~ $3
+		)
+	`, mediaId, userUUID, fmt.Sprintf(`\mmedia:%d\M`, mediaId), models.EntityLesson).Scan(&owned, &lessonIds)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return false, nil, err
+	}
+
+	return owned, lessonIds, nil
+}
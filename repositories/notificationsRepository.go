@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type NotificationsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationsRepository(conn *pgxpool.Pool) *NotificationsRepository {
+	return &NotificationsRepository{db: conn}
+}
+
+// FindByUser возвращает уведомления пользователя, новые первыми
+func (r *NotificationsRepository) FindByUser(c context.Context, userUUID uuid.UUID, unreadOnly bool) ([]models.Notification, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	select id, user_uuid, type, title, body, entity_type, entity_id, read_at, created_at
+	from notifications
+	where user_uuid = $1 and (not $2 or read_at is null)
+	order by created_at desc, id desc
+	limit 200
+	`, userUUID, unreadOnly)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(&n.Id, &n.User_uuid, &n.Type, &n.Title, &n.Body, &n.Entity_type, &n.Entity_id, &n.Read_at, &n.Created_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// MarkRead отмечает уведомление прочитанным. pgx.ErrNoRows, если уведомление не принадлежит пользователю.
+func (r *NotificationsRepository) MarkRead(c context.Context, userUUID uuid.UUID, id int) error {
+	logger := logger.GetLogger()
+
+	tag, err := r.db.Exec(c, "update notifications set read_at = coalesce(read_at, now()) where id = $1 and user_uuid = $2", id, userUUID)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// notifyUser создает уведомление в транзакции события, чтобы оно не появилось без самого события
+func notifyUser(c context.Context, tx pgx.Tx, notification models.Notification) error {
+	_, err := tx.Exec(c,
+		`
+	insert into notifications (user_uuid, type, title, body, entity_type, entity_id)
+	values ($1, $2, $3, $4, $5, $6)
+	`,
+		notification.User_uuid, notification.Type, notification.Title, notification.Body, notification.Entity_type, notification.Entity_id,
+	)
+	if err != nil {
+		logger.GetLogger().Error("could not execute in database", zap.String("db_msg", err.Error()))
+	}
+	return err
+}
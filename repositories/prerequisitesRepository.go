@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type PrerequisitesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPrerequisitesRepository(conn *pgxpool.Pool) *PrerequisitesRepository {
+	return &PrerequisitesRepository{db: conn}
+}
+
+type prerequisiteTables struct {
+	edges, entities, idColumn, titleColumn string
+}
+
+// таблицы связей и сущностей для каждого типа контента
+var prerequisiteTablesByEntity = map[string]prerequisiteTables{
+	models.EntityLesson: {"lesson_prerequisites", "lessons", "lesson_id", "lesson_title"},
+	models.EntityCourse: {"course_prerequisites", "courses", "id", "name"},
+}
+
+// курс пройден, если пройдены все его уроки
+const courseCompletedCondition = `not exists (
+	select 1 from course_lessons cl
+	join lessons l on l.lesson_id = cl.lesson_id and l.deleted_at is null
+	where cl.course_id = %s
+		and not exists (select 1 from lesson_completions lc where lc.lesson_id = cl.lesson_id and lc.user_uuid = $1)
+)`
+
+func (r *PrerequisitesRepository) FindByEntity(c context.Context, entityType string, id int) ([]models.PrerequisiteRef, error) {
+	logger := logger.GetLogger()
+
+	tables := prerequisiteTablesByEntity[entityType]
+	rows, err := r.db.Query(c, fmt.Sprintf(`
+	select e.%[3]s, e.%[4]s
+	from %[1]s p
+	join %[2]s e on e.%[3]s = p.prerequisite_id
+	where p.entity_id = $1 and e.deleted_at is null
+	order by e.%[3]s
+	`, tables.edges, tables.entities, tables.idColumn, tables.titleColumn), id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanPrerequisiteRefs(rows)
+}
+
+// Replace заменяет предварительные требования сущности.
+// validate получает весь граф после изменения и может отменить сохранение, например при появлении цикла.
+// Таблица блокируется на время транзакции, чтобы два параллельных сохранения не создали цикл вместе.
+func (r *PrerequisitesRepository) Replace(c context.Context, entityType string, id int, prerequisiteIds []int, validate func(edges map[int][]int) error) error {
+	logger := logger.GetLogger()
+
+	tables := prerequisiteTablesByEntity[entityType]
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err := tx.Exec(c, fmt.Sprintf("lock table %s in share row exclusive mode", tables.edges)); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	if _, err := tx.Exec(c, fmt.Sprintf("delete from %s where entity_id = $1", tables.edges), id); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	for _, prerequisiteId := range prerequisiteIds {
+		tag, err := tx.Exec(c, fmt.Sprintf(`
+		insert into %[1]s (entity_id, prerequisite_id)
+		select $1, %[3]s from %[2]s where %[3]s = $2
+		`, tables.edges, tables.entities, tables.idColumn), id, prerequisiteId)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%s %d not found: %w", entityType, prerequisiteId, pgx.ErrNoRows)
+		}
+	}
+
+	edges, err := findEdges(c, tx, tables.edges)
+	if err != nil {
+		return err
+	}
+	if err := validate(edges); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// FindLessonEdges возвращает предварительные требования для переданных уроков; удаленные уроки-требования пропускаются
+func (r *PrerequisitesRepository) FindLessonEdges(c context.Context, lessonIds []int) (map[int][]int, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	select p.entity_id, p.prerequisite_id
+	from lesson_prerequisites p
+	join lessons l on l.lesson_id = p.prerequisite_id and l.deleted_at is null
+	where p.entity_id = any($1)
+	order by p.prerequisite_id
+	`, lessonIds)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanEdges(rows)
+}
+
+// MissingForLesson возвращает непройденные учеником уроки-требования урока
+// и непройденные курсы-требования курсов, в которые урок входит
+func (r *PrerequisitesRepository) MissingForLesson(c context.Context, userUUID uuid.UUID, lessonId int) ([]models.PrerequisiteRef, []models.PrerequisiteRef, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	select l.lesson_id, l.lesson_title
+	from lesson_prerequisites p
+	join lessons l on l.lesson_id = p.prerequisite_id and l.deleted_at is null
+	where p.entity_id = $2
+		and not exists (select 1 from lesson_completions lc where lc.lesson_id = p.prerequisite_id and lc.user_uuid = $1)
+	order by l.lesson_id
+	`, userUUID, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, nil, err
+	}
+	lessons, err := scanPrerequisiteRefs(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = r.db.Query(c, `
+	select distinct co.id, co.name
+	from course_lessons own
+	join course_prerequisites p on p.entity_id = own.course_id
+	join courses co on co.id = p.prerequisite_id and co.deleted_at is null
+	where own.lesson_id = $2
+		and not `+fmt.Sprintf(courseCompletedCondition, "p.prerequisite_id")+`
+	order by co.id
+	`, userUUID, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, nil, err
+	}
+	courses, err := scanPrerequisiteRefs(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return lessons, courses, nil
+}
+
+// MissingForCourse возвращает непройденные учеником курсы-требования курса
+func (r *PrerequisitesRepository) MissingForCourse(c context.Context, userUUID uuid.UUID, courseId int) ([]models.PrerequisiteRef, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	select co.id, co.name
+	from course_prerequisites p
+	join courses co on co.id = p.prerequisite_id and co.deleted_at is null
+	where p.entity_id = $2
+		and not `+fmt.Sprintf(courseCompletedCondition, "p.prerequisite_id")+`
+	order by co.id
+	`, userUUID, courseId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanPrerequisiteRefs(rows)
+}
+
+func findEdges(c context.Context, tx pgx.Tx, table string) (map[int][]int, error) {
+	logger := logger.GetLogger()
+
+	rows, err := tx.Query(c, fmt.Sprintf("select entity_id, prerequisite_id from %s order by prerequisite_id", table))
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanEdges(rows)
+}
+
+func scanEdges(rows pgx.Rows) (map[int][]int, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	edges := make(map[int][]int)
+	for rows.Next() {
+		var entityId, prerequisiteId int
+		if err := rows.Scan(&entityId, &prerequisiteId); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		edges[entityId] = append(edges[entityId], prerequisiteId)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return edges, nil
+}
+
+func scanPrerequisiteRefs(rows pgx.Rows) ([]models.PrerequisiteRef, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	refs := make([]models.PrerequisiteRef, 0)
+	for rows.Next() {
+		var ref models.PrerequisiteRef
+		if err := rows.Scan(&ref.Id, &ref.Title); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,221 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type ProgressRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProgressRepository(conn *pgxpool.Pool) *ProgressRepository {
+	return &ProgressRepository{db: conn}
+}
+
+const lessonProgressColumns = `
+	l.lesson_id,
+	l.lesson_title,
+	l.duration_sec,
+	coalesce(p.position_sec, 0),
+	coalesce(p.watched_sec, 0),
+	p.updated_at,
+	lc.completed_at
+	from lessons l
+	left join lesson_progress p on p.lesson_id = l.lesson_id and p.user_uuid = $1
+	left join lesson_completions lc on lc.lesson_id = l.lesson_id and lc.user_uuid = $1
+	`
+
+func scanLessonProgress(rows pgx.Rows) ([]models.LessonProgress, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	progress := make([]models.LessonProgress, 0)
+	for rows.Next() {
+		var p models.LessonProgress
+		err := rows.Scan(&p.Lesson_id, &p.Title, &p.Duration_sec, &p.Position_sec, &p.Watched_sec, &p.Updated_at, &p.Completed_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		p.Completed = p.Completed_at != nil
+		if p.Duration_sec > 0 {
+			p.Watched_percent = min(float64(p.Watched_sec)/float64(p.Duration_sec)*100, 100)
+		}
+		progress = append(progress, p)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+// Find возвращает прогресс ученика по уроку, для непросмотренного урока — нулевую позицию
+func (r *ProgressRepository) Find(c context.Context, userUUID uuid.UUID, lessonId int) (models.LessonProgress, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+lessonProgressColumns+`
+	where l.lesson_id = $2 and l.deleted_at is null
+	`, userUUID, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.LessonProgress{}, err
+	}
+
+	progress, err := scanLessonProgress(rows)
+	if err != nil {
+		return models.LessonProgress{}, err
+	}
+	if len(progress) == 0 {
+		return models.LessonProgress{}, pgx.ErrNoRows
+	}
+	return progress[0], nil
+}
+
+// FindByCourse возвращает прогресс ученика по урокам курса в порядке курса
+func (r *ProgressRepository) FindByCourse(c context.Context, userUUID uuid.UUID, courseId int, visibleOnly bool) (models.CourseProgress, error) {
+	logger := logger.GetLogger()
+
+	sql := `select ` + lessonProgressColumns + `
+	join course_lessons cl on cl.lesson_id = l.lesson_id
+	where cl.course_id = $2 and l.deleted_at is null`
+	if visibleOnly {
+		sql += ` and ` + visibleLessonCondition
+	}
+	sql += ` order by cl.position`
+
+	rows, err := r.db.Query(c, sql, userUUID, courseId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.CourseProgress{}, err
+	}
+
+	lessons, err := scanLessonProgress(rows)
+	if err != nil {
+		return models.CourseProgress{}, err
+	}
+
+	progress := models.CourseProgress{
+		Course_id:     courseId,
+		User_uuid:     userUUID,
+		Total_lessons: len(lessons),
+		Lessons:       lessons,
+	}
+	for _, lesson := range lessons {
+		if lesson.Completed {
+			progress.Completed_lessons++
+		}
+	}
+	if progress.Total_lessons > 0 {
+		progress.Percent = float64(progress.Completed_lessons) / float64(progress.Total_lessons) * 100
+	}
+
+	return progress, nil
+}
+
+// RecordHeartbeat сохраняет позицию плеера и засчитывает отрезок, просмотренный с прошлого heartbeat.
+// Перемотки не засчитываются, а всего засчитывается не больше, чем можно досмотреть с первого heartbeat.
+// Когда просмотрена доля threshold от длительности, урок отмечается пройденным.
+func (r *ProgressRepository) RecordHeartbeat(c context.Context, userUUID uuid.UUID, lesson models.Lesson, position int, threshold float64) (models.LessonProgress, error) {
+	logger := logger.GetLogger()
+
+	position = max(position, 0)
+	if lesson.Duration_sec > 0 {
+		position = min(position, lesson.Duration_sec)
+	}
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.LessonProgress{}, err
+	}
+	defer tx.Rollback(c)
+
+	var (
+		lastPosition int
+		rawIntervals []byte
+		elapsedSec   float64
+		// null у прогресса, сохраненного до появления started_at; такой прогресс по времени не ограничивается
+		startedSec *float64
+	)
+	err = tx.QueryRow(c, `
+	select position_sec, watched_intervals, extract(epoch from now() - updated_at)::float8,
+		extract(epoch from now() - started_at)::float8
+	from lesson_progress
+	where user_uuid = $1 and lesson_id = $2
+	for update
+	`, userUUID, lesson.Id).Scan(&lastPosition, &rawIntervals, &elapsedSec, &startedSec)
+	firstHeartbeat := errors.Is(err, pgx.ErrNoRows)
+	if err != nil && !firstHeartbeat {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.LessonProgress{}, err
+	}
+
+	intervals := make([][2]int, 0)
+	if len(rawIntervals) > 0 {
+		if err := json.Unmarshal(rawIntervals, &intervals); err != nil {
+			logger.Error("could not decode watched intervals", zap.Error(err))
+			return models.LessonProgress{}, err
+		}
+	}
+	if !firstHeartbeat {
+		elapsed := time.Duration(elapsedSec * float64(time.Second))
+		if start, end, ok := utils.WatchedSegment(lastPosition, position, elapsed); ok {
+			credited := utils.AddInterval(intervals, start, end)
+			// несколько вкладок или поддельные heartbeat не засчитают больше, чем прошло времени
+			if startedSec == nil || utils.CoveredSeconds(credited) <= utils.MaxWatchedSeconds(time.Duration(*startedSec*float64(time.Second))) {
+				intervals = credited
+			}
+		}
+	}
+	watched := utils.CoveredSeconds(intervals)
+
+	rawIntervals, err = json.Marshal(intervals)
+	if err != nil {
+		return models.LessonProgress{}, err
+	}
+
+	_, err = tx.Exec(c,
+		`
+	insert into lesson_progress (user_uuid, lesson_id, position_sec, watched_intervals, watched_sec, started_at, updated_at)
+	values ($1, $2, $3, $4, $5, now(), now())
+	on conflict (user_uuid, lesson_id) do update
+	set position_sec = excluded.position_sec,
+		watched_intervals = excluded.watched_intervals,
+		watched_sec = excluded.watched_sec,
+		updated_at = now()
+	`,
+		userUUID, lesson.Id, position, rawIntervals, watched,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return models.LessonProgress{}, err
+	}
+
+	// у урока без длительности долю просмотра не посчитать, его отмечают вручную
+	if lesson.Duration_sec > 0 && float64(watched) >= threshold*float64(lesson.Duration_sec) {
+		if err := completeLesson(c, tx, userUUID, lesson.Id); err != nil {
+			return models.LessonProgress{}, err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.LessonProgress{}, err
+	}
+
+	return r.Find(c, userUUID, lesson.Id)
+}
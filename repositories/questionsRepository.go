@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type QuestionsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewQuestionsRepository(conn *pgxpool.Pool) *QuestionsRepository {
+	return &QuestionsRepository{db: conn}
+}
+
+const questionColumns = `
+	id, subject_id, type, text, options, answer_bool, answer_number, tolerance, accepted_answers,
+	points, feedback_correct, feedback_incorrect, created_at, updated_at
+	from questions
+	`
+
+func scanQuestions(rows pgx.Rows) ([]models.Question, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	questions := make([]models.Question, 0)
+	for rows.Next() {
+		var q models.Question
+		err := rows.Scan(&q.Id, &q.Subject_id, &q.Type, &q.Text, &q.Options, &q.Answer_bool, &q.Answer_number, &q.Tolerance, &q.Accepted_answers,
+			&q.Points, &q.Feedback_correct, &q.Feedback_incorrect, &q.Created_at, &q.Updated_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		questions = append(questions, q)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return questions, nil
+}
+
+// FindBySubject возвращает банк вопросов предмета, пустой questionType — все типы
+func (r *QuestionsRepository) FindBySubject(c context.Context, subjectId int, questionType string) ([]models.Question, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+questionColumns+`
+	where subject_id = $1 and ($2 = '' or type = $2)
+	order by id
+	`, subjectId, questionType)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanQuestions(rows)
+}
+
+func (r *QuestionsRepository) FindById(c context.Context, id int) (models.Question, error) {
+	questions, err := r.FindByIds(c, []int{id})
+	if err != nil {
+		return models.Question{}, err
+	}
+	if len(questions) == 0 {
+		return models.Question{}, pgx.ErrNoRows
+	}
+	return questions[id], nil
+}
+
+// FindByIds возвращает вопросы по id, отсутствующие id в результат не попадают
+func (r *QuestionsRepository) FindByIds(c context.Context, ids []int) (map[int]models.Question, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+questionColumns+` where id = any($1)`, ids)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	questions, err := scanQuestions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[int]models.Question, len(questions))
+	for _, question := range questions {
+		byId[question.Id] = question
+	}
+	return byId, nil
+}
+
+func (r *QuestionsRepository) Create(c context.Context, question models.Question) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	err := r.db.QueryRow(c,
+		`
+	insert into questions (subject_id, type, text, options, answer_bool, answer_number, tolerance, accepted_answers,
+		points, feedback_correct, feedback_incorrect)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	returning id
+	`,
+		question.Subject_id, question.Type, question.Text, question.Options, question.Answer_bool, question.Answer_number,
+		question.Tolerance, question.Accepted_answers, question.Points, question.Feedback_correct, question.Feedback_incorrect,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update меняет вопрос. Уже проверенные попытки сохраняют свои результаты.
+func (r *QuestionsRepository) Update(c context.Context, id int, question models.Question) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c,
+		`
+	update questions
+	set type = $1, text = $2, options = $3, answer_bool = $4, answer_number = $5, tolerance = $6, accepted_answers = $7,
+		points = $8, feedback_correct = $9, feedback_incorrect = $10, updated_at = now()
+	where id = $11
+	`,
+		question.Type, question.Text, question.Options, question.Answer_bool, question.Answer_number, question.Tolerance,
+		question.Accepted_answers, question.Points, question.Feedback_correct, question.Feedback_incorrect, id,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Delete удаляет вопрос из банка и из всех квизов
+func (r *QuestionsRepository) Delete(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	for _, sql := range []string{
+		"delete from quiz_questions where question_id = $1",
+		"delete from questions where id = $1",
+	} {
+		if _, err := tx.Exec(c, sql, id); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
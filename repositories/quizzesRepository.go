@@ -0,0 +1,445 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrQuizHasAttempts = errors.New("quiz already has attempts")
+	ErrNoAttemptsLeft  = errors.New("no attempts left")
+	ErrAttemptClosed   = errors.New("attempt is already finished")
+)
+
+type QuizzesRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewQuizzesRepository(conn *pgxpool.Pool) *QuizzesRepository {
+	return &QuizzesRepository{db: conn}
+}
+
+const quizColumns = `
+	q.id, q.title, q.description, q.lesson_id, q.course_id, q.position, q.time_limit_sec, q.max_attempts,
+	q.shuffle_questions, q.shuffle_options, q.pass_percent,
+	coalesce((select array_agg(qq.question_id order by qq.position) from quiz_questions qq where qq.quiz_id = q.id), '{}'),
+	q.created_at, q.updated_at
+	from quizzes q
+	`
+
+func scanQuizzes(rows pgx.Rows) ([]models.Quiz, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	quizzes := make([]models.Quiz, 0)
+	for rows.Next() {
+		var q models.Quiz
+		err := rows.Scan(&q.Id, &q.Title, &q.Description, &q.Lesson_id, &q.Course_id, &q.Position, &q.Time_limit_sec, &q.Max_attempts,
+			&q.Shuffle_questions, &q.Shuffle_options, &q.Pass_percent, &q.Question_ids, &q.Created_at, &q.Updated_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		quizzes = append(quizzes, q)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return quizzes, nil
+}
+
+func (r *QuizzesRepository) FindById(c context.Context, id int) (models.Quiz, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+quizColumns+` where q.id = $1`, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.Quiz{}, err
+	}
+
+	quizzes, err := scanQuizzes(rows)
+	if err != nil {
+		return models.Quiz{}, err
+	}
+	if len(quizzes) == 0 {
+		return models.Quiz{}, pgx.ErrNoRows
+	}
+	return quizzes[0], nil
+}
+
+func (r *QuizzesRepository) FindByLesson(c context.Context, lessonId int) ([]models.Quiz, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+quizColumns+` where q.lesson_id = $1 order by q.position, q.id`, lessonId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanQuizzes(rows)
+}
+
+// FindByCourse возвращает квизы-модули курса в порядке курса
+func (r *QuizzesRepository) FindByCourse(c context.Context, courseId int) ([]models.Quiz, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+quizColumns+` where q.course_id = $1 order by q.position, q.id`, courseId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanQuizzes(rows)
+}
+
+func (r *QuizzesRepository) Create(c context.Context, quiz models.Quiz) (int, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	defer tx.Rollback(c)
+
+	var id int
+	err = tx.QueryRow(c,
+		`
+	insert into quizzes (title, description, lesson_id, course_id, position, time_limit_sec, max_attempts,
+		shuffle_questions, shuffle_options, pass_percent)
+	values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	returning id
+	`,
+		quiz.Title, quiz.Description, quiz.Lesson_id, quiz.Course_id, quiz.Position, quiz.Time_limit_sec, quiz.Max_attempts,
+		quiz.Shuffle_questions, quiz.Shuffle_options, quiz.Pass_percent,
+	).Scan(&id)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	if err := replaceQuizQuestions(c, tx, id, quiz.Question_ids); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update меняет настройки и состав квиза. Начатые попытки сохраняют свой набор вопросов.
+func (r *QuizzesRepository) Update(c context.Context, id int, quiz models.Quiz) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c,
+		`
+	update quizzes
+	set title = $1, description = $2, lesson_id = $3, course_id = $4, position = $5, time_limit_sec = $6,
+		max_attempts = $7, shuffle_questions = $8, shuffle_options = $9, pass_percent = $10, updated_at = now()
+	where id = $11
+	`,
+		quiz.Title, quiz.Description, quiz.Lesson_id, quiz.Course_id, quiz.Position, quiz.Time_limit_sec,
+		quiz.Max_attempts, quiz.Shuffle_questions, quiz.Shuffle_options, quiz.Pass_percent, id,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	if err := replaceQuizQuestions(c, tx, id, quiz.Question_ids); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func replaceQuizQuestions(c context.Context, tx pgx.Tx, quizId int, questionIds []int) error {
+	logger := logger.GetLogger()
+
+	if _, err := tx.Exec(c, "delete from quiz_questions where quiz_id = $1", quizId); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	for position, questionId := range questionIds {
+		_, err := tx.Exec(c, "insert into quiz_questions (quiz_id, question_id, position) values ($1, $2, $3)", quizId, questionId, position)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete удаляет квиз без попыток. ErrQuizHasAttempts, если ученики уже его проходили: оценки терять нельзя.
+func (r *QuizzesRepository) Delete(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	var hasAttempts bool
+	err = tx.QueryRow(c, "select exists (select 1 from quiz_attempts where quiz_id = $1)", id).Scan(&hasAttempts)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if hasAttempts {
+		return ErrQuizHasAttempts
+	}
+
+	for _, sql := range []string{
+		"delete from quiz_questions where quiz_id = $1",
+		"delete from quizzes where id = $1",
+	} {
+		if _, err := tx.Exec(c, sql, id); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+const attemptColumns = `
+	a.id, a.quiz_id, a.user_uuid, a.attempt_number, a.status, a.started_at, a.deadline, a.submitted_at,
+	a.score, a.max_score, a.question_order, a.option_order, a.results, q.pass_percent
+	from quiz_attempts a
+	join quizzes q on q.id = a.quiz_id
+	`
+
+func scanAttempts(rows pgx.Rows) ([]models.QuizAttempt, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	attempts := make([]models.QuizAttempt, 0)
+	for rows.Next() {
+		var a models.QuizAttempt
+		var passPercent float64
+		err := rows.Scan(&a.Id, &a.Quiz_id, &a.User_uuid, &a.Attempt_number, &a.Status, &a.Started_at, &a.Deadline, &a.Submitted_at,
+			&a.Score, &a.Max_score, &a.Question_order, &a.Option_order, &a.Results, &passPercent)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		if a.Max_score > 0 {
+			a.Percent = a.Score / a.Max_score * 100
+		}
+		a.Passed = a.Status != models.AttemptInProgress && a.Percent >= passPercent
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+func (r *QuizzesRepository) FindAttempt(c context.Context, id int) (models.QuizAttempt, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+attemptColumns+` where a.id = $1`, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+
+	attempts, err := scanAttempts(rows)
+	if err != nil {
+		return models.QuizAttempt{}, err
+	}
+	if len(attempts) == 0 {
+		return models.QuizAttempt{}, pgx.ErrNoRows
+	}
+	return attempts[0], nil
+}
+
+// FindAttempts возвращает попытки ученика по квизу в порядке прохождения
+func (r *QuizzesRepository) FindAttempts(c context.Context, quizId int, userUUID uuid.UUID) ([]models.QuizAttempt, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `select `+attemptColumns+` where a.quiz_id = $1 and a.user_uuid = $2 order by a.attempt_number`, quizId, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanAttempts(rows)
+}
+
+// StartAttempt возвращает незавершенную попытку ученика или начинает новую с переданным порядком вопросов.
+// Попытки, у которых истекло время (с запасом grace), закрываются с нулевым баллом.
+// ErrNoAttemptsLeft, если лимит попыток исчерпан.
+func (r *QuizzesRepository) StartAttempt(c context.Context, quiz models.Quiz, userUUID uuid.UUID, attempt models.QuizAttempt, grace time.Duration) (models.QuizAttempt, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+	defer tx.Rollback(c)
+
+	// параллельный старт двух попыток обошел бы лимит
+	if _, err := tx.Exec(c, "select pg_advisory_xact_lock($1, hashtext($2))", quiz.Id, userUUID.String()); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+
+	_, err = tx.Exec(c,
+		`
+	update quiz_attempts
+	set status = 'expired', submitted_at = deadline, score = 0
+	where quiz_id = $1 and user_uuid = $2 and status = 'in_progress' and deadline < $3
+	`,
+		quiz.Id, userUUID, time.Now().Add(-grace),
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+
+	var (
+		started    int
+		inProgress *int
+	)
+	err = tx.QueryRow(c,
+		`select count(*), max(id) filter (where status = 'in_progress') from quiz_attempts where quiz_id = $1 and user_uuid = $2`,
+		quiz.Id, userUUID,
+	).Scan(&started, &inProgress)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+
+	id := 0
+	switch {
+	case inProgress != nil:
+		id = *inProgress
+	case quiz.Max_attempts != nil && started >= *quiz.Max_attempts:
+		return models.QuizAttempt{}, ErrNoAttemptsLeft
+	default:
+		err = tx.QueryRow(c,
+			`
+		insert into quiz_attempts (quiz_id, user_uuid, attempt_number, status, deadline, max_score, question_order, option_order)
+		values ($1, $2, $3, 'in_progress', now() + make_interval(secs => $4), $5, $6, $7)
+		returning id
+		`,
+			quiz.Id, userUUID, started+1, quiz.Time_limit_sec, attempt.Max_score, attempt.Question_order, attempt.Option_order,
+		).Scan(&id)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.QuizAttempt{}, err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+
+	return r.FindAttempt(c, id)
+}
+
+// SubmitAttempt сохраняет результаты проверки. ErrAttemptClosed, если попытка уже сдана или закрыта по времени.
+// Опыт начисляется за прирост лучшего результата ученика по квизу.
+func (r *QuizzesRepository) SubmitAttempt(c context.Context, id int, status string, score float64, results []models.QuestionResult) (models.QuizAttempt, error) {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+	defer tx.Rollback(c)
+
+	var (
+		quizId   int
+		userUUID uuid.UUID
+		maxScore float64
+	)
+	err = tx.QueryRow(c,
+		`
+	update quiz_attempts
+	set status = $2, score = $3, results = $4, submitted_at = now()
+	where id = $1 and status = 'in_progress'
+	returning quiz_id, user_uuid, max_score::float8
+	`,
+		id, status, score, results,
+	).Scan(&quizId, &userUUID, &maxScore)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.QuizAttempt{}, ErrAttemptClosed
+	}
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+
+	if status == models.AttemptSubmitted && maxScore > 0 {
+		var previousBest float64
+		err := tx.QueryRow(c,
+			`
+		select coalesce(max(score / nullif(max_score, 0) * 100), 0)::float8 from quiz_attempts
+		where quiz_id = $1 and user_uuid = $2 and id <> $3 and status <> 'in_progress'
+		`,
+			quizId, userUUID, id,
+		).Scan(&previousBest)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.QuizAttempt{}, err
+		}
+
+		err = applyGamificationEvent(c, tx, models.GamificationEvent{
+			User_uuid:    userUUID,
+			Type:         models.GamificationQuizSubmitted,
+			Entity_id:    quizId,
+			Xp:           utils.QuizXP(previousBest, score/maxScore*100),
+			Learner_made: true,
+		})
+		if err != nil {
+			return models.QuizAttempt{}, err
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return models.QuizAttempt{}, err
+	}
+
+	return r.FindAttempt(c, id)
+}
@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const (
+	// пара уроков учитывается, если оба прошли хотя бы столько учеников
+	cooccurrenceMinLearners = 2
+	// сколько похожих уроков хранится для каждого урока
+	cooccurrenceTopN = 20
+)
+
+type RecommendationsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRecommendationsRepository(conn *pgxpool.Pool) *RecommendationsRepository {
+	return &RecommendationsRepository{db: conn}
+}
+
+// FindProfile возвращает профиль ученика; если ученик его не заполнял — пустой профиль
+func (r *RecommendationsRepository) FindProfile(c context.Context, userUUID uuid.UUID) (models.LearnerProfile, error) {
+	logger := logger.GetLogger()
+
+	profile := models.LearnerProfile{User_uuid: userUUID, Interests: make([]string, 0)}
+	err := r.db.QueryRow(c,
+		"select interests, age, level, updated_at from learner_profiles where user_uuid = $1", userUUID,
+	).Scan(&profile.Interests, &profile.Age, &profile.Level, &profile.Updated_at)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return profile, nil
+	}
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.LearnerProfile{}, err
+	}
+	return profile, nil
+}
+
+func (r *RecommendationsRepository) SaveProfile(c context.Context, profile models.LearnerProfile) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c,
+		`
+	insert into learner_profiles (user_uuid, interests, age, level, updated_at)
+	values ($1, $2, $3, $4, now())
+	on conflict (user_uuid) do update
+	set interests = excluded.interests, age = excluded.age, level = excluded.level, updated_at = now()
+	`,
+		profile.User_uuid, profile.Interests, profile.Age, profile.Level,
+	)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// FindCandidates возвращает видимые уроки, которые ученик еще не прошел, с сигналами для ранжирования:
+// сходство с пройденными уроками по lesson_cooccurrence, пройденные уроки того же предмета и популярность.
+func (r *RecommendationsRepository) FindCandidates(c context.Context, userUUID uuid.UUID) ([]models.RecommendationCandidate, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	with done as (
+		select lesson_id from lesson_completions where user_uuid = $1
+	),
+	similar as (
+		select co.related_lesson_id as lesson_id, sum(co.score) as similarity,
+			(array_agg(src.title order by co.score desc, src.lesson_id))[1] as similar_lesson
+		from lesson_cooccurrence co
+		join done on done.lesson_id = co.lesson_id
+		join lessons src on src.lesson_id = co.lesson_id and src.deleted_at is null
+		group by co.related_lesson_id
+	),
+	subjects as (
+		select l.subject_id, count(*) as completed
+		from done join lessons l on l.lesson_id = done.lesson_id
+		group by l.subject_id
+	),
+	popularity as (
+		select lesson_id, count(*) as learners from lesson_completions group by lesson_id
+	)
+	select l.lesson_id, l.title, l.subject_id, l.level, l.interest, l.target_age_min, l.target_age_max,
+		coalesce(p.learners, 0), coalesce(sim.similarity, 0), coalesce(sim.similar_lesson, ''), coalesce(s.completed, 0)
+	from lessons l
+	left join similar sim on sim.lesson_id = l.lesson_id
+	left join subjects s on s.subject_id = l.subject_id
+	left join popularity p on p.lesson_id = l.lesson_id
+	where l.deleted_at is null and `+visibleLessonCondition+`
+		and l.lesson_id not in (select lesson_id from done)
+	`, userUUID)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]models.RecommendationCandidate, 0)
+	for rows.Next() {
+		var candidate models.RecommendationCandidate
+		err := rows.Scan(&candidate.Lesson_id, &candidate.Title, &candidate.Subject_id, &candidate.Level, &candidate.Interest,
+			&candidate.Target_age_min, &candidate.Target_age_max,
+			&candidate.Learners, &candidate.Similarity, &candidate.Similar_lesson, &candidate.Subject_completed)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// RecomputeCooccurrence пересчитывает похожие уроки: два урока похожи, если их прошли одни и те же ученики.
+// score — косинусная мера count(оба) / sqrt(count(a) * count(b)); для урока хранятся лучшие cooccurrenceTopN.
+func (r *RecommendationsRepository) RecomputeCooccurrence(c context.Context) (int, bool, error) {
+	logger := logger.GetLogger()
+
+	stored := 0
+	acquired, err := WithAdvisoryLock(c, r.db, AdvisoryLockRecommendations, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(c, "delete from lesson_cooccurrence"); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+
+		tag, err := tx.Exec(c, `
+		with learners as (
+			select lesson_id, count(*) as learners from lesson_completions group by lesson_id
+		),
+		pairs as (
+			select a.lesson_id, b.lesson_id as related_lesson_id, count(*) as learners,
+				count(*) / sqrt(la.learners::float8 * lb.learners) as score
+			from lesson_completions a
+			join lesson_completions b on b.user_uuid = a.user_uuid and b.lesson_id <> a.lesson_id
+			join learners la on la.lesson_id = a.lesson_id
+			join learners lb on lb.lesson_id = b.lesson_id
+			group by a.lesson_id, b.lesson_id, la.learners, lb.learners
+			having count(*) >= $1
+		)
+		insert into lesson_cooccurrence (lesson_id, related_lesson_id, learners, score, computed_at)
+		select lesson_id, related_lesson_id, learners, score, now()
+		from (
+			select pairs.*, row_number() over (partition by lesson_id order by score desc, related_lesson_id) as rank
+			from pairs
+		) ranked
+		where rank <= $2
+		`, cooccurrenceMinLearners, cooccurrenceTopN)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		stored = int(tag.RowsAffected())
+		return nil
+	})
+
+	return stored, acquired, err
+}
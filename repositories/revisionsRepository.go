@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type RevisionsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRevisionsRepository(conn *pgxpool.Pool) *RevisionsRepository {
+	return &RevisionsRepository{db: conn}
+}
+
+const insertRevisionQuery = `
+	insert into content_revisions (entity_type, entity_id, revision, status, comment, data, author_uuid)
+	values ($1, $2,
+		(select coalesce(max(revision), 0) + 1 from content_revisions where entity_type = $1 and entity_id = $2),
+		$3, $4, $5, $6)
+	returning revision
+	`
+
+func insertRevisionArgs(revision models.ContentRevision) []any {
+	return []any{revision.Entity_type, revision.Entity_id, revision.Status, revision.Comment, revision.Data, revision.Author_uuid}
+}
+
+// Create добавляет новую неизменяемую ревизию со следующим номером.
+// Уникальный индекс (entity_type, entity_id, revision) не даст записать две ревизии с одним номером.
+func (r *RevisionsRepository) Create(c context.Context, revision models.ContentRevision) (int, error) {
+	logger := logger.GetLogger()
+
+	var number int
+	row := r.db.QueryRow(c, insertRevisionQuery, insertRevisionArgs(revision)...)
+	if err := row.Scan(&number); err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	return number, nil
+}
+
+// FindByEntity возвращает историю ревизий без снимков данных
+func (r *RevisionsRepository) FindByEntity(c context.Context, entityType string, entityId int) ([]models.ContentRevision, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c,
+		`
+	select id, entity_type, entity_id, revision, status, comment, author_uuid, created_at
+	from content_revisions
+	where entity_type = $1 and entity_id = $2
+	order by revision desc
+	`, entityType, entityId)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := make([]models.ContentRevision, 0)
+	for rows.Next() {
+		var revision models.ContentRevision
+		err := rows.Scan(&revision.Id, &revision.Entity_type, &revision.Entity_id, &revision.Revision, &revision.Status, &revision.Comment, &revision.Author_uuid, &revision.Created_at)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (r *RevisionsRepository) FindByRevision(c context.Context, entityType string, entityId int, number int) (models.ContentRevision, error) {
+	logger := logger.GetLogger()
+
+	var revision models.ContentRevision
+	row := r.db.QueryRow(c,
+		`
+	select id, entity_type, entity_id, revision, status, comment, data, author_uuid, created_at
+	from content_revisions
+	where entity_type = $1 and entity_id = $2 and revision = $3
+	`, entityType, entityId, number)
+	err := row.Scan(&revision.Id, &revision.Entity_type, &revision.Entity_id, &revision.Revision, &revision.Status, &revision.Comment, &revision.Data, &revision.Author_uuid, &revision.Created_at)
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.ContentRevision{}, err
+	}
+
+	return revision, nil
+}
+
+// HasAppliedAfter сообщает, менялась ли сущность после ревизии number; ревизии pending изменениями не считаются
+func (r *RevisionsRepository) HasAppliedAfter(c context.Context, entityType string, entityId int, number int) (bool, error) {
+	logger := logger.GetLogger()
+
+	var changed bool
+	row := r.db.QueryRow(c,
+		`
+	select exists(select 1 from content_revisions
+		where entity_type = $1 and entity_id = $2 and revision > $3 and status <> 'pending')
+	`, entityType, entityId, number)
+	if err := row.Scan(&changed); err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return false, err
+	}
+
+	return changed, nil
+}
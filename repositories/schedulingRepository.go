@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// ключи pg_advisory_xact_lock для фоновых задач, чтобы при нескольких репликах задачу выполняла только одна
+const (
+	AdvisoryLockPublication     int64 = 3001
+	AdvisoryLockSearchIndex     int64 = 3002
+	AdvisoryLockTrashPurge      int64 = 3003
+	AdvisoryLockCertificates    int64 = 3004
+	AdvisoryLockRecommendations int64 = 3005
+	AdvisoryLockLeaderboards    int64 = 3006
+)
+
+type SchedulingRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSchedulingRepository(conn *pgxpool.Pool) *SchedulingRepository {
+	return &SchedulingRepository{db: conn}
+}
+
+type ScheduleResult struct {
+	Published   int
+	Unpublished int
+}
+
+// WithAdvisoryLock выполняет fn в транзакции, если удалось взять advisory lock.
+// Если lock держит другая реплика, возвращает false без ошибки.
+func WithAdvisoryLock(c context.Context, db *pgxpool.Pool, key int64, fn func(tx pgx.Tx) error) (bool, error) {
+	logger := logger.GetLogger()
+
+	tx, err := db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return false, err
+	}
+	defer tx.Rollback(c)
+
+	var acquired bool
+	if err := tx.QueryRow(c, "select pg_try_advisory_xact_lock($1)", key).Scan(&acquired); err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return false, err
+	}
+	if !acquired {
+		return false, nil
+	}
+
+	if err := fn(tx); err != nil {
+		return true, err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return true, err
+	}
+	return true, nil
+}
+
+// ApplySchedules публикует уроки и курсы, у которых наступил publish_at, и архивирует те, у которых прошел unpublish_at.
+// Каждый переход записывается ревизией со снимком из последней примененной ревизии. Сработавшая дата очищается:
+// расписание срабатывает один раз, и возвращенный на доработку контент снова не опубликуется.
+func (r *SchedulingRepository) ApplySchedules(c context.Context) (ScheduleResult, bool, error) {
+	var result ScheduleResult
+
+	acquired, err := WithAdvisoryLock(c, r.db, AdvisoryLockPublication, func(tx pgx.Tx) error {
+		for _, table := range []struct{ name, idColumn, entityType string }{
+			{"lessons", "lesson_id", models.EntityLesson},
+			{"courses", "id", models.EntityCourse},
+		} {
+			published, err := applyTransition(c, tx, table.name, table.idColumn, table.entityType,
+				"deleted_at is null and status in ('draft', 'in_review') and publish_at <= now() and (unpublish_at is null or unpublish_at > now())",
+				"publish_at", models.StatusPublished, "scheduled publish")
+			if err != nil {
+				return err
+			}
+
+			unpublished, err := applyTransition(c, tx, table.name, table.idColumn, table.entityType,
+				"deleted_at is null and status = 'published' and unpublish_at <= now()",
+				"unpublish_at", models.StatusArchived, "scheduled unpublish")
+			if err != nil {
+				return err
+			}
+
+			result.Published += published
+			result.Unpublished += unpublished
+		}
+		return nil
+	})
+
+	return result, acquired, err
+}
+
+// applyTransition переводит строки, подходящие под condition, в статус status и очищает сработавшую дату dateColumn
+func applyTransition(c context.Context, tx pgx.Tx, table, idColumn, entityType, condition, dateColumn, status, comment string) (int, error) {
+	logger := logger.GetLogger()
+
+	sql := fmt.Sprintf(`
+	with changed as (
+		update %[1]s
+		set status = $1, is_published = ($1 = 'published'), %[4]s = null, updated_at = now()
+		where %[3]s
+		returning %[2]s as id
+	)
+	insert into content_revisions (entity_type, entity_id, revision, status, comment, data)
+	select $2, changed.id,
+		coalesce((select max(revision) from content_revisions where entity_type = $2 and entity_id = changed.id), 0) + 1,
+		$1, $3,
+		coalesce(last.data, '{}'::jsonb)
+	from changed
+	left join lateral (
+		select data from content_revisions
+		where entity_type = $2 and entity_id = changed.id and status <> 'pending'
+		order by revision desc
+		limit 1
+	) last on true
+	`, table, idColumn, condition, dateColumn)
+
+	tag, err := tx.Exec(c, sql, status, entityType, comment)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,291 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// конфигурации полнотекстового поиска: russian и english со стеммингом,
+// simple — для казахского (своего словаря в Postgres нет) и для точных префиксов
+var searchConfigs = []string{"russian", "english", "simple"}
+
+// веса полей по порядку: заголовок, описание, тело
+const searchWeights = "ABC"
+
+type searchTable struct {
+	name, idColumn string
+	fields         []string
+	// колонки content_translations с переводами fields, в том же порядке
+	translations []string
+}
+
+var searchTables = map[string]searchTable{
+	models.EntityLesson:  {"lessons", "lesson_id", []string{"lesson_title", "description", "body"}, []string{"title", "description", "body"}},
+	models.EntityCourse:  {"courses", "id", []string{"name", "description"}, []string{"title", "description"}},
+	models.EntitySubject: {"subjects", "id", []string{"name"}, []string{"title"}},
+}
+
+type SearchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSearchRepository(conn *pgxpool.Pool) *SearchRepository {
+	return &SearchRepository{db: conn}
+}
+
+// searchVector собирает выражение tsvector из колонок таблицы с весами A, B, C.
+// К каждой колонке добавляются ее переводы на все языки, поэтому урок находится и по переведенному тексту.
+func searchVector(entityType string) string {
+	table := searchTables[entityType]
+	parts := make([]string, 0, len(table.fields)*len(searchConfigs))
+	for i, field := range table.fields {
+		text := fmt.Sprintf(
+			"coalesce(%s, '') || ' ' || coalesce((select string_agg(t.%s, ' ') from content_translations t where t.entity_type = '%s' and t.entity_id = %s.%s), '')",
+			field, table.translations[i], entityType, table.name, table.idColumn)
+		for _, config := range searchConfigs {
+			parts = append(parts, fmt.Sprintf("setweight(to_tsvector('%s', %s), '%c')", config, text, searchWeights[i]))
+		}
+	}
+	return strings.Join(parts, " || ")
+}
+
+// searchQuery объединяет tsquery во всех конфигурациях, чтобы запрос находил текст на любом из языков
+func searchQuery(param string) string {
+	parts := make([]string, len(searchConfigs))
+	for i, config := range searchConfigs {
+		parts[i] = fmt.Sprintf("to_tsquery('%s', %s)", config, param)
+	}
+	return "(" + strings.Join(parts, " || ") + ")"
+}
+
+// refreshSearchVector пересчитывает search_vector после изменения текста.
+// Ошибка только логируется: колонка остается null и ее заполнит фоновая индексация.
+func refreshSearchVector(c context.Context, db *pgxpool.Pool, entityType string, id int) {
+	logger := logger.GetLogger()
+
+	table := searchTables[entityType]
+	_, err := db.Exec(c, fmt.Sprintf("update %s set search_vector = %s where %s = $1", table.name, searchVector(entityType), table.idColumn), id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+	}
+}
+
+// resetSearchVector сбрасывает search_vector, чтобы сущность переиндексировалась с новым текстом
+func resetSearchVector(c context.Context, db *pgxpool.Pool, entityType string, id int) error {
+	logger := logger.GetLogger()
+
+	table := searchTables[entityType]
+	_, err := db.Exec(c, fmt.Sprintf("update %s set search_vector = null where %s = $1", table.name, table.idColumn), id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+	}
+	return err
+}
+
+// IndexMissing заполняет search_vector у записей, которые еще не проиндексированы
+func (r *SearchRepository) IndexMissing(c context.Context) (int, bool, error) {
+	logger := logger.GetLogger()
+
+	indexed := 0
+	acquired, err := WithAdvisoryLock(c, r.db, AdvisoryLockSearchIndex, func(tx pgx.Tx) error {
+		for entityType, table := range searchTables {
+			tag, err := tx.Exec(c, fmt.Sprintf("update %s set search_vector = %s where search_vector is null", table.name, searchVector(entityType)))
+			if err != nil {
+				logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+				return err
+			}
+			indexed += int(tag.RowsAffected())
+		}
+		return nil
+	})
+
+	return indexed, acquired, err
+}
+
+// matchesSQL строит CTE с найденными уроками, курсами и предметами с учетом фильтров
+func matchesSQL(filter models.SearchFilter) (string, []any, bool) {
+	args := []any{filter.Query}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	wanted := func(entityType string) bool {
+		if len(filter.Types) == 0 {
+			return true
+		}
+		for _, t := range filter.Types {
+			if t == entityType {
+				return true
+			}
+		}
+		return false
+	}
+
+	parts := make([]string, 0, 3)
+
+	if wanted(models.EntityLesson) {
+		sql := `
+		select 'lesson'::text as type, l.lesson_id as id, l.lesson_title as title,
+			concat_ws(' ', l.description, l.body) as content, l.subject_id, l.level::text as level,
+			ts_rank_cd(l.search_vector, q.query) as rank
+		from lessons l, q
+		where l.search_vector @@ q.query and l.deleted_at is null`
+		if filter.Visible_only {
+			sql += ` and ` + visibleLessonCondition
+		}
+		if filter.Subject_id != nil {
+			sql += ` and l.subject_id = ` + arg(*filter.Subject_id)
+		}
+		if filter.Level != "" {
+			sql += ` and l.level = ` + arg(filter.Level)
+		}
+		for _, group := range filter.Tag_groups {
+			sql += ` and exists (select 1 from lesson_tags lt where lt.entity_id = l.lesson_id and lt.tag_id = any(` + arg(group) + `))`
+		}
+		parts = append(parts, sql)
+	}
+
+	// у курсов нет предмета и уровня, поэтому при таких фильтрах они не ищутся
+	if wanted(models.EntityCourse) && filter.Subject_id == nil && filter.Level == "" {
+		sql := `
+		select 'course'::text, co.id, co.name, co.description, null::int, null::text,
+			ts_rank_cd(co.search_vector, q.query)
+		from courses co, q
+		where co.search_vector @@ q.query and co.deleted_at is null`
+		if filter.Visible_only {
+			sql += ` and ` + visibleCourseCondition
+		}
+		for _, group := range filter.Tag_groups {
+			sql += ` and exists (select 1 from course_tags ct where ct.entity_id = co.id and ct.tag_id = any(` + arg(group) + `))`
+		}
+		parts = append(parts, sql)
+	}
+
+	if wanted(models.EntitySubject) && filter.Level == "" && len(filter.Tag_groups) == 0 {
+		sql := `
+		select 'subject'::text, s.id, s.name, s.name, s.id, null::text,
+			ts_rank_cd(s.search_vector, q.query)
+		from subjects s, q
+		where s.search_vector @@ q.query and s.deleted_at is null`
+		if filter.Subject_id != nil {
+			sql += ` and s.id = ` + arg(*filter.Subject_id)
+		}
+		parts = append(parts, sql)
+	}
+
+	if len(parts) == 0 {
+		return "", nil, false
+	}
+
+	return `with q as (select ` + searchQuery("$1") + ` as query),
+	matches as (` + strings.Join(parts, "\n\t\tunion all") + `
+	)`, args, true
+}
+
+// Search ищет по урокам, курсам и предметам и считает фасеты по всем найденным записям
+func (r *SearchRepository) Search(c context.Context, filter models.SearchFilter) (models.SearchResponse, error) {
+	logger := logger.GetLogger()
+
+	response := models.SearchResponse{
+		Results: make([]models.SearchResult, 0),
+		Facets:  map[string][]models.SearchFacet{},
+	}
+
+	matches, args, ok := matchesSQL(filter)
+	if !ok {
+		return response, nil
+	}
+
+	if err := r.db.QueryRow(c, matches+` select count(*) from matches`, args...).Scan(&response.Total); err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.SearchResponse{}, err
+	}
+
+	headlineOptions := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter=\" … \"",
+		utils.HighlightStart, utils.HighlightStop)
+	resultArgs := append(append([]any{}, args...), headlineOptions, filter.Limit, filter.Offset)
+	n := len(args)
+
+	rows, err := r.db.Query(c, matches+fmt.Sprintf(`
+	select m.type, m.id, m.title, ts_headline('simple', m.content, q.query, $%d), m.rank, m.subject_id, m.level
+	from matches m, q
+	order by m.rank desc, m.type, m.id
+	limit $%d offset $%d
+	`, n+1, n+2, n+3), resultArgs...)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.SearchResponse{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var result models.SearchResult
+		err := rows.Scan(&result.Type, &result.Id, &result.Title, &result.Snippet, &result.Rank, &result.Subject_id, &result.Level)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.SearchResponse{}, err
+		}
+
+		result.Snippet = utils.HighlightSnippet(result.Snippet)
+		response.Results = append(response.Results, result)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return models.SearchResponse{}, err
+	}
+
+	facetRows, err := r.db.Query(c, matches+`
+	select 'subject', s.id::text, s.name, count(*)
+	from matches m
+	join subjects s on s.id = m.subject_id
+	where m.type = 'lesson'
+	group by s.id, s.name
+	union all
+	select 'level', m.level, m.level, count(*)
+	from matches m
+	where m.level is not null
+	group by m.level
+	union all
+	select 'tag', t.slug, t.name, count(*)
+	from matches m
+	join (
+		select 'lesson'::text as type, entity_id, tag_id from lesson_tags
+		union all
+		select 'course'::text, entity_id, tag_id from course_tags
+	) et on et.type = m.type and et.entity_id = m.id
+	join tags t on t.id = et.tag_id
+	group by t.slug, t.name
+	order by 1, 4 desc, 3
+	`, args...)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return models.SearchResponse{}, err
+	}
+	defer facetRows.Close()
+
+	for facetRows.Next() {
+		var name string
+		var facet models.SearchFacet
+		if err := facetRows.Scan(&name, &facet.Value, &facet.Label, &facet.Count); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return models.SearchResponse{}, err
+		}
+
+		response.Facets[name] = append(response.Facets[name], facet)
+	}
+	if err := facetRows.Err(); err != nil {
+		logger.Error(err.Error())
+		return models.SearchResponse{}, err
+	}
+
+	return response, nil
+}
@@ -21,7 +21,7 @@ func (r *SubjectsRepository) FindById(c context.Context, id int) (models.Subject
 	logger := logger.GetLogger()
 
 	var subject models.Subject
-	row := r.db.QueryRow(c, "select id, name from subjects where id = $1", id)
+	row := r.db.QueryRow(c, "select id, name from subjects where id = $1 and deleted_at is null", id)
 	err := row.Scan(&subject.Id, &subject.Name)
 	if err != nil {
 		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
@@ -34,7 +34,7 @@ func (r *SubjectsRepository) FindById(c context.Context, id int) (models.Subject
 func (r *SubjectsRepository) FindAll(c context.Context) ([]models.Subject, error) {
 	logger := logger.GetLogger()
 
-	rows, err := r.db.Query(c, "select id, name from subjects")
+	rows, err := r.db.Query(c, "select id, name from subjects where deleted_at is null")
 	if err != nil {
 		logger.Error("could not query database", zap.String("db_msg", err.Error()))
 		return []models.Subject{}, err
@@ -68,6 +68,7 @@ func (r *SubjectsRepository) Create(c context.Context, subject models.Subject) (
 		return 0, err
 	}
 
+	refreshSearchVector(c, r.db, models.EntitySubject, id)
 	return id, nil
 
 }
@@ -75,18 +76,21 @@ func (r *SubjectsRepository) Create(c context.Context, subject models.Subject) (
 func (r *SubjectsRepository) Update(c context.Context, id int, Updsubject models.Subject) error {
 	logger := logger.GetLogger()
 
-	_, err := r.db.Exec(c, "update subjects set name = $1 where id = $2", Updsubject.Name, id)
+	_, err := r.db.Exec(c, "update subjects set name = $1, search_vector = null where id = $2", Updsubject.Name, id)
 	if err != nil {
 		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
 		return err
 	}
+
+	refreshSearchVector(c, r.db, models.EntitySubject, id)
 	return nil
 }
 
+// Delete переносит предмет в корзину, окончательно его удалит фоновая очистка корзины
 func (r *SubjectsRepository) Delete(c context.Context, id int) error {
 	logger := logger.GetLogger()
 
-	_, err := r.db.Exec(c, "delete from subjects where id = $1", id)
+	_, err := r.db.Exec(c, "update subjects set deleted_at = now() where id = $1 and deleted_at is null", id)
 	if err != nil {
 		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
 		return err
@@ -0,0 +1,274 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var ErrDuplicateSlug = errors.New("tag with this slug already exists")
+
+type TagsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTagsRepository(conn *pgxpool.Pool) *TagsRepository {
+	return &TagsRepository{db: conn}
+}
+
+// таблицы связей тегов для каждого типа контента
+var tagTablesByEntity = map[string]string{
+	models.EntityLesson: "lesson_tags",
+	models.EntityCourse: "course_tags",
+}
+
+func scanTag(row interface{ Scan(...any) error }) (models.Tag, error) {
+	var tag models.Tag
+	err := row.Scan(&tag.Id, &tag.Name, &tag.Slug, &tag.Parent_id, &tag.Created_at)
+	return tag, err
+}
+
+func scanTags(rows pgx.Rows) ([]models.Tag, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	tags := make([]models.Tag, 0)
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (r *TagsRepository) FindAll(c context.Context) ([]models.Tag, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, "select id, name, slug, parent_id, created_at from tags order by parent_id nulls first, name")
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanTags(rows)
+}
+
+func (r *TagsRepository) FindById(c context.Context, id int) (models.Tag, error) {
+	logger := logger.GetLogger()
+
+	tag, err := scanTag(r.db.QueryRow(c, "select id, name, slug, parent_id, created_at from tags where id = $1", id))
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return models.Tag{}, err
+	}
+	return tag, nil
+}
+
+func (r *TagsRepository) Create(c context.Context, tag models.Tag) (int, error) {
+	logger := logger.GetLogger()
+
+	var id int
+	err := r.db.QueryRow(c, "insert into tags (name, slug, parent_id) values ($1, $2, $3) returning id", tag.Name, tag.Slug, tag.Parent_id).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, ErrDuplicateSlug
+	}
+	if err != nil {
+		logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return id, nil
+}
+
+// Update меняет тег. validate получает иерархию после изменения (тег -> родитель) и может отменить сохранение.
+func (r *TagsRepository) Update(c context.Context, id int, tag models.Tag, validate func(edges map[int][]int) error) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err := tx.Exec(c, "lock table tags in share row exclusive mode"); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	_, err = tx.Exec(c, "update tags set name = $1, slug = $2, parent_id = $3 where id = $4", tag.Name, tag.Slug, tag.Parent_id, id)
+	if isUniqueViolation(err) {
+		return ErrDuplicateSlug
+	}
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	rows, err := tx.Query(c, "select id, parent_id from tags where parent_id is not null")
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	edges, err := scanEdges(rows)
+	if err != nil {
+		return err
+	}
+	if err := validate(edges); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Delete удаляет тег, дочерние теги переходят к его родителю
+func (r *TagsRepository) Delete(c context.Context, id int) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	_, err = tx.Exec(c, "update tags set parent_id = (select parent_id from tags where id = $1) where parent_id = $1", id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	for _, table := range tagTablesByEntity {
+		if _, err := tx.Exec(c, fmt.Sprintf("delete from %s where tag_id = $1", table), id); err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(c, "delete from tags where id = $1", id); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// ExpandSlugs возвращает для каждого slug id тега вместе со всеми его потомками.
+// Неизвестные slug в результат не попадают.
+func (r *TagsRepository) ExpandSlugs(c context.Context, slugs []string) (map[string][]int, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, `
+	with recursive subtree as (
+		select slug as root, id from tags where slug = any($1)
+		union all
+		select s.root, t.id from tags t join subtree s on t.parent_id = s.id
+	)
+	select root, id from subtree
+	`, slugs)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	expanded := make(map[string][]int)
+	for rows.Next() {
+		var root string
+		var id int
+		if err := rows.Scan(&root, &id); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		expanded[root] = append(expanded[root], id)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return expanded, nil
+}
+
+func (r *TagsRepository) FindByEntity(c context.Context, entityType string, id int) ([]models.Tag, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, fmt.Sprintf(`
+	select t.id, t.name, t.slug, t.parent_id, t.created_at
+	from %s et
+	join tags t on t.id = et.tag_id
+	where et.entity_id = $1
+	order by t.name
+	`, tagTablesByEntity[entityType]), id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanTags(rows)
+}
+
+// ReplaceForEntity заменяет теги урока или курса
+func (r *TagsRepository) ReplaceForEntity(c context.Context, entityType string, id int, tagIds []int) error {
+	logger := logger.GetLogger()
+
+	table := tagTablesByEntity[entityType]
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	if _, err := tx.Exec(c, fmt.Sprintf("delete from %s where entity_id = $1", table), id); err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	for _, tagId := range tagIds {
+		tag, err := tx.Exec(c, fmt.Sprintf("insert into %s (entity_id, tag_id) select $1, id from tags where id = $2", table), id, tagId)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("tag %d not found: %w", tagId, pgx.ErrNoRows)
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
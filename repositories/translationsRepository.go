@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type TranslationsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTranslationsRepository(conn *pgxpool.Pool) *TranslationsRepository {
+	return &TranslationsRepository{db: conn}
+}
+
+type translatableTable struct {
+	name, idColumn, titleColumn string
+	// пусто, если у таблицы нет updated_at и устаревший перевод не определить
+	updatedColumn string
+}
+
+var translatableTables = map[string]translatableTable{
+	models.EntityLesson:  {"lessons", "lesson_id", "lesson_title", "updated_at"},
+	models.EntityCourse:  {"courses", "id", "name", "updated_at"},
+	models.EntitySubject: {"subjects", "id", "name", ""},
+}
+
+const translationColumns = "entity_type, entity_id, locale, title, description, body, body_html, updated_at"
+
+func scanTranslation(row interface{ Scan(...any) error }) (models.Translation, error) {
+	var translation models.Translation
+	err := row.Scan(&translation.Entity_type, &translation.Entity_id, &translation.Locale, &translation.Title,
+		&translation.Description, &translation.Body, &translation.Body_html, &translation.Updated_at)
+	return translation, err
+}
+
+func scanTranslations(rows pgx.Rows) ([]models.Translation, error) {
+	logger := logger.GetLogger()
+	defer rows.Close()
+
+	translations := make([]models.Translation, 0)
+	for rows.Next() {
+		translation, err := scanTranslation(rows)
+		if err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		translations = append(translations, translation)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+func (r *TranslationsRepository) FindByEntity(c context.Context, entityType string, id int) ([]models.Translation, error) {
+	logger := logger.GetLogger()
+
+	rows, err := r.db.Query(c, "select "+translationColumns+" from content_translations where entity_type = $1 and entity_id = $2 order by locale", entityType, id)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	return scanTranslations(rows)
+}
+
+// FindBest возвращает для каждой сущности перевод на самый предпочтительный из locales язык.
+// Сущности без перевода на эти языки в результат не попадают.
+func (r *TranslationsRepository) FindBest(c context.Context, entityType string, ids []int, locales []string) (map[int]models.Translation, error) {
+	logger := logger.GetLogger()
+
+	best := make(map[int]models.Translation)
+	if len(ids) == 0 || len(locales) == 0 {
+		return best, nil
+	}
+
+	rows, err := r.db.Query(c, `
+	select distinct on (entity_id) `+translationColumns+`
+	from content_translations
+	where entity_type = $1 and entity_id = any($2) and locale = any($3)
+	order by entity_id, array_position($3, locale)
+	`, entityType, ids, locales)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+
+	translations, err := scanTranslations(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, translation := range translations {
+		best[translation.Entity_id] = translation
+	}
+
+	return best, nil
+}
+
+// Upsert сохраняет перевод и переиндексирует сущность: переводы входят в search_vector
+func (r *TranslationsRepository) Upsert(c context.Context, translation models.Translation) error {
+	logger := logger.GetLogger()
+
+	_, err := r.db.Exec(c, `
+	insert into content_translations (entity_type, entity_id, locale, title, description, body, body_html, updated_at)
+	values ($1, $2, $3, $4, $5, $6, $7, now())
+	on conflict (entity_type, entity_id, locale) do update
+	set title = excluded.title, description = excluded.description, body = excluded.body,
+		body_html = excluded.body_html, updated_at = now()
+	`, translation.Entity_type, translation.Entity_id, translation.Locale, translation.Title,
+		translation.Description, translation.Body, translation.Body_html)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+
+	return r.reindex(c, translation.Entity_type, translation.Entity_id)
+}
+
+func (r *TranslationsRepository) Delete(c context.Context, entityType string, id int, locale string) (bool, error) {
+	logger := logger.GetLogger()
+
+	tag, err := r.db.Exec(c, "delete from content_translations where entity_type = $1 and entity_id = $2 and locale = $3", entityType, id, locale)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	return true, r.reindex(c, entityType, id)
+}
+
+// reindex сбрасывает search_vector сущности и сразу пересчитывает его; если пересчет не удался,
+// сущность переиндексирует фоновая индексация
+func (r *TranslationsRepository) reindex(c context.Context, entityType string, id int) error {
+	if err := resetSearchVector(c, r.db, entityType, id); err != nil {
+		return err
+	}
+
+	refreshSearchVector(c, r.db, entityType, id)
+	return nil
+}
+
+// FindMissing строит отчет по непереведенным и устаревшим переводам.
+// Перевод устарел, если исходный текст менялся после последнего сохранения перевода.
+func (r *TranslationsRepository) FindMissing(c context.Context, entityTypes []string, locales []string) ([]models.MissingTranslation, error) {
+	logger := logger.GetLogger()
+
+	parts := make([]string, 0, len(entityTypes))
+	for _, entityType := range entityTypes {
+		table := translatableTables[entityType]
+
+		condition := "t.entity_id is null"
+		if table.updatedColumn != "" {
+			condition += fmt.Sprintf(" or t.updated_at < e.%s", table.updatedColumn)
+		}
+
+		parts = append(parts, fmt.Sprintf(`
+		select '%[1]s'::text as entity_type, e.%[3]s as entity_id, e.%[4]s as title, loc.locale,
+			case when t.entity_id is null then '%[5]s' else '%[6]s' end as status
+		from %[2]s e
+		cross join unnest($1::text[]) as loc(locale)
+		left join content_translations t
+			on t.entity_type = '%[1]s' and t.entity_id = e.%[3]s and t.locale = loc.locale
+		where e.deleted_at is null and (%[7]s)`,
+			entityType, table.name, table.idColumn, table.titleColumn,
+			models.TranslationMissing, models.TranslationOutdated, condition))
+	}
+
+	rows, err := r.db.Query(c, strings.Join(parts, "\n\t\tunion all")+"\n\torder by entity_type, entity_id, locale", locales)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := make([]models.MissingTranslation, 0)
+	for rows.Next() {
+		var item models.MissingTranslation
+		if err := rows.Scan(&item.Entity_type, &item.Entity_id, &item.Title, &item.Locale, &item.Status); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		report = append(report, item)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return report, nil
+}
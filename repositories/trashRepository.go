@@ -0,0 +1,229 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+type TrashRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTrashRepository(conn *pgxpool.Pool) *TrashRepository {
+	return &TrashRepository{db: conn}
+}
+
+type trashTable struct {
+	name, idColumn, titleExpr string
+	// дополнительное условие окончательного удаления
+	purgeCondition string
+	// строки других таблиц, которые удаляются вместе с записью
+	cascades []trashReference
+}
+
+// trashReference — колонка таблицы, которая ссылается на запись из корзины
+type trashReference struct {
+	table, column string
+}
+
+// notReferenced строит условие, что на запись table.idColumn не ссылается ни одна из refs
+func notReferenced(table, idColumn string, refs ...trashReference) string {
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		parts[i] = fmt.Sprintf("not exists (select 1 from %[1]s r where r.%[2]s = %[3]s.%[4]s)", ref.table, ref.column, table, idColumn)
+	}
+	return strings.Join(parts, " and ")
+}
+
+// таблицы с мягким удалением в порядке очистки: сначала уроки, потом то, на что они ссылаются.
+// Запись с учебной историей (прохождения, записи на курс, сертификаты, ответы) остается в корзине:
+// ее нельзя удалить, не потеряв историю ученика. Структурные связи удаляются вместе с записью.
+var trashTables = map[string]trashTable{
+	models.EntityLesson: {"lessons", "lesson_id", "lesson_title",
+		notReferenced("lessons", "lesson_id",
+			trashReference{"lesson_completions", "lesson_id"},
+			trashReference{"lesson_progress", "lesson_id"},
+			trashReference{"assignments", "lesson_id"},
+			trashReference{"quizzes", "lesson_id"},
+			trashReference{"flashcard_decks", "lesson_id"}),
+		[]trashReference{
+			{"course_lessons", "lesson_id"},
+			{"lesson_resources", "lesson_id"},
+			{"lesson_captions", "lesson_id"},
+			{"lesson_tags", "entity_id"},
+			{"lesson_prerequisites", "entity_id"},
+			{"lesson_prerequisites", "prerequisite_id"},
+			{"lesson_cooccurrence", "lesson_id"},
+			{"lesson_cooccurrence", "related_lesson_id"},
+		}},
+	models.EntityCourse: {"courses", "id", "name",
+		notReferenced("courses", "id",
+			trashReference{"enrollments", "course_id"},
+			trashReference{"certificates", "course_id"},
+			trashReference{"assignments", "course_id"},
+			trashReference{"quizzes", "course_id"},
+			trashReference{"grade_overrides", "course_id"}),
+		[]trashReference{
+			{"course_lessons", "course_id"},
+			{"course_tags", "entity_id"},
+			{"course_prerequisites", "entity_id"},
+			{"course_prerequisites", "prerequisite_id"},
+			{"learning_path_courses", "course_id"},
+			{"course_gradebook_settings", "course_id"},
+			{"leaderboard_scores", "course_id"},
+		}},
+	// предмет не удаляется, пока на него ссылаются уроки, в том числе лежащие в корзине
+	models.EntitySubject: {"subjects", "id", "name",
+		notReferenced("subjects", "id",
+			trashReference{"lessons", "subject_id"},
+			trashReference{"flashcard_decks", "subject_id"}),
+		nil},
+	// личные настройки и прогресс удаляются вместе с пользователем, учебная история и авторство его держат
+	models.EntityUser: {"users", "uuid", "concat_ws(' ', user_name, user_surname)",
+		notReferenced("users", "uuid",
+			trashReference{"enrollments", "user_uuid"},
+			trashReference{"enrollments", "enrolled_by"},
+			trashReference{"lesson_completions", "user_uuid"},
+			trashReference{"certificates", "user_uuid"},
+			trashReference{"assignment_submissions", "user_uuid"},
+			trashReference{"quiz_attempts", "user_uuid"},
+			trashReference{"xapi_statements", "user_uuid"},
+			trashReference{"grade_overrides", "user_uuid"},
+			trashReference{"grade_overrides", "created_by"},
+			trashReference{"assignments", "created_by"},
+			trashReference{"learning_paths", "created_by"},
+			trashReference{"flashcard_decks", "created_by"},
+			trashReference{"classes", "created_by"},
+			trashReference{"content_revisions", "author_uuid"}),
+		[]trashReference{
+			{"learner_profiles", "user_uuid"},
+			{"leaderboard_privacy", "user_uuid"},
+			{"leaderboard_scores", "user_uuid"},
+			{"user_gamification", "user_uuid"},
+			{"user_badges", "user_uuid"},
+			{"xp_events", "user_uuid"},
+			{"notifications", "user_uuid"},
+			{"lesson_progress", "user_uuid"},
+			{"flashcard_states", "user_uuid"},
+			{"flashcard_reviews", "user_uuid"},
+			{"learning_path_enrollments", "user_uuid"},
+			{"class_members", "user_uuid"},
+		}},
+}
+
+// FindAll возвращает удаленные записи переданных типов, сначала недавно удаленные
+func (r *TrashRepository) FindAll(c context.Context, entityTypes []string) ([]models.TrashItem, error) {
+	logger := logger.GetLogger()
+
+	parts := make([]string, 0, len(entityTypes))
+	for _, entityType := range entityTypes {
+		table := trashTables[entityType]
+		parts = append(parts, fmt.Sprintf(`
+		select '%s'::text, %s::text, %s, deleted_at from %s where deleted_at is not null`,
+			entityType, table.idColumn, table.titleExpr, table.name))
+	}
+
+	rows, err := r.db.Query(c, strings.Join(parts, "\n\t\tunion all")+"\n\torder by 4 desc")
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.TrashItem, 0)
+	for rows.Next() {
+		var item models.TrashItem
+		if err := rows.Scan(&item.Type, &item.Id, &item.Title, &item.Deleted_at); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Restore достает запись из корзины. pgx.ErrNoRows, если такой записи в корзине нет.
+func (r *TrashRepository) Restore(c context.Context, entityType string, id any) error {
+	logger := logger.GetLogger()
+
+	table := trashTables[entityType]
+	tag, err := r.db.Exec(c,
+		fmt.Sprintf("update %s set deleted_at = null where %s = $1 and deleted_at is not null", table.name, table.idColumn),
+		id)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Purge окончательно удаляет записи, лежащие в корзине дольше retention.
+// Каждая таблица очищается в своей транзакции: ошибка в одной не мешает очистить остальные.
+func (r *TrashRepository) Purge(c context.Context, retention time.Duration) (int, bool, error) {
+	cutoff := time.Now().Add(-retention)
+
+	purged := 0
+	var errs []error
+	for _, entityType := range models.TrashItems {
+		table := trashTables[entityType]
+
+		acquired, err := WithAdvisoryLock(c, r.db, AdvisoryLockTrashPurge, func(tx pgx.Tx) error {
+			count, err := purgeTable(c, tx, table, cutoff)
+			purged += count
+			return err
+		})
+		if !acquired {
+			return purged, false, err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("purge %s: %w", table.name, err))
+		}
+	}
+
+	return purged, true, errors.Join(errs...)
+}
+
+// purgeTable удаляет из table записи, удаленные раньше cutoff, вместе со связанными строками
+func purgeTable(c context.Context, tx pgx.Tx, table trashTable, cutoff time.Time) (int, error) {
+	logger := logger.GetLogger()
+
+	condition := "deleted_at < $1"
+	if table.purgeCondition != "" {
+		condition += " and " + table.purgeCondition
+	}
+
+	for _, ref := range table.cascades {
+		_, err := tx.Exec(c,
+			fmt.Sprintf("delete from %s where %s in (select %s from %s where %s)", ref.table, ref.column, table.idColumn, table.name, condition),
+			cutoff)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return 0, err
+		}
+	}
+
+	tag, err := tx.Exec(c, fmt.Sprintf("delete from %s where %s", table.name, condition), cutoff)
+	if err != nil {
+		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -39,7 +39,7 @@ func (r *UsersRepository) FindByUUID(c context.Context, strUUID string) (models.
 	}
 
 	var user models.User
-	row := r.db.QueryRow(c, "select uuid, user_name, user_surname, role_id, email, password_hash from users where uuid = $1", parsed_UUID)
+	row := r.db.QueryRow(c, "select uuid, user_name, user_surname, role_id, email, password_hash from users where uuid = $1 and deleted_at is null", parsed_UUID)
 	err = row.Scan(&user.UUID, &user.Name, &user.Surname, &user.Role_id, &user.Email, &user.PasswordHash)
 
 	if err != nil {
@@ -53,7 +53,7 @@ func (r *UsersRepository) FindByUUID(c context.Context, strUUID string) (models.
 func (r *UsersRepository) FindAll(c context.Context) ([]models.User, error) {
 	logger := logger.GetLogger()
 
-	rows, err := r.db.Query(c, "select uuid, user_name, user_surname, role_id, email, password_hash from users")
+	rows, err := r.db.Query(c, "select uuid, user_name, user_surname, role_id, email, password_hash from users where deleted_at is null")
 	if err != nil {
 		logger.Error("could not query database", zap.String("db_msg", err.Error()))
 		return nil, err
@@ -110,6 +110,7 @@ func (r *UsersRepository) ChangePassword(c context.Context, password []byte, str
 	return nil
 }
 
+// Delete переносит пользователя в корзину, окончательно его удалит фоновая очистка корзины
 func (r *UsersRepository) Delete(c context.Context, strUUID string) error {
 	logger := logger.GetLogger()
 	parsed_UUID, err := uuid.Parse(strUUID)
@@ -118,7 +119,7 @@ func (r *UsersRepository) Delete(c context.Context, strUUID string) error {
 		return err
 	}
 
-	_, err = r.db.Exec(c, "delete from users where uuid = $1", parsed_UUID)
+	_, err = r.db.Exec(c, "update users set deleted_at = now() where uuid = $1 and deleted_at is null", parsed_UUID)
 	if err != nil {
 		logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
 		return err
@@ -130,7 +131,7 @@ func (u *UsersRepository) FindByEmail(c context.Context, email string) (models.U
 	logger := logger.GetLogger()
 
 	var user models.User
-	row := u.db.QueryRow(c, "select uuid, user_name, user_surname, role_id, email, password_hash from users where email = $1", email)
+	row := u.db.QueryRow(c, "select uuid, user_name, user_surname, role_id, email, password_hash from users where email = $1 and deleted_at is null", email)
 	err := row.Scan(&user.UUID, &user.Name, &user.Surname, &user.Role_id, &user.Email, &user.PasswordHash)
 
 	if err != nil {
@@ -177,6 +178,6 @@ func (r *UsersRepository) Activate(c context.Context, strUUID string) error {
 
 func (r *UsersRepository) CountByRoleID(ctx context.Context, roleID int) (int, error) {
 	var cnt int
-	err := r.db.QueryRow(ctx, `select COUNT(*) from users where role_id = $1`, roleID).Scan(&cnt)
+	err := r.db.QueryRow(ctx, `select COUNT(*) from users where role_id = $1 and deleted_at is null`, roleID).Scan(&cnt)
 	return cnt, err
 }
@@ -0,0 +1,192 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-EdTech/logger"
+	"go-EdTech/models"
+	"go-EdTech/utils"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrStatementConflict = errors.New("statement with this id already exists with different content")
+	ErrVoidTargetMissing = errors.New("statement to void does not exist or is not yours")
+)
+
+type XAPIRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewXAPIRepository(conn *pgxpool.Pool) *XAPIRepository {
+	return &XAPIRepository{db: conn}
+}
+
+// Store сохраняет утверждения одной транзакцией. Повтор того же утверждения игнорируется,
+// ErrStatementConflict — если id занят утверждением с другим actor, verb или object.
+// Утверждение с глаголом voided аннулирует утверждение, на которое ссылается: без voidAny — только
+// утверждение о записавшем пользователе или сохраненное им. ErrVoidTargetMissing, если такого нет.
+func (r *XAPIRepository) Store(c context.Context, records []models.XAPIRecord, voidAny bool) error {
+	logger := logger.GetLogger()
+
+	tx, err := r.db.Begin(c)
+	if err != nil {
+		logger.Error("could not begin transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	defer tx.Rollback(c)
+
+	for _, record := range records {
+		tag, err := tx.Exec(c,
+			`
+		insert into xapi_statements (id, statement, actor_key, verb_id, object_type, object_id, timestamp, stored, user_uuid)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		on conflict (id) do nothing
+		`,
+			record.Id, record.Statement, record.Actor_key, record.Verb_id, record.Object_type, record.Object_id,
+			record.Timestamp, record.Stored, record.User_uuid,
+		)
+		if err != nil {
+			logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			var same bool
+			err := tx.QueryRow(c,
+				"select actor_key = $2 and verb_id = $3 and object_id = $4 from xapi_statements where id = $1",
+				record.Id, record.Actor_key, record.Verb_id, record.Object_id,
+			).Scan(&same)
+			if err != nil {
+				logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+				return err
+			}
+			if !same {
+				return ErrStatementConflict
+			}
+			continue
+		}
+
+		if record.Verb_id == models.XAPIVerbVoided {
+			sql := "update xapi_statements set voided = true where id = $1::uuid and verb_id <> $2"
+			args := []any{record.Object_id, models.XAPIVerbVoided}
+			if !voidAny {
+				if record.User_uuid == nil {
+					return ErrVoidTargetMissing
+				}
+				ownKey, err := utils.XAPIActorKey(utils.XAPIUserAgent(*record.User_uuid))
+				if err != nil {
+					return err
+				}
+				sql += " and (actor_key = $3 or user_uuid = $4)"
+				args = append(args, ownKey, *record.User_uuid)
+			}
+
+			tag, err := tx.Exec(c, sql, args...)
+			if err != nil {
+				logger.Error("could not execute in database", zap.String("db_msg", err.Error()))
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return ErrVoidTargetMissing
+			}
+		}
+	}
+
+	if err := tx.Commit(c); err != nil {
+		logger.Error("could not commit transaction", zap.String("db_msg", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// FindById возвращает утверждение; voided выбирает между действующими и аннулированными
+func (r *XAPIRepository) FindById(c context.Context, id uuid.UUID, voided bool) (models.XAPIRecord, error) {
+	logger := logger.GetLogger()
+
+	var record models.XAPIRecord
+	err := r.db.QueryRow(c,
+		`select id, statement, actor_key, verb_id, object_type, object_id, timestamp, stored, user_uuid
+		from xapi_statements where id = $1 and voided = $2`,
+		id, voided,
+	).Scan(&record.Id, &record.Statement, &record.Actor_key, &record.Verb_id, &record.Object_type, &record.Object_id,
+		&record.Timestamp, &record.Stored, &record.User_uuid)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+		}
+		return models.XAPIRecord{}, err
+	}
+	return record, nil
+}
+
+// Find возвращает действующие утверждения по фильтру, по умолчанию новые первыми
+func (r *XAPIRepository) Find(c context.Context, filter models.XAPIFilter) ([]json.RawMessage, error) {
+	logger := logger.GetLogger()
+
+	sql := `select statement from xapi_statements where not voided`
+	args := []any{}
+
+	if filter.Actor_key != "" {
+		args = append(args, filter.Actor_key)
+		sql += fmt.Sprintf(` and actor_key = $%d`, len(args))
+	}
+	if filter.Verb_id != "" {
+		args = append(args, filter.Verb_id)
+		sql += fmt.Sprintf(` and verb_id = $%d`, len(args))
+	}
+	if filter.Activity_id != "" {
+		args = append(args, filter.Activity_id)
+		sql += fmt.Sprintf(` and object_type = 'Activity' and object_id = $%d`, len(args))
+	}
+	if filter.Since != nil {
+		args = append(args, *filter.Since)
+		sql += fmt.Sprintf(` and stored > $%d`, len(args))
+	}
+	if filter.Until != nil {
+		args = append(args, *filter.Until)
+		sql += fmt.Sprintf(` and stored <= $%d`, len(args))
+	}
+	if filter.Owner_uuid != nil {
+		args = append(args, *filter.Owner_uuid, filter.Owner_key)
+		sql += fmt.Sprintf(` and (user_uuid = $%d or actor_key = $%d)`, len(args)-1, len(args))
+	}
+
+	if filter.Ascending {
+		sql += ` order by stored, id`
+	} else {
+		sql += ` order by stored desc, id desc`
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	sql += fmt.Sprintf(` limit $%d offset $%d`, len(args)-1, len(args))
+
+	rows, err := r.db.Query(c, sql, args...)
+	if err != nil {
+		logger.Error("could not query database", zap.String("db_msg", err.Error()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	statements := make([]json.RawMessage, 0)
+	for rows.Next() {
+		var statement json.RawMessage
+		if err := rows.Scan(&statement); err != nil {
+			logger.Error("could not scan query row", zap.String("db_msg", err.Error()))
+			return nil, err
+		}
+
+		statements = append(statements, statement)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error(err.Error())
+		return nil, err
+	}
+
+	return statements, nil
+}
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const MaxCaptionSize = 1 << 20 // 1 MB
+
+var (
+	ErrCaptionTooLarge     = errors.New("caption file is too large")
+	ErrCaptionEncoding     = errors.New("caption file must be UTF-8 encoded")
+	ErrCaptionFormat       = errors.New("unsupported caption format, expected .srt or .vtt")
+	ErrCaptionEmpty        = errors.New("caption file has no cues")
+	ErrCaptionMissingVTTID = errors.New("WebVTT file must start with WEBVTT")
+
+	languageRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	timestampRegex = regexp.MustCompile(`^(?:(\d{2,}):)?([0-5]\d):([0-5]\d)[.,](\d{3})$`)
+)
+
+// IsValidLanguageTag проверяет, что строка похожа на BCP 47 тег (en, ru, kk, en-US)
+func IsValidLanguageTag(tag string) bool {
+	return languageRegexp.MatchString(tag)
+}
+
+// NormalizeCaptions валидирует SRT или WebVTT файл и всегда возвращает WebVTT
+func NormalizeCaptions(filename string, data []byte) (string, error) {
+	if len(data) > MaxCaptionSize {
+		return "", ErrCaptionTooLarge
+	}
+	if !utf8.Valid(data) {
+		return "", ErrCaptionEncoding
+	}
+
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".srt":
+		return convertSRTToWebVTT(text)
+	case ".vtt":
+		return validateWebVTT(text)
+	default:
+		return "", ErrCaptionFormat
+	}
+}
+
+func convertSRTToWebVTT(text string) (string, error) {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+
+	cues := 0
+	for i, block := range splitCaptionBlocks(text) {
+		lines := strings.Split(block, "\n")
+
+		// номер реплики в SRT необязателен и в WebVTT не нужен
+		if !strings.Contains(lines[0], "-->") {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			return "", fmt.Errorf("block %d: missing timing line", i+1)
+		}
+
+		start, end, err := parseTimingLine(lines[0])
+		if err != nil {
+			return "", fmt.Errorf("block %d: %w", i+1, err)
+		}
+
+		b.WriteString("\n")
+		b.WriteString(formatVTTTimestamp(start) + " --> " + formatVTTTimestamp(end) + "\n")
+		for _, line := range lines[1:] {
+			b.WriteString(line + "\n")
+		}
+		cues++
+	}
+
+	if cues == 0 {
+		return "", ErrCaptionEmpty
+	}
+	return b.String(), nil
+}
+
+func validateWebVTT(text string) (string, error) {
+	blocks := splitCaptionBlocks(text)
+	if len(blocks) == 0 {
+		return "", ErrCaptionMissingVTTID
+	}
+
+	header := strings.SplitN(blocks[0], "\n", 2)[0]
+	if header != "WEBVTT" && !strings.HasPrefix(header, "WEBVTT ") && !strings.HasPrefix(header, "WEBVTT\t") {
+		return "", ErrCaptionMissingVTTID
+	}
+
+	cues := 0
+	for i, block := range blocks[1:] {
+		lines := strings.Split(block, "\n")
+		if strings.HasPrefix(lines[0], "NOTE") || lines[0] == "STYLE" || lines[0] == "REGION" {
+			continue
+		}
+
+		// у реплики может быть идентификатор перед строкой таймингов
+		timing := lines[0]
+		if !strings.Contains(timing, "-->") {
+			if len(lines) < 2 {
+				return "", fmt.Errorf("block %d: missing timing line", i+2)
+			}
+			timing = lines[1]
+		}
+
+		if _, _, err := parseTimingLine(timing); err != nil {
+			return "", fmt.Errorf("block %d: %w", i+2, err)
+		}
+		cues++
+	}
+
+	if cues == 0 {
+		return "", ErrCaptionEmpty
+	}
+	return text, nil
+}
+
+func splitCaptionBlocks(text string) []string {
+	blocks := make([]string, 0)
+	for _, block := range strings.Split(text, "\n\n") {
+		block = strings.Trim(block, "\n")
+		if strings.TrimSpace(block) == "" {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// parseTimingLine разбирает "00:00:01,000 --> 00:00:04,000 [settings]"
+func parseTimingLine(line string) (time.Duration, time.Duration, error) {
+	parts := strings.SplitN(line, "-->", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid timing line %q", line)
+	}
+
+	right := strings.Fields(parts[1])
+	if len(right) == 0 {
+		return 0, 0, fmt.Errorf("invalid timing line %q", line)
+	}
+
+	start, err := parseCaptionTimestamp(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseCaptionTimestamp(right[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("cue ends before it starts in %q", line)
+	}
+
+	return start, end, nil
+}
+
+func parseCaptionTimestamp(s string) (time.Duration, error) {
+	m := timestampRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	var parts [4]int
+	for i, v := range m[1:] {
+		if v != "" {
+			parts[i], _ = strconv.Atoi(v)
+		}
+	}
+
+	return time.Duration(parts[0])*time.Hour +
+		time.Duration(parts[1])*time.Minute +
+		time.Duration(parts[2])*time.Second +
+		time.Duration(parts[3])*time.Millisecond, nil
+}
+
+func formatVTTTimestamp(d time.Duration) string {
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", h, m, s, d/time.Millisecond)
+}
@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Пакеты IMS Common Cartridge 1.3. Разбор не зависит от пространств имен,
+// поэтому принимает и пакеты SCORM 1.2/2004 — они используют тот же IMS Content Packaging манифест.
+
+const (
+	CartridgeManifestName = "imsmanifest.xml"
+	MaxPackageSize        = 1 << 30 // 1 GB
+
+	CartridgeWebContent = "webcontent"
+	CartridgeWebLink    = "imswl_xmlv1p3"
+	// SCORM и старые версии CC
+	cartridgeWebLinkV1p1 = "imswl_xmlv1p1"
+
+	// ссылки CC на файлы пакета вида $IMS-CC-FILEBASE$/images/a.png
+	cartridgeFileBase = "$IMS-CC-FILEBASE$"
+)
+
+// CartridgeManifest — разобранный imsmanifest.xml
+type CartridgeManifest struct {
+	Identifier    string                  `xml:"identifier,attr"`
+	Title         string                  `xml:"metadata>lom>general>title>string"`
+	Description   string                  `xml:"metadata>lom>general>description>string"`
+	Organizations []CartridgeOrganization `xml:"organizations>organization"`
+	Resources     []CartridgeResource     `xml:"resources>resource"`
+}
+
+type CartridgeOrganization struct {
+	Title string          `xml:"title"`
+	Items []CartridgeItem `xml:"item"`
+}
+
+type CartridgeItem struct {
+	Identifier    string          `xml:"identifier,attr"`
+	Identifierref string          `xml:"identifierref,attr"`
+	Title         string          `xml:"title"`
+	Items         []CartridgeItem `xml:"item"`
+}
+
+type CartridgeResource struct {
+	Identifier string          `xml:"identifier,attr"`
+	Type       string          `xml:"type,attr"`
+	Href       string          `xml:"href,attr"`
+	Files      []CartridgeFile `xml:"file"`
+}
+
+type CartridgeFile struct {
+	Href string `xml:"href,attr"`
+}
+
+// CartridgeWebLinkData — содержимое xml файла ресурса-ссылки
+type CartridgeWebLinkData struct {
+	Title string `xml:"title"`
+	Url   struct {
+		Href string `xml:"href,attr"`
+	} `xml:"url"`
+}
+
+func ParseCartridgeManifest(data []byte) (CartridgeManifest, error) {
+	var manifest CartridgeManifest
+	if err := xml.Unmarshal(data, &manifest); err != nil {
+		return CartridgeManifest{}, fmt.Errorf("invalid %s: %w", CartridgeManifestName, err)
+	}
+	return manifest, nil
+}
+
+func ParseCartridgeWebLink(data []byte) (CartridgeWebLinkData, error) {
+	var link CartridgeWebLinkData
+	err := xml.Unmarshal(data, &link)
+	return link, err
+}
+
+func IsCartridgeWebLink(resourceType string) bool {
+	return resourceType == CartridgeWebLink || resourceType == cartridgeWebLinkV1p1
+}
+
+// LeafItems возвращает элементы со ссылкой на ресурс в порядке обхода структуры курса.
+// Папки (элементы без ресурса) разворачиваются.
+func (o CartridgeOrganization) LeafItems() []CartridgeItem {
+	leaves := make([]CartridgeItem, 0)
+	var walk func(items []CartridgeItem)
+	walk = func(items []CartridgeItem) {
+		for _, item := range items {
+			if item.Identifierref != "" {
+				leaves = append(leaves, item)
+			}
+			walk(item.Items)
+		}
+	}
+	walk(o.Items)
+	return leaves
+}
+
+// ResolvePackagePath превращает ссылку из файла пакета в путь внутри архива.
+// Внешние адреса (http, mailto, data, абсолютные пути) возвращаются с ok=false.
+func ResolvePackagePath(fromFile string, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, cartridgeFileBase+"/") {
+		ref = strings.TrimPrefix(ref, cartridgeFileBase+"/")
+		fromFile = "web_resources/"
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" || parsed.Path == "" || strings.HasPrefix(parsed.Path, "/") {
+		return "", false
+	}
+
+	resolved := path.Join(path.Dir(fromFile), parsed.Path)
+	if strings.HasPrefix(resolved, "../") || resolved == ".." {
+		return "", false
+	}
+	return resolved, true
+}
+
+// Структуры для записи манифеста. Имена с префиксами пишутся как есть, пространства имен объявлены в корне.
+
+type cartridgeManifestXML struct {
+	XMLName        xml.Name `xml:"manifest"`
+	Xmlns          string   `xml:"xmlns,attr"`
+	XmlnsLom       string   `xml:"xmlns:lomimscc,attr"`
+	XmlnsXsi       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Identifier     string   `xml:"identifier,attr"`
+
+	Metadata struct {
+		Schema        string `xml:"schema"`
+		Schemaversion string `xml:"schemaversion"`
+		Lom           struct {
+			Title       cartridgeLangString  `xml:"lomimscc:general>lomimscc:title>lomimscc:string"`
+			Description *cartridgeLangString `xml:"lomimscc:general>lomimscc:description>lomimscc:string,omitempty"`
+		} `xml:"lomimscc:lom"`
+	} `xml:"metadata"`
+
+	Organizations struct {
+		Organization struct {
+			Identifier string           `xml:"identifier,attr"`
+			Structure  string           `xml:"structure,attr"`
+			Root       cartridgeItemXML `xml:"item"`
+		} `xml:"organization"`
+	} `xml:"organizations"`
+
+	Resources struct {
+		Resource []cartridgeResourceXML `xml:"resource"`
+	} `xml:"resources"`
+}
+
+type cartridgeLangString struct {
+	Language string `xml:"language,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type cartridgeItemXML struct {
+	Identifier    string             `xml:"identifier,attr"`
+	Identifierref string             `xml:"identifierref,attr,omitempty"`
+	Title         string             `xml:"title,omitempty"`
+	Items         []cartridgeItemXML `xml:"item"`
+}
+
+type cartridgeResourceXML struct {
+	Identifier string          `xml:"identifier,attr"`
+	Type       string          `xml:"type,attr"`
+	Href       string          `xml:"href,attr,omitempty"`
+	Files      []CartridgeFile `xml:"file"`
+}
+
+// CartridgeBuilder собирает манифест экспортируемого курса
+type CartridgeBuilder struct {
+	manifest cartridgeManifestXML
+	modules  []cartridgeItemXML
+	nextId   int
+}
+
+func NewCartridgeBuilder(identifier string, title string, description string, language string) *CartridgeBuilder {
+	b := &CartridgeBuilder{}
+	m := &b.manifest
+	m.Xmlns = "http://www.imsglobal.org/xsd/imsccv1p3/imscp_v1p1"
+	m.XmlnsLom = "http://ltsc.ieee.org/xsd/imsccv1p3/LOM/manifest"
+	m.XmlnsXsi = "http://www.w3.org/2001/XMLSchema-instance"
+	m.SchemaLocation = "http://www.imsglobal.org/xsd/imsccv1p3/imscp_v1p1 http://www.imsglobal.org/profile/cc/ccv1p3/ccv1p3_imscp_v1p2_v1p0.xsd " +
+		"http://ltsc.ieee.org/xsd/imsccv1p3/LOM/manifest http://www.imsglobal.org/profile/cc/ccv1p3/LOM/ccv1p3_lommanifest_v1p0.xsd"
+	m.Identifier = identifier
+	m.Metadata.Schema = "IMS Common Cartridge"
+	m.Metadata.Schemaversion = "1.3.0"
+	m.Metadata.Lom.Title = cartridgeLangString{Language: language, Value: title}
+	if description != "" {
+		m.Metadata.Lom.Description = &cartridgeLangString{Language: language, Value: description}
+	}
+	m.Organizations.Organization.Identifier = "organization"
+	m.Organizations.Organization.Structure = "rooted-hierarchy"
+	m.Organizations.Organization.Root.Identifier = "root"
+	return b
+}
+
+func (b *CartridgeBuilder) id(prefix string) string {
+	b.nextId++
+	return fmt.Sprintf("%s_%d", prefix, b.nextId)
+}
+
+// AddModule добавляет папку верхнего уровня (урок) и возвращает ее индекс для AddItem
+func (b *CartridgeBuilder) AddModule(title string) int {
+	b.modules = append(b.modules, cartridgeItemXML{Identifier: b.id("module"), Title: title})
+	return len(b.modules) - 1
+}
+
+// AddItem добавляет в модуль элемент, ссылающийся на новый ресурс
+func (b *CartridgeBuilder) AddItem(module int, title string, resourceType string, href string, files ...string) {
+	resourceId := b.id("resource")
+
+	resource := cartridgeResourceXML{Identifier: resourceId, Type: resourceType}
+	if resourceType == CartridgeWebContent {
+		resource.Href = href
+	}
+	for _, file := range append([]string{href}, files...) {
+		resource.Files = append(resource.Files, CartridgeFile{Href: file})
+	}
+	b.manifest.Resources.Resource = append(b.manifest.Resources.Resource, resource)
+
+	b.modules[module].Items = append(b.modules[module].Items, cartridgeItemXML{
+		Identifier:    b.id("item"),
+		Identifierref: resourceId,
+		Title:         title,
+	})
+}
+
+func (b *CartridgeBuilder) Manifest() ([]byte, error) {
+	b.manifest.Organizations.Organization.Root.Items = b.modules
+	data, err := xml.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// CartridgeWebLinkXML формирует файл ресурса-ссылки imswl
+func CartridgeWebLinkXML(title string, href string) ([]byte, error) {
+	link := struct {
+		XMLName xml.Name `xml:"webLink"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Title   string   `xml:"title"`
+		Url     struct {
+			Href   string `xml:"href,attr"`
+			Target string `xml:"target,attr"`
+		} `xml:"url"`
+	}{Xmlns: "http://www.imsglobal.org/xsd/imsccv1p3/imswl_v1p3", Title: title}
+	link.Url.Href = href
+	link.Url.Target = "_blank"
+
+	data, err := xml.MarshalIndent(link, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"go-EdTech/models"
+	"os"
+	"strings"
+	"text/template"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// DefaultCertificateFont — встроенный Go Regular: латиница, кириллица и греческий. Для других алфавитов,
+// например казахских букв вне русского алфавита или CJK, нужен макет со своим TrueType шрифтом.
+var DefaultCertificateFont = goregular.TTF
+
+// DefaultCertificateTemplate — альбомный A4, если CERTIFICATE_TEMPLATE не задан
+var DefaultCertificateTemplate = models.CertificateTemplate{
+	Width:  842,
+	Height: 595,
+	Border: true,
+	Lines: []models.CertificateLine{
+		{Text: "CERTIFICATE OF COMPLETION", Size: 32, Y: 430, Align: "center"},
+		{Text: "This certifies that", Size: 16, Y: 370, Align: "center"},
+		{Text: "{{.Student}}", Size: 28, Y: 320, Align: "center"},
+		{Text: "has successfully completed the course", Size: 16, Y: 270, Align: "center"},
+		{Text: "{{.Course}}", Size: 22, Y: 230, Align: "center"},
+		{Text: "Date: {{.Date}}", Size: 12, X: 72, Y: 80, Align: "left"},
+		{Text: "Serial: {{.Serial}}", Size: 12, X: 770, Y: 80, Align: "right"},
+	},
+}
+
+// certificateData — поля, доступные в строках макета
+type certificateData struct {
+	Student string
+	Course  string
+	Date    string
+	Serial  string
+}
+
+// LoadCertificateTemplate читает макет из JSON и шрифт, указанный в нем.
+// Пустой path — макет по умолчанию; без шрифта в макете используется DefaultCertificateFont.
+func LoadCertificateTemplate(path string) (models.CertificateTemplate, []byte, error) {
+	if path == "" {
+		return DefaultCertificateTemplate, DefaultCertificateFont, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return models.CertificateTemplate{}, nil, err
+	}
+	var certificateTemplate models.CertificateTemplate
+	if err := json.Unmarshal(data, &certificateTemplate); err != nil {
+		return models.CertificateTemplate{}, nil, fmt.Errorf("invalid certificate template: %w", err)
+	}
+	if certificateTemplate.Width <= 0 || certificateTemplate.Height <= 0 || len(certificateTemplate.Lines) == 0 {
+		return models.CertificateTemplate{}, nil, fmt.Errorf("certificate template needs width, height and lines")
+	}
+
+	fontData := DefaultCertificateFont
+	if certificateTemplate.Font != "" {
+		fontData, err = os.ReadFile(certificateTemplate.Font)
+		if err != nil {
+			return models.CertificateTemplate{}, nil, err
+		}
+	}
+	return certificateTemplate, fontData, nil
+}
+
+// RenderCertificate генерирует PDF сертификата по макету.
+// Если шрифт макета не может отобразить текст строки, возвращает ErrUnencodableText, а не искаженный PDF.
+func RenderCertificate(certificateTemplate models.CertificateTemplate, fontData []byte, certificate models.Certificate) ([]byte, error) {
+	font, err := newPDFFont(fontData)
+	if err != nil {
+		return nil, err
+	}
+
+	data := certificateData{
+		Student: certificate.Student_name,
+		Course:  certificate.Course_title,
+		Date:    certificate.Issued_at.Format("02.01.2006"),
+		Serial:  certificate.Serial,
+	}
+
+	texts := make([]pdfText, 0, len(certificateTemplate.Lines))
+	for i, line := range certificateTemplate.Lines {
+		lineTemplate, err := template.New(fmt.Sprintf("line%d", i)).Parse(line.Text)
+		if err != nil {
+			return nil, fmt.Errorf("certificate template line %d: %w", i+1, err)
+		}
+		var text strings.Builder
+		if err := lineTemplate.Execute(&text, data); err != nil {
+			return nil, fmt.Errorf("certificate template line %d: %w", i+1, err)
+		}
+
+		if err := font.check(text.String()); err != nil {
+			return nil, fmt.Errorf("certificate template line %d: %w", i+1, err)
+		}
+
+		size := line.Size
+		if size <= 0 {
+			size = 12
+		}
+		x := line.X
+		switch line.Align {
+		case "center":
+			x = (certificateTemplate.Width - font.Width(text.String(), size)) / 2
+		case "right":
+			x = line.X - font.Width(text.String(), size)
+		}
+		texts = append(texts, pdfText{text: text.String(), size: size, x: x, y: line.Y})
+	}
+
+	return writePDF(certificateTemplate.Width, certificateTemplate.Height, certificateTemplate.Border, font, texts)
+}
+
+// GenerateCertificateSerial возвращает случайный номер вида XXXX-XXXX-XXXX-XXXX
+func GenerateCertificateSerial() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	encoded := base32.StdEncoding.EncodeToString(b)
+	return encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16], nil
+}
+
+// NormalizeCertificateSerial приводит введенный номер к виду, в котором он хранится
+func NormalizeCertificateSerial(serial string) string {
+	return strings.ToUpper(strings.TrimSpace(serial))
+}
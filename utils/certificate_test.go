@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"errors"
+	"go-EdTech/models"
+	"testing"
+	"time"
+)
+
+func TestRenderCertificateDefaultFont(t *testing.T) {
+	tests := []struct {
+		name    string
+		student string
+		wantErr error
+	}{
+		{"ascii", "John Smith", nil},
+		{"latin-1", "José Müller", nil},
+		{"cyrillic", "Иван Петров", ErrUnencodableText},
+		{"cjk", "山田太郎", ErrUnencodableText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf, err := RenderCertificate(DefaultCertificateTemplate, nil, models.Certificate{
+				Serial:       "AAAA-BBBB-CCCC-DDDD",
+				Student_name: tt.student,
+				Course_title: "Go",
+				Issued_at:    time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RenderCertificate(%q) error = %v, want %v", tt.student, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && len(pdf) == 0 {
+				t.Errorf("RenderCertificate(%q) returned empty PDF", tt.student)
+			}
+		})
+	}
+}
+
+func TestRenderCertificateEmbeddedFont(t *testing.T) {
+	certificateTemplate, fontData, err := LoadCertificateTemplate("")
+	if err != nil {
+		t.Fatalf("LoadCertificateTemplate() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		student string
+		wantErr error
+	}{
+		{"ascii", "John Smith", nil},
+		{"latin-1", "José Müller", nil},
+		{"cyrillic", "Иван Петров", nil},
+		{"greek", "Νίκος Παπαδόπουλος", nil},
+		{"cjk", "山田太郎", ErrUnencodableText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdf, err := RenderCertificate(certificateTemplate, fontData, models.Certificate{
+				Serial:       "AAAA-BBBB-CCCC-DDDD",
+				Student_name: tt.student,
+				Course_title: "Основы Go",
+				Issued_at:    time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RenderCertificate(%q) error = %v, want %v", tt.student, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && len(pdf) == 0 {
+				t.Errorf("RenderCertificate(%q) returned empty PDF", tt.student)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"go-EdTech/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CurrentUserUUID возвращает uuid пользователя, сохраненный AuthMiddleware
+func CurrentUserUUID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get("userID")
+	if !exists {
+		return uuid.Nil, false
+	}
+	userUUID, ok := value.(uuid.UUID)
+	return userUUID, ok
+}
+
+// CurrentRole возвращает роль пользователя, сохраненную AuthMiddleware
+func CurrentRole(c *gin.Context) *models.Role {
+	value, exists := c.Get("userRole")
+	if !exists {
+		return nil
+	}
+	role, _ := value.(*models.Role)
+	return role
+}
+
+// HasRole проверяет, что у текущего пользователя одна из переданных ролей
+func HasRole(c *gin.Context, roles ...string) bool {
+	role := CurrentRole(c)
+	if role == nil {
+		return false
+	}
+	for _, name := range roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CanManageContent — учителя, редакторы и администраторы видят и редактируют неопубликованный контент
+func CanManageContent(c *gin.Context) bool {
+	return HasRole(c, models.RoleAdmin, models.RoleTeacher, models.RoleContentLead)
+}
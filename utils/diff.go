@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/json"
+	"go-EdTech/models"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffSnapshots сравнивает два JSON снимка поле за полем.
+// Для многострочных текстов дополнительно строится построчный diff.
+func DiffSnapshots(oldData, newData json.RawMessage) ([]models.FieldChange, error) {
+	var oldFields, newFields map[string]any
+	if err := json.Unmarshal(oldData, &oldFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(newData, &newFields); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(newFields))
+	for key := range newFields {
+		keys = append(keys, key)
+	}
+	for key := range oldFields {
+		if _, ok := newFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]models.FieldChange, 0)
+	for _, key := range keys {
+		oldValue, newValue := oldFields[key], newFields[key]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		change := models.FieldChange{Field: key, Old: oldValue, New: newValue}
+
+		oldText, oldIsText := oldValue.(string)
+		newText, newIsText := newValue.(string)
+		if oldIsText && newIsText && (strings.Contains(oldText, "\n") || strings.Contains(newText, "\n")) {
+			change.Lines = DiffLines(oldText, newText)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// DiffLines строит построчный diff через наибольшую общую подпоследовательность
+func DiffLines(oldText, newText string) []models.DiffLine {
+	a := strings.Split(oldText, "\n")
+	b := strings.Split(newText, "\n")
+
+	// lcs[i][j] — длина НОП для a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]models.DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, models.DiffLine{Op: "=", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, models.DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			lines = append(lines, models.DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, models.DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, models.DiffLine{Op: "+", Text: b[j]})
+	}
+
+	return lines
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"fmt"
+	"go-EdTech/models"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // часовые пояса учеников не зависят от tzdata в образе
+)
+
+// опыт за события обучения
+const (
+	XPLessonCompleted = 10
+	XPCourseCompleted = 50
+	// за квиз на 100% — столько опыта; пересдача приносит только прирост лучшего результата
+	XPQuizMax = 20
+)
+
+// QuizXP возвращает опыт за попытку квиза с учетом лучшего прошлого результата, проценты 0..100
+func QuizXP(previousBestPercent, percent float64) int {
+	earned := math.Round(XPQuizMax*percent/100) - math.Round(XPQuizMax*previousBestPercent/100)
+	return int(max(0, earned))
+}
+
+// LoadUserLocation возвращает часовой пояс ученика; пустой или неизвестный пояс — UTC
+func LoadUserLocation(timeZone string) *time.Location {
+	if timeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// LocalDate возвращает календарную дату момента в часовом поясе ученика как полночь UTC
+func LocalDate(moment time.Time, location *time.Location) time.Time {
+	year, month, day := moment.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func sameDate(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// AdvanceStreak продлевает серию активностью в день today. Повторная активность в тот же день серию не меняет,
+// пропуск дня начинает серию заново.
+func AdvanceStreak(state models.GamificationState, today time.Time) models.GamificationState {
+	switch {
+	case state.Last_active_date != nil && sameDate(*state.Last_active_date, today):
+	case state.Last_active_date != nil && sameDate(state.Last_active_date.AddDate(0, 0, 1), today):
+		state.Current_streak++
+	default:
+		state.Current_streak = 1
+	}
+	state.Longest_streak = max(state.Longest_streak, state.Current_streak)
+	state.Last_active_date = &today
+	return state
+}
+
+// CurrentStreak возвращает действующую серию: она жива, если ученик занимался сегодня или вчера
+func CurrentStreak(state models.GamificationState, today time.Time) int {
+	if state.Last_active_date == nil {
+		return 0
+	}
+	if sameDate(*state.Last_active_date, today) || sameDate(state.Last_active_date.AddDate(0, 0, 1), today) {
+		return state.Current_streak
+	}
+	return 0
+}
+
+// ValidateBadgeRule проверяет правило бейджа
+func ValidateBadgeRule(rule models.BadgeRule) error {
+	if !slices.Contains(models.BadgeMetrics, rule.Metric) {
+		return fmt.Errorf("metric must be one of %s", strings.Join(models.BadgeMetrics, ", "))
+	}
+	if rule.Threshold <= 0 {
+		return fmt.Errorf("threshold must be positive")
+	}
+	if rule.Subject_id != nil && !slices.Contains(models.BadgeSubjectMetrics, rule.Metric) {
+		return fmt.Errorf("subject_id is supported only for %s", strings.Join(models.BadgeSubjectMetrics, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"fmt"
+	"go-EdTech/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+// настройки журнала для курса, где преподаватель их еще не менял
+var (
+	DefaultGradeCategories = []models.GradeCategory{
+		{Name: "Quizzes", Weight: 40, Item_type: models.GradeItemQuiz},
+		{Name: "Assignments", Weight: 60, Item_type: models.GradeItemAssignment},
+	}
+	DefaultLetterScheme = []models.LetterGrade{
+		{Letter: "A", Min_percent: 90},
+		{Letter: "B", Min_percent: 80},
+		{Letter: "C", Min_percent: 70},
+		{Letter: "D", Min_percent: 60},
+		{Letter: "F", Min_percent: 0},
+	}
+)
+
+// GradeKey — элемент журнала, тип и id
+type GradeKey struct {
+	Type string
+	Id   int
+}
+
+// ValidateGradebookSettings проверяет категории и шкалу и сортирует шкалу от высшей оценки к низшей
+func ValidateGradebookSettings(settings *models.GradebookSettings) error {
+	if len(settings.Categories) == 0 {
+		return fmt.Errorf("at least one category is required")
+	}
+
+	names := make(map[string]bool)
+	for i := range settings.Categories {
+		category := &settings.Categories[i]
+		category.Name = strings.TrimSpace(category.Name)
+		if category.Name == "" || names[category.Name] {
+			return fmt.Errorf("category names must be unique and not empty")
+		}
+		names[category.Name] = true
+		if category.Weight <= 0 {
+			return fmt.Errorf("weight of %q must be positive", category.Name)
+		}
+		if category.Item_type != models.GradeItemQuiz && category.Item_type != models.GradeItemAssignment {
+			return fmt.Errorf("item_type of %q must be quiz or assignment", category.Name)
+		}
+	}
+
+	if len(settings.Letter_scheme) == 0 {
+		return fmt.Errorf("letter scheme is required")
+	}
+	letters := make(map[string]bool)
+	for i := range settings.Letter_scheme {
+		grade := &settings.Letter_scheme[i]
+		grade.Letter = strings.TrimSpace(grade.Letter)
+		if grade.Letter == "" || letters[grade.Letter] {
+			return fmt.Errorf("letters must be unique and not empty")
+		}
+		letters[grade.Letter] = true
+		if grade.Min_percent < 0 || grade.Min_percent > 100 {
+			return fmt.Errorf("min_percent of %q must be between 0 and 100", grade.Letter)
+		}
+	}
+	slices.SortFunc(settings.Letter_scheme, func(a, b models.LetterGrade) int {
+		switch {
+		case a.Min_percent > b.Min_percent:
+			return -1
+		case a.Min_percent < b.Min_percent:
+			return 1
+		}
+		return 0
+	})
+	if settings.Letter_scheme[len(settings.Letter_scheme)-1].Min_percent != 0 {
+		return fmt.Errorf("the lowest letter must start at 0 percent")
+	}
+
+	return nil
+}
+
+// AssignGradeCategories раскладывает элементы журнала по категориям; элемент без категории в оценку не входит
+func AssignGradeCategories(items []models.GradebookItem, categories []models.GradeCategory) {
+	for i := range items {
+		items[i].Category = ""
+		for _, category := range categories {
+			if category.Item_type == items[i].Type && slices.Contains(category.Item_ids, items[i].Id) {
+				items[i].Category = category.Name
+				break
+			}
+		}
+		if items[i].Category != "" {
+			continue
+		}
+		for _, category := range categories {
+			if category.Item_type == items[i].Type && len(category.Item_ids) == 0 {
+				items[i].Category = category.Name
+				break
+			}
+		}
+	}
+}
+
+// LetterFor возвращает букву шкалы, отсортированной по убыванию Min_percent
+func LetterFor(percent float64, scheme []models.LetterGrade) string {
+	for _, grade := range scheme {
+		if percent >= grade.Min_percent {
+			return grade.Letter
+		}
+	}
+	return ""
+}
+
+// ComputeStudentGrade считает оценку ученика. Непройденный квиз и задание до срока в оценку не входят,
+// несданное после срока задание — 0. Категория считается по баллам, курс — по весам категорий с оценками.
+// Переопределения заменяют процент элемента или итог курса.
+func ComputeStudentGrade(
+	courseId int,
+	items []models.GradebookItem,
+	settings models.GradebookSettings,
+	scores map[GradeKey]models.GradeScore,
+	overrides map[GradeKey]float64,
+	now time.Time) models.StudentGrade {
+	grade := models.StudentGrade{
+		Course_id:  courseId,
+		Categories: make([]models.CategoryGrade, 0, len(settings.Categories)),
+		Items:      make([]models.ItemGrade, 0, len(items)),
+	}
+
+	earned := make(map[string]float64)
+	possible := make(map[string]float64)
+	for _, item := range items {
+		key := GradeKey{Type: item.Type, Id: item.Id}
+		itemGrade := models.ItemGrade{Type: item.Type, Id: item.Id}
+
+		if percent, ok := overrides[key]; ok {
+			itemGrade.Percent = &percent
+			itemGrade.Overridden = true
+		} else if score, ok := scores[key]; ok && score.Max_score > 0 {
+			percent := score.Score / score.Max_score * 100
+			itemGrade.Percent = &percent
+		} else if item.Type == models.GradeItemAssignment && item.Due_at != nil && now.After(*item.Due_at) {
+			percent := 0.0
+			itemGrade.Percent = &percent
+		}
+		grade.Items = append(grade.Items, itemGrade)
+
+		if itemGrade.Percent != nil && item.Category != "" && item.Max_score > 0 {
+			earned[item.Category] += *itemGrade.Percent / 100 * item.Max_score
+			possible[item.Category] += item.Max_score
+		}
+	}
+
+	var weighted, weights float64
+	for _, category := range settings.Categories {
+		categoryGrade := models.CategoryGrade{Name: category.Name, Weight: category.Weight}
+		if possible[category.Name] > 0 {
+			percent := earned[category.Name] / possible[category.Name] * 100
+			categoryGrade.Percent = &percent
+			weighted += percent * category.Weight
+			weights += category.Weight
+		}
+		grade.Categories = append(grade.Categories, categoryGrade)
+	}
+
+	if percent, ok := overrides[GradeKey{Type: models.GradeItemCourse, Id: courseId}]; ok {
+		grade.Percent = &percent
+		grade.Overridden = true
+	} else if weights > 0 {
+		percent := weighted / weights
+		grade.Percent = &percent
+	}
+	if grade.Percent != nil {
+		grade.Letter = LetterFor(*grade.Percent, settings.Letter_scheme)
+	}
+
+	return grade
+}
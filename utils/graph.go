@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FindCycle ищет цикл в ориентированном графе зависимостей (узел -> его предварительные требования).
+// Возвращает путь цикла, например [1 2 3 1], или nil, если граф ацикличен.
+func FindCycle(edges map[int][]int) []int {
+	const (
+		unvisited = iota
+		inStack
+		done
+	)
+
+	state := make(map[int]int)
+	stack := make([]int, 0)
+
+	var visit func(node int) []int
+	visit = func(node int) []int {
+		state[node] = inStack
+		stack = append(stack, node)
+
+		for _, next := range edges[node] {
+			switch state[next] {
+			case inStack:
+				for i, n := range stack {
+					if n == next {
+						cycle := append([]int{}, stack[i:]...)
+						return append(cycle, next)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[node] = done
+		return nil
+	}
+
+	// обходим узлы в стабильном порядке, чтобы ошибка была воспроизводимой
+	nodes := make([]int, 0, len(edges))
+	for node := range edges {
+		nodes = append(nodes, node)
+	}
+	sort.Ints(nodes)
+
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			if cycle := visit(node); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+var ErrDependencyCycle = errors.New("prerequisites would create a cycle")
+
+// ValidateAcyclic возвращает ErrDependencyCycle с путем цикла, если граф содержит цикл
+func ValidateAcyclic(edges map[int][]int) error {
+	cycle := FindCycle(edges)
+	if cycle == nil {
+		return nil
+	}
+
+	path := make([]string, len(cycle))
+	for i, node := range cycle {
+		path[i] = strconv.Itoa(node)
+	}
+	return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(path, " -> "))
+}
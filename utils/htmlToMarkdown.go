@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	markdownSpecialChars = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "$", `\$`, "<", `\<`)
+	extraBlankLines      = regexp.MustCompile(`\n{3,}`)
+	whitespaceRun        = regexp.MustCompile(`\s+`)
+)
+
+// HTMLToMarkdown переводит HTML страницу из импортированного пакета в Markdown тела урока.
+// resolve переписывает адреса ссылок и картинок, например путь внутри пакета в media:42.
+// Неподдерживаемые теги сохраняются только текстом.
+func HTMLToMarkdown(input string, resolve func(url string) string) string {
+	doc, err := xhtml.Parse(strings.NewReader(input))
+	if err != nil {
+		return ""
+	}
+
+	root := findElement(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+
+	c := &markdownConverter{resolve: resolve}
+	c.children(root)
+
+	out := extraBlankLines.ReplaceAllString(c.out.String(), "\n\n")
+	return strings.TrimSpace(out)
+}
+
+func findElement(n *xhtml.Node, a atom.Atom) *xhtml.Node {
+	if n.Type == xhtml.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// HTMLTitle возвращает содержимое <title> страницы
+func HTMLTitle(input string) string {
+	doc, err := xhtml.Parse(strings.NewReader(input))
+	if err != nil {
+		return ""
+	}
+	if title := findElement(doc, atom.Title); title != nil {
+		return strings.TrimSpace(textContent(title))
+	}
+	return ""
+}
+
+func textContent(n *xhtml.Node) string {
+	if n.Type == xhtml.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+func attr(n *xhtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+type markdownConverter struct {
+	out     strings.Builder
+	resolve func(url string) string
+}
+
+func (c *markdownConverter) write(s string) {
+	c.out.WriteString(s)
+}
+
+func (c *markdownConverter) block() {
+	c.write("\n\n")
+}
+
+func (c *markdownConverter) children(n *xhtml.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child)
+	}
+}
+
+func (c *markdownConverter) inline(n *xhtml.Node) string {
+	inner := &markdownConverter{resolve: c.resolve}
+	inner.children(n)
+	return strings.TrimSpace(inner.out.String())
+}
+
+// nested конвертирует вложенный блок и добавляет prefix ко всем строкам, кроме первой
+func (c *markdownConverter) nested(n *xhtml.Node, prefix string) string {
+	text := extraBlankLines.ReplaceAllString(c.inline(n), "\n\n")
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = prefix + lines[i]
+		} else {
+			lines[i] = strings.TrimRight(prefix, " ")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *markdownConverter) node(n *xhtml.Node) {
+	switch n.Type {
+	case xhtml.TextNode:
+		c.write(markdownSpecialChars.Replace(whitespaceRun.ReplaceAllString(n.Data, " ")))
+		return
+	case xhtml.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Noscript, atom.Template, atom.Iframe, atom.Object:
+		return
+
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		c.block()
+		c.write(strings.Repeat("#", level) + " " + c.inline(n))
+		c.block()
+
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main, atom.Table, atom.Tr:
+		c.block()
+		c.children(n)
+		c.block()
+
+	case atom.Td, atom.Th:
+		c.children(n)
+		c.write(" | ")
+
+	case atom.Br:
+		c.write("  \n")
+
+	case atom.Hr:
+		c.block()
+		c.write("---")
+		c.block()
+
+	case atom.Strong, atom.B:
+		if text := c.inline(n); text != "" {
+			c.write("**" + text + "**")
+		}
+
+	case atom.Em, atom.I:
+		if text := c.inline(n); text != "" {
+			c.write("*" + text + "*")
+		}
+
+	case atom.Del, atom.S:
+		if text := c.inline(n); text != "" {
+			c.write("~~" + text + "~~")
+		}
+
+	case atom.Code:
+		c.write("`" + strings.ReplaceAll(textContent(n), "`", "'") + "`")
+
+	case atom.Pre:
+		c.block()
+		c.write("```\n" + strings.TrimRight(textContent(n), "\n") + "\n```")
+		c.block()
+
+	case atom.A:
+		href := attr(n, "href")
+		text := c.inline(n)
+		if href == "" || strings.HasPrefix(href, "#") {
+			c.write(text)
+			return
+		}
+		if c.resolve != nil {
+			href = c.resolve(href)
+		}
+		c.write("[" + text + "](" + href + ")")
+
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return
+		}
+		if c.resolve != nil {
+			src = c.resolve(src)
+		}
+		c.write("![" + markdownSpecialChars.Replace(attr(n, "alt")) + "](" + src + ")")
+
+	case atom.Ul, atom.Ol:
+		c.block()
+		number := 1
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != xhtml.ElementNode || child.DataAtom != atom.Li {
+				continue
+			}
+			marker := "- "
+			if n.DataAtom == atom.Ol {
+				marker = strconv.Itoa(number) + ". "
+				number++
+			}
+			c.write(marker + c.nested(child, "    ") + "\n")
+		}
+		c.block()
+
+	case atom.Blockquote:
+		c.block()
+		c.write("> " + c.nested(n, "> "))
+		c.block()
+
+	default:
+		c.children(n)
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"go-EdTech/models"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// с этого возраста ученик может показываться в рейтингах под настоящим именем
+const LeaderboardAdultAge = 18
+
+var nicknamePattern = regexp.MustCompile(`^[\p{L}\p{N} _.-]+$`)
+
+// LeaderboardPeriodStart возвращает начало окна рейтинга по UTC: неделя — с понедельника, месяц — с 1 числа
+func LeaderboardPeriodStart(period string, now time.Time) time.Time {
+	year, month, day := now.UTC().Date()
+	switch period {
+	case models.LeaderboardPeriodWeek:
+		today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	case models.LeaderboardPeriodMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Unix(0, 0).UTC()
+	}
+}
+
+// IsMinor — ученик младше LeaderboardAdultAge или не указал возраст
+func IsMinor(age *int) bool {
+	return age == nil || *age < LeaderboardAdultAge
+}
+
+// ValidateNickname проверяет ник: 3–24 символа, буквы, цифры, пробел, точка, дефис и подчеркивание
+func ValidateNickname(nickname string) error {
+	length := utf8.RuneCountInString(nickname)
+	if length < 3 || length > 24 {
+		return fmt.Errorf("nickname must be 3 to 24 characters long")
+	}
+	if !nicknamePattern.MatchString(nickname) {
+		return fmt.Errorf("nickname may contain only letters, digits, spaces, dots, dashes and underscores")
+	}
+	return nil
+}
+
+// generatedNickname — ник по умолчанию, стабильный для ученика
+func generatedNickname(userUUID uuid.UUID) string {
+	return "Learner " + strings.ToUpper(strings.ReplaceAll(userUUID.String(), "-", "")[:6])
+}
+
+// LeaderboardDisplayName возвращает имя ученика в рейтинге с учетом его настроек.
+// Несовершеннолетние показываются только под ником, даже если настройка другая.
+func LeaderboardDisplayName(row models.LeaderboardRow) string {
+	visibility := row.Visibility
+	if visibility == "" || (visibility == models.LeaderboardShowName && IsMinor(row.Age)) {
+		visibility = models.LeaderboardShowNickname
+		if !IsMinor(row.Age) {
+			visibility = models.LeaderboardShowName
+		}
+	}
+
+	if visibility == models.LeaderboardShowName && row.Name != "" {
+		name := row.Name
+		if surname := []rune(strings.TrimSpace(row.Surname)); len(surname) > 0 {
+			name += " " + string(surname[0]) + "."
+		}
+		return name
+	}
+	if row.Nickname != "" {
+		return row.Nickname
+	}
+	return generatedNickname(row.User_uuid)
+}
+
+// LeaderboardEntries собирает записи рейтинга с учетом приватности. Строки идут по местам, место берется из строки:
+// равные очки делят место (1, 1, 3). Зритель вне первых limit мест — последняя лишняя строка, он попадает только в me.
+// lessonsTotal > 0 добавляет процент прохождения курса.
+func LeaderboardEntries(rows []models.LeaderboardRow, viewer uuid.UUID, limit, lessonsTotal int) ([]models.LeaderboardEntry, *models.LeaderboardEntry) {
+	entries := make([]models.LeaderboardEntry, 0, len(rows))
+	var me *models.LeaderboardEntry
+	for i, row := range rows {
+		entry := models.LeaderboardEntry{
+			Rank:         row.Rank,
+			Display_name: LeaderboardDisplayName(row),
+			Score:        row.Score,
+			Is_me:        row.User_uuid == viewer,
+		}
+		if lessonsTotal > 0 {
+			percent := min(100, float64(row.Score)*100/float64(lessonsTotal))
+			entry.Percent = &percent
+		}
+		if entry.Is_me {
+			viewerEntry := entry
+			me = &viewerEntry
+		}
+		if i < limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, me
+}
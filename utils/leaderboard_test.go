@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"go-EdTech/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLeaderboardPeriodStart(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		period string
+		now    time.Time
+		want   time.Time
+	}{
+		{"week on monday", models.LeaderboardPeriodWeek, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), date(2026, 10, 19)},
+		{"week on sunday night", models.LeaderboardPeriodWeek, time.Date(2026, 10, 25, 23, 59, 59, 0, time.UTC), date(2026, 10, 19)},
+		{"week in local monday is utc sunday", models.LeaderboardPeriodWeek, time.Date(2026, 10, 26, 1, 0, 0, 0, moscow), date(2026, 10, 19)},
+		{"week across new year", models.LeaderboardPeriodWeek, time.Date(2027, 1, 1, 12, 0, 0, 0, time.UTC), date(2026, 12, 28)},
+		{"month", models.LeaderboardPeriodMonth, time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC), date(2026, 10, 1)},
+		{"month in local first day is utc last day", models.LeaderboardPeriodMonth, time.Date(2026, 11, 1, 2, 0, 0, 0, moscow), date(2026, 10, 1)},
+		{"all time", models.LeaderboardPeriodAll, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), time.Unix(0, 0).UTC()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LeaderboardPeriodStart(tt.period, tt.now); !got.Equal(tt.want) {
+				t.Errorf("LeaderboardPeriodStart(%q, %s) = %s, want %s", tt.period, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardDisplayName(t *testing.T) {
+	adult, minor := 30, 15
+	user := uuid.MustParse("a1b2c3d4-0000-0000-0000-000000000000")
+
+	tests := []struct {
+		name string
+		row  models.LeaderboardRow
+		want string
+	}{
+		{"adult by default", models.LeaderboardRow{Name: "Anna", Surname: "Smith", Age: &adult}, "Anna S."},
+		{"adult without surname", models.LeaderboardRow{Name: "Anna", Age: &adult}, "Anna"},
+		{"cyrillic surname initial", models.LeaderboardRow{Name: "Анна", Surname: "Смирнова", Age: &adult}, "Анна С."},
+		{"adult chose nickname", models.LeaderboardRow{Name: "Anna", Surname: "Smith", Age: &adult, Visibility: models.LeaderboardShowNickname, Nickname: "anna_s"}, "anna_s"},
+		{"minor by default", models.LeaderboardRow{Name: "Tom", Surname: "Lee", Age: &minor, Nickname: "tommy"}, "tommy"},
+		{"minor asked for name", models.LeaderboardRow{Name: "Tom", Surname: "Lee", Age: &minor, Visibility: models.LeaderboardShowName, Nickname: "tommy"}, "tommy"},
+		{"unknown age is a minor", models.LeaderboardRow{Name: "Tom", Visibility: models.LeaderboardShowName, Nickname: "tommy"}, "tommy"},
+		{"minor without nickname", models.LeaderboardRow{User_uuid: user, Name: "Tom", Age: &minor}, "Learner A1B2C3"},
+		{"adult without name", models.LeaderboardRow{User_uuid: user, Age: &adult}, "Learner A1B2C3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LeaderboardDisplayName(tt.row); got != tt.want {
+				t.Errorf("LeaderboardDisplayName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardEntries(t *testing.T) {
+	adult := 30
+	users := make([]uuid.UUID, 5)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+	row := func(rank, user, score int) models.LeaderboardRow {
+		return models.LeaderboardRow{Rank: rank, User_uuid: users[user], Score: score, Name: "User", Age: &adult}
+	}
+	// места приходят из rank(): равные очки делят место, следующее место пропускается
+	rows := []models.LeaderboardRow{row(1, 0, 50), row(1, 1, 50), row(3, 2, 40), row(3, 3, 40), row(5, 4, 10)}
+	ranks := func(entries []models.LeaderboardEntry) []int {
+		result := make([]int, 0, len(entries))
+		for _, entry := range entries {
+			result = append(result, entry.Rank)
+		}
+		return result
+	}
+
+	t.Run("ties share rank", func(t *testing.T) {
+		entries, me := LeaderboardEntries(rows, users[3], 5, 0)
+		if want := []int{1, 1, 3, 3, 5}; !reflect.DeepEqual(ranks(entries), want) {
+			t.Errorf("ranks = %v, want %v", ranks(entries), want)
+		}
+		if me == nil || me.Rank != 3 || !entries[3].Is_me {
+			t.Errorf("viewer entry = %+v, want rank 3 marked as me", me)
+		}
+	})
+
+	t.Run("viewer outside limit", func(t *testing.T) {
+		visible := []models.LeaderboardRow{rows[0], rows[1], rows[4]}
+		entries, me := LeaderboardEntries(visible, users[4], 2, 0)
+		if want := []int{1, 1}; !reflect.DeepEqual(ranks(entries), want) {
+			t.Errorf("ranks = %v, want %v", ranks(entries), want)
+		}
+		if me == nil || me.Rank != 5 {
+			t.Errorf("viewer entry = %+v, want rank 5", me)
+		}
+	})
+
+	t.Run("viewer not ranked", func(t *testing.T) {
+		if _, me := LeaderboardEntries(rows, uuid.New(), 5, 0); me != nil {
+			t.Errorf("viewer entry = %+v, want nil", me)
+		}
+	})
+
+	t.Run("course percent", func(t *testing.T) {
+		entries, _ := LeaderboardEntries([]models.LeaderboardRow{row(1, 0, 12), row(2, 1, 5)}, users[0], 2, 10)
+		if *entries[0].Percent != 100 || *entries[1].Percent != 50 {
+			t.Errorf("percents = %v, %v, want 100, 50", *entries[0].Percent, *entries[1].Percent)
+		}
+	})
+}
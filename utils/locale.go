@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"go-EdTech/models"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseAcceptLanguage возвращает языки из заголовка Accept-Language по убыванию q.
+// Регион отбрасывается: "kk-KZ" -> "kk". Языки с q=0 пропускаются.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	items := make([]weighted, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		locale, _, _ := strings.Cut(tag, "-")
+		items = append(items, weighted{locale, q})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	locales := make([]string, 0, len(items))
+	for _, item := range items {
+		if !slices.Contains(locales, item.locale) {
+			locales = append(locales, item.locale)
+		}
+	}
+	return locales
+}
+
+func IsSupportedLocale(locale string) bool {
+	return slices.Contains(models.SupportedLocales, locale)
+}
+
+// TranslationLocales возвращает поддерживаемые языки, в которых стоит искать перевод, по порядку предпочтения.
+// Список обрывается на исходном языке: если он предпочтительнее, перевод не нужен.
+// "*" и незнакомые языки пропускаются, в итоге всегда остается исходный текст.
+func TranslationLocales(header string) []string {
+	locales := make([]string, 0)
+	for _, locale := range ParseAcceptLanguage(header) {
+		if locale == models.DefaultLocale {
+			break
+		}
+		if IsSupportedLocale(locale) {
+			locales = append(locales, locale)
+		}
+	}
+	return locales
+}
@@ -0,0 +1,661 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Небольшой рендерер Markdown для тела урока.
+// Поддерживает заголовки, абзацы, списки, цитаты, блоки кода, ссылки, картинки
+// и формулы в разделителях KaTeX: $...$ внутри строки и $$...$$ отдельным блоком.
+// Результат всегда проходит через SanitizeHTML.
+//
+// Текст приходит от пользователя, поэтому разбор должен оставаться линейным на любом вводе:
+// закрывающие скобки и маркеры ищутся по заранее посчитанным таблицам, а не поиском из каждой позиции.
+
+const (
+	// MaxMarkdownSize — предельный размер тела урока в байтах
+	MaxMarkdownSize = 256 << 10
+	// глубже цитаты и списки рендерятся как обычный текст
+	maxMarkdownNesting = 16
+	// длиннее заголовок ссылки не ищется
+	maxLinkTitle = 1024
+)
+
+var (
+	headingRegexp     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	hrRegexp          = regexp.MustCompile(`^ {0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	unorderedRegexp   = regexp.MustCompile(`^ {0,3}[-*+]\s+(.*)$`)
+	orderedRegexp     = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)]\s+(.*)$`)
+	fenceRegexp       = regexp.MustCompile("^ {0,3}(```+|~~~+)\\s*([\\w+-]*)")
+	inlineHTMLRegexp  = regexp.MustCompile(`^</?[a-zA-Z][a-zA-Z0-9]*(?:\s+[a-zA-Z_:][-a-zA-Z0-9_:.]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*\s*/?>`)
+	mediaRefRegexp    = regexp.MustCompile(`^media:(\d+)$`)
+	markdownEscapable = "\\`*_{}[]()#+-.!~$|<>\"'"
+)
+
+// MediaResolver превращает ссылку вида media:42 в URL для отдачи файла
+type MediaResolver func(mediaId int) string
+
+// RenderMarkdown рендерит Markdown в безопасный HTML и возвращает id файлов из хранилища, на которые ссылается текст
+func RenderMarkdown(source string, resolve MediaResolver) (string, []int) {
+	r := &markdownRenderer{resolve: resolve}
+
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\t", "    ")
+
+	r.renderBlocks(strings.Split(source, "\n"))
+
+	return SanitizeHTML(r.out.String()), r.mediaIds
+}
+
+type markdownRenderer struct {
+	out      strings.Builder
+	resolve  MediaResolver
+	mediaIds []int
+	depth    int
+}
+
+func (r *markdownRenderer) renderBlocks(lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case fenceRegexp.MatchString(line):
+			i = r.renderFence(lines, i)
+
+		case strings.HasPrefix(trimmed, "$$"):
+			i = r.renderDisplayMath(lines, i)
+
+		case headingRegexp.MatchString(trimmed):
+			m := headingRegexp.FindStringSubmatch(trimmed)
+			level := len(m[1])
+			fmt.Fprintf(&r.out, "<h%d>%s</h%d>\n", level, r.renderInline(m[2]), level)
+			i++
+
+		case hrRegexp.MatchString(line):
+			r.out.WriteString("<hr>\n")
+			i++
+
+		case strings.HasPrefix(trimmed, ">") && r.depth < maxMarkdownNesting:
+			quoted := make([]string, 0)
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			r.out.WriteString("<blockquote>\n")
+			r.depth++
+			r.renderBlocks(quoted)
+			r.depth--
+			r.out.WriteString("</blockquote>\n")
+
+		case (unorderedRegexp.MatchString(line) || orderedRegexp.MatchString(line)) && r.depth < maxMarkdownNesting:
+			i = r.renderList(lines, i)
+
+		default:
+			i = r.renderParagraph(lines, i)
+		}
+	}
+}
+
+func (r *markdownRenderer) renderFence(lines []string, start int) int {
+	m := fenceRegexp.FindStringSubmatch(lines[start])
+	fence, lang := m[1], m[2]
+
+	code := make([]string, 0)
+	i := start + 1
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+
+	if lang != "" {
+		fmt.Fprintf(&r.out, `<pre><code class="language-%s">`, html.EscapeString(lang))
+	} else {
+		r.out.WriteString("<pre><code>")
+	}
+	r.out.WriteString(html.EscapeString(strings.Join(code, "\n")))
+	r.out.WriteString("</code></pre>\n")
+
+	return i
+}
+
+func (r *markdownRenderer) renderDisplayMath(lines []string, start int) int {
+	first := strings.TrimPrefix(strings.TrimSpace(lines[start]), "$$")
+
+	// формула в одну строку: $$ x^2 $$
+	if strings.HasSuffix(first, "$$") {
+		r.writeDisplayMath(strings.TrimSuffix(first, "$$"))
+		return start + 1
+	}
+
+	formula := []string{first}
+	i := start + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasSuffix(trimmed, "$$") {
+			formula = append(formula, strings.TrimSuffix(trimmed, "$$"))
+			i++
+			break
+		}
+		formula = append(formula, lines[i])
+	}
+
+	r.writeDisplayMath(strings.Join(formula, "\n"))
+	return i
+}
+
+func (r *markdownRenderer) writeDisplayMath(formula string) {
+	r.out.WriteString(`<div class="math math-display">\[`)
+	r.out.WriteString(html.EscapeString(strings.TrimSpace(formula)))
+	r.out.WriteString("\\]</div>\n")
+}
+
+func (r *markdownRenderer) renderList(lines []string, start int) int {
+	ordered := orderedRegexp.MatchString(lines[start])
+	indent := len(lines[start]) - len(strings.TrimLeft(lines[start], " "))
+
+	if ordered {
+		m := orderedRegexp.FindStringSubmatch(lines[start])
+		if n, _ := strconv.Atoi(m[1]); n != 1 {
+			fmt.Fprintf(&r.out, "<ol start=\"%d\">\n", n)
+		} else {
+			r.out.WriteString("<ol>\n")
+		}
+	} else {
+		r.out.WriteString("<ul>\n")
+	}
+
+	i := start
+	for i < len(lines) {
+		var first string
+		if ordered {
+			m := orderedRegexp.FindStringSubmatch(lines[i])
+			if m == nil {
+				break
+			}
+			first = m[2]
+		} else {
+			m := unorderedRegexp.FindStringSubmatch(lines[i])
+			if m == nil {
+				break
+			}
+			first = m[1]
+		}
+
+		// строки с отступом относятся к текущему пункту (вложенные списки, продолжение текста)
+		item := []string{first}
+		loose := false
+		i++
+		for i < len(lines) {
+			line := lines[i]
+			lineIndent := len(line) - len(strings.TrimLeft(line, " "))
+			if strings.TrimSpace(line) == "" {
+				if i+1 < len(lines) && len(lines[i+1])-len(strings.TrimLeft(lines[i+1], " ")) > indent && strings.TrimSpace(lines[i+1]) != "" {
+					item = append(item, "")
+					loose = true
+					i++
+					continue
+				}
+				break
+			}
+			if lineIndent <= indent && (unorderedRegexp.MatchString(line) || orderedRegexp.MatchString(line)) {
+				break
+			}
+			if lineIndent <= indent && isBlockStart(line) {
+				break
+			}
+			item = append(item, strings.TrimPrefix(line, strings.Repeat(" ", min(lineIndent, indent+4))))
+			i++
+		}
+
+		r.out.WriteString("<li>")
+		if len(item) == 1 || (!loose && !hasNestedBlock(item[1:])) {
+			r.out.WriteString(r.renderInline(strings.Join(item, "\n")))
+		} else {
+			r.out.WriteString(r.renderInline(item[0]))
+			r.out.WriteString("\n")
+			r.depth++
+			r.renderBlocks(item[1:])
+			r.depth--
+		}
+		r.out.WriteString("</li>\n")
+
+		// пустая строка между пунктами не заканчивает список
+		if i+1 < len(lines) && strings.TrimSpace(lines[i]) == "" && sameListKind(lines[i+1], ordered) {
+			i++
+		}
+	}
+
+	if ordered {
+		r.out.WriteString("</ol>\n")
+	} else {
+		r.out.WriteString("</ul>\n")
+	}
+	return i
+}
+
+func sameListKind(line string, ordered bool) bool {
+	if ordered {
+		return orderedRegexp.MatchString(line)
+	}
+	return unorderedRegexp.MatchString(line)
+}
+
+func hasNestedBlock(lines []string) bool {
+	for _, line := range lines {
+		if isBlockStart(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlockStart(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return fenceRegexp.MatchString(line) ||
+		strings.HasPrefix(trimmed, "$$") ||
+		strings.HasPrefix(trimmed, ">") ||
+		headingRegexp.MatchString(trimmed) ||
+		hrRegexp.MatchString(line) ||
+		unorderedRegexp.MatchString(trimmed) ||
+		orderedRegexp.MatchString(trimmed)
+}
+
+func (r *markdownRenderer) renderParagraph(lines []string, start int) int {
+	para := []string{strings.TrimLeft(lines[start], " ")}
+	i := start + 1
+	for ; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" || isBlockStart(lines[i]) {
+			break
+		}
+		para = append(para, strings.TrimLeft(lines[i], " "))
+	}
+
+	r.out.WriteString("<p>")
+	r.out.WriteString(r.renderInline(strings.TrimRight(strings.Join(para, "\n"), " ")))
+	r.out.WriteString("</p>\n")
+	return i
+}
+
+func (r *markdownRenderer) renderInline(text string) string {
+	return r.renderSpan(newInlineScanner(text), 0, len(text), false)
+}
+
+// renderSpan рендерит text[from:to]. Внутри текста ссылки другие ссылки не распознаются, как в CommonMark.
+func (r *markdownRenderer) renderSpan(sc *inlineScanner, from, to int, inLink bool) string {
+	var b strings.Builder
+	text := sc.text
+
+	for i := from; i < to; {
+		ch := text[i]
+		rest := text[i:to]
+
+		switch {
+		case ch == '\\' && i+1 < to && strings.IndexByte(markdownEscapable, text[i+1]) >= 0:
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case ch == '\\' && i+1 < to && text[i+1] == '\n':
+			b.WriteString("<br>\n")
+			i += 2
+			continue
+
+		case ch == '`':
+			ticks := len(rest) - len(strings.TrimLeft(rest, "`"))
+			if end := sc.codeEnd(i+ticks, to, ticks); end >= 0 {
+				code := strings.TrimSpace(text[i+ticks : end])
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i = end + ticks
+				continue
+			}
+			// незакрытые обратные кавычки выводим целиком, иначе каждая из них снова искала бы пару
+			b.WriteString(html.EscapeString(rest[:ticks]))
+			i += ticks
+			continue
+
+		case ch == '$' && !strings.HasPrefix(rest, "$$"):
+			if end := sc.mathEnd(i, to); end > 0 {
+				b.WriteString(`<span class="math math-inline">\(` + html.EscapeString(text[i+1:end]) + `\)</span>`)
+				i = end + 1
+				continue
+			}
+
+		case ch == '!' && strings.HasPrefix(rest, "!["):
+			if link, ok := sc.link(i+1, to); ok {
+				src := r.resolveURL(link.dest)
+				b.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(text[link.labelFrom:link.labelTo]) + `"`)
+				if link.title != "" {
+					b.WriteString(` title="` + html.EscapeString(link.title) + `"`)
+				}
+				b.WriteString(">")
+				i = link.end
+				continue
+			}
+
+		case ch == '[' && !inLink:
+			if link, ok := sc.link(i, to); ok {
+				href := r.resolveURL(link.dest)
+				b.WriteString(`<a href="` + html.EscapeString(href) + `"`)
+				if link.title != "" {
+					b.WriteString(` title="` + html.EscapeString(link.title) + `"`)
+				}
+				b.WriteString(">" + r.renderSpan(sc, link.labelFrom, link.labelTo, true) + "</a>")
+				i = link.end
+				continue
+			}
+
+		case ch == '<':
+			// сырой HTML пропускаем как есть, его почистит SanitizeHTML
+			if m := inlineHTMLRegexp.FindString(rest); m != "" {
+				b.WriteString(m)
+				i += len(m)
+				continue
+			}
+
+		case ch == '*' || ch == '_' || ch == '~':
+			if marker, tag, end := sc.emphasis(i, to); end > 0 {
+				b.WriteString("<" + tag + ">" + r.renderSpan(sc, i+len(marker), end, inLink) + "</" + tag + ">")
+				i = end + len(marker)
+				continue
+			}
+
+		case ch == ' ' && strings.HasPrefix(rest, "  \n"):
+			b.WriteString("<br>\n")
+			i += 3
+			continue
+		}
+
+		b.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+
+	return b.String()
+}
+
+var emphasisMarkers = []struct{ marker, tag string }{
+	{"**", "strong"},
+	{"__", "strong"},
+	{"~~", "del"},
+	{"*", "em"},
+	{"_", "em"},
+}
+
+// inlineScanner хранит таблицы для строки абзаца: с ними поиск пары для скобки или маркера стоит O(1),
+// и разбор всей строки остается линейным даже на тексте вроде "[a]([a]([a](..." или "*a **b **b ...".
+type inlineScanner struct {
+	text string
+	// для '[' — индекс парной ']', иначе -1
+	closeBracket []int
+	// первая позиция >= i, где заканчивается адрес в <...>: пробельный символ, ')' или '>'
+	destEnd []int
+	// первая позиция >= i, где заканчивается обычный адрес: пробельный символ или ')' без пары внутри адреса,
+	// чтобы скобки в адресе вроде /wiki/Go_(language) не обрывали ссылку
+	parenEnd []int
+	// для маркера выделения — первая позиция >= i, где он может закрыться
+	closers map[string][]int
+	// отрезки [from, to), в которых пары для `...` (по числу кавычек) и $...$ точно нет
+	noCode map[int]span
+	noMath span
+}
+
+type span struct{ from, to int }
+
+// covers — в отрезке [from, to) уже известно, что пары нет
+func (s span) covers(from, to int) bool {
+	return from >= s.from && to <= s.to
+}
+
+type inlineLink struct {
+	labelFrom, labelTo int
+	dest, title        string
+	end                int
+}
+
+func newInlineScanner(text string) *inlineScanner {
+	n := len(text)
+	sc := &inlineScanner{
+		text:         text,
+		closeBracket: make([]int, n),
+		destEnd:      make([]int, n+1),
+		parenEnd:     make([]int, n+1),
+		closers:      make(map[string][]int),
+		noCode:       make(map[int]span),
+		noMath:       span{from: n + 1},
+	}
+
+	open := make([]int, 0)
+	for i := 0; i < n; i++ {
+		sc.closeBracket[i] = -1
+		switch text[i] {
+		case '\\':
+			if i+1 < n {
+				sc.closeBracket[i+1] = -1
+				i++
+			}
+		case '[':
+			open = append(open, i)
+		case ']':
+			if len(open) > 0 {
+				sc.closeBracket[open[len(open)-1]] = i
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	sc.destEnd[n] = n
+	for i := n - 1; i >= 0; i-- {
+		switch text[i] {
+		case ' ', '\t', '\n', '\r', '\f', '\v', ')', '>':
+			sc.destEnd[i] = i
+		default:
+			sc.destEnd[i] = sc.destEnd[i+1]
+		}
+	}
+
+	// depth[i] — баланс неэкранированных скобок до позиции i. Адрес с позиции i заканчивается на первой ')',
+	// перед которой баланс равен depth[i]: на ней он впервые уходит ниже, чем был в начале адреса.
+	depth := make([]int, n+1)
+	escaped := make([]bool, n)
+	for i := 0; i < n; i++ {
+		depth[i+1] = depth[i]
+		switch {
+		case escaped[i]:
+		case text[i] == '\\' && i+1 < n:
+			escaped[i+1] = true
+		case text[i] == '(':
+			depth[i+1]++
+		case text[i] == ')':
+			depth[i+1]--
+		}
+	}
+	nearestClose := make(map[int]int)
+	space := n
+	sc.parenEnd[n] = n
+	for i := n - 1; i >= 0; i-- {
+		switch {
+		case strings.IndexByte(" \t\n\r\f\v", text[i]) >= 0:
+			space = i
+		case text[i] == ')' && !escaped[i]:
+			nearestClose[depth[i]] = i
+		}
+		sc.parenEnd[i] = space
+		if close, ok := nearestClose[depth[i]]; ok && close < space {
+			sc.parenEnd[i] = close
+		}
+	}
+
+	return sc
+}
+
+// link разбирает [текст](адрес "заголовок") с позиции '['
+func (sc *inlineScanner) link(start, to int) (inlineLink, bool) {
+	text := sc.text
+	closing := sc.closeBracket[start]
+	if closing < 0 || closing+1 >= to || text[closing+1] != '(' {
+		return inlineLink{}, false
+	}
+
+	link := inlineLink{labelFrom: start + 1, labelTo: closing}
+	i := skipSpaces(text, closing+2, to)
+
+	angled := i < to && text[i] == '<'
+	if angled {
+		i++
+	}
+	destEnd := sc.parenEnd[i]
+	if angled {
+		destEnd = sc.destEnd[i]
+	}
+	destEnd = min(destEnd, to)
+	link.dest = text[i:destEnd]
+	i = destEnd
+	if angled && i < to && text[i] == '>' {
+		i++
+	}
+
+	if afterSpaces := skipSpaces(text, i, to); afterSpaces > i && afterSpaces < to && text[afterSpaces] == '"' {
+		if title, end, ok := linkTitle(text, afterSpaces+1, min(to, afterSpaces+1+maxLinkTitle)); ok {
+			link.title = title
+			i = end + 1
+		}
+	}
+
+	i = skipSpaces(text, i, to)
+	if i >= to || text[i] != ')' {
+		return inlineLink{}, false
+	}
+	link.end = i + 1
+	return link, true
+}
+
+// linkTitle читает заголовок ссылки после открывающей кавычки до неэкранированной '"' и снимает экранирование
+func linkTitle(text string, from, limit int) (string, int, bool) {
+	var title strings.Builder
+	for j := from; j < limit; j++ {
+		switch {
+		case text[j] == '\\' && j+1 < limit && strings.IndexByte(markdownEscapable, text[j+1]) >= 0:
+			j++
+			title.WriteByte(text[j])
+		case text[j] == '"':
+			return title.String(), j, true
+		default:
+			title.WriteByte(text[j])
+		}
+	}
+	return "", 0, false
+}
+
+func skipSpaces(text string, i, to int) int {
+	for i < to && strings.IndexByte(" \t\n\r\f\v", text[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// codeEnd ищет закрывающие ticks обратных кавычек, начиная с from.
+// Неудачный поиск запоминается: дальше в том же отрезке пары тем более нет.
+func (sc *inlineScanner) codeEnd(from, to, ticks int) int {
+	if failed, ok := sc.noCode[ticks]; ok && failed.covers(from, to) {
+		return -1
+	}
+	end := strings.Index(sc.text[from:to], sc.text[from-ticks:from])
+	if end < 0 {
+		sc.noCode[ticks] = span{from: from, to: to}
+		return -1
+	}
+	return from + end
+}
+
+// mathEnd ищет закрывающий $ как pandoc: после открывающего нет пробела,
+// перед закрывающим нет пробела и за ним не идет цифра (чтобы "$5 и $6" не стали формулой)
+func (sc *inlineScanner) mathEnd(start, to int) int {
+	text := sc.text
+	if start+2 >= to || text[start+1] == ' ' || text[start+1] == '\n' || sc.noMath.covers(start, to) {
+		return -1
+	}
+	for j := start + 2; j < to; j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '$':
+			if text[j-1] == ' ' || (j+1 < to && text[j+1] >= '0' && text[j+1] <= '9') {
+				continue
+			}
+			return j
+		}
+	}
+	sc.noMath = span{from: start, to: to}
+	return -1
+}
+
+// emphasis возвращает маркер, тег и позицию закрывающего маркера для выделения с позиции start
+func (sc *inlineScanner) emphasis(start, to int) (string, string, int) {
+	text := sc.text
+	for _, d := range emphasisMarkers {
+		if !strings.HasPrefix(text[start:to], d.marker) {
+			continue
+		}
+		from := start + len(d.marker)
+		if from >= to || text[from] == ' ' || text[from] == '\n' {
+			continue
+		}
+		end := sc.closer(d.marker)[from]
+		if end < 0 || end+len(d.marker) > to || end == from || text[end-1] == ' ' {
+			continue
+		}
+		return d.marker, d.tag, end
+	}
+	return "", "", -1
+}
+
+// closer считает для маркера таблицу ближайших закрывающих позиций справа налево.
+// Одиночный маркер не должен совпасть с первым символом двойного, поэтому пары вроде ** пропускаются.
+func (sc *inlineScanner) closer(marker string) []int {
+	if table, ok := sc.closers[marker]; ok {
+		return table
+	}
+
+	text := sc.text
+	n := len(text)
+	table := make([]int, n+2)
+	table[n], table[n+1] = -1, -1
+	for i := n - 1; i >= 0; i-- {
+		switch {
+		case !strings.HasPrefix(text[i:], marker):
+			table[i] = table[i+1]
+		case len(marker) == 1 && i+1 < n && text[i+1] == marker[0]:
+			table[i] = table[i+2]
+		default:
+			table[i] = i
+		}
+	}
+
+	sc.closers[marker] = table
+	return table
+}
+
+// resolveURL заменяет ссылки media:42 на адрес файла в хранилище
+func (r *markdownRenderer) resolveURL(url string) string {
+	m := mediaRefRegexp.FindStringSubmatch(url)
+	if m == nil {
+		return url
+	}
+
+	id, _ := strconv.Atoi(m[1])
+	r.mediaIds = append(r.mediaIds, id)
+	if r.resolve == nil {
+		return fmt.Sprintf("/media/%d", id)
+	}
+	return r.resolve(id)
+}
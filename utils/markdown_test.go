@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"heading and paragraph", "# Title\n\nHello", "<h1>Title</h1>\n<p>Hello</p>\n"},
+		{"emphasis", "*a* **b** _c_ __d__ ~~e~~", "<p><em>a</em> <strong>b</strong> <em>c</em> <strong>d</strong> <del>e</del></p>\n"},
+		{"emphasis around strong", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+		{"unclosed emphasis", "*a **b", "<p>*a **b</p>\n"},
+		{"inline code", "`a*b*` and ``c`d``", "<p><code>a*b*</code> and <code>c`d</code></p>\n"},
+		{"inline math", "$x^2$ costs $5 and $6", `<p><span class="math math-inline">\(x^2\)</span> costs $5 and $6</p>` + "\n"},
+		{"display math", "$$\na < b\n$$", `<div class="math math-display">\[a &lt; b\]</div>` + "\n"},
+		{"link with title", `[site](https://a.example "Site")`, `<p><a href="https://a.example" title="Site" rel="nofollow noopener noreferrer">site</a></p>` + "\n"},
+		{"link with nested brackets", "[a [b] c](/x)", `<p><a href="/x" rel="nofollow noopener noreferrer">a [b] c</a></p>` + "\n"},
+		{"no link inside link", "[a [b](/y) c](/x)", `<p><a href="/x" rel="nofollow noopener noreferrer">a [b](/y) c</a></p>` + "\n"},
+		{"escaped bracket", `\[a](/x)`, "<p>[a](/x)</p>\n"},
+		{"image from storage", "![pic](media:7)", `<p><img src="/media/7" alt="pic"></p>` + "\n"},
+		{"fenced code", "```go\n<b>\n```", `<pre><code class="language-go">&lt;b&gt;</code></pre>` + "\n"},
+		{"list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"quote", "> a", "<blockquote>\n<p>a</p>\n</blockquote>\n"},
+		{"link with parens in url", "[Go](https://en.wikipedia.org/wiki/Go_(language))", `<p><a href="https://en.wikipedia.org/wiki/Go_(language)" rel="nofollow noopener noreferrer">Go</a></p>` + "\n"},
+		{"link with unbalanced paren", "[a](/x)y)", `<p><a href="/x" rel="nofollow noopener noreferrer">a</a>y)</p>` + "\n"},
+		{"link with escaped paren", `[a](/x\()`, `<p><a href="/x%5C%28" rel="nofollow noopener noreferrer">a</a></p>` + "\n"},
+		{"javascript link", "[x](javascript:alert(1))", `<p><a rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"javascript image", "![x](javascript:alert(1))", `<p><img alt="x"></p>` + "\n"},
+		{"raw script", "<script>alert(1)</script>ok", "<p>ok</p>\n"},
+		{"raw iframe", `<iframe src="https://evil.example"></iframe>`, "<p></p>\n"},
+		{"event handler", `<img src="/media/1" onerror="alert(1)">`, `<p><img src="/media/1"></p>` + "\n"},
+		{"title breakout", `[x](/a "\"><script>")`, `<p><a href="/a" title="&#34;&gt;&lt;script&gt;" rel="nofollow noopener noreferrer">x</a></p>` + "\n"},
+		{"unclosed script keeps the rest", "<script>alert(1)\n\nrest", "<p>alert(1)</p>\n<p>rest</p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := RenderMarkdown(tt.source, nil); got != tt.want {
+				t.Errorf("RenderMarkdown(%q)\n got: %q\nwant: %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownMediaIds(t *testing.T) {
+	_, ids := RenderMarkdown("![a](media:3) [b](media:5) `media:9` [c](https://x.example)", nil)
+	if want := []int{3, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("media ids = %v, want %v", ids, want)
+	}
+
+	got, _ := RenderMarkdown("![a](media:3)", func(id int) string { return fmt.Sprintf("files/%d.png", id) })
+	if !strings.Contains(got, `src="files/3.png"`) {
+		t.Errorf("resolver was not applied: %s", got)
+	}
+}
+
+// на вводе размера MaxMarkdownSize разбор должен оставаться линейным
+func TestRenderMarkdownPathological(t *testing.T) {
+	repeat := func(s string) string { return strings.Repeat(s, MaxMarkdownSize/len(s)) }
+
+	tests := map[string]string{
+		"link openers":      repeat("[a]("),
+		"open brackets":     repeat("["),
+		"nested links":      strings.Repeat("[", MaxMarkdownSize/8) + "a" + strings.Repeat("](x)", MaxMarkdownSize/8),
+		"emphasis pairs":    "*a " + repeat("**b ") + " *",
+		"mixed emphasis":    repeat("*a _b ~~c __d **e "),
+		"backticks":         repeat("`a"),
+		"dollars":           repeat("$a "),
+		"nested quotes":     repeat(">"),
+		"nested lists":      repeat("  - a\n"),
+		"mixed inline":      repeat("[*a `b $c]("),
+		"unclosed link tag": repeat(`[a](b "`),
+		"parens in url":     "[a](" + repeat("(b") + repeat(")"),
+		"escaped title":     repeat(`[a](b "\"`),
+	}
+
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			RenderMarkdown(source, nil)
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("rendering %d bytes took %s", len(source), elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go-EdTech/config"
+	"go-EdTech/models"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const MaxMediaSize = 100 << 20 // 100 MB
+
+// StoreMedia сохраняет содержимое на диск под именем sha256 хеша.
+// Одинаковые файлы хранятся один раз, поэтому несколько записей media могут ссылаться на один blob.
+func StoreMedia(r io.Reader) (string, int64, error) {
+	if err := os.MkdirAll(config.Config.MediaPath, 0755); err != nil {
+		return "", 0, err
+	}
+
+	tmp, err := os.CreateTemp(config.Config.MediaPath, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	path := MediaFilePath(checksum)
+	if _, err := os.Stat(path); err == nil {
+		return checksum, size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, err
+	}
+
+	return checksum, size, nil
+}
+
+// StoreUploadedMedia сохраняет загруженный файл и заполняет метаданные для таблицы media
+func StoreUploadedMedia(fileHeader *multipart.FileHeader) (models.Media, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return models.Media{}, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return models.Media{}, err
+	}
+
+	checksum, size, err := StoreMedia(file)
+	if err != nil {
+		return models.Media{}, err
+	}
+
+	return models.Media{
+		Filename:  filepath.Base(fileHeader.Filename),
+		Mime_type: DetectMimeType(fileHeader.Filename, head[:n]),
+		Size:      size,
+		Checksum:  checksum,
+	}, nil
+}
+
+func MediaFilePath(checksum string) string {
+	return filepath.Join(config.Config.MediaPath, checksum[:2], checksum)
+}
+
+// DetectMimeType сначала смотрит на расширение файла, затем на содержимое
+func DetectMimeType(filename string, head []byte) string {
+	if mimeType := mime.TypeByExtension(filepath.Ext(filename)); mimeType != "" {
+		return mimeType
+	}
+	return http.DetectContentType(head)
+}
@@ -0,0 +1,418 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrUnencodableText — в тексте есть символ, которого нет в шрифте; подставлять '?' вместо имени нельзя
+var ErrUnencodableText = errors.New("text has characters the font cannot render")
+
+// ширины Helvetica для символов 32..126 в тысячных долях кегля
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// trueTypeFont — таблицы TrueType, нужные для встраивания: метрики и cmap
+type trueTypeFont struct {
+	data       []byte
+	tables     map[string][]byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	bbox       [4]int
+	advances   []int
+	cmap       []byte
+	cmapFormat int
+}
+
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("font file is too short")
+	}
+	if version := binary.BigEndian.Uint32(data); version != 0x00010000 && version != 0x74727565 {
+		return nil, fmt.Errorf("only TrueType fonts are supported")
+	}
+
+	font := &trueTypeFont{data: data, tables: make(map[string][]byte)}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return nil, fmt.Errorf("font table directory is truncated")
+		}
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("font table %q is out of bounds", data[record:record+4])
+		}
+		font.tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+
+	head, hhea, hmtx := font.tables["head"], font.tables["hhea"], font.tables["hmtx"]
+	if len(head) < 54 || len(hhea) < 36 || hmtx == nil {
+		return nil, fmt.Errorf("font has no head, hhea or hmtx table")
+	}
+	font.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if font.unitsPerEm == 0 {
+		return nil, fmt.Errorf("font has zero units per em")
+	}
+	for i := range font.bbox {
+		font.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+i*2:])))
+	}
+	font.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	font.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if metrics == 0 || len(hmtx) < metrics*4 {
+		return nil, fmt.Errorf("font hmtx table is truncated")
+	}
+	font.advances = make([]int, metrics)
+	for i := range font.advances {
+		font.advances[i] = int(binary.BigEndian.Uint16(hmtx[i*4:]))
+	}
+
+	if err := font.selectCmap(); err != nil {
+		return nil, err
+	}
+	return font, nil
+}
+
+// selectCmap выбирает юникодную подтаблицу cmap: формат 12 для всего юникода, иначе формат 4
+func (f *trueTypeFont) selectCmap() error {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return fmt.Errorf("font has no cmap table")
+	}
+
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numTables && 4+i*8+8 <= len(cmap); i++ {
+		record := cmap[4+i*8:]
+		platform := binary.BigEndian.Uint16(record)
+		encoding := binary.BigEndian.Uint16(record[2:])
+		offset := int(binary.BigEndian.Uint32(record[4:]))
+		if offset+2 > len(cmap) || !(platform == 0 || platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+
+		format := int(binary.BigEndian.Uint16(cmap[offset:]))
+		if format == 12 || format == 4 && f.cmapFormat != 12 {
+			f.cmap = cmap[offset:]
+			f.cmapFormat = format
+		}
+	}
+	if f.cmap == nil {
+		return fmt.Errorf("font has no unicode cmap")
+	}
+	return nil
+}
+
+func (f *trueTypeFont) u16(table []byte, offset int) int {
+	if offset < 0 || offset+2 > len(table) {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(table[offset:]))
+}
+
+func (f *trueTypeFont) u32(table []byte, offset int) int {
+	if offset < 0 || offset+4 > len(table) {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(table[offset:]))
+}
+
+// glyph возвращает id глифа символа, 0 — глифа в шрифте нет
+func (f *trueTypeFont) glyph(r rune) int {
+	code := int(r)
+	if f.cmapFormat == 12 {
+		groups := f.u32(f.cmap, 12)
+		for i := 0; i < groups; i++ {
+			group := 16 + i*12
+			start, end := f.u32(f.cmap, group), f.u32(f.cmap, group+4)
+			if code >= start && code <= end {
+				return f.u32(f.cmap, group+8) + code - start
+			}
+		}
+		return 0
+	}
+
+	if code > 0xFFFF {
+		return 0
+	}
+	segments := f.u16(f.cmap, 6) / 2
+	endCodes := 14
+	startCodes := endCodes + segments*2 + 2
+	deltas := startCodes + segments*2
+	rangeOffsets := deltas + segments*2
+	for i := 0; i < segments; i++ {
+		if code > f.u16(f.cmap, endCodes+i*2) {
+			continue
+		}
+		start := f.u16(f.cmap, startCodes+i*2)
+		if code < start {
+			return 0
+		}
+		delta := f.u16(f.cmap, deltas+i*2)
+		rangeOffset := f.u16(f.cmap, rangeOffsets+i*2)
+		if rangeOffset == 0 {
+			return (code + delta) & 0xFFFF
+		}
+		glyph := f.u16(f.cmap, rangeOffsets+i*2+rangeOffset+(code-start)*2)
+		if glyph == 0 {
+			return 0
+		}
+		return (glyph + delta) & 0xFFFF
+	}
+	return 0
+}
+
+// advance возвращает ширину глифа в тысячных долях кегля
+func (f *trueTypeFont) advance(glyph int) int {
+	if glyph >= len(f.advances) {
+		glyph = len(f.advances) - 1
+	}
+	return f.advances[glyph] * 1000 / f.unitsPerEm
+}
+
+func (f *trueTypeFont) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// pdfFont — встроенный TrueType с кодировкой Identity-H или стандартный Helvetica в WinAnsi
+type pdfFont struct {
+	trueType *trueTypeFont
+	used     map[int]rune
+}
+
+func newPDFFont(fontData []byte) (*pdfFont, error) {
+	if len(fontData) == 0 {
+		return &pdfFont{}, nil
+	}
+	trueType, err := parseTrueType(fontData)
+	if err != nil {
+		return nil, err
+	}
+	return &pdfFont{trueType: trueType, used: make(map[int]rune)}, nil
+}
+
+// inWinAnsi сообщает, есть ли символ в Latin-1 части WinAnsi, которую покрывает Helvetica
+func inWinAnsi(r rune) bool {
+	return r >= 32 && r <= 126 || r >= 160 && r <= 255
+}
+
+// winAnsi возвращает байт символа для Helvetica; символы вне Latin-1 отсекает check
+func winAnsi(r rune) byte {
+	if inWinAnsi(r) {
+		return byte(r)
+	}
+	return '?'
+}
+
+// check возвращает ErrUnencodableText, если шрифт не может отобразить символ текста
+func (f *pdfFont) check(text string) error {
+	for _, r := range text {
+		if f.trueType != nil && f.trueType.glyph(r) == 0 || f.trueType == nil && !inWinAnsi(r) {
+			return fmt.Errorf("%w: %q", ErrUnencodableText, r)
+		}
+	}
+	return nil
+}
+
+// Width возвращает ширину текста в пунктах
+func (f *pdfFont) Width(text string, size float64) float64 {
+	total := 0
+	for _, r := range text {
+		if f.trueType != nil {
+			total += f.trueType.advance(f.trueType.glyph(r))
+			continue
+		}
+		if b := winAnsi(r); b <= 126 {
+			total += helveticaWidths[b-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// encode возвращает шестнадцатеричную строку PDF для оператора Tj
+func (f *pdfFont) encode(text string) string {
+	var hex strings.Builder
+	hex.WriteByte('<')
+	for _, r := range text {
+		if f.trueType != nil {
+			glyph := f.trueType.glyph(r)
+			f.used[glyph] = r
+			fmt.Fprintf(&hex, "%04X", glyph)
+		} else {
+			fmt.Fprintf(&hex, "%02X", winAnsi(r))
+		}
+	}
+	hex.WriteByte('>')
+	return hex.String()
+}
+
+// pdfDocument собирает объекты PDF; id объекта — его номер в списке, начиная с 1
+type pdfDocument struct {
+	objects [][]byte
+}
+
+func (d *pdfDocument) reserve() int {
+	d.objects = append(d.objects, nil)
+	return len(d.objects)
+}
+
+func (d *pdfDocument) set(id int, object string) {
+	d.objects[id-1] = []byte(object)
+}
+
+func (d *pdfDocument) add(object string) int {
+	id := d.reserve()
+	d.set(id, object)
+	return id
+}
+
+// addStream добавляет сжатый поток; extra — дополнительные ключи словаря
+func (d *pdfDocument) addStream(data []byte, extra string) (int, error) {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	if _, err := writer.Write(data); err != nil {
+		return 0, err
+	}
+	if err := writer.Close(); err != nil {
+		return 0, err
+	}
+	return d.add(fmt.Sprintf("<< /Length %d /Filter /FlateDecode%s >>\nstream\n%s\nendstream", compressed.Len(), extra, compressed.Bytes())), nil
+}
+
+func (d *pdfDocument) bytes(root int) []byte {
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(d.objects))
+	for i, object := range d.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, root, xref)
+	return out.Bytes()
+}
+
+func pdfNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// pdfText — строка текста на странице, X и Y — начало базовой линии
+type pdfText struct {
+	text string
+	size float64
+	x    float64
+	y    float64
+}
+
+// writePDF собирает одностраничный PDF с рамкой по краю страницы и строками текста
+func writePDF(width, height float64, border bool, font *pdfFont, texts []pdfText) ([]byte, error) {
+	var content strings.Builder
+	if border {
+		fmt.Fprintf(&content, "3 w 24 24 %s %s re S\n", pdfNumber(width-48), pdfNumber(height-48))
+		fmt.Fprintf(&content, "1 w 32 32 %s %s re S\n", pdfNumber(width-64), pdfNumber(height-64))
+	}
+	for _, text := range texts {
+		fmt.Fprintf(&content, "BT /F1 %s Tf %s %s Td %s Tj ET\n", pdfNumber(text.size), pdfNumber(text.x), pdfNumber(text.y), font.encode(text.text))
+	}
+
+	doc := &pdfDocument{}
+	catalog := doc.reserve()
+	pages := doc.reserve()
+	page := doc.reserve()
+
+	contents, err := doc.addStream([]byte(content.String()), "")
+	if err != nil {
+		return nil, err
+	}
+	fontId, err := font.write(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	doc.set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%d 0 R] /Count 1 >>", page))
+	doc.set(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+		pages, pdfNumber(width), pdfNumber(height), fontId, contents))
+
+	return doc.bytes(catalog), nil
+}
+
+// write добавляет объекты шрифта; для TrueType — после encode, чтобы знать использованные глифы
+func (f *pdfFont) write(doc *pdfDocument) (int, error) {
+	if f.trueType == nil {
+		return doc.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"), nil
+	}
+	font := f.trueType
+
+	fontFile, err := doc.addStream(font.data, fmt.Sprintf(" /Length1 %d", len(font.data)))
+	if err != nil {
+		return 0, err
+	}
+	descriptor := doc.add(fmt.Sprintf(
+		"<< /Type /FontDescriptor /FontName /CertificateFont /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		font.scale(font.bbox[0]), font.scale(font.bbox[1]), font.scale(font.bbox[2]), font.scale(font.bbox[3]),
+		font.scale(font.ascent), font.scale(font.descent), font.scale(font.ascent), fontFile))
+
+	glyphs := make([]int, 0, len(f.used))
+	for glyph := range f.used {
+		glyphs = append(glyphs, glyph)
+	}
+	slices.Sort(glyphs)
+
+	var widths, cmap strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", glyph, font.advance(glyph))
+	}
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(glyphs); start += 100 {
+		chunk := glyphs[start:min(start+100, len(glyphs))]
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(chunk))
+		for _, glyph := range chunk {
+			fmt.Fprintf(&cmap, "<%04X> <", glyph)
+			for _, unit := range utf16.Encode([]rune{f.used[glyph]}) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+
+	toUnicode, err := doc.addStream([]byte(cmap.String()), "")
+	if err != nil {
+		return 0, err
+	}
+	cidFont := doc.add(fmt.Sprintf(
+		"<< /Type /Font /Subtype /CIDFontType2 /BaseFont /CertificateFont /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		descriptor, widths.String()))
+	return doc.add(fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type0 /BaseFont /CertificateFont /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		cidFont, toUnicode)), nil
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// плеер может ускорять видео, поэтому за время между heartbeat позиция может уйти дальше реального времени
+	MaxPlaybackRate = 2.0
+	// запас на задержку сети между heartbeat
+	HeartbeatSlack = 5 * time.Second
+)
+
+// WatchedSegment возвращает отрезок, просмотренный между двумя heartbeat.
+// false — позиция ушла назад или дальше, чем можно досмотреть за прошедшее время: это перемотка.
+func WatchedSegment(lastPosition, position int, elapsed time.Duration) (int, int, bool) {
+	delta := position - lastPosition
+	if delta <= 0 {
+		return 0, 0, false
+	}
+
+	if delta > MaxWatchedSeconds(elapsed) {
+		return 0, 0, false
+	}
+
+	return lastPosition, position, true
+}
+
+// MaxWatchedSeconds — сколько секунд видео можно досмотреть за время elapsed на максимальной скорости.
+// Запас на задержку сети добавляется к реальному времени и скоростью не умножается.
+func MaxWatchedSeconds(elapsed time.Duration) int {
+	return int(elapsed.Seconds()*MaxPlaybackRate + HeartbeatSlack.Seconds())
+}
+
+// AddInterval добавляет отрезок [start, end) к списку и склеивает пересекающиеся и соседние отрезки
+func AddInterval(intervals [][2]int, start, end int) [][2]int {
+	if end <= start {
+		return intervals
+	}
+
+	all := append(append(make([][2]int, 0, len(intervals)+1), intervals...), [2]int{start, end})
+	sort.Slice(all, func(i, j int) bool { return all[i][0] < all[j][0] })
+
+	merged := make([][2]int, 0, len(all))
+	for _, interval := range all {
+		last := len(merged) - 1
+		if last >= 0 && interval[0] <= merged[last][1] {
+			merged[last][1] = max(merged[last][1], interval[1])
+			continue
+		}
+		merged = append(merged, interval)
+	}
+
+	return merged
+}
+
+// CoveredSeconds считает суммарную длину склеенных отрезков
+func CoveredSeconds(intervals [][2]int) int {
+	total := 0
+	for _, interval := range intervals {
+		total += interval[1] - interval[0]
+	}
+	return total
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatchedSegment(t *testing.T) {
+	tests := []struct {
+		name         string
+		lastPosition int
+		position     int
+		elapsed      time.Duration
+		wantStart    int
+		wantEnd      int
+		wantOk       bool
+	}{
+		{"normal playback", 100, 110, 10 * time.Second, 100, 110, true},
+		{"double speed", 100, 120, 10 * time.Second, 100, 120, true},
+		{"network slack on top of double speed", 100, 125, 10 * time.Second, 100, 125, true},
+		{"slack is not doubled", 100, 126, 10 * time.Second, 0, 0, false},
+		{"late first heartbeat within slack", 0, 5, 0, 0, 5, true},
+		{"seek forward", 100, 400, 10 * time.Second, 0, 0, false},
+		{"seek backward", 100, 50, 10 * time.Second, 0, 0, false},
+		{"paused", 100, 100, 10 * time.Second, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := WatchedSegment(tt.lastPosition, tt.position, tt.elapsed)
+			if start != tt.wantStart || end != tt.wantEnd || ok != tt.wantOk {
+				t.Errorf("WatchedSegment(%d, %d, %s) = %d, %d, %v, want %d, %d, %v",
+					tt.lastPosition, tt.position, tt.elapsed, start, end, ok, tt.wantStart, tt.wantEnd, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestMaxWatchedSeconds(t *testing.T) {
+	tests := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 5},
+		{10 * time.Second, 25},
+		{time.Hour, 7205},
+	}
+
+	for _, tt := range tests {
+		if got := MaxWatchedSeconds(tt.elapsed); got != tt.want {
+			t.Errorf("MaxWatchedSeconds(%s) = %d, want %d", tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestAddInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals [][2]int
+		start     int
+		end       int
+		want      [][2]int
+	}{
+		{"first interval", [][2]int{}, 0, 10, [][2]int{{0, 10}}},
+		{"empty interval is ignored", [][2]int{{0, 10}}, 20, 20, [][2]int{{0, 10}}},
+		{"reversed interval is ignored", [][2]int{{0, 10}}, 30, 20, [][2]int{{0, 10}}},
+		{"separate interval is kept sorted", [][2]int{{20, 30}}, 0, 10, [][2]int{{0, 10}, {20, 30}}},
+		{"adjacent intervals are merged", [][2]int{{0, 10}}, 10, 20, [][2]int{{0, 20}}},
+		{"overlapping intervals are merged", [][2]int{{0, 10}}, 5, 15, [][2]int{{0, 15}}},
+		{"rewatch inside interval", [][2]int{{0, 30}}, 5, 15, [][2]int{{0, 30}}},
+		{"bridge between intervals", [][2]int{{0, 10}, {20, 30}, {50, 60}}, 8, 22, [][2]int{{0, 30}, {50, 60}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := append(make([][2]int, 0, len(tt.intervals)), tt.intervals...)
+			got := AddInterval(tt.intervals, tt.start, tt.end)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AddInterval(%v, %d, %d) = %v, want %v", tt.intervals, tt.start, tt.end, got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.intervals, before) {
+				t.Errorf("AddInterval changed its input to %v", tt.intervals)
+			}
+			if covered, want := CoveredSeconds(got), CoveredSeconds(tt.want); covered != want {
+				t.Errorf("CoveredSeconds(%v) = %d, want %d", got, covered, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"fmt"
+	"go-EdTech/models"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QuizDeadlineGrace — запас после окончания времени квиза на задержку сети при отправке ответов
+const QuizDeadlineGrace = 30 * time.Second
+
+// NormalizeQuestion проверяет вопрос и заполняет недостающие id вариантов.
+// Поля ответа, не относящиеся к типу вопроса, сбрасываются.
+func NormalizeQuestion(question *models.Question) error {
+	question.Text = strings.TrimSpace(question.Text)
+	if question.Text == "" {
+		return fmt.Errorf("question text is required")
+	}
+	if question.Points <= 0 {
+		question.Points = 1
+	}
+
+	options := question.Options
+	answerBool, answerNumber, accepted := question.Answer_bool, question.Answer_number, question.Accepted_answers
+	question.Options, question.Answer_bool, question.Answer_number, question.Accepted_answers = nil, nil, nil, nil
+
+	switch question.Type {
+	case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+		if len(options) < 2 {
+			return fmt.Errorf("choice question needs at least two options")
+		}
+
+		seen := make(map[string]bool)
+		correct := 0
+		for i := range options {
+			options[i].Text = strings.TrimSpace(options[i].Text)
+			if options[i].Text == "" {
+				return fmt.Errorf("option %d has no text", i+1)
+			}
+			if options[i].Id == "" {
+				options[i].Id = strconv.Itoa(i + 1)
+			}
+			if seen[options[i].Id] {
+				return fmt.Errorf("duplicate option id %q", options[i].Id)
+			}
+			seen[options[i].Id] = true
+			if options[i].Correct {
+				correct++
+			}
+		}
+		if question.Type == models.QuestionSingleChoice && correct != 1 {
+			return fmt.Errorf("single choice question needs exactly one correct option")
+		}
+		if correct == 0 {
+			return fmt.Errorf("multiple choice question needs at least one correct option")
+		}
+		question.Options = options
+	case models.QuestionTrueFalse:
+		if answerBool == nil {
+			return fmt.Errorf("answer_bool is required")
+		}
+		question.Answer_bool = answerBool
+	case models.QuestionNumeric:
+		if answerNumber == nil {
+			return fmt.Errorf("answer_number is required")
+		}
+		if question.Tolerance < 0 {
+			return fmt.Errorf("tolerance can not be negative")
+		}
+		question.Answer_number = answerNumber
+	case models.QuestionShortAnswer:
+		for _, answer := range accepted {
+			if normalizeShortAnswer(answer) != "" {
+				question.Accepted_answers = append(question.Accepted_answers, strings.TrimSpace(answer))
+			}
+		}
+		if len(question.Accepted_answers) == 0 {
+			return fmt.Errorf("accepted_answers is required")
+		}
+	default:
+		return fmt.Errorf("question type must be one of %s", strings.Join(models.QuestionTypes, ", "))
+	}
+
+	if question.Type != models.QuestionNumeric {
+		question.Tolerance = 0
+	}
+	return nil
+}
+
+func normalizeShortAnswer(answer string) string {
+	return strings.ToLower(strings.Join(strings.Fields(answer), " "))
+}
+
+// GradeAnswer оценивает ответ на вопрос. В multiple_choice за каждый неверно выбранный вариант
+// снимается один верный, поэтому частичный балл возможен, а выбор всех вариантов ничего не дает.
+func GradeAnswer(question models.Question, answer models.QuizAnswer) models.QuestionResult {
+	result := models.QuestionResult{Question_id: question.Id, Points: question.Points}
+
+	switch question.Type {
+	case models.QuestionSingleChoice:
+		if len(answer.Option_ids) == 1 {
+			for _, option := range question.Options {
+				if option.Id == answer.Option_ids[0] {
+					result.Correct = option.Correct
+				}
+			}
+		}
+	case models.QuestionMultipleChoice:
+		right, wrong, total := 0, 0, 0
+		for _, option := range question.Options {
+			selected := slices.Contains(answer.Option_ids, option.Id)
+			switch {
+			case option.Correct && selected:
+				right++
+			case !option.Correct && selected:
+				wrong++
+			}
+			if option.Correct {
+				total++
+			}
+		}
+		result.Correct = right == total && wrong == 0
+		if !result.Correct && total > 0 {
+			result.Earned = float64(question.Points) * math.Max(0, float64(right-wrong)/float64(total))
+		}
+	case models.QuestionTrueFalse:
+		result.Correct = answer.Bool != nil && question.Answer_bool != nil && *answer.Bool == *question.Answer_bool
+	case models.QuestionNumeric:
+		result.Correct = answer.Number != nil && question.Answer_number != nil &&
+			math.Abs(*answer.Number-*question.Answer_number) <= question.Tolerance
+	case models.QuestionShortAnswer:
+		given := normalizeShortAnswer(answer.Text)
+		for _, accepted := range question.Accepted_answers {
+			if given != "" && given == normalizeShortAnswer(accepted) {
+				result.Correct = true
+			}
+		}
+	}
+
+	if result.Correct {
+		result.Earned = float64(question.Points)
+		result.Feedback = question.Feedback_correct
+	} else {
+		result.Feedback = question.Feedback_incorrect
+	}
+	return result
+}
+
+// ShuffledIds возвращает перемешанную копию, исходный порядок не меняется
+func ShuffledIds[T any](ids []T) []T {
+	shuffled := slices.Clone(ids)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	return shuffled
+}
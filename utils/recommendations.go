@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"go-EdTech/models"
+	"math"
+	"sort"
+	"strings"
+)
+
+// веса сигналов рекомендаций
+const (
+	recommendInterestWeight   = 3.0
+	recommendSimilarityWeight = 4.0
+	recommendAgeWeight        = 1.5
+	recommendLevelWeight      = 1.0
+	recommendSubjectWeight    = 1.0
+	recommendPopularityWeight = 0.5
+)
+
+// MaxLearnerInterests — сколько интересов можно указать в профиле
+const MaxLearnerInterests = 20
+
+// NormalizeInterests убирает пустые и повторяющиеся без учета регистра интересы, сохраняя порядок
+func NormalizeInterests(interests []string) []string {
+	result := make([]string, 0, len(interests))
+	seen := make(map[string]bool)
+	for _, interest := range interests {
+		interest = strings.TrimSpace(interest)
+		key := strings.ToLower(interest)
+		if interest == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, interest)
+	}
+	return result
+}
+
+// RankRecommendations оценивает кандидатов по профилю ученика и возвращает limit лучших.
+// Уроки, не подходящие ученику по возрасту, отбрасываются.
+func RankRecommendations(profile models.LearnerProfile, candidates []models.RecommendationCandidate, limit int) []models.Recommendation {
+	interests := make(map[string]string)
+	for _, interest := range profile.Interests {
+		interests[strings.ToLower(interest)] = interest
+	}
+
+	maxLearners := 0
+	for _, candidate := range candidates {
+		maxLearners = max(maxLearners, candidate.Learners)
+	}
+
+	recommendations := make([]models.Recommendation, 0)
+	for _, candidate := range candidates {
+		var score float64
+		reasons := make([]string, 0)
+
+		if profile.Age != nil {
+			age := *profile.Age
+			if (candidate.Target_age_min > 0 && age < candidate.Target_age_min) || (candidate.Target_age_max > 0 && age > candidate.Target_age_max) {
+				continue
+			}
+			if candidate.Target_age_min > 0 || candidate.Target_age_max > 0 {
+				score += recommendAgeWeight
+				reasons = append(reasons, fmt.Sprintf("suitable for age %d", age))
+			}
+		}
+
+		if interest, ok := interests[strings.ToLower(strings.TrimSpace(candidate.Interest))]; ok {
+			score += recommendInterestWeight
+			reasons = append(reasons, fmt.Sprintf("matches your interest in %s", interest))
+		}
+
+		if candidate.Similarity > 0 {
+			// сходство складывается по всем пройденным урокам, поэтому сглаживается
+			score += recommendSimilarityWeight * (1 - math.Exp(-candidate.Similarity))
+			reasons = append(reasons, fmt.Sprintf("learners who completed %q also completed this lesson", candidate.Similar_lesson))
+		}
+
+		if profile.Level != "" && strings.EqualFold(candidate.Level, profile.Level) {
+			score += recommendLevelWeight
+			reasons = append(reasons, fmt.Sprintf("%s level", candidate.Level))
+		}
+
+		if candidate.Subject_completed > 0 {
+			score += recommendSubjectWeight * math.Min(1, float64(candidate.Subject_completed)/5)
+			reasons = append(reasons, "continues a subject you are studying")
+		}
+
+		if maxLearners > 0 && candidate.Learners > 0 {
+			score += recommendPopularityWeight * math.Log1p(float64(candidate.Learners)) / math.Log1p(float64(maxLearners))
+			if len(reasons) == 0 {
+				reasons = append(reasons, "popular with other learners")
+			}
+		}
+
+		if score <= 0 {
+			continue
+		}
+
+		explanation := strings.Join(reasons, "; ")
+		explanation = strings.ToUpper(explanation[:1]) + explanation[1:]
+		recommendations = append(recommendations, models.Recommendation{
+			Lesson_id:   candidate.Lesson_id,
+			Title:       candidate.Title,
+			Subject_id:  candidate.Subject_id,
+			Level:       candidate.Level,
+			Score:       math.Round(score*100) / 100,
+			Reasons:     reasons,
+			Explanation: explanation,
+		})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].Lesson_id < recommendations[j].Lesson_id
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// лимиты размера для вложений урока в зависимости от типа файла
+var resourceSizeLimits = map[string]int64{
+	".pdf":  20 << 20,
+	".doc":  20 << 20,
+	".docx": 20 << 20,
+	".odt":  20 << 20,
+	".txt":  5 << 20,
+	".ppt":  50 << 20,
+	".pptx": 50 << 20,
+	".odp":  50 << 20,
+	".xls":  20 << 20,
+	".xlsx": 20 << 20,
+	".ods":  20 << 20,
+	".csv":  5 << 20,
+	".png":  10 << 20,
+	".jpg":  10 << 20,
+	".jpeg": 10 << 20,
+	".gif":  10 << 20,
+	".mp3":  50 << 20,
+	".zip":  100 << 20,
+}
+
+// ResourceSizeLimit возвращает максимальный размер для файла и false, если тип не разрешен
+func ResourceSizeLimit(filename string) (int64, bool) {
+	limit, ok := resourceSizeLimits[strings.ToLower(filepath.Ext(filename))]
+	return limit, ok
+}
@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"bytes"
+	"html"
+	"net/url"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// разрешенные теги и их атрибуты; все остальное вырезается
+var allowedTags = map[string][]string{
+	"p": nil, "br": nil, "hr": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"strong": nil, "b": nil, "em": nil, "i": nil, "u": nil, "del": nil, "s": nil,
+	"sup": nil, "sub": nil, "mark": nil, "small": nil,
+	"blockquote": nil, "pre": nil, "code": {"class"},
+	"ul": nil, "ol": {"start"}, "li": nil,
+	"a":   {"href", "title"},
+	"img": {"src", "alt", "title", "width", "height"},
+	"span": {"class"}, "div": {"class"},
+	"table": nil, "thead": nil, "tbody": nil, "tr": nil,
+	"th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+}
+
+// содержимое этих тегов удаляется вместе с ними. embed сюда не входит: он пустой и без закрывающего тега
+// пропустил бы весь текст после себя, а как неразрешенный тег он и так вырезается.
+var droppedContentTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"noscript": true, "template": true, "textarea": true,
+}
+
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// lastDroppedCloseTags возвращает для тегов из droppedContentTags позицию последнего закрывающего тега, -1 — если его нет
+func lastDroppedCloseTags(input string) map[string]int {
+	lower := []byte(input)
+	for i, ch := range lower {
+		if ch >= 'A' && ch <= 'Z' {
+			lower[i] = ch + 'a' - 'A'
+		}
+	}
+
+	positions := make(map[string]int, len(droppedContentTags))
+	for tag := range droppedContentTags {
+		positions[tag] = -1
+		closing := []byte("</" + tag)
+		for end := len(lower); end > 0; {
+			i := bytes.LastIndex(lower[:end], closing)
+			if i < 0 {
+				break
+			}
+			// </scriptx не закрывает script
+			if next := i + len(closing); next == len(lower) || bytes.IndexByte([]byte(" \t\n\r\f/>"), lower[next]) >= 0 {
+				positions[tag] = i
+				break
+			}
+			end = i
+		}
+	}
+	return positions
+}
+
+// SanitizeHTML оставляет только теги и атрибуты из allowlist и безопасные URL
+func SanitizeHTML(input string) string {
+	var b strings.Builder
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(input))
+	lastClose := lastDroppedCloseTags(input)
+
+	open := make([]string, 0)
+	skipDepth := 0
+	offset := 0
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == xhtml.ErrorToken {
+			// io.EOF или битый HTML — в обоих случаях просто закрываем то, что открыли
+			break
+		}
+		offset += len(tokenizer.Raw())
+
+		token := tokenizer.Token()
+
+		switch tokenType {
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			if droppedContentTags[token.Data] {
+				if tokenType != xhtml.StartTagToken {
+					continue
+				}
+				// без закрывающего тега дальше него удалилось бы все тело: вырезаем только сам тег,
+				// а следующий текст разбираем как обычный HTML
+				if lastClose[token.Data] < offset {
+					tokenizer.NextIsNotRawText()
+					continue
+				}
+				skipDepth++
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+			attrs, ok := allowedTags[token.Data]
+			if !ok {
+				continue
+			}
+
+			b.WriteString("<" + token.Data)
+			for _, attr := range token.Attr {
+				value, ok := sanitizeAttribute(token.Data, attr, attrs)
+				if ok {
+					b.WriteString(" " + attr.Key + `="` + html.EscapeString(value) + `"`)
+				}
+			}
+			if token.Data == "a" {
+				b.WriteString(` rel="nofollow noopener noreferrer"`)
+			}
+			b.WriteString(">")
+
+			if !voidTags[token.Data] && tokenType == xhtml.StartTagToken {
+				open = append(open, token.Data)
+			}
+
+		case xhtml.EndTagToken:
+			if droppedContentTags[token.Data] {
+				if skipDepth > 0 {
+					skipDepth--
+				}
+				continue
+			}
+			if skipDepth > 0 {
+				continue
+			}
+
+			// закрываем только открытые нами теги, попутно закрывая незакрытые вложенные
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == token.Data {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+
+		case xhtml.TextToken:
+			if skipDepth == 0 {
+				b.WriteString(html.EscapeString(token.Data))
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+
+	return b.String()
+}
+
+func sanitizeAttribute(tag string, attr xhtml.Attribute, allowed []string) (string, bool) {
+	if attr.Namespace != "" {
+		return "", false
+	}
+
+	found := false
+	for _, key := range allowed {
+		if key == attr.Key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", false
+	}
+
+	switch attr.Key {
+	case "href", "src":
+		return sanitizeURL(attr.Val, tag == "a")
+	case "class":
+		return sanitizeClass(attr.Val)
+	case "start", "width", "height", "colspan", "rowspan":
+		for _, r := range attr.Val {
+			if r < '0' || r > '9' {
+				return "", false
+			}
+		}
+		return attr.Val, attr.Val != ""
+	default:
+		return attr.Val, true
+	}
+}
+
+func sanitizeURL(raw string, allowMailto bool) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "":
+		// относительные ссылки без схемы, например /media/42 или #section
+		return parsed.String(), true
+	case "http", "https":
+		return parsed.String(), true
+	case "mailto":
+		return parsed.String(), allowMailto
+	default:
+		return "", false
+	}
+}
+
+// оставляем только классы, которые нужны KaTeX и подсветке синтаксиса
+func sanitizeClass(value string) (string, bool) {
+	classes := make([]string, 0)
+	for _, class := range strings.Fields(value) {
+		if class == "math" || class == "math-inline" || class == "math-display" || strings.HasPrefix(class, "language-") {
+			classes = append(classes, class)
+		}
+	}
+	return strings.Join(classes, " "), len(classes) > 0
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"allowed markup", `<p>Hi <strong>there</strong></p>`, `<p>Hi <strong>there</strong></p>`},
+		{"script dropped with content", `<p>a</p><script>alert(1)</script><p>b</p>`, `<p>a</p><p>b</p>`},
+		{"iframe dropped with content", `<iframe src="https://evil.example">x</iframe>ok`, `ok`},
+		{"style dropped with content", `<style>p{color:red}</style>ok`, `ok`},
+		{"nested dropped tags", `<object><iframe>x</iframe><p>in</p></object>ok`, `ok`},
+		{"embed removed", `<embed src="https://evil.example/x.swf"><p>after</p>`, `<p>after</p>`},
+		{"unknown tag keeps text", `<svg><g>text</g></svg>`, `text`},
+		{"event handler removed", `<img src="/media/1" onerror="alert(1)">`, `<img src="/media/1">`},
+		{"event handler on link", `<a href="https://a.example" onclick="x()">a</a>`, `<a href="https://a.example" rel="nofollow noopener noreferrer">a</a>`},
+		{"style attribute removed", `<p style="background:url(javascript:x)">p</p>`, `<p>p</p>`},
+		{"javascript url", `<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript url mixed case", `<a href=" JaVaScRiPt:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript url with entities", `<a href="javascript&colon;alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript url with tab", `<a href="java&#9;script:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"data url image", `<img src="data:image/svg+xml;base64,PHN2Zz4=">`, `<img>`},
+		{"mailto only for links", `<a href="mailto:a@b.c">m</a><img src="mailto:a@b.c">`, `<a href="mailto:a@b.c" rel="nofollow noopener noreferrer">m</a><img>`},
+		{"relative url kept", `<a href="#part">p</a>`, `<a href="#part" rel="nofollow noopener noreferrer">p</a>`},
+		{"rel cannot be overridden", `<a href="/x" rel="opener">x</a>`, `<a href="/x" rel="nofollow noopener noreferrer">x</a>`},
+		{"class allowlist", `<span class="math evil math-inline">x</span>`, `<span class="math math-inline">x</span>`},
+		{"numeric attributes", `<ol start="3x"><li>a</li></ol>`, `<ol><li>a</li></ol>`},
+		{"text escaped", `<p>1 &lt; 2 &amp; "q"</p>`, `<p>1 &lt; 2 &amp; &#34;q&#34;</p>`},
+		{"attribute breakout escaped", `<img alt='"><script>alert(1)</script>'>`, `<img alt="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">`},
+		{"noscript mutation", `<noscript><p title="</noscript><img src=x onerror=alert(1)>">`, `<img src="x">&#34;&gt;`},
+		{"unclosed script keeps the rest", `<p>a</p><script>alert(1)<p>b</p>`, `<p>a</p>alert(1)<p>b</p>`},
+		{"unclosed iframe keeps the rest", `<iframe src="https://evil.example"><p>b</p>`, `<p>b</p>`},
+		{"script closed later drops content", `<script>a<p>b</p></script>c`, `c`},
+		{"second script unclosed", `<script>a</script><script>b<p>c</p>`, `b<p>c</p>`},
+		{"similar end tag does not close", `<script>a</scriptx><p>b</p>`, `a<p>b</p>`},
+		{"unclosed tags closed", `<ul><li><em>a`, `<ul><li><em>a</em></li></ul>`},
+		{"stray end tag ignored", `</div><p>a</p></b>`, `<p>a</p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.input); got != tt.want {
+				t.Errorf("SanitizeHTML(%q)\n got: %s\nwant: %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeHTMLDeepNesting(t *testing.T) {
+	input := strings.Repeat("<div>", 10000) + "x" + strings.Repeat("</div>", 10000)
+
+	got := SanitizeHTML(input)
+	if strings.Count(got, "<div>") != strings.Count(got, "</div>") {
+		t.Fatalf("unbalanced output for deeply nested input")
+	}
+}
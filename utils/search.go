@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// маркеры подсветки из ts_headline; заменяются на <mark> после экранирования текста
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+// BuildPrefixQuery превращает пользовательский ввод в tsquery, где каждое слово ищется по префиксу:
+// "линейные урав" -> "линейные:* & урав:*". Операторы tsquery из ввода не пропускаются.
+func BuildPrefixQuery(input string) string {
+	terms := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// HighlightSnippet экранирует фрагмент из ts_headline и размечает совпадения тегом <mark>
+func HighlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, HighlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, HighlightStop, "</mark>")
+}
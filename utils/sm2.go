@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"go-EdTech/models"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	FlashcardDefaultEase = 2.5
+	FlashcardMinEase     = 1.3
+	// карточка с интервалом от стольких дней считается выученной
+	FlashcardMatureInterval = 21
+)
+
+// NewFlashcardState — состояние карточки, которую ученик еще не повторял
+func NewFlashcardState(userUUID uuid.UUID, cardId int, now time.Time) models.FlashcardState {
+	return models.FlashcardState{User_uuid: userUUID, Card_id: cardId, Ease: FlashcardDefaultEase, Due_at: now}
+}
+
+// ScheduleReview применяет оценку по алгоритму SM-2 и назначает следующее повторение.
+// Оценка ниже ReviewGradePass сбрасывает серию и возвращает карточку на завтра.
+func ScheduleReview(state models.FlashcardState, grade int, now time.Time) models.FlashcardState {
+	if grade >= models.ReviewGradePass {
+		switch state.Repetitions {
+		case 0:
+			state.Interval_days = 1
+		case 1:
+			state.Interval_days = 6
+		default:
+			state.Interval_days = int(math.Round(float64(state.Interval_days) * state.Ease))
+		}
+		state.Repetitions++
+	} else {
+		if state.Repetitions > 0 {
+			state.Lapses++
+		}
+		state.Repetitions = 0
+		state.Interval_days = 1
+	}
+
+	q := float64(models.ReviewGradeMax - grade)
+	state.Ease = math.Max(FlashcardMinEase, state.Ease+0.1-q*(0.08+q*0.02))
+
+	state.Due_at = now.AddDate(0, 0, state.Interval_days)
+	state.Last_reviewed_at = &now
+	return state
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCSVSafeRow(t *testing.T) {
+	tests := []struct {
+		name string
+		row  []string
+		want []string
+	}{
+		{"plain values", []string{"Anna Smith", "87.5", "B"}, []string{"Anna Smith", "87.5", "B"}},
+		{"empty cell", []string{""}, []string{""}},
+		{"formula", []string{"=HYPERLINK(\"http://evil\")"}, []string{"'=HYPERLINK(\"http://evil\")"}},
+		{"plus", []string{"+1+cmd|' /C calc'!A0"}, []string{"'+1+cmd|' /C calc'!A0"}},
+		{"minus", []string{"-2+3"}, []string{"'-2+3"}},
+		{"at", []string{"@SUM(A1:A2)"}, []string{"'@SUM(A1:A2)"}},
+		{"tab", []string{"\t=1"}, []string{"'\t=1"}},
+		{"carriage return", []string{"\r=1"}, []string{"'\r=1"}},
+		{"sign inside value", []string{"Anna = Smith"}, []string{"Anna = Smith"}},
+		{"cyrillic name", []string{"Анна Смирнова"}, []string{"Анна Смирнова"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CSVSafeRow(tt.row); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CSVSafeRow(%q) = %q, want %q", tt.row, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"go-EdTech/models"
+	"strings"
+	"unicode"
+)
+
+// NormalizeLevel приводит уровень к каноническому виду без учета регистра
+func NormalizeLevel(level string) (string, bool) {
+	for _, known := range models.Levels {
+		if strings.EqualFold(strings.TrimSpace(level), known) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// Slugify делает slug из названия тега: буквы и цифры в нижнем регистре, остальное заменяется дефисом.
+// Кириллица сохраняется, чтобы русские и казахские теги оставались читаемыми.
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// ParseList разбирает значение query-параметра вида "a,b,c", пустые элементы пропускаются
+func ParseList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
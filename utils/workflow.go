@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"go-EdTech/models"
+	"slices"
+	"time"
+)
+
+var (
+	ErrInvalidTransition   = errors.New("invalid status transition")
+	ErrTransitionForbidden = errors.New("status transition is not allowed for the role")
+)
+
+// contentTransitions описывает допустимые переходы статусов и роли, которым они разрешены
+var contentTransitions = map[string]map[string][]string{
+	models.StatusDraft: {
+		models.StatusInReview: {models.RoleAdmin, models.RoleTeacher, models.RoleContentLead},
+	},
+	models.StatusInReview: {
+		models.StatusPublished: {models.RoleAdmin, models.RoleContentLead},
+		models.StatusDraft:     {models.RoleAdmin, models.RoleTeacher, models.RoleContentLead},
+	},
+	models.StatusPublished: {
+		models.StatusArchived: {models.RoleAdmin, models.RoleContentLead},
+	},
+	models.StatusArchived: {
+		models.StatusDraft: {models.RoleAdmin, models.RoleTeacher, models.RoleContentLead},
+	},
+}
+
+// ValidateTransition проверяет, что пользователь с ролью role может перевести контент из from в to
+func ValidateTransition(from, to string, role string) error {
+	targets, ok := contentTransitions[from]
+	if !ok {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidTransition, from)
+	}
+
+	roles, ok := targets[to]
+	if !ok {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+
+	if !slices.Contains(roles, role) {
+		return fmt.Errorf("%w: %s cannot move content from %s to %s", ErrTransitionForbidden, role, from, to)
+	}
+
+	return nil
+}
+
+// IsWithinPublicationWindow повторяет в Go условие видимости из репозиториев
+func IsWithinPublicationWindow(isPublished bool, publishAt *time.Time, unpublishAt *time.Time, now time.Time) bool {
+	if !isPublished {
+		return false
+	}
+	if publishAt != nil && publishAt.After(now) {
+		return false
+	}
+	if unpublishAt != nil && !unpublishAt.After(now) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-EdTech/config"
+	"go-EdTech/models"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// XAPIUserAgent — агент пользователя платформы: account с uuid на домашней странице XAPI_HOMEPAGE
+func XAPIUserAgent(userUUID uuid.UUID) models.XAPIAgent {
+	return models.XAPIAgent{
+		Object_type: "Agent",
+		Account:     &models.XAPIAccount{Home_page: config.Config.XAPIHomePage, Name: userUUID.String()},
+	}
+}
+
+// XAPIPlatformAgent — authority утверждений, которые сервер формирует сам
+func XAPIPlatformAgent() models.XAPIAgent {
+	return models.XAPIAgent{
+		Object_type: "Agent",
+		Name:        "EdTech",
+		Account:     &models.XAPIAccount{Home_page: config.Config.XAPIHomePage, Name: "platform"},
+	}
+}
+
+// XAPIActivityId возвращает IRI активности платформы, например https://host/lessons/5
+func XAPIActivityId(kind string, id int) string {
+	return strings.TrimRight(config.Config.XAPIHomePage, "/") + "/" + kind + "/" + strconv.Itoa(id)
+}
+
+// XAPIActivity собирает объект-активность с названием
+func XAPIActivity(kind string, id int, activityType string, title string) models.XAPIObject {
+	return models.XAPIObject{
+		Object_type: "Activity",
+		Id:          XAPIActivityId(kind, id),
+		Definition:  &models.XAPIActivityDefinition{Name: map[string]string{"en-US": title}, Type: activityType},
+	}
+}
+
+// NewXAPIStatement собирает утверждение о действии пользователя; display глагола — последняя часть IRI
+func NewXAPIStatement(userUUID uuid.UUID, verb string, object models.XAPIObject, result *models.XAPIResult) models.XAPIStatement {
+	return models.XAPIStatement{
+		Actor:  XAPIUserAgent(userUUID),
+		Verb:   models.XAPIVerb{Id: verb, Display: map[string]string{"en-US": verb[strings.LastIndex(verb, "/")+1:]}},
+		Object: object,
+		Result: result,
+	}
+}
+
+// XAPIProgressExtension — расширение результата cmi5 с процентом прохождения
+const XAPIProgressExtension = "https://w3id.org/xapi/cmi5/result/extensions/progress"
+
+// XAPIAnswerResult собирает результат ответа на вопрос квиза в формате ответов cmi.interaction
+func XAPIAnswerResult(answer models.QuizAnswer, result models.QuestionResult) *models.XAPIResult {
+	response := answer.Text
+	switch {
+	case len(answer.Option_ids) > 0:
+		response = strings.Join(answer.Option_ids, "[,]")
+	case answer.Bool != nil:
+		response = strconv.FormatBool(*answer.Bool)
+	case answer.Number != nil:
+		response = strconv.FormatFloat(*answer.Number, 'f', -1, 64)
+	}
+
+	raw, maxScore, minScore := result.Earned, float64(result.Points), 0.0
+	score := &models.XAPIScore{Raw: &raw, Min: &minScore, Max: &maxScore}
+	if maxScore > 0 {
+		scaled := raw / maxScore
+		score.Scaled = &scaled
+	}
+	success := result.Correct
+	return &models.XAPIResult{Score: score, Success: &success, Response: response}
+}
+
+// XAPIActorKey возвращает единственный идентификатор агента в виде строки для поиска
+func XAPIActorKey(agent models.XAPIAgent) (string, error) {
+	keys := make([]string, 0, 1)
+	if agent.Mbox != "" {
+		if !strings.HasPrefix(agent.Mbox, "mailto:") {
+			return "", fmt.Errorf("mbox must be a mailto IRI")
+		}
+		keys = append(keys, "mbox:"+strings.ToLower(agent.Mbox))
+	}
+	if agent.Mbox_sha1sum != "" {
+		keys = append(keys, "mbox_sha1sum:"+strings.ToLower(agent.Mbox_sha1sum))
+	}
+	if agent.Openid != "" {
+		keys = append(keys, "openid:"+agent.Openid)
+	}
+	if agent.Account != nil {
+		if !isAbsoluteIRI(agent.Account.Home_page) || agent.Account.Name == "" {
+			return "", fmt.Errorf("account needs homePage IRI and name")
+		}
+		keys = append(keys, "account:"+agent.Account.Home_page+"|"+agent.Account.Name)
+	}
+	if len(keys) != 1 {
+		return "", fmt.Errorf("agent must have exactly one of mbox, mbox_sha1sum, openid or account")
+	}
+	return keys[0], nil
+}
+
+func isAbsoluteIRI(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Scheme != ""
+}
+
+// PrepareXAPIStatement проверяет утверждение и дополняет его id, stored, authority, timestamp и version.
+// statementId — id из PUT ?statementId, пусто для POST.
+func PrepareXAPIStatement(raw json.RawMessage, statementId string, authority models.XAPIAgent, userUUID *uuid.UUID, now time.Time) (models.XAPIRecord, error) {
+	var statement models.XAPIStatement
+	if err := json.Unmarshal(raw, &statement); err != nil {
+		return models.XAPIRecord{}, fmt.Errorf("invalid statement: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return models.XAPIRecord{}, fmt.Errorf("statement must be an object")
+	}
+
+	if statementId != "" {
+		if statement.Id != "" && !strings.EqualFold(statement.Id, statementId) {
+			return models.XAPIRecord{}, fmt.Errorf("statement id does not match statementId")
+		}
+		statement.Id = statementId
+	}
+	id := uuid.New()
+	if statement.Id != "" {
+		parsed, err := uuid.Parse(statement.Id)
+		if err != nil {
+			return models.XAPIRecord{}, fmt.Errorf("statement id must be a UUID")
+		}
+		id = parsed
+	}
+
+	record := models.XAPIRecord{Id: id, User_uuid: userUUID, Stored: now, Timestamp: now}
+
+	if statement.Actor.Object_type != "Group" {
+		key, err := XAPIActorKey(statement.Actor)
+		if err != nil {
+			return models.XAPIRecord{}, fmt.Errorf("actor: %w", err)
+		}
+		record.Actor_key = key
+	} else if key, err := XAPIActorKey(statement.Actor); err == nil {
+		record.Actor_key = key
+	}
+
+	if !isAbsoluteIRI(statement.Verb.Id) {
+		return models.XAPIRecord{}, fmt.Errorf("verb id must be an IRI")
+	}
+	record.Verb_id = statement.Verb.Id
+
+	record.Object_type = statement.Object.Object_type
+	switch statement.Object.Object_type {
+	case "", "Activity":
+		if !isAbsoluteIRI(statement.Object.Id) {
+			return models.XAPIRecord{}, fmt.Errorf("activity id must be an IRI")
+		}
+		record.Object_type = "Activity"
+		record.Object_id = statement.Object.Id
+	case "StatementRef":
+		if _, err := uuid.Parse(statement.Object.Id); err != nil {
+			return models.XAPIRecord{}, fmt.Errorf("statement reference id must be a UUID")
+		}
+		record.Object_id = strings.ToLower(statement.Object.Id)
+	case "Agent", "Group", "SubStatement":
+	default:
+		return models.XAPIRecord{}, fmt.Errorf("unknown object type %q", statement.Object.Object_type)
+	}
+	if record.Verb_id == models.XAPIVerbVoided && record.Object_type != "StatementRef" {
+		return models.XAPIRecord{}, fmt.Errorf("voiding statement must reference a statement")
+	}
+
+	if result := statement.Result; result != nil && result.Score != nil && result.Score.Scaled != nil {
+		if *result.Score.Scaled < -1 || *result.Score.Scaled > 1 {
+			return models.XAPIRecord{}, fmt.Errorf("score scaled must be between -1 and 1")
+		}
+	}
+	if statement.Timestamp != nil {
+		record.Timestamp = *statement.Timestamp
+	}
+
+	set := func(key string, value any) error {
+		data, err := json.Marshal(value)
+		fields[key] = data
+		return err
+	}
+	if err := set("id", id.String()); err != nil {
+		return models.XAPIRecord{}, err
+	}
+	if err := set("stored", now.UTC().Format(time.RFC3339Nano)); err != nil {
+		return models.XAPIRecord{}, err
+	}
+	if err := set("authority", authority); err != nil {
+		return models.XAPIRecord{}, err
+	}
+	if statement.Timestamp == nil {
+		if err := set("timestamp", now.UTC().Format(time.RFC3339Nano)); err != nil {
+			return models.XAPIRecord{}, err
+		}
+	}
+	if statement.Version == "" {
+		if err := set("version", models.XAPIVersion); err != nil {
+			return models.XAPIRecord{}, err
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return models.XAPIRecord{}, err
+	}
+	record.Statement = data
+	return record, nil
+}